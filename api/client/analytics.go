@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/requests"
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+)
+
+// GetCoverageGaps returns the cells of the polygon that are farther than the threshold from every
+// sucursal.
+func (client *Client) GetCoverageGaps(ctx context.Context, coverage *requests.CoverageGaps) (*responses.CoverageGapsResponse, error) {
+	gaps := &responses.CoverageGapsResponse{}
+	return gaps, client.doJSON(ctx, http.MethodPost, "/analytics/coverage-gaps", nil, coverage, gaps)
+}
+
+// GetCatchments returns the area closer to each sucursal than to any other.
+func (client *Client) GetCatchments(ctx context.Context, catchments *requests.Catchments) (*responses.CatchmentsResponse, error) {
+	result := &responses.CatchmentsResponse{}
+	return result, client.doJSON(ctx, http.MethodPost, "/analytics/catchments", nil, catchments, result)
+}
+
+// DemandOptions selects the recorded searches of the demand analytics. Zero fields keep the
+// defaults of the API.
+type DemandOptions struct {
+	From time.Time
+	To   time.Time
+	// Size of the heatmap cells, only used by GetDemandHeatmap.
+	CellDegrees float64
+}
+
+func (options *DemandOptions) query() url.Values {
+	query := url.Values{}
+	if options == nil {
+		return query
+	}
+	if !options.From.IsZero() {
+		query.Set("from", options.From.Format(time.RFC3339))
+	}
+	if !options.To.IsZero() {
+		query.Set("to", options.To.Format(time.RFC3339))
+	}
+	if options.CellDegrees > 0 {
+		query.Set("cellDegrees", strconv.FormatFloat(options.CellDegrees, 'f', -1, 64))
+	}
+	return query
+}
+
+// GetDemandHeatmap returns how many searches were made in each cell of a grid.
+func (client *Client) GetDemandHeatmap(ctx context.Context, options *DemandOptions) (*responses.DemandHeatmapResponse, error) {
+	heatmap := &responses.DemandHeatmapResponse{}
+	return heatmap, client.doJSON(ctx, http.MethodGet, "/analytics/demand/heatmap", options.query(), nil, heatmap)
+}
+
+// GetDemandBySucursal returns how many searches each sucursal was the closest to.
+func (client *Client) GetDemandBySucursal(ctx context.Context, options *DemandOptions) (*responses.SucursalDemandResponse, error) {
+	demand := &responses.SucursalDemandResponse{}
+	return demand, client.doJSON(ctx, http.MethodGet, "/analytics/demand/sucursales", options.query(), nil, demand)
+}
+
+// GetDemandStatus returns the status of the demand recorder.
+func (client *Client) GetDemandStatus(ctx context.Context) (*responses.DemandStatusResponse, error) {
+	status := &responses.DemandStatusResponse{}
+	return status, client.doJSON(ctx, http.MethodGet, "/analytics/demand/status", nil, nil, status)
+}
+
+// GetSiteSelection proposes sites for new sucursales that serve the most demand.
+func (client *Client) GetSiteSelection(ctx context.Context, selection *requests.SiteSelection) (*responses.SiteSelectionResponse, error) {
+	sites := &responses.SiteSelectionResponse{}
+	return sites, client.doJSON(ctx, http.MethodPost, "/analytics/site-selection", nil, selection, sites)
+}
+
+// GetClosureSimulation simulates closing and opening sucursales.
+func (client *Client) GetClosureSimulation(ctx context.Context, simulation *requests.ClosureSimulation) (*responses.ClosureSimulationResponse, error) {
+	result := &responses.ClosureSimulationResponse{}
+	return result, client.doJSON(ctx, http.MethodPost, "/analytics/closure-simulation", nil, simulation, result)
+}
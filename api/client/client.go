@@ -0,0 +1,236 @@
+// Package client is a typed client of the sucursal API, using the same models and payloads as the
+// API itself.
+//
+//	api, err := client.New("https://sucursales.example.com", client.WithToken(token))
+//	closest, err := api.GetClosestSucursal(ctx, -34.6037, -58.3816)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Defaults of the client.
+const (
+	DefaultTimeout    = 30 * time.Second
+	DefaultMaxRetries = 3
+	DefaultRetryWait  = 500 * time.Millisecond
+	// Longest wait between retries, whatever the backoff or the Retry-After header says.
+	maxRetryWait = 30 * time.Second
+)
+
+// Client calls the sucursal API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string
+	maxRetries int
+	retryWait  time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient makes the client send requests through httpClient instead of a client with a
+// DefaultTimeout.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(client *Client) {
+		client.httpClient = httpClient
+	}
+}
+
+// WithToken sends the token as a bearer token, for deployments behind a gateway that requires one.
+func WithToken(token string) Option {
+	return func(client *Client) {
+		client.token = token
+	}
+}
+
+// WithRetries sets how many times a request is retried when the API answers 429 or a 5xx status,
+// and the wait before the first retry, which doubles on every retry unless the API sends a
+// Retry-After header. Zero retries disables them.
+//
+// Only GET, HEAD and DELETE requests are retried on any of those statuses. Other requests, such as
+// creates and applied syncs, may have been carried out before a gateway failed, so they are only
+// retried when the API answers 429 or 503 with a Retry-After header, which means it turned them away.
+func WithRetries(maxRetries int, wait time.Duration) Option {
+	return func(client *Client) {
+		client.maxRetries = maxRetries
+		client.retryWait = wait
+	}
+}
+
+// New returns a client of the API at baseURL, such as https://sucursales.example.com.
+func New(baseURL string, options ...Option) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing base URL")
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, errors.Errorf("base URL %q must be an http or https URL", baseURL)
+	}
+	client := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		maxRetries: DefaultMaxRetries,
+		retryWait:  DefaultRetryWait,
+	}
+	for _, option := range options {
+		option(client)
+	}
+	return client, nil
+}
+
+// request is a request to the API. The body is kept in memory so that it can be sent again.
+type request struct {
+	method string
+	// Path with its segments already escaped.
+	path        string
+	query       url.Values
+	contentType string
+	body        []byte
+	accept      string
+}
+
+// jsonRequest returns a request with the payload, if any, encoded as JSON.
+func jsonRequest(method string, path string, query url.Values, payload interface{}) (*request, error) {
+	req := &request{method: method, path: path, query: query, accept: "application/json"}
+	if payload != nil {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, errors.Wrap(err, "encoding request")
+		}
+		req.body = body
+		req.contentType = "application/json"
+	}
+	return req, nil
+}
+
+// doJSON sends a JSON request and decodes the JSON response into result, unless it is nil.
+func (client *Client) doJSON(ctx context.Context, method string, path string, query url.Values, payload interface{}, result interface{}) error {
+	req, err := jsonRequest(method, path, query, payload)
+	if err != nil {
+		return err
+	}
+	return client.decode(ctx, req, result)
+}
+
+func (client *Client) decode(ctx context.Context, req *request, result interface{}) error {
+	body, err := client.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	return errors.Wrapf(json.Unmarshal(body, result), "decoding response of %s %s", req.method, req.path)
+}
+
+// do sends the request, retrying it as described in WithRetries, and returns the body of the
+// response. Responses with an error status are returned as *Error.
+func (client *Client) do(ctx context.Context, req *request) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		body, asked, err := client.send(ctx, req)
+		failure, isFailure := err.(*Error)
+		if err == nil || !isFailure || !retryable(req, failure, asked) || attempt >= client.maxRetries {
+			return body, err
+		}
+		wait := client.retryWait * time.Duration(math.Pow(2, float64(attempt)))
+		if asked >= 0 {
+			wait = asked
+		}
+		if wait > maxRetryWait {
+			wait = maxRetryWait
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether the failed request can be sent again. asked is the wait of the
+// Retry-After header, negative when there was none.
+func retryable(req *request, failure *Error, asked time.Duration) bool {
+	if !failure.Temporary() {
+		return false
+	}
+	switch req.method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return true
+	}
+	turnedAway := failure.StatusCode == http.StatusTooManyRequests || failure.StatusCode == http.StatusServiceUnavailable
+	return turnedAway && asked >= 0
+}
+
+// send sends the request once and returns the body of the response along with how long the API
+// asked to wait before retrying, or a negative duration if it didn't.
+func (client *Client) send(ctx context.Context, req *request) ([]byte, time.Duration, error) {
+	target := client.baseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+	httpRequest, err := http.NewRequest(req.method, target, body)
+	if err != nil {
+		return nil, -1, errors.Wrap(err, "creating request")
+	}
+	httpRequest = httpRequest.WithContext(ctx)
+	if req.accept != "" {
+		httpRequest.Header.Set("Accept", req.accept)
+	}
+	if req.body != nil {
+		httpRequest.Header.Set("Content-Type", req.contentType)
+	}
+	if client.token != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+client.token)
+	}
+	response, err := client.httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, -1, errors.Wrapf(err, "%s %s", req.method, req.path)
+	}
+	defer response.Body.Close()
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, -1, errors.Wrapf(err, "reading response of %s %s", req.method, req.path)
+	}
+	if response.StatusCode >= 300 {
+		return nil, retryAfter(response.Header.Get("Retry-After")), newError(req, response.StatusCode, contents)
+	}
+	return contents, -1, nil
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date, returning a negative
+// duration when there is no valid header.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return -1
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+		return 0
+	}
+	return -1
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers"
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/requests"
+	"github.com/NJRodriguez/shiny-waddle/api/importer"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb/mocks"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+var testSucursales = []models.Sucursal{
+	{ID: "c", Address: "Av. Cabildo 2000, C1428 CABA", Latitude: -34.56, Longitude: -58.45},
+	{ID: "a", Address: "Florida 296, C1005 CABA", Latitude: -34.6, Longitude: -58.41},
+	{ID: "b", Address: "Av. Rivadavia 5000, C1424 CABA", Latitude: -34.6, Longitude: -58.42},
+}
+
+// newAPI serves the API with the documents client and returns a client of it.
+func newAPI(t *testing.T, documents *mocks.DocumentsClient) *Client {
+	controller, err := controllers.NewAPIController(documents)
+	require.NoError(t, err)
+	router := mux.NewRouter()
+	controller.RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	client, err := New(server.URL+"/", WithRetries(0, 0))
+	require.NoError(t, err)
+	return client
+}
+
+func marshalSucursales(t *testing.T, sucursales []models.Sucursal) []map[string]*dynamodb.AttributeValue {
+	items := []map[string]*dynamodb.AttributeValue{}
+	for _, sucursal := range sucursales {
+		item, err := dynamodbattribute.MarshalMap(sucursal)
+		require.NoError(t, err)
+		items = append(items, item)
+	}
+	return items
+}
+
+func TestListAllSucursalesFollowsCursors(t *testing.T) {
+	documents := &mocks.DocumentsClient{}
+	documents.On("ListAll").Return(marshalSucursales(t, testSucursales), nil).Twice()
+	api := newAPI(t, documents)
+
+	pages := [][]models.Sucursal{}
+	err := api.EachSucursalPage(context.Background(), &ListOptions{Limit: 2}, func(page []models.Sucursal) error {
+		pages = append(pages, page)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][]models.Sucursal{{testSucursales[1], testSucursales[2]}, {testSucursales[0]}}, pages)
+	documents.AssertExpectations(t)
+
+	documents.On("ListAll").Return(marshalSucursales(t, testSucursales), nil).Once()
+	all, err := api.ListAllSucursales(context.Background(), nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, testSucursales, all)
+	documents.AssertExpectations(t)
+}
+
+func TestGetAndDeleteSucursal(t *testing.T) {
+	documents := &mocks.DocumentsClient{}
+	item, err := dynamodbattribute.MarshalMap(testSucursales[0])
+	require.NoError(t, err)
+	key := models.SucursalKey{ID: "c"}
+	documents.On("Get", key).Return(&dynamodb.GetItemOutput{Item: item}, nil).Once()
+	documents.On("Delete", key).Return(&dynamodb.DeleteItemOutput{}, nil).Once()
+	api := newAPI(t, documents)
+
+	sucursal, err := api.GetSucursal(context.Background(), "c")
+	require.NoError(t, err)
+	require.Equal(t, &testSucursales[0], sucursal)
+	deleted, err := api.DeleteSucursal(context.Background(), "c")
+	require.NoError(t, err)
+	require.Equal(t, "c", deleted.ID)
+	documents.AssertExpectations(t)
+}
+
+func TestErrorsExposeTheAPIErrorDetails(t *testing.T) {
+	api := newAPI(t, &mocks.DocumentsClient{})
+	_, err := api.CreateSucursal(context.Background(), &requests.PostSucursal{ID: "not a uuid", Address: "Florida 296"})
+	require.Error(t, err)
+	failure, ok := err.(*Error)
+	require.True(t, ok)
+	require.Equal(t, http.StatusBadRequest, failure.StatusCode)
+	require.Equal(t, "Error when validating payload", failure.Message)
+	require.Equal(t, []string{"ID must be in valid UUID v4 format"}, failure.Errors)
+	require.False(t, failure.Temporary())
+	require.EqualError(t, err, "POST /sucursal: 400 Bad Request: Error when validating payload (ID must be in valid UUID v4 format)")
+}
+
+func TestRetriesTemporaryErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			writer.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = writer.Write([]byte(`{"provinces":[],"unknown":3}`))
+		}
+	}))
+	defer server.Close()
+
+	api, err := New(server.URL, WithRetries(2, time.Millisecond))
+	require.NoError(t, err)
+	provinces, err := api.GetProvinces(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, provinces.Unknown)
+	require.Equal(t, int32(3), calls)
+
+	atomic.StoreInt32(&calls, 0)
+	api, err = New(server.URL, WithRetries(1, time.Millisecond))
+	require.NoError(t, err)
+	_, err = api.GetProvinces(context.Background())
+	require.Error(t, err)
+	require.Equal(t, http.StatusTooManyRequests, err.(*Error).StatusCode)
+	require.Equal(t, int32(2), calls)
+}
+
+func TestRetriesOnlyPostsTheAPITurnedAway(t *testing.T) {
+	var calls int32
+	status := int32(http.StatusBadGateway)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			if atomic.LoadInt32(&status) == http.StatusServiceUnavailable {
+				writer.Header().Set("Retry-After", "0")
+			}
+			writer.WriteHeader(int(atomic.LoadInt32(&status)))
+			return
+		}
+		_, _ = writer.Write([]byte(`{"id":"a"}`))
+	}))
+	defer server.Close()
+	api, err := New(server.URL, WithRetries(2, time.Millisecond))
+	require.NoError(t, err)
+	sucursal := &requests.PostSucursal{ID: "a", Address: "Florida 296"}
+
+	// The create may have been made before the gateway failed.
+	_, err = api.CreateSucursal(context.Background(), sucursal)
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadGateway, err.(*Error).StatusCode)
+	require.Equal(t, int32(1), calls)
+
+	atomic.StoreInt32(&calls, 0)
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	created, err := api.CreateSucursal(context.Background(), sucursal)
+	require.NoError(t, err)
+	require.Equal(t, "a", created.ID)
+	require.Equal(t, int32(2), calls)
+}
+
+func TestRetriesStopWhenTheContextIsDone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		writer.Header().Set("Retry-After", "30")
+		writer.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	api, err := New(server.URL)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = api.GetProvinces(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestNewRejectsInvalidURLs(t *testing.T) {
+	for _, invalid := range []string{"", "localhost:8080", "ftp://example.com", "http://"} {
+		_, err := New(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestSnapshotFormatsMatchTheImporter(t *testing.T) {
+	require.Equal(t, importer.FormatJSON, SnapshotJSON)
+	require.Equal(t, importer.FormatJSONL, SnapshotJSONL)
+	require.Equal(t, importer.FormatCSV, SnapshotCSV)
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+)
+
+// Error is an error response of the API. The message and validation errors are those of the
+// ApiError payload of the API, when the response has one.
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+	responses.ApiError
+	// Sucursales that the sucursal sent to CreateSucursal looks like a duplicate of.
+	Candidates []responses.DuplicateCandidate
+	// Body of the response, in case it isn't a JSON error payload.
+	Body []byte
+}
+
+func newError(req *request, statusCode int, body []byte) *Error {
+	failure := &Error{Method: req.method, Path: req.path, StatusCode: statusCode, Body: body}
+	payload := struct {
+		responses.ApiError
+		Candidates []responses.DuplicateCandidate `json:"candidates"`
+	}{}
+	if json.Unmarshal(body, &payload) == nil {
+		failure.ApiError = payload.ApiError
+		failure.Candidates = payload.Candidates
+	}
+	return failure
+}
+
+func (err *Error) Error() string {
+	message := fmt.Sprintf("%s %s: %d %s", err.Method, err.Path, err.StatusCode, http.StatusText(err.StatusCode))
+	if err.Message != "" {
+		message += ": " + err.Message
+	}
+	if len(err.Errors) > 0 {
+		message += " (" + strings.Join(err.Errors, "; ") + ")"
+	}
+	return message
+}
+
+// Temporary reports whether sending the request again may succeed, which is the case when the API
+// is rate limiting requests or failed on its side.
+func (err *Error) Temporary() bool {
+	return err.StatusCode == http.StatusTooManyRequests || err.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,264 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/requests"
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+)
+
+// CreateSucursal creates a sucursal. When it looks like a duplicate of existing sucursales, the
+// *Error has a 409 status and lists them as candidates.
+func (client *Client) CreateSucursal(ctx context.Context, sucursal *requests.PostSucursal) (*responses.PostSucursal, error) {
+	created := &responses.PostSucursal{}
+	return created, client.doJSON(ctx, http.MethodPost, "/sucursal", nil, sucursal, created)
+}
+
+// GetSucursal returns the sucursal with the id.
+func (client *Client) GetSucursal(ctx context.Context, id string) (*models.Sucursal, error) {
+	sucursal := &models.Sucursal{}
+	return sucursal, client.doJSON(ctx, http.MethodGet, "/sucursal/"+url.PathEscape(id), nil, nil, sucursal)
+}
+
+// DeleteSucursal deletes the sucursal with the id.
+func (client *Client) DeleteSucursal(ctx context.Context, id string) (*responses.DeleteSucursal, error) {
+	deleted := &responses.DeleteSucursal{}
+	return deleted, client.doJSON(ctx, http.MethodDelete, "/sucursal/"+url.PathEscape(id), nil, nil, deleted)
+}
+
+// ListOptions filters the sucursales listed and pages through them. Every field is optional.
+type ListOptions struct {
+	// Province code or name.
+	Province string
+	Locality string
+	// Only sucursales within RadiusKm of Position, in any format the API accepts.
+	Position string
+	RadiusKm float64
+	// Most sucursales in a page, every sucursal when zero.
+	Limit int
+	// Cursor of the page, as returned in the previous page.
+	Cursor string
+}
+
+func (options *ListOptions) query() url.Values {
+	query := url.Values{}
+	if options == nil {
+		return query
+	}
+	if options.Province != "" {
+		query.Set("province", options.Province)
+	}
+	if options.Locality != "" {
+		query.Set("locality", options.Locality)
+	}
+	if options.Position != "" || options.RadiusKm != 0 {
+		query.Set("position", options.Position)
+		query.Set("radiusKm", strconv.FormatFloat(options.RadiusKm, 'f', -1, 64))
+	}
+	if options.Limit > 0 {
+		query.Set("limit", strconv.Itoa(options.Limit))
+	}
+	if options.Cursor != "" {
+		query.Set("cursor", options.Cursor)
+	}
+	return query
+}
+
+// ListSucursales returns a page of sucursales, or all of them when options has no limit. The
+// NextCursor of the response is empty on the last page.
+func (client *Client) ListSucursales(ctx context.Context, options *ListOptions) (*responses.ListSucursalesResponse, error) {
+	list := &responses.ListSucursalesResponse{}
+	return list, client.doJSON(ctx, http.MethodGet, "/sucursal", options.query(), nil, list)
+}
+
+// EachSucursalPage calls visit with every page of sucursales, starting from the cursor of options,
+// until the last page or until visit returns an error, which is returned.
+func (client *Client) EachSucursalPage(ctx context.Context, options *ListOptions, visit func(page []models.Sucursal) error) error {
+	paged := ListOptions{}
+	if options != nil {
+		paged = *options
+	}
+	for {
+		list, err := client.ListSucursales(ctx, &paged)
+		if err != nil {
+			return err
+		}
+		if err := visit(list.Sucursales); err != nil {
+			return err
+		}
+		if list.NextCursor == "" {
+			return nil
+		}
+		paged.Cursor = list.NextCursor
+	}
+}
+
+// ListAllSucursales returns every sucursal matching the filters of options, fetched in pages of
+// options.Limit sucursales.
+func (client *Client) ListAllSucursales(ctx context.Context, options *ListOptions) ([]models.Sucursal, error) {
+	sucursales := []models.Sucursal{}
+	err := client.EachSucursalPage(ctx, options, func(page []models.Sucursal) error {
+		sucursales = append(sucursales, page...)
+		return nil
+	})
+	return sucursales, err
+}
+
+// GetClosestSucursal returns the sucursal closest to the position.
+func (client *Client) GetClosestSucursal(ctx context.Context, latitude float64, longitude float64) (*responses.ClosestSucursalResponse, error) {
+	closest := &responses.ClosestSucursalResponse{}
+	return closest, client.doJSON(ctx, http.MethodGet, "/sucursal/"+coordinates(latitude, longitude), nil, nil, closest)
+}
+
+// GetNearestSucursal returns the sucursal closest to the address, which the API geocodes.
+func (client *Client) GetNearestSucursal(ctx context.Context, address string) (*responses.ClosestSucursalResponse, error) {
+	closest := &responses.ClosestSucursalResponse{}
+	return closest, client.doJSON(ctx, http.MethodGet, "/sucursal/nearest", url.Values{"address": {address}}, nil, closest)
+}
+
+// GetNearestSucursalTo returns the sucursal closest to the position, in any format the API accepts,
+// such as a plus code or a geohash.
+func (client *Client) GetNearestSucursalTo(ctx context.Context, position string) (*responses.ClosestSucursalResponse, error) {
+	closest := &responses.ClosestSucursalResponse{}
+	return closest, client.doJSON(ctx, http.MethodGet, "/sucursal/nearest", url.Values{"position": {position}}, nil, closest)
+}
+
+// GetServingSucursales returns the sucursales whose service area contains the position.
+func (client *Client) GetServingSucursales(ctx context.Context, latitude float64, longitude float64) (*responses.ServingSucursalesResponse, error) {
+	serving := &responses.ServingSucursalesResponse{}
+	return serving, client.doJSON(ctx, http.MethodGet, "/sucursal/serving/"+coordinates(latitude, longitude), nil, nil, serving)
+}
+
+// GetProvinces returns how many sucursales there are in each province.
+func (client *Client) GetProvinces(ctx context.Context) (*responses.ProvincesResponse, error) {
+	provinces := &responses.ProvincesResponse{}
+	return provinces, client.doJSON(ctx, http.MethodGet, "/sucursal/provinces", nil, nil, provinces)
+}
+
+// BoundingBox is an area in decimal degrees.
+type BoundingBox struct {
+	West, South, East, North float64
+}
+
+// GetClusters returns the clusters of sucursales at the zoom level, within the bounding box if it
+// isn't nil.
+func (client *Client) GetClusters(ctx context.Context, zoom int, bbox *BoundingBox) (*responses.ClustersResponse, error) {
+	query := url.Values{"zoom": {strconv.Itoa(zoom)}}
+	if bbox != nil {
+		query.Set("bbox", formatFloats(bbox.West, bbox.South, bbox.East, bbox.North))
+	}
+	clusters := &responses.ClustersResponse{}
+	return clusters, client.doJSON(ctx, http.MethodGet, "/sucursal/clusters", query, nil, clusters)
+}
+
+// DuplicatesOptions overrides the thresholds of the duplicates report. Zero fields keep those of
+// the API.
+type DuplicatesOptions struct {
+	MaxDistanceM  float64
+	MinSimilarity float64
+}
+
+// GetDuplicatesReport returns the pairs of sucursales that look like duplicates of each other.
+func (client *Client) GetDuplicatesReport(ctx context.Context, options *DuplicatesOptions) (*responses.DuplicatesReportResponse, error) {
+	query := url.Values{}
+	if options != nil && options.MaxDistanceM > 0 {
+		query.Set("maxDistanceM", strconv.FormatFloat(options.MaxDistanceM, 'f', -1, 64))
+	}
+	if options != nil && options.MinSimilarity > 0 {
+		query.Set("minSimilarity", strconv.FormatFloat(options.MinSimilarity, 'f', -1, 64))
+	}
+	report := &responses.DuplicatesReportResponse{}
+	return report, client.doJSON(ctx, http.MethodGet, "/sucursal/duplicates", query, nil, report)
+}
+
+// AssignCustomers assigns customers to sucursales, respecting their capacity.
+func (client *Client) AssignCustomers(ctx context.Context, assign *requests.AssignCustomers) (*responses.AssignCustomersResponse, error) {
+	assignments := &responses.AssignCustomersResponse{}
+	return assignments, client.doJSON(ctx, http.MethodPost, "/sucursal/assignments", nil, assign, assignments)
+}
+
+// GetVisitRoute orders the visits to the sucursales.
+func (client *Client) GetVisitRoute(ctx context.Context, visit *requests.VisitRoute) (*responses.VisitRouteResponse, error) {
+	route := &responses.VisitRouteResponse{}
+	return route, client.doJSON(ctx, http.MethodPost, "/sucursal/visit-route", nil, visit, route)
+}
+
+// SyncOptions controls a sync.
+type SyncOptions struct {
+	// Makes the changes rather than only planning them.
+	Apply bool
+}
+
+// Formats of the snapshots sent to SyncSucursales, the same as those of sucursal-sync.
+const (
+	SnapshotJSON  = "json"
+	SnapshotJSONL = "jsonl"
+	SnapshotCSV   = "csv"
+)
+
+// SyncSucursales reconciles the table with a snapshot in one of the snapshot formats. When the
+// changes delete more of the sucursales than the API allows, the *Error has a 409 status.
+func (client *Client) SyncSucursales(ctx context.Context, snapshot []byte, format string, options *SyncOptions) (*responses.SyncSucursalesResponse, error) {
+	query := url.Values{}
+	if options != nil && options.Apply {
+		query.Set("apply", "true")
+	}
+	contentTypes := map[string]string{
+		SnapshotJSON:  "application/json",
+		SnapshotJSONL: "application/x-ndjson",
+		SnapshotCSV:   "text/csv",
+	}
+	req := &request{
+		method:      http.MethodPost,
+		path:        "/sucursal/sync",
+		query:       query,
+		contentType: contentTypes[format],
+		body:        snapshot,
+		accept:      "application/json",
+	}
+	if req.contentType == "" {
+		req.contentType = contentTypes[SnapshotJSON]
+	}
+	synced := &responses.SyncSucursalesResponse{}
+	return synced, client.decode(ctx, req, synced)
+}
+
+// GetTile returns the Mapbox Vector Tile of the sucursales at the tile coordinates.
+func (client *Client) GetTile(ctx context.Context, z int, x int, y int) ([]byte, error) {
+	return client.do(ctx, &request{
+		method: http.MethodGet,
+		path:   "/tiles/" + strconv.Itoa(z) + "/" + strconv.Itoa(x) + "/" + strconv.Itoa(y) + ".mvt",
+	})
+}
+
+// GetIndexStatus returns the status of the spatial index of the API.
+func (client *Client) GetIndexStatus(ctx context.Context) (*responses.IndexStatusResponse, error) {
+	status := &responses.IndexStatusResponse{}
+	return status, client.doJSON(ctx, http.MethodGet, "/index/status", nil, nil, status)
+}
+
+// GetCacheStatus returns the statistics of the documents cache of the API, which are zero when the
+// cache is disabled.
+func (client *Client) GetCacheStatus(ctx context.Context) (*responses.CacheStatusResponse, error) {
+	stats := &responses.CacheStatusResponse{}
+	return stats, client.doJSON(ctx, http.MethodGet, "/cache/status", nil, nil, stats)
+}
+
+func coordinates(latitude float64, longitude float64) string {
+	return strconv.FormatFloat(latitude, 'f', -1, 64) + "/" + strconv.FormatFloat(longitude, 'f', -1, 64)
+}
+
+func formatFloats(values ...float64) string {
+	formatted := ""
+	for i, value := range values {
+		if i > 0 {
+			formatted += ","
+		}
+		formatted += strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return formatted
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/requests"
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+	"github.com/NJRodriguez/shiny-waddle/lib/geocoding"
+	"github.com/pkg/errors"
+)
+
+// geocode resolves the address with the configured geocoder. When it fails, the error response has
+// already been written and false is returned.
+func (instance *APIController) geocode(writer http.ResponseWriter, address string) (*geocoding.Result, bool) {
+	if instance.geocoder == nil {
+		log.Println("Geocoding is not enabled.")
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: coordinatesRequired})
+		return nil, false
+	}
+	result, err := instance.geocoder.Geocode(address)
+	if errors.Cause(err) == geocoding.ErrAddressNotFound {
+		log.Printf("Address could not be geocoded: %s", address)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: addressNotFound})
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error when trying to geocode address: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: internalServerError})
+		return nil, false
+	}
+	return result, true
+}
+
+// validateAddress geocodes the address of a sucursal created with coordinates and checks that it is
+// close enough to them. Returns the warnings for the response, or false when the sucursal was rejected
+// and the error response has already been written.
+func (instance *APIController) validateAddress(writer http.ResponseWriter, sucursal *requests.PostSucursal) ([]string, bool) {
+	if instance.addressValidation == "" || instance.addressValidation == AddressValidationOff || instance.geocoder == nil || sucursal.Geocoding != nil {
+		return nil, true
+	}
+	geocoded, err := instance.geocoder.Geocode(sucursal.Address)
+	if errors.Cause(err) == geocoding.ErrAddressNotFound {
+		log.Printf("Address could not be geocoded for validation: %s", sucursal.Address)
+		return []string{addressNotValidated}, true
+	}
+	if err != nil {
+		log.Printf("Error when trying to geocode address for validation: %s", err)
+		return []string{addressNotValidated}, true
+	}
+	distance := geo.Distance(*sucursal.Latitude, *sucursal.Longitude, geocoded.Latitude, geocoded.Longitude)
+	if distance <= instance.addressMaxDistanceKm {
+		return nil, true
+	}
+	message := fmt.Sprintf(addressTooFar, distance)
+	if instance.addressValidation == AddressValidationReject {
+		log.Printf("Rejecting sucursal since its address is %f km away from the coordinates.", distance)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: message})
+		return nil, false
+	}
+	return []string{message}, true
+}
+
+// normalizeAddress builds the normalized address of a sucursal. When reverse geocoding is enabled,
+// its coordinates are reverse geocoded to find the structured address components.
+func (instance *APIController) normalizeAddress(sucursal *requests.PostSucursal) *models.NormalizedAddress {
+	normalized := &models.NormalizedAddress{Text: geocoding.Normalize(sucursal.Address)}
+	if instance.reverseGeocoder == nil {
+		return normalized
+	}
+	result, err := instance.reverseGeocoder.ReverseGeocode(*sucursal.Latitude, *sucursal.Longitude)
+	if err != nil {
+		if errors.Cause(err) != geocoding.ErrLocationNotFound {
+			log.Printf("Error when trying to reverse geocode sucursal: %s", err)
+		}
+		return normalized
+	}
+	normalized.Components = &models.AddressComponents{
+		Street:     result.Street,
+		Number:     result.Number,
+		Locality:   result.Locality,
+		Province:   result.Province,
+		PostalCode: result.PostalCode,
+		Country:    result.Country,
+	}
+	normalized.MatchedAddress = result.FormattedAddress
+	normalized.DistanceKm = result.DistanceKm
+	return normalized
+}
@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/requests"
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+	"github.com/NJRodriguez/shiny-waddle/lib/geojson"
+	"github.com/NJRodriguez/shiny-waddle/lib/voronoi"
+)
+
+// Most grid cells analysed by a single coverage request.
+const maxCoverageCells = 250000
+
+// Kilometers in a degree of latitude, as measured by geo.Distance.
+var kmPerDegree = geo.Distance(0, 0, 1, 0)
+
+func (instance *APIController) GetCoverageGaps(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error when trying to read request body: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidRequestBody})
+		return
+	}
+	coverage := &requests.CoverageGaps{}
+	valErrs, err := ValidateRequest(body, coverage)
+	if err != nil {
+		log.Printf("Error when trying to validate requests: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidRequestBody})
+		return
+	}
+	if valErrs != nil {
+		log.Println("Validation error in payload.")
+		writer.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(writer).Encode(valErrs)
+		return
+	}
+	response := coverageGrid(coverage)
+	if response.Rows*response.Columns > maxCoverageCells {
+		log.Printf("Coverage grid of %dx%d cells is too large.", response.Rows, response.Columns)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: fmt.Sprintf(tooManyCells, maxCoverageCells)})
+		return
+	}
+	tree, sucursales, ok := instance.sucursalTree(writer)
+	if !ok {
+		return
+	}
+	for row := 0; row < response.Rows; row++ {
+		for column := 0; column < response.Columns; column++ {
+			center := models.Position{
+				Latitude:  response.Origin.Latitude - (float64(row)+0.5)*response.CellHeightDegrees,
+				Longitude: response.Origin.Longitude + (float64(column)+0.5)*response.CellWidthDegrees,
+			}
+			if !geo.PointInPolygon(center.Latitude, center.Longitude, coverage.Polygon) {
+				continue
+			}
+			response.Cells++
+			nearest, _ := tree.Nearest(center.Latitude, center.Longitude)
+			distance := calcDistance(&center, sucursales[nearest.ID])
+			if distance > coverage.ThresholdKm {
+				response.Gaps = append(response.Gaps, responses.CoverageGap{
+					Row:               row,
+					Column:            column,
+					Position:          center,
+					DistanceInKm:      distance,
+					NearestSucursalID: nearest.ID,
+				})
+			}
+		}
+	}
+	if wantsGeoJSON(r) {
+		features := []*geojson.Feature{}
+		for _, gap := range response.Gaps {
+			features = append(features, coverageGapFeature(&response, &gap))
+		}
+		writeFeatureCollection(writer, features)
+		return
+	}
+	_ = json.NewEncoder(writer).Encode(&response)
+}
+
+func (instance *APIController) GetCatchments(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error when trying to read request body: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidRequestBody})
+		return
+	}
+	catchments := &requests.Catchments{}
+	valErrs, err := ValidateRequest(body, catchments)
+	if err != nil {
+		log.Printf("Error when trying to validate requests: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidRequestBody})
+		return
+	}
+	if valErrs != nil {
+		log.Println("Validation error in payload.")
+		writer.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(writer).Encode(valErrs)
+		return
+	}
+	sucursales, ok := instance.listSucursales(writer)
+	if !ok {
+		return
+	}
+	response := voronoiCatchments(sucursales, catchments.Boundary[0])
+	if wantsGeoJSON(r) {
+		features := []*geojson.Feature{}
+		for _, catchment := range response.Catchments {
+			features = append(features, geojson.NewFeature(catchment.SucursalID, geojson.NewPolygon(catchment.Coordinates), map[string]interface{}{
+				"sucursalId": catchment.SucursalID,
+				"areaKm2":    catchment.AreaKm2,
+			}))
+		}
+		writeFeatureCollection(writer, features)
+		return
+	}
+	_ = json.NewEncoder(writer).Encode(&response)
+}
+
+// voronoiCatchments splits the boundary ring into the areas closest to each sucursal. The cells are
+// computed and measured in a local projection centered at the boundary, so they are meant for regions
+// up to a few hundred kilometers wide. Sucursales whose catchment is outside the boundary are left out.
+func voronoiCatchments(sucursales []*models.Sucursal, boundary [][]float64) responses.CatchmentsResponse {
+	south, west, north, east := 90.0, 180.0, -90.0, -180.0
+	for _, position := range boundary {
+		west, east = math.Min(west, position[0]), math.Max(east, position[0])
+		south, north = math.Min(south, position[1]), math.Max(north, position[1])
+	}
+	projection := geo.NewLocalProjection((south+north)/2, (west+east)/2)
+	// The ring is closed, the last position repeats the first one.
+	ring := make([]voronoi.Point, 0, len(boundary)-1)
+	for _, position := range boundary[:len(boundary)-1] {
+		x, y := projection.Forward(position[1], position[0])
+		ring = append(ring, voronoi.Point{X: x, Y: y})
+	}
+	sites := make([]voronoi.Point, len(sucursales))
+	for i, sucursal := range sucursales {
+		x, y := projection.Forward(sucursal.Latitude, sucursal.Longitude)
+		sites[i] = voronoi.Point{X: x, Y: y}
+	}
+	response := responses.CatchmentsResponse{BoundaryAreaKm2: math.Abs(voronoi.Area(ring)), Catchments: []responses.Catchment{}}
+	for i, cell := range voronoi.Cells(sites, ring) {
+		area := voronoi.Area(cell)
+		if area == 0 {
+			continue
+		}
+		if area < 0 {
+			// GeoJSON exterior rings are counterclockwise.
+			for left, right := 0, len(cell)-1; left < right; left, right = left+1, right-1 {
+				cell[left], cell[right] = cell[right], cell[left]
+			}
+		}
+		coordinates := make([][]float64, 0, len(cell)+1)
+		for _, point := range append(cell, cell[0]) {
+			latitude, longitude := projection.Inverse(point.X, point.Y)
+			coordinates = append(coordinates, []float64{longitude, latitude})
+		}
+		response.Catchments = append(response.Catchments, responses.Catchment{
+			SucursalID:  sucursales[i].ID,
+			AreaKm2:     math.Abs(area),
+			Coordinates: [][][]float64{coordinates},
+		})
+	}
+	return response
+}
+
+// coverageGrid lays a grid of square cells over the bounding box of the polygon. Cells are cellSizeKm
+// high, and as wide in degrees as they are at the middle latitude of the box.
+func coverageGrid(coverage *requests.CoverageGaps) responses.CoverageGapsResponse {
+	south, west, north, east := 90.0, 180.0, -90.0, -180.0
+	for _, position := range coverage.Polygon[0] {
+		west, east = math.Min(west, position[0]), math.Max(east, position[0])
+		south, north = math.Min(south, position[1]), math.Max(north, position[1])
+	}
+	height := coverage.CellSizeKm / kmPerDegree
+	width := height / math.Max(math.Cos((south+north)/2*math.Pi/180), 0.01)
+	return responses.CoverageGapsResponse{
+		Origin:            models.Position{Latitude: north, Longitude: west},
+		Rows:              gridCells(north-south, height),
+		Columns:           gridCells(east-west, width),
+		CellHeightDegrees: height,
+		CellWidthDegrees:  width,
+		Gaps:              []responses.CoverageGap{},
+	}
+}
+
+// gridCells returns the number of cells of the given size needed to cover the length, capped so that
+// it can be multiplied safely with the number of cells of the other axis.
+func gridCells(length float64, size float64) int {
+	return int(math.Min(math.Max(1, math.Ceil(length/size)), maxCoverageCells+1))
+}
+
+// coverageGapFeature renders a gap as a GeoJSON polygon of its cell.
+func coverageGapFeature(grid *responses.CoverageGapsResponse, gap *responses.CoverageGap) *geojson.Feature {
+	north := grid.Origin.Latitude - float64(gap.Row)*grid.CellHeightDegrees
+	south := north - grid.CellHeightDegrees
+	west := grid.Origin.Longitude + float64(gap.Column)*grid.CellWidthDegrees
+	east := west + grid.CellWidthDegrees
+	cell := geojson.NewPolygon([][][]float64{{{west, north}, {west, south}, {east, south}, {east, north}, {west, north}}})
+	return geojson.NewFeature(fmt.Sprintf("%d/%d", gap.Row, gap.Column), cell, map[string]interface{}{
+		"row":               gap.Row,
+		"column":            gap.Column,
+		"distanceInKm":      gap.DistanceInKm,
+		"nearestSucursalId": gap.NearestSucursalID,
+	})
+}
@@ -5,14 +5,22 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"log"
-	"math"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/requests"
 	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/importer"
 	"github.com/NJRodriguez/shiny-waddle/api/models"
 	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb"
+	"github.com/NJRodriguez/shiny-waddle/lib/cache"
+	"github.com/NJRodriguez/shiny-waddle/lib/demand"
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+	"github.com/NJRodriguez/shiny-waddle/lib/geocoding"
+	"github.com/NJRodriguez/shiny-waddle/lib/geojson"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	dynamodbSdk "github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
@@ -28,19 +36,65 @@ var (
 )
 
 const (
-	internalServerError     = "Internal server error"
-	idExistsError           = "Id already exists in database"
-	idNotFoundError         = "Id not found in database"
-	sucursalesNotFoundError = "No sucursales were found. Please load sucursales onto database"
-	invalidRequestBody      = "Failed to parse the request body"
-	invalidLatitude         = "Latitude must be in float64 format"
-	invalidLongitude        = "Longitude must be in float64 format"
-	invalidLatitudeVal      = "Latitude must be between -90 and 90"
-	invalidLongitudeVal     = "Longitude must be between -180 and 180"
+	internalServerError        = "Internal server error"
+	idExistsError              = "Id already exists in database"
+	idNotFoundError            = "Id not found in database"
+	sucursalesNotFoundError    = "No sucursales were found. Please load sucursales onto database"
+	invalidRequestBody         = "Failed to parse the request body"
+	invalidLatitude            = "Latitude must be in decimal degrees, DMS or degrees decimal minutes format"
+	invalidLongitude           = "Longitude must be in decimal degrees, DMS or degrees decimal minutes format"
+	invalidLatitudeVal         = "Latitude must be between -90 and 90"
+	invalidLongitudeVal        = "Longitude must be between -180 and 180"
+	coordinatesRequired        = "Latitude and longitude are required since geocoding is not enabled"
+	addressNotFound            = "Address could not be geocoded"
+	addressTooFar              = "Address is %.2f km away from the coordinates"
+	addressNotValidated        = "Address could not be geocoded to validate the coordinates"
+	invalidProvince            = "Province must be an Argentine province code or name"
+	invalidPosition            = "Position must be a latitude/longitude pair, plus code, geohash or projected coordinates"
+	addressOrPosition          = "Either the address or the position query parameter is required"
+	invalidTile                = "Tile coordinates are out of range for the zoom level"
+	invalidZoom                = "Zoom must be an integer between 0 and 30"
+	invalidBoundingBox         = "Bounding box must be west,south,east,north in decimal degrees"
+	invalidRadius              = "Radius must be a positive number of km"
+	invalidLimit               = "Limit must be a positive integer"
+	invalidCursor              = "Cursor must be the nextCursor of a previous page of the same query"
+	tooManyCells               = "The polygon covers more than %d grid cells, use a larger cell size"
+	duplicateSucursal          = "Sucursal looks like a duplicate of existing sucursales, set force to create it anyway"
+	invalidDuplicateDistance   = "Max distance must be a non-negative number of meters"
+	invalidDuplicateSimilarity = "Min similarity must be a number between 0 and 1"
+	demandNotEnabled           = "Demand analytics are not enabled"
+	invalidTimeRange           = "From and to must be RFC 3339 times, from before to and at most %d days apart"
+	invalidCellDegrees         = "Cell size must be a number of degrees of at least %g"
+	demandRequired             = "Demand points are required since demand analytics are not enabled"
+	noDemandRecorded           = "No searches were recorded in the time range"
+	unknownSucursales          = "Sucursales not found in database: %s"
+	duplicatedSucursalID       = "Sucursal id %s is already in use"
+	noSucursalesLeft           = "At least one sucursal must be left open"
+	invalidMaxLegs             = "Max legs must be at least %d to visit a sucursal and reach the end of the route"
+	invalidSnapshot            = "Snapshot could not be read: %s"
+	invalidApply               = "Apply must be true or false"
+	tooManyDeletes             = "The sync would delete %d of %d sucursales, more than the %g%% allowed"
+	syncFailed                 = "The sync stopped after making %d of %d changes"
 )
 
 type APIController struct {
-	documentsClient dynamodb.DocumentsClient
+	documentsClient      dynamodb.DocumentsClient
+	geocoder             geocoding.Geocoder
+	reverseGeocoder      geocoding.ReverseGeocoder
+	addressValidation    string
+	addressMaxDistanceKm float64
+	tileCache            *cache.LRU
+	clusterCache         *cache.LRU
+	index                *spatialIndex
+	indexSource          dynamodb.DocumentsClient
+	documentsCache       *dynamodb.CachingClient
+	duplicates           *duplicateDetection
+	demandRecorder       *demand.Recorder
+	demandStore          demand.Store
+	roads                *roadRanking
+	syncMaxDeletePercent float64
+	// Closed by Close to stop the background work of the controller.
+	stop chan struct{}
 }
 
 type APIControllerArgs struct {
@@ -48,7 +102,7 @@ type APIControllerArgs struct {
 	Region    string
 }
 
-func NewAPIController(documentsClient dynamodb.DocumentsClient) (*APIController, error) {
+func NewAPIController(documentsClient dynamodb.DocumentsClient, options ...Option) (*APIController, error) {
 	validate = validator.New()
 	generatedTranslator, err := RegisterErrors(validate)
 	if err != nil {
@@ -56,17 +110,69 @@ func NewAPIController(documentsClient dynamodb.DocumentsClient) (*APIController,
 		return nil, err
 	}
 	translator = generatedTranslator
-	return &APIController{
-		documentsClient,
-	}, nil
+	instance := &APIController{
+		documentsClient:      documentsClient,
+		tileCache:            cache.NewLRU(defaultTileCacheSize, defaultTileCacheTTLSeconds*time.Second),
+		clusterCache:         cache.NewLRU(maxClusterZoom+1, defaultTileCacheTTLSeconds*time.Second),
+		syncMaxDeletePercent: importer.DefaultMaxDeletePercent,
+		stop:                 make(chan struct{}),
+	}
+	for _, option := range options {
+		option(instance)
+	}
+	if instance.index != nil {
+		source := instance.indexSource
+		if source == nil {
+			source = documentsClient
+		}
+		log.Println("Loading sucursales into the spatial index...")
+		if err := instance.index.load(source); err != nil {
+			log.Printf("Error when trying to load the spatial index, scanning the table until it loads: %s", err)
+		}
+		if instance.index.refreshInterval > 0 {
+			go instance.index.refreshPeriodically(source, instance.stop)
+		}
+	}
+	return instance, nil
+}
+
+// Close stops refreshing the spatial index. The controller keeps serving requests.
+func (instance *APIController) Close() {
+	close(instance.stop)
 }
 
 func (instance *APIController) RegisterRoutes(router *mux.Router) {
 
 	//Sucursales routes
 	router.HandleFunc("/sucursal", instance.CreateSucursal).Methods("POST")
+	router.HandleFunc("/sucursal", instance.ListSucursales).Methods("GET")
+	router.HandleFunc("/sucursal/assignments", instance.AssignCustomers).Methods("POST")
+	router.HandleFunc("/sucursal/visit-route", instance.GetVisitRoute).Methods("POST")
+	router.HandleFunc("/sucursal/sync", instance.SyncSucursales).Methods("POST")
+	router.HandleFunc("/sucursal/provinces", instance.GetProvinces).Methods("GET")
+	router.HandleFunc("/sucursal/clusters", instance.GetClusters).Methods("GET")
+	router.HandleFunc("/sucursal/duplicates", instance.GetDuplicatesReport).Methods("GET")
+	router.HandleFunc("/sucursal/nearest", instance.GetNearestSucursal).Methods("GET")
+	router.HandleFunc("/sucursal/serving/{lat}/{lon}", instance.GetServingSucursales).Methods("GET")
 	router.HandleFunc("/sucursal/{id}", instance.GetSucursal).Methods("GET")
+	router.HandleFunc("/sucursal/{id}", instance.DeleteSucursal).Methods("DELETE")
 	router.HandleFunc("/sucursal/{lat}/{lon}", instance.GetClosestSucursal).Methods("GET")
+
+	//Map tiles routes
+	router.HandleFunc("/tiles/{z:[0-9]+}/{x:[0-9]+}/{y:[0-9]+}.mvt", instance.GetTile).Methods("GET")
+
+	//Analytics routes
+	router.HandleFunc("/analytics/coverage-gaps", instance.GetCoverageGaps).Methods("POST")
+	router.HandleFunc("/analytics/catchments", instance.GetCatchments).Methods("POST")
+	router.HandleFunc("/analytics/demand/heatmap", instance.GetDemandHeatmap).Methods("GET")
+	router.HandleFunc("/analytics/demand/sucursales", instance.GetDemandBySucursal).Methods("GET")
+	router.HandleFunc("/analytics/demand/status", instance.GetDemandStatus).Methods("GET")
+	router.HandleFunc("/analytics/site-selection", instance.GetSiteSelection).Methods("POST")
+	router.HandleFunc("/analytics/closure-simulation", instance.GetClosureSimulation).Methods("POST")
+
+	//Status routes
+	router.HandleFunc("/index/status", instance.GetIndexStatus).Methods("GET")
+	router.HandleFunc("/cache/status", instance.GetCacheStatus).Methods("GET")
 }
 
 func (instance *APIController) CreateSucursal(writer http.ResponseWriter, r *http.Request) {
@@ -96,6 +202,62 @@ func (instance *APIController) CreateSucursal(writer http.ResponseWriter, r *htt
 		log.Printf("Error when trying to deserialize request body: %s", err)
 		writer.WriteHeader(http.StatusBadRequest)
 		generateErrorMessage(writer, &responses.ErrorMsg{Message: internalServerError})
+		return
+	}
+	if sucursal.Address == "" {
+		sucursal.Address = sucursal.StructuredAddress.String()
+	}
+	if sucursal.Position != "" {
+		latitude, longitude, err := geo.ParsePosition(sucursal.Position)
+		if err != nil {
+			log.Printf("Error when trying to parse position %s: %s", sucursal.Position, err)
+			writer.WriteHeader(http.StatusBadRequest)
+			generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidPosition})
+			return
+		}
+		sucursal.Latitude = &latitude
+		sucursal.Longitude = &longitude
+	}
+	if sucursal.Latitude == nil {
+		geocoded, ok := instance.geocode(writer, sucursal.Address)
+		if !ok {
+			return
+		}
+		sucursal.Latitude = &geocoded.Latitude
+		sucursal.Longitude = &geocoded.Longitude
+		sucursal.Geocoding = &models.Geocoding{
+			Confidence:     geocoded.Confidence,
+			MatchedAddress: geocoded.MatchedAddress,
+			Provider:       geocoded.Provider,
+		}
+	}
+	warnings, ok := instance.validateAddress(writer, sucursal)
+	if !ok {
+		return
+	}
+	sucursal.NormalizedAddress = instance.normalizeAddress(sucursal)
+	if instance.duplicates != nil && !sucursal.Force {
+		candidates, err := instance.duplicateCandidates(&models.Sucursal{
+			ID:        sucursal.ID,
+			Address:   sucursal.Address,
+			Latitude:  *sucursal.Latitude,
+			Longitude: *sucursal.Longitude,
+		})
+		if err != nil {
+			log.Printf("Error when trying to look for duplicate sucursales: %s", err)
+			writer.WriteHeader(http.StatusBadRequest)
+			generateErrorMessage(writer, &responses.ErrorMsg{Message: internalServerError})
+			return
+		}
+		if len(candidates) > 0 {
+			log.Printf("Sucursal %s looks like a duplicate of %d sucursales.", sucursal.ID, len(candidates))
+			writer.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(writer).Encode(&responses.DuplicateSucursalResponse{
+				Message:    duplicateSucursal,
+				Candidates: candidates,
+			})
+			return
+		}
 	}
 	_, err = instance.documentsClient.Create(sucursal)
 	if err != nil {
@@ -113,7 +275,21 @@ func (instance *APIController) CreateSucursal(writer http.ResponseWriter, r *htt
 		}
 		return
 	}
-	_ = json.NewEncoder(writer).Encode(responses.PostSucursal{Message: "Successfully created sucursal", ID: sucursal.ID})
+	instance.tileCache.Purge()
+	instance.clusterCache.Purge()
+	instance.indexCreatedSucursal(sucursal)
+	response := responses.PostSucursal{
+		Message:           "Successfully created sucursal",
+		ID:                sucursal.ID,
+		NormalizedAddress: sucursal.NormalizedAddress,
+		Warnings:          warnings,
+	}
+	if sucursal.Geocoding != nil {
+		response.Latitude = sucursal.Latitude
+		response.Longitude = sucursal.Longitude
+		response.Geocoding = sucursal.Geocoding
+	}
+	_ = json.NewEncoder(writer).Encode(response)
 }
 
 func (instance *APIController) GetSucursal(writer http.ResponseWriter, r *http.Request) {
@@ -145,9 +321,208 @@ func (instance *APIController) GetSucursal(writer http.ResponseWriter, r *http.R
 		generateErrorMessage(writer, &responses.ErrorMsg{Message: internalServerError})
 		return
 	}
+	if wantsGeoJSON(r) {
+		writeFeature(writer, sucursalFeature(&sucursal, nil))
+		return
+	}
 	_ = json.NewEncoder(writer).Encode(sucursal)
 }
 
+func (instance *APIController) DeleteSucursal(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	id := mux.Vars(r)["id"]
+	sucursalKey := models.SucursalKey{
+		ID: id,
+	}
+	if _, err := instance.documentsClient.Delete(sucursalKey); err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodbSdk.ErrCodeConditionalCheckFailedException {
+			log.Printf("Sucursal %s does not exist in db.", id)
+			writer.WriteHeader(http.StatusBadRequest)
+			generateErrorMessage(writer, &responses.ErrorMsg{Message: idNotFoundError})
+			return
+		}
+		log.Printf("Error when trying to delete Sucursal %s: %s", id, err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: internalServerError})
+		return
+	}
+	instance.tileCache.Purge()
+	instance.clusterCache.Purge()
+	if instance.index != nil {
+		instance.index.remove(id)
+	}
+	_ = json.NewEncoder(writer).Encode(&responses.DeleteSucursal{
+		Message: "Successfully deleted sucursal",
+		ID:      id,
+	})
+}
+
+func (instance *APIController) ListSucursales(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	province := ""
+	if filter := r.URL.Query().Get("province"); filter != "" {
+		province = models.ProvinceCode(filter)
+		if province == "" {
+			log.Printf("Invalid province filter: %s", filter)
+			writer.WriteHeader(http.StatusBadRequest)
+			generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidProvince})
+			return
+		}
+	}
+	locality := geocoding.Normalize(r.URL.Query().Get("locality"))
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			log.Printf("Invalid limit: %s", value)
+			writer.WriteHeader(http.StatusBadRequest)
+			generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidLimit})
+			return
+		}
+		limit = parsed
+	}
+	cursor := r.URL.Query().Get("cursor")
+	var sucursales []*models.Sucursal
+	var center *models.Position
+	if radius := r.URL.Query().Get("radiusKm"); radius != "" {
+		radiusKm, err := strconv.ParseFloat(radius, 64)
+		if err != nil || !(radiusKm > 0) {
+			log.Printf("Invalid radius filter: %s", radius)
+			writer.WriteHeader(http.StatusBadRequest)
+			generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidRadius})
+			return
+		}
+		latitude, longitude, err := geo.ParsePosition(r.URL.Query().Get("position"))
+		if err != nil {
+			log.Printf("Invalid position filter %s: %s", r.URL.Query().Get("position"), err)
+			writer.WriteHeader(http.StatusBadRequest)
+			generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidPosition})
+			return
+		}
+		center = &models.Position{Latitude: latitude, Longitude: longitude}
+		within, ok := instance.sucursalesWithin(writer, center, radiusKm)
+		if !ok {
+			return
+		}
+		sucursales = within
+	} else {
+		all, ok := instance.listSucursales(writer)
+		if !ok {
+			return
+		}
+		sucursales = all
+	}
+	response := responses.ListSucursalesResponse{Sucursales: []models.Sucursal{}}
+	for _, sucursal := range sucursales {
+		if province != "" && sucursal.Province() != province {
+			continue
+		}
+		if locality != "" && geocoding.Normalize(sucursal.Locality()) != locality {
+			continue
+		}
+		response.Sucursales = append(response.Sucursales, *sucursal)
+	}
+	if (limit > 0 || cursor != "") && center != nil {
+		page, next, err := paginateByDistance(response.Sucursales, center, cursor, limit)
+		if err != nil {
+			log.Printf("Invalid cursor %s: %s", cursor, err)
+			writer.WriteHeader(http.StatusBadRequest)
+			generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidCursor})
+			return
+		}
+		response.Sucursales, response.NextCursor = page, next
+	} else if limit > 0 || cursor != "" {
+		response.Sucursales, response.NextCursor = paginate(response.Sucursales, cursor, limit)
+	}
+	if wantsGeoJSON(r) {
+		features := []*geojson.Feature{}
+		for i := range response.Sucursales {
+			features = append(features, sucursalFeature(&response.Sucursales[i], nil))
+		}
+		writeFeatureCollection(writer, features)
+		return
+	}
+	_ = json.NewEncoder(writer).Encode(&response)
+}
+
+// paginate sorts the sucursales by id and returns up to limit of them with an id after the cursor,
+// along with the cursor of the next page, which is empty on the last page. Ids are used as cursors
+// so that pages stay consistent while sucursales are created or deleted.
+func paginate(sucursales []models.Sucursal, cursor string, limit int) ([]models.Sucursal, string) {
+	sort.Slice(sucursales, func(i, j int) bool {
+		return sucursales[i].ID < sucursales[j].ID
+	})
+	start := sort.Search(len(sucursales), func(i int) bool {
+		return sucursales[i].ID > cursor
+	})
+	page := sucursales[start:]
+	if limit > 0 && len(page) > limit {
+		return page[:limit], page[limit-1].ID
+	}
+	return page, ""
+}
+
+// paginateByDistance is paginate for sucursales within a radius, which keeps them closest first. The
+// cursor holds the distance and id of the last sucursal of the page, such as 1.25_b309060a, so that
+// pages stay consistent while sucursales are created or deleted.
+func paginateByDistance(sucursales []models.Sucursal, center *models.Position, cursor string, limit int) ([]models.Sucursal, string, error) {
+	distances := make(map[string]float64, len(sucursales))
+	for i := range sucursales {
+		distances[sucursales[i].ID] = calcDistance(center, &sucursales[i])
+	}
+	before := func(distance float64, id string, otherDistance float64, otherID string) bool {
+		return distance < otherDistance || (distance == otherDistance && id < otherID)
+	}
+	sort.Slice(sucursales, func(i, j int) bool {
+		return before(distances[sucursales[i].ID], sucursales[i].ID, distances[sucursales[j].ID], sucursales[j].ID)
+	})
+	start := 0
+	if cursor != "" {
+		parts := strings.SplitN(cursor, "_", 2)
+		if len(parts) != 2 {
+			return nil, "", errors.New("cursor is not a distance and an id")
+		}
+		cursorDistance, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "parsing cursor distance")
+		}
+		start = sort.Search(len(sucursales), func(i int) bool {
+			return before(cursorDistance, parts[1], distances[sucursales[i].ID], sucursales[i].ID)
+		})
+	}
+	page := sucursales[start:]
+	if limit > 0 && len(page) > limit {
+		last := page[limit-1]
+		return page[:limit], strconv.FormatFloat(distances[last.ID], 'g', -1, 64) + "_" + last.ID, nil
+	}
+	return page, "", nil
+}
+
+func (instance *APIController) GetProvinces(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	sucursales, ok := instance.listSucursales(writer)
+	if !ok {
+		return
+	}
+	counts := map[string]int{}
+	unknown := 0
+	for _, sucursal := range sucursales {
+		if province := sucursal.Province(); province != "" {
+			counts[province]++
+		} else {
+			unknown++
+		}
+	}
+	response := responses.ProvincesResponse{Provinces: []responses.ProvinceSummary{}, Unknown: unknown}
+	for code, count := range counts {
+		response.Provinces = append(response.Provinces, responses.ProvinceSummary{Code: code, Name: models.Provinces[code], Sucursales: count})
+	}
+	sort.Slice(response.Provinces, func(i, j int) bool {
+		return response.Provinces[i].Code < response.Provinces[j].Code
+	})
+	_ = json.NewEncoder(writer).Encode(&response)
+}
+
 func (instance *APIController) GetClosestSucursal(writer http.ResponseWriter, r *http.Request) {
 	setJSONContentType(writer)
 	pathVars := mux.Vars(r)
@@ -160,38 +535,131 @@ func (instance *APIController) GetClosestSucursal(writer http.ResponseWriter, r
 		generateErrorMessage(writer, &responses.ErrorMsg{Message: err.Error()})
 		return
 	}
+	closestSucursal, ok := instance.closestSucursal(writer, position)
+	if !ok {
+		return
+	}
+	instance.recordDemand(position, closestSucursal.Sucursal.ID)
+	writeClosestSucursal(writer, r, &responses.ClosestSucursalResponse{Sucursal: *closestSucursal.Sucursal, DistanceInKm: closestSucursal.Distance, Route: closestSucursal.Route})
+}
+
+func (instance *APIController) GetNearestSucursal(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	address := r.URL.Query().Get("address")
+	positionParam := r.URL.Query().Get("position")
+	var position *models.Position
+	var geocodingInfo *models.Geocoding
+	switch {
+	case positionParam != "":
+		latitude, longitude, err := geo.ParsePosition(positionParam)
+		if err != nil {
+			log.Printf("Error when trying to parse position %s: %s", positionParam, err)
+			writer.WriteHeader(http.StatusBadRequest)
+			generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidPosition})
+			return
+		}
+		position = &models.Position{Latitude: latitude, Longitude: longitude}
+	case address != "":
+		geocoded, ok := instance.geocode(writer, address)
+		if !ok {
+			return
+		}
+		position = &models.Position{Latitude: geocoded.Latitude, Longitude: geocoded.Longitude}
+		geocodingInfo = &models.Geocoding{
+			Confidence:     geocoded.Confidence,
+			MatchedAddress: geocoded.MatchedAddress,
+			Provider:       geocoded.Provider,
+		}
+	default:
+		log.Println("Address and position query parameters are missing.")
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: addressOrPosition})
+		return
+	}
+	closestSucursal, ok := instance.closestSucursal(writer, position)
+	if !ok {
+		return
+	}
+	writeClosestSucursal(writer, r, &responses.ClosestSucursalResponse{
+		Sucursal:     *closestSucursal.Sucursal,
+		DistanceInKm: closestSucursal.Distance,
+		Geocoding:    geocodingInfo,
+		Route:        closestSucursal.Route,
+	})
+}
+
+func (instance *APIController) GetServingSucursales(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	pathVars := mux.Vars(r)
+	lat := pathVars["lat"]
+	lon := pathVars["lon"]
+	position, err := validateLatLon(lat, lon)
+	if err != nil {
+		log.Println("Error when validating latitude/longitude")
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: err.Error()})
+		return
+	}
+	sucursales, ok := instance.listSucursales(writer)
+	if !ok {
+		return
+	}
+	serving := []*models.SucursalWithDistance{}
+	for _, sucursal := range sucursales {
+		if sucursal.Serves(position) {
+			serving = append(serving, &models.SucursalWithDistance{Sucursal: sucursal, Distance: calcDistance(position, sucursal)})
+		}
+	}
+	sort.SliceStable(serving, func(i, j int) bool {
+		return serving[i].Distance < serving[j].Distance
+	})
+	response := responses.ServingSucursalesResponse{Sucursales: []responses.ClosestSucursalResponse{}}
+	for _, sucursal := range serving {
+		response.Sucursales = append(response.Sucursales, responses.ClosestSucursalResponse{Sucursal: *sucursal.Sucursal, DistanceInKm: sucursal.Distance})
+	}
+	if wantsGeoJSON(r) {
+		features := []*geojson.Feature{}
+		for i := range response.Sucursales {
+			features = append(features, closestSucursalFeature(&response.Sucursales[i]))
+		}
+		writeFeatureCollection(writer, features)
+		return
+	}
+	_ = json.NewEncoder(writer).Encode(&response)
+}
+
+// listSucursales loads every sucursal from the database. When it fails, the error response has
+// already been written and false is returned.
+func (instance *APIController) listSucursales(writer http.ResponseWriter) ([]*models.Sucursal, bool) {
 	result, err := instance.documentsClient.ListAll()
 	if err != nil {
 		log.Println("Error when trying to list all items from dynamodb table.")
 		writer.WriteHeader(http.StatusBadRequest)
 		generateErrorMessage(writer, &responses.ErrorMsg{Message: internalServerError})
-		return
+		return nil, false
 	}
 	if len(result) == 0 {
 		log.Println("No sucursales are loaded in database!")
 		writer.WriteHeader(http.StatusBadRequest)
 		generateErrorMessage(writer, &responses.ErrorMsg{Message: sucursalesNotFoundError})
-		return
+		return nil, false
 	}
 	sucursales, err := models.ToSucursalArray(result)
 	if err != nil {
 		log.Println("Error when trying to convert dynamodb result to sucursales array.")
 		writer.WriteHeader(http.StatusBadRequest)
 		generateErrorMessage(writer, &responses.ErrorMsg{Message: internalServerError})
-		return
+		return nil, false
 	}
-	closestSucursal := &models.SucursalWithDistance{}
-	for _, sucursal := range sucursales {
-		distance := calcDistance(position, sucursal)
-		if closestSucursal.Sucursal == nil {
-			closestSucursal = &models.SucursalWithDistance{Sucursal: sucursal, Distance: distance}
-		}
-		if distance < closestSucursal.Distance {
-			closestSucursal.Sucursal = sucursal
-			closestSucursal.Distance = distance
-		}
+	return sucursales, true
+}
+
+func writeClosestSucursal(writer http.ResponseWriter, r *http.Request, response *responses.ClosestSucursalResponse) {
+	if wantsGeoJSON(r) {
+		writeFeature(writer, closestSucursalFeature(response))
+		return
 	}
-	_ = json.NewEncoder(writer).Encode(&responses.ClosestSucursalResponse{Sucursal: *closestSucursal.Sucursal, DistanceInKm: closestSucursal.Distance})
+	_ = json.NewEncoder(writer).Encode(response)
 }
 
 func generateErrorMessage(writer http.ResponseWriter, msg *responses.ErrorMsg) {
@@ -214,42 +682,39 @@ func deserializePostSucursalRequest(request []byte) (*requests.PostSucursal, err
 }
 
 func validateLatLon(lat string, lon string) (*models.Position, error) {
-	latFloat, err := strconv.ParseFloat(lat, 64)
+	latFloat, err := geo.ParseLatitude(lat)
+	if err == geo.ErrCoordinateOutOfRange {
+		return nil, errors.New(invalidLatitudeVal)
+	}
 	if err != nil {
 		return nil, errors.New(invalidLatitude)
 	}
-	if latFloat > 90 || latFloat < -90 {
-		return nil, errors.New(invalidLatitudeVal)
+	lonFloat, err := geo.ParseLongitude(lon)
+	if err == geo.ErrCoordinateOutOfRange {
+		return nil, errors.New(invalidLongitudeVal)
 	}
-	lonFloat, err := strconv.ParseFloat(lon, 64)
 	if err != nil {
 		return nil, errors.New(invalidLongitude)
 	}
-	if lonFloat > 180 || lonFloat < -180 {
-		return nil, errors.New(invalidLongitudeVal)
-	}
 	return &models.Position{Latitude: latFloat, Longitude: lonFloat}, nil
 }
 
-func calcDistance(position *models.Position, sucursal *models.Sucursal) float64 {
-	const PI float64 = 3.141592653589793
-
-	radlat1 := float64(PI * position.Latitude / 180)
-	radlat2 := float64(PI * sucursal.Latitude / 180)
-
-	theta := float64(position.Longitude - sucursal.Longitude)
-	radtheta := float64(PI * theta / 180)
-
-	dist := math.Sin(radlat1)*math.Sin(radlat2) + math.Cos(radlat1)*math.Cos(radlat2)*math.Cos(radtheta)
-
-	if dist > 1 {
-		dist = 1
+// findClosestSucursal returns the sucursal closest to the position. Sucursales must not be empty.
+func findClosestSucursal(position *models.Position, sucursales []*models.Sucursal) *models.SucursalWithDistance {
+	closestSucursal := &models.SucursalWithDistance{}
+	for _, sucursal := range sucursales {
+		distance := calcDistance(position, sucursal)
+		if closestSucursal.Sucursal == nil {
+			closestSucursal = &models.SucursalWithDistance{Sucursal: sucursal, Distance: distance}
+		}
+		if distance < closestSucursal.Distance {
+			closestSucursal.Sucursal = sucursal
+			closestSucursal.Distance = distance
+		}
 	}
+	return closestSucursal
+}
 
-	dist = math.Acos(dist)
-	dist = dist * 180 / PI
-	dist = dist * 60 * 1.1515
-	dist = dist * 1.609344
-
-	return dist
+func calcDistance(position *models.Position, sucursal *models.Sucursal) float64 {
+	return geo.Distance(position.Latitude, position.Longitude, sucursal.Latitude, sucursal.Longitude)
 }
@@ -6,18 +6,36 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/requests"
 	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/importer"
 	"github.com/NJRodriguez/shiny-waddle/api/models"
+	documents "github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb"
 	documentsMock "github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb/mocks"
+	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodbstreams"
+	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodbstreams/fake"
+	"github.com/NJRodriguez/shiny-waddle/lib/cluster"
+	"github.com/NJRodriguez/shiny-waddle/lib/demand"
+	"github.com/NJRodriguez/shiny-waddle/lib/geocoding"
+	geocoderMock "github.com/NJRodriguez/shiny-waddle/lib/geocoding/mocks"
+	"github.com/NJRodriguez/shiny-waddle/lib/geojson"
+	"github.com/NJRodriguez/shiny-waddle/lib/mvt"
+	"github.com/NJRodriguez/shiny-waddle/lib/osm"
+	"github.com/NJRodriguez/shiny-waddle/lib/roads"
+	"github.com/NJRodriguez/shiny-waddle/lib/siting"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/gorilla/mux"
 	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -25,6 +43,7 @@ type APIControllerTestSuite struct {
 	suite.Suite
 	controller    *APIController
 	documentsMock *documentsMock.DocumentsClient
+	geocoderMock  *geocoderMock.Geocoder
 	router        *mux.Router
 }
 
@@ -41,7 +60,8 @@ func executeRequest(req *http.Request, router *mux.Router) *httptest.ResponseRec
 
 func (testSuite *APIControllerTestSuite) SetupTest() {
 	testSuite.documentsMock = &documentsMock.DocumentsClient{}
-	controller, _ := NewAPIController(testSuite.documentsMock)
+	testSuite.geocoderMock = &geocoderMock.Geocoder{}
+	controller, _ := NewAPIController(testSuite.documentsMock, WithGeocoder(testSuite.geocoderMock))
 	testSuite.controller = controller
 	testSuite.router = mux.NewRouter()
 	controller.RegisterRoutes(testSuite.router)
@@ -142,20 +162,1841 @@ func (testSuite *APIControllerTestSuite) TestCreateSucursalWithValidParamsReturn
 	}
 	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(mockPostSucursal))
 
+	mockPostSucursal.NormalizedAddress = normalizedAddress(mockPostSucursal.Address)
 	testSuite.documentsMock.On("Create", &mockPostSucursal).Return(nil, nil).Once()
 
 	testSuite.Require().NoError(reqErr)
 	expectedResult := testCaseResult{
 		http.StatusOK,
 		responses.PostSucursal{
-			Message: "Successfully created sucursal",
-			ID:      mockUUID.String(),
+			Message:           "Successfully created sucursal",
+			ID:                mockUUID.String(),
+			NormalizedAddress: normalizedAddress(mockPostSucursal.Address),
 		},
 	}
 	testSuite.verifyResponse(request, expectedResult)
 }
 
-func (testSuite *APIControllerTestSuite) verifyResponse(request *http.Request, expectedResult testCaseResult) {
+func (testSuite *APIControllerTestSuite) TestGetServingSucursalesReturnsSucursalesServingThePositionOrderedByDistance() {
+	mockPosition := &models.Position{Latitude: -34.6037, Longitude: -58.3816}
+	request, reqErr := http.NewRequest("GET", fmt.Sprintf("/sucursal/serving/%f/%f", mockPosition.Latitude, mockPosition.Longitude), nil)
+	mockSucursales := []models.Sucursal{
+		{
+			ID:        "closest but not serving",
+			Address:   "Florida 296, C1005 CABA",
+			Latitude:  -34.6040,
+			Longitude: -58.3810,
+		},
+		{
+			ID:        "far polygon",
+			Address:   "Av. Rivadavia 5000, C1424 CABA",
+			Latitude:  -34.6180,
+			Longitude: -58.4400,
+			ServiceArea: &models.ServiceArea{
+				Type: models.ServiceAreaPolygon,
+				Coordinates: [][][]float64{
+					{{-58.45, -34.63}, {-58.37, -34.63}, {-58.37, -34.59}, {-58.45, -34.59}, {-58.45, -34.63}},
+				},
+			},
+		},
+		{
+			ID:          "near radius",
+			Address:     "Av. Corrientes 1200, C1043 CABA",
+			Latitude:    -34.6035,
+			Longitude:   -58.3830,
+			ServiceArea: &models.ServiceArea{Type: models.ServiceAreaRadius, RadiusKm: 2},
+		},
+		{
+			ID:          "radius too small",
+			Address:     "Av. Cabildo 2000, C1428 CABA",
+			Latitude:    -34.5600,
+			Longitude:   -58.4560,
+			ServiceArea: &models.ServiceArea{Type: models.ServiceAreaRadius, RadiusKm: 1},
+		},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusOK,
+		responses.ServingSucursalesResponse{
+			Sucursales: []responses.ClosestSucursalResponse{
+				{Sucursal: mockSucursales[2], DistanceInKm: calcDistance(mockPosition, &mockSucursales[2])},
+				{Sucursal: mockSucursales[1], DistanceInKm: calcDistance(mockPosition, &mockSucursales[1])},
+			},
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalWithInvalidServiceAreaReturnsBadRequest() {
+	mockLat := -34.604258
+	mockLon := -58.375094
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(requests.PostSucursal{
+		ID:        uuid.NewV4().String(),
+		Address:   "Florida 296, C1005 CABA",
+		Latitude:  &mockLat,
+		Longitude: &mockLon,
+		ServiceArea: &models.ServiceArea{
+			Type:        models.ServiceAreaPolygon,
+			Coordinates: [][][]float64{{{-58.45, -34.63}, {-58.37, -34.63}, {-58.37, -34.59}}},
+		},
+	}))
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusBadRequest,
+		ApiError{
+			Message: "Error when validating payload",
+			Errors: []string{
+				"Coordinates must be a GeoJSON polygon made of closed rings with at least four positions",
+			},
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalWithoutCoordinatesGeocodesAddress() {
+	mockUUID := uuid.NewV4()
+	mockPostSucursal := requests.PostSucursal{
+		ID:      mockUUID.String(),
+		Address: "Florida 296, CABA",
+	}
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(mockPostSucursal))
+	mockResult := &geocoding.Result{
+		Latitude:       -34.604258,
+		Longitude:      -58.375094,
+		Confidence:     0.8,
+		MatchedAddress: "Florida 296, C1005 CABA",
+		Provider:       geocoding.GazetteerProvider,
+	}
+	mockGeocoding := &models.Geocoding{Confidence: 0.8, MatchedAddress: "Florida 296, C1005 CABA", Provider: geocoding.GazetteerProvider}
+	expectedSucursal := mockPostSucursal
+	expectedSucursal.Latitude = &mockResult.Latitude
+	expectedSucursal.Longitude = &mockResult.Longitude
+	expectedSucursal.Geocoding = mockGeocoding
+
+	testSuite.geocoderMock.On("Geocode", "Florida 296, CABA").Return(mockResult, nil).Once()
+	expectedSucursal.NormalizedAddress = normalizedAddress(expectedSucursal.Address)
+	testSuite.documentsMock.On("Create", &expectedSucursal).Return(nil, nil).Once()
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusOK,
+		responses.PostSucursal{
+			Message:           "Successfully created sucursal",
+			ID:                mockUUID.String(),
+			Latitude:          &mockResult.Latitude,
+			Longitude:         &mockResult.Longitude,
+			Geocoding:         mockGeocoding,
+			NormalizedAddress: normalizedAddress(expectedSucursal.Address),
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalWithUnknownAddressReturnsBadRequest() {
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(requests.PostSucursal{
+		ID:      uuid.NewV4().String(),
+		Address: "Calle Falsa 123",
+	}))
+	testSuite.geocoderMock.On("Geocode", "Calle Falsa 123").Return(nil, geocoding.ErrAddressNotFound).Once()
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusBadRequest,
+		responses.ErrorMsg{
+			Message: addressNotFound,
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalWithOnlyLatitudeReturnsBadRequest() {
+	mockLat := -34.604258
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(requests.PostSucursal{
+		ID:       uuid.NewV4().String(),
+		Address:  "Florida 296, C1005 CABA",
+		Latitude: &mockLat,
+	}))
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusBadRequest,
+		ApiError{
+			Message: "Error when validating payload",
+			Errors: []string{
+				"Longitude is a required field when Latitude is present",
+			},
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestGetNearestSucursalReturnsClosestSucursal() {
+	request, reqErr := http.NewRequest("GET", "/sucursal/nearest?address=Florida+296", nil)
+	mockSucursales := []models.Sucursal{
+		{
+			ID:        "winner",
+			Address:   "Florida 296, C1005 CABA",
+			Latitude:  -34.604258,
+			Longitude: -58.375094,
+		},
+		{
+			ID:        "failure",
+			Address:   "Av. Cabildo 2000, C1428 CABA",
+			Latitude:  -34.5600,
+			Longitude: -58.4560,
+		},
+	}
+	mockResult := &geocoding.Result{Latitude: -34.6037, Longitude: -58.3816, Confidence: 1, MatchedAddress: "Florida 296", Provider: geocoding.GazetteerProvider}
+	testSuite.geocoderMock.On("Geocode", "Florida 296").Return(mockResult, nil).Once()
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusOK,
+		responses.ClosestSucursalResponse{
+			Sucursal:     mockSucursales[0],
+			DistanceInKm: calcDistance(&models.Position{Latitude: mockResult.Latitude, Longitude: mockResult.Longitude}, &mockSucursales[0]),
+			Geocoding:    &models.Geocoding{Confidence: 1, MatchedAddress: "Florida 296", Provider: geocoding.GazetteerProvider},
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestGetNearestSucursalWithoutGeocoderReturnsBadRequest() {
+	testSuite.useController()
+	request, reqErr := http.NewRequest("GET", "/sucursal/nearest?address=Florida+296", nil)
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusBadRequest,
+		responses.ErrorMsg{
+			Message: coordinatesRequired,
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalWithReverseGeocoderStoresNormalizedAddress() {
+	reverseGeocoder := &geocoderMock.ReverseGeocoder{}
+	testSuite.useController(WithReverseGeocoder(reverseGeocoder))
+	mockLat := -34.604258
+	mockLon := -58.375094
+	mockUUID := uuid.NewV4()
+	mockPostSucursal := requests.PostSucursal{
+		ID:        mockUUID.String(),
+		Address:   "Florida 296, C1005 CABA",
+		Latitude:  &mockLat,
+		Longitude: &mockLon,
+	}
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(mockPostSucursal))
+	reverseGeocoder.On("ReverseGeocode", mockLat, mockLon).Return(&geocoding.ReverseResult{
+		Address: geocoding.Address{
+			Street:     "Florida",
+			Number:     "296",
+			Locality:   "Ciudad Autónoma de Buenos Aires",
+			Province:   "C",
+			PostalCode: "C1005AAF",
+			Country:    "AR",
+		},
+		FormattedAddress: "Florida 296, C1005AAF CABA",
+		DistanceKm:       0.01,
+		Provider:         geocoding.GazetteerProvider,
+	}, nil).Once()
+	expectedNormalizedAddress := &models.NormalizedAddress{
+		Text: "florida 296 c1005 ciudad autonoma de buenos aires",
+		Components: &models.AddressComponents{
+			Street:     "Florida",
+			Number:     "296",
+			Locality:   "Ciudad Autónoma de Buenos Aires",
+			Province:   "C",
+			PostalCode: "C1005AAF",
+			Country:    "AR",
+		},
+		MatchedAddress: "Florida 296, C1005AAF CABA",
+		DistanceKm:     0.01,
+	}
+	expectedSucursal := mockPostSucursal
+	expectedSucursal.NormalizedAddress = expectedNormalizedAddress
+	testSuite.documentsMock.On("Create", &expectedSucursal).Return(nil, nil).Once()
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusOK,
+		responses.PostSucursal{
+			Message:           "Successfully created sucursal",
+			ID:                mockUUID.String(),
+			NormalizedAddress: expectedNormalizedAddress,
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalWithFarAddressIsRejected() {
+	testSuite.useController(WithGeocoder(testSuite.geocoderMock), WithAddressValidation(AddressValidationReject, 0.5))
+	mockLat := -34.5600
+	mockLon := -58.4560
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(requests.PostSucursal{
+		ID:        uuid.NewV4().String(),
+		Address:   "Florida 296, C1005 CABA",
+		Latitude:  &mockLat,
+		Longitude: &mockLon,
+	}))
+	mockResult := &geocoding.Result{Latitude: -34.604258, Longitude: -58.375094, Confidence: 1}
+	testSuite.geocoderMock.On("Geocode", "Florida 296, C1005 CABA").Return(mockResult, nil).Once()
+
+	testSuite.Require().NoError(reqErr)
+	distance := calcDistance(&models.Position{Latitude: mockLat, Longitude: mockLon}, &models.Sucursal{Latitude: mockResult.Latitude, Longitude: mockResult.Longitude})
+	expectedResult := testCaseResult{
+		http.StatusBadRequest,
+		responses.ErrorMsg{
+			Message: fmt.Sprintf(addressTooFar, distance),
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+	testSuite.documentsMock.AssertNotCalled(testSuite.T(), "Create", mock.Anything)
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalWithFarAddressReturnsWarning() {
+	testSuite.useController(WithGeocoder(testSuite.geocoderMock), WithAddressValidation(AddressValidationWarn, 0.5))
+	mockLat := -34.5600
+	mockLon := -58.4560
+	mockUUID := uuid.NewV4()
+	mockPostSucursal := requests.PostSucursal{
+		ID:        mockUUID.String(),
+		Address:   "Florida 296, C1005 CABA",
+		Latitude:  &mockLat,
+		Longitude: &mockLon,
+	}
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(mockPostSucursal))
+	mockResult := &geocoding.Result{Latitude: -34.604258, Longitude: -58.375094, Confidence: 1}
+	testSuite.geocoderMock.On("Geocode", "Florida 296, C1005 CABA").Return(mockResult, nil).Once()
+	mockPostSucursal.NormalizedAddress = normalizedAddress(mockPostSucursal.Address)
+	testSuite.documentsMock.On("Create", &mockPostSucursal).Return(nil, nil).Once()
+
+	testSuite.Require().NoError(reqErr)
+	distance := calcDistance(&models.Position{Latitude: mockLat, Longitude: mockLon}, &models.Sucursal{Latitude: mockResult.Latitude, Longitude: mockResult.Longitude})
+	expectedResult := testCaseResult{
+		http.StatusOK,
+		responses.PostSucursal{
+			Message:           "Successfully created sucursal",
+			ID:                mockUUID.String(),
+			Warnings:          []string{fmt.Sprintf(addressTooFar, distance)},
+			NormalizedAddress: normalizedAddress(mockPostSucursal.Address),
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalWithStructuredAddressRendersSingleLineAddress() {
+	mockLat := -34.604258
+	mockLon := -58.375094
+	mockUUID := uuid.NewV4()
+	mockPostSucursal := requests.PostSucursal{
+		ID:        mockUUID.String(),
+		Latitude:  &mockLat,
+		Longitude: &mockLon,
+		StructuredAddress: &models.AddressComponents{
+			Street:     "Florida",
+			Number:     "296",
+			Locality:   "Ciudad Autónoma de Buenos Aires",
+			Province:   "C",
+			PostalCode: "C1005AAF",
+			Country:    "AR",
+		},
+	}
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(mockPostSucursal))
+	expectedSucursal := mockPostSucursal
+	expectedSucursal.Address = "Florida 296, C1005AAF Ciudad Autónoma de Buenos Aires"
+	expectedSucursal.NormalizedAddress = normalizedAddress(expectedSucursal.Address)
+	testSuite.documentsMock.On("Create", &expectedSucursal).Return(nil, nil).Once()
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusOK,
+		responses.PostSucursal{
+			Message:           "Successfully created sucursal",
+			ID:                mockUUID.String(),
+			NormalizedAddress: normalizedAddress(expectedSucursal.Address),
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalWithInvalidStructuredAddressReturnsBadRequest() {
+	mockLat := -34.604258
+	mockLon := -58.375094
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(requests.PostSucursal{
+		ID:        uuid.NewV4().String(),
+		Latitude:  &mockLat,
+		Longitude: &mockLon,
+		StructuredAddress: &models.AddressComponents{
+			Street:     "Florida",
+			Number:     "296",
+			Province:   "I",
+			PostalCode: "C10005",
+		},
+	}))
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusBadRequest,
+		ApiError{
+			Message: "Error when validating payload",
+			Errors: []string{
+				"Province must be an Argentine province code like C or X",
+				"PostalCode must be a CPA postal code like C1005AAB or a four digit postal code",
+			},
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalWithEmptyStructuredAddressReturnsBadRequest() {
+	mockLat := -34.604258
+	mockLon := -58.375094
+	for _, components := range []*models.AddressComponents{{}, {Number: "296", Province: "C", PostalCode: "C1005AAB"}} {
+		request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(requests.PostSucursal{
+			ID:                uuid.NewV4().String(),
+			Latitude:          &mockLat,
+			Longitude:         &mockLon,
+			StructuredAddress: components,
+		}))
+
+		testSuite.Require().NoError(reqErr)
+		expectedResult := testCaseResult{
+			http.StatusBadRequest,
+			ApiError{
+				Message: "Error when validating payload",
+				Errors:  []string{"Street or Locality is required"},
+			},
+		}
+		testSuite.verifyResponse(request, expectedResult)
+	}
+	testSuite.documentsMock.AssertNotCalled(testSuite.T(), "Create", mock.Anything)
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalWithPostalCodeFromAnotherProvinceReturnsBadRequest() {
+	mockLat := -31.4135
+	mockLon := -64.18105
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(requests.PostSucursal{
+		ID:        uuid.NewV4().String(),
+		Latitude:  &mockLat,
+		Longitude: &mockLon,
+		StructuredAddress: &models.AddressComponents{
+			Street:     "Av. Colón",
+			Number:     "500",
+			Locality:   "Córdoba",
+			Province:   "X",
+			PostalCode: "C5000AAA",
+		},
+	}))
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusBadRequest,
+		ApiError{
+			Message: "Error when validating payload",
+			Errors: []string{
+				"PostalCode must start with the province code X",
+			},
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestListSucursalesFiltersByProvince() {
+	request, reqErr := http.NewRequest("GET", "/sucursal?province=C%C3%B3rdoba", nil)
+	mockSucursales := []models.Sucursal{
+		{
+			ID:        "caba",
+			Address:   "Florida 296, C1005 CABA",
+			Latitude:  -34.604258,
+			Longitude: -58.375094,
+		},
+		{
+			ID:        "cordoba single line",
+			Address:   "Av. Colón 500, X5000 Córdoba",
+			Latitude:  -31.4135,
+			Longitude: -64.18105,
+		},
+		{
+			ID:                "cordoba structured",
+			Address:           "Dean Funes 100, Córdoba",
+			StructuredAddress: &models.AddressComponents{Street: "Dean Funes", Number: "100", Locality: "Córdoba", Province: "X"},
+			Latitude:          -31.4140,
+			Longitude:         -64.1860,
+		},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusOK,
+		responses.ListSucursalesResponse{
+			Sucursales: []models.Sucursal{mockSucursales[1], mockSucursales[2]},
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestGetProvincesGroupsSucursalesByProvince() {
+	request, reqErr := http.NewRequest("GET", "/sucursal/provinces", nil)
+	mockSucursales := []models.Sucursal{
+		{ID: "caba", Address: "Florida 296, C1005 CABA"},
+		{ID: "caba 2", Address: "Av. Corrientes 1200, C1043AAZ CABA"},
+		{ID: "cordoba", Address: "Av. Colón 500, X5000 Córdoba"},
+		{ID: "unknown", Address: "123 Fake St."},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusOK,
+		responses.ProvincesResponse{
+			Provinces: []responses.ProvinceSummary{
+				{Code: "C", Name: "Ciudad Autónoma de Buenos Aires", Sucursales: 2},
+				{Code: "X", Name: "Córdoba", Sucursales: 1},
+			},
+			Unknown: 1,
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestGetClosestSucursalWithDMSParamsReturnsClosestSucursal() {
+	request, reqErr := http.NewRequest("GET", "/sucursal/"+url.PathEscape(`34°36'13"S`)+"/"+url.PathEscape(`58°22'54"O`), nil)
+	mockPosition := &models.Position{Latitude: -(34 + 36.0/60 + 13.0/3600), Longitude: -(58 + 22.0/60 + 54.0/3600)}
+	mockSucursales := []models.Sucursal{
+		{ID: "winner", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094},
+		{ID: "failure", Address: "Av. Colón 500, X5000 Córdoba", Latitude: -31.4135, Longitude: -64.18105},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusOK,
+		responses.ClosestSucursalResponse{
+			Sucursal:     mockSucursales[0],
+			DistanceInKm: calcDistance(mockPosition, &mockSucursales[0]),
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestGetClosestSucursalWithOutOfRangeLongitudeReturnsBadRequest() {
+	request, reqErr := http.NewRequest("GET", "/sucursal/-34.6/"+url.PathEscape("190°W"), nil)
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusBadRequest,
+		responses.ErrorMsg{
+			Message: invalidLongitudeVal,
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalWithProjectedPositionStoresWGS84() {
+	mockUUID := uuid.NewV4()
+	mockPostSucursal := requests.PostSucursal{
+		ID:       mockUUID.String(),
+		Address:  "Florida 296, C1005 CABA",
+		Position: "posgar:5:5648457,6170144",
+	}
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(mockPostSucursal))
+	var created *requests.PostSucursal
+	testSuite.documentsMock.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		created = args.Get(0).(*requests.PostSucursal)
+	}).Return(nil, nil).Once()
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusOK,
+		responses.PostSucursal{
+			Message:           "Successfully created sucursal",
+			ID:                mockUUID.String(),
+			NormalizedAddress: normalizedAddress(mockPostSucursal.Address),
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+	testSuite.Require().InDelta(-34.603722, *created.Latitude, 0.00001)
+	testSuite.Require().InDelta(-58.381592, *created.Longitude, 0.00001)
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalWithPositionAndLatitudeReturnsBadRequest() {
+	mockLat := -34.604258
+	mockLon := -58.375094
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(requests.PostSucursal{
+		ID:        uuid.NewV4().String(),
+		Address:   "Florida 296, C1005 CABA",
+		Latitude:  &mockLat,
+		Longitude: &mockLon,
+		Position:  "48Q39JW9+G9",
+	}))
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusBadRequest,
+		ApiError{
+			Message: "Error when validating payload",
+			Errors: []string{
+				"Position can't be sent together with Latitude",
+			},
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestGetNearestSucursalByPlusCodeReturnsClosestSucursal() {
+	request, reqErr := http.NewRequest("GET", "/sucursal/nearest?position="+url.QueryEscape("48Q39JW9+G9"), nil)
+	mockSucursales := []models.Sucursal{
+		{ID: "winner", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094},
+		{ID: "failure", Address: "Av. Colón 500, X5000 Córdoba", Latitude: -31.4135, Longitude: -64.18105},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusOK,
+		responses.ClosestSucursalResponse{
+			Sucursal:     mockSucursales[0],
+			DistanceInKm: calcDistance(&models.Position{Latitude: -34.6036875, Longitude: -58.3815625}, &mockSucursales[0]),
+		},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestGetSucursalWithGeoJSONAcceptReturnsFeature() {
+	mockSucursal := models.Sucursal{
+		ID:          "b309060a-ce7b-4649-abc1-4cf3f6e51d1b",
+		Address:     "Florida 296, C1005 CABA",
+		Latitude:    -34.604258,
+		Longitude:   -58.375094,
+		ServiceArea: &models.ServiceArea{Type: models.ServiceAreaRadius, RadiusKm: 2.5},
+	}
+	request, reqErr := http.NewRequest("GET", "/sucursal/"+mockSucursal.ID, nil)
+	request.Header.Set("Accept", "application/geo+json, application/json;q=0.9")
+	marshaledSucursal, err := dynamodbattribute.MarshalMap(mockSucursal)
+	testSuite.Require().NoError(err)
+	testSuite.documentsMock.On("Get", models.SucursalKey{ID: mockSucursal.ID}).Return(&dynamodb.GetItemOutput{Item: marshaledSucursal}, nil).Once()
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusOK,
+		geojson.NewFeature(mockSucursal.ID, geojson.NewPoint(mockSucursal.Latitude, mockSucursal.Longitude), map[string]interface{}{
+			"id":          mockSucursal.ID,
+			"address":     mockSucursal.Address,
+			"serviceArea": map[string]interface{}{"type": "radius", "radiusKm": 2.5},
+		}),
+	}
+	response := testSuite.verifyResponse(request, expectedResult)
+	testSuite.Require().Equal(geojson.MediaType, response.Header.Get("Content-Type"))
+}
+
+func (testSuite *APIControllerTestSuite) TestGetClosestSucursalWithGeoJSONAcceptReturnsFeatureWithDistance() {
+	mockPosition := &models.Position{Latitude: -34.6037, Longitude: -58.3816}
+	request, reqErr := http.NewRequest("GET", fmt.Sprintf("/sucursal/%f/%f", mockPosition.Latitude, mockPosition.Longitude), nil)
+	request.Header.Set("Accept", geojson.MediaType)
+	mockSucursales := []models.Sucursal{
+		{ID: "winner", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusOK,
+		geojson.NewFeature("winner", geojson.NewPoint(-34.604258, -58.375094), map[string]interface{}{
+			"id":           "winner",
+			"address":      "Florida 296, C1005 CABA",
+			"distanceInKm": calcDistance(mockPosition, &mockSucursales[0]),
+		}),
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestListSucursalesWithGeoJSONAcceptReturnsFeatureCollection() {
+	request, reqErr := http.NewRequest("GET", "/sucursal", nil)
+	request.Header.Set("Accept", geojson.MediaType)
+	mockSucursales := []models.Sucursal{
+		{ID: "caba", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094},
+		{ID: "cordoba", Address: "Av. Colón 500, X5000 Córdoba", Latitude: -31.4135, Longitude: -64.18105},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusOK,
+		geojson.NewFeatureCollection([]*geojson.Feature{
+			geojson.NewFeature("caba", geojson.NewPoint(-34.604258, -58.375094), map[string]interface{}{"id": "caba", "address": "Florida 296, C1005 CABA"}),
+			geojson.NewFeature("cordoba", geojson.NewPoint(-31.4135, -64.18105), map[string]interface{}{"id": "cordoba", "address": "Av. Colón 500, X5000 Córdoba"}),
+		}),
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestGetTileReturnsCachedVectorTile() {
+	mockSucursales := []models.Sucursal{
+		{ID: "florida", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094},
+		{ID: "corrientes", Address: "Av. Corrientes 1200, C1043 CABA", Latitude: -34.6035, Longitude: -58.383},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	sucursales := []*models.Sucursal{&mockSucursales[0], &mockSucursales[1]}
+	expectedResult := testCaseResult{http.StatusOK, string(encodeClusterTile(clusterSucursales(sucursales, 4), 4, 5, 9))}
+
+	for i := 0; i < 2; i++ {
+		request, reqErr := http.NewRequest("GET", "/tiles/4/5/9.mvt", nil)
+		testSuite.Require().NoError(reqErr)
+		response := testSuite.verifyResponse(request, expectedResult)
+		testSuite.Require().Equal(mvt.MediaType, response.Header.Get("Content-Type"))
+	}
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestGetTileWithInvalidCoordinatesReturnsBadRequest() {
+	request, reqErr := http.NewRequest("GET", "/tiles/2/4/0.mvt", nil)
+
+	testSuite.Require().NoError(reqErr)
+	expectedResult := testCaseResult{
+		http.StatusBadRequest,
+		responses.ErrorMsg{Message: invalidTile},
+	}
+	testSuite.verifyResponse(request, expectedResult)
+}
+
+func (testSuite *APIControllerTestSuite) TestGetClustersReturnsCachedClustersInsideBoundingBox() {
+	mockSucursales := []models.Sucursal{
+		{ID: "florida", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094},
+		{ID: "corrientes", Address: "Av. Corrientes 1200, C1043 CABA", Latitude: -34.6035, Longitude: -58.383},
+		{ID: "cordoba", Address: "Av. Colón 500, X5000 Córdoba", Latitude: -31.4135, Longitude: -64.18105},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+
+	expectedResult := testCaseResult{
+		http.StatusOK,
+		responses.ClustersResponse{Zoom: 4, Clusters: []responses.ClusterSummary{
+			{
+				Position:   models.Position{Latitude: -31.4135, Longitude: -64.18105},
+				Count:      1,
+				Bounds:     cluster.Bounds{South: -31.4135, West: -64.18105, North: -31.4135, East: -64.18105},
+				SucursalID: "cordoba",
+			},
+			{
+				Position: models.Position{Latitude: (-34.604258 + -34.6035) / 2, Longitude: (-58.375094 + -58.383) / 2},
+				Count:    2,
+				Bounds:   cluster.Bounds{South: -34.604258, West: -58.383, North: -34.6035, East: -58.375094},
+			},
+		}},
+	}
+	request, reqErr := http.NewRequest("GET", "/sucursal/clusters?zoom=4", nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, expectedResult)
+
+	expectedResult.value = responses.ClustersResponse{Zoom: 4, Clusters: expectedResult.value.(responses.ClustersResponse).Clusters[1:]}
+	request, reqErr = http.NewRequest("GET", "/sucursal/clusters?zoom=4&bbox=-60,-35,-58,-34", nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, expectedResult)
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestGetClustersWithInvalidParamsReturnsBadRequest() {
+	testCases := map[string]string{
+		"/sucursal/clusters":                             invalidZoom,
+		"/sucursal/clusters?zoom=31":                     invalidZoom,
+		"/sucursal/clusters?zoom=4&bbox=-60,-35,-58":     invalidBoundingBox,
+		"/sucursal/clusters?zoom=4&bbox=-60,-34,-58,-35": invalidBoundingBox,
+	}
+	for url, message := range testCases {
+		request, reqErr := http.NewRequest("GET", url, nil)
+		testSuite.Require().NoError(reqErr)
+		testSuite.verifyResponse(request, testCaseResult{http.StatusBadRequest, responses.ErrorMsg{Message: message}})
+	}
+}
+
+func (testSuite *APIControllerTestSuite) TestGetClosestSucursalWithSpatialIndexDoesNotScanTable() {
+	mockSucursales := []models.Sucursal{
+		{ID: "caba", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094},
+		{ID: "cordoba", Address: "Av. Colón 500, X5000 Córdoba", Latitude: -31.4135, Longitude: -64.18105},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	testSuite.useController(WithSpatialIndex(0, 0))
+
+	mockPosition := &models.Position{Latitude: -31.42, Longitude: -64.19}
+	request, reqErr := http.NewRequest("GET", fmt.Sprintf("/sucursal/%f/%f", mockPosition.Latitude, mockPosition.Longitude), nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.ClosestSucursalResponse{Sucursal: mockSucursales[1], DistanceInKm: calcDistance(mockPosition, &mockSucursales[1])},
+	})
+
+	mockLat := -31.42
+	mockLon := -64.19
+	mockPostSucursal := requests.PostSucursal{ID: uuid.NewV4().String(), Address: "Av. Colón 1000, X5000 Córdoba", Latitude: &mockLat, Longitude: &mockLon}
+	mockPostSucursal.NormalizedAddress = normalizedAddress(mockPostSucursal.Address)
+	testSuite.documentsMock.On("Create", &mockPostSucursal).Return(nil, nil).Once()
+	request, reqErr = http.NewRequest("POST", "/sucursal", convertStructToBuffer(mockPostSucursal))
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.PostSucursal{Message: "Successfully created sucursal", ID: mockPostSucursal.ID, NormalizedAddress: normalizedAddress(mockPostSucursal.Address)},
+	})
+
+	request, reqErr = http.NewRequest("GET", fmt.Sprintf("/sucursal/%f/%f", mockPosition.Latitude, mockPosition.Longitude), nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.ClosestSucursalResponse{
+			Sucursal: models.Sucursal{
+				ID: mockPostSucursal.ID, Address: mockPostSucursal.Address, Latitude: mockLat, Longitude: mockLon,
+				NormalizedAddress: mockPostSucursal.NormalizedAddress,
+			},
+			DistanceInKm: 0,
+		},
+	})
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestSpatialIndexLoadsFromItsSource() {
+	mockSucursales := []models.Sucursal{
+		{ID: "caba", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094},
+	}
+	source := &documentsMock.DocumentsClient{}
+	source.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	testSuite.useController(WithSpatialIndex(0, 0), WithIndexSource(source))
+
+	mockPosition := &models.Position{Latitude: -34.6037, Longitude: -58.3816}
+	request, reqErr := http.NewRequest("GET", fmt.Sprintf("/sucursal/%f/%f", mockPosition.Latitude, mockPosition.Longitude), nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.ClosestSucursalResponse{Sucursal: mockSucursales[0], DistanceInKm: calcDistance(mockPosition, &mockSucursales[0])},
+	})
+	source.AssertExpectations(testSuite.T())
+	testSuite.documentsMock.AssertNotCalled(testSuite.T(), "ListAll")
+}
+
+func (testSuite *APIControllerTestSuite) TestGetClosestSucursalWithStaleSpatialIndexScansTable() {
+	mockSucursales := []models.Sucursal{
+		{ID: "caba", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Twice()
+	testSuite.useController(WithSpatialIndex(0, time.Minute))
+	loadedAt := testSuite.controller.index.loadedAt
+	testSuite.controller.index.now = func() time.Time { return loadedAt.Add(2 * time.Minute) }
+
+	mockPosition := &models.Position{Latitude: -34.6037, Longitude: -58.3816}
+	request, reqErr := http.NewRequest("GET", fmt.Sprintf("/sucursal/%f/%f", mockPosition.Latitude, mockPosition.Longitude), nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.ClosestSucursalResponse{Sucursal: mockSucursales[0], DistanceInKm: calcDistance(mockPosition, &mockSucursales[0])},
+	})
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+
+	status := testSuite.controller.index.status()
+	testSuite.Require().True(status.Stale)
+	testSuite.Require().Equal(uint64(1), status.Fallbacks)
+	testSuite.Require().Equal(1, status.Sucursales)
+	testSuite.Require().Equal(float64(120), status.StalenessSeconds)
+}
+
+func (testSuite *APIControllerTestSuite) TestListSucursalesWithinRadiusReturnsClosestFirst() {
+	mockSucursales := []models.Sucursal{
+		{ID: "cordoba", Address: "Av. Colón 500, X5000 Córdoba", Latitude: -31.4135, Longitude: -64.18105},
+		{ID: "florida", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094},
+		{ID: "corrientes", Address: "Av. Corrientes 1200, C1043 CABA", Latitude: -34.6035, Longitude: -58.383},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+
+	request, reqErr := http.NewRequest("GET", "/sucursal?position=-34.6037,-58.3816&radiusKm=5", nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.ListSucursalesResponse{Sucursales: []models.Sucursal{mockSucursales[2], mockSucursales[1]}},
+	})
+
+	request, reqErr = http.NewRequest("GET", "/sucursal?position=-34.6037,-58.3816&radiusKm=-1", nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{http.StatusBadRequest, responses.ErrorMsg{Message: invalidRadius}})
+}
+
+func (testSuite *APIControllerTestSuite) TestStreamRecordsUpdateSpatialIndex() {
+	mockSucursales := []models.Sucursal{
+		{ID: "caba", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	testSuite.useController(WithSpatialIndex(0, 0))
+	streamArn := "arn:aws:dynamodb:us-east-1:000000000000:table/sucursal_table/stream/2020-10-19T00:00:00.000"
+	stream := fake.NewStream(streamArn)
+	stream.AddShard("shard", "")
+	consumer := dynamodbstreams.NewConsumer(stream, streamArn, testSuite.controller.ApplyStreamRecords, dynamodbstreams.NewMemoryCheckpoints())
+	testSuite.Require().NoError(consumer.Poll())
+
+	cordoba := models.Sucursal{ID: "cordoba", Address: "Av. Colón 500, X5000 Córdoba", Latitude: -31.4135, Longitude: -64.18105}
+	cordobaItem := marshalSucursales(testSuite, []models.Sucursal{cordoba})[0]
+	cabaItem := marshalSucursales(testSuite, mockSucursales)[0]
+	stream.Put("shard", dynamodbstreams.EventInsert, map[string]*dynamodb.AttributeValue{"id": cordobaItem["id"]}, cordobaItem, nil)
+	stream.Put("shard", dynamodbstreams.EventRemove, map[string]*dynamodb.AttributeValue{"id": cabaItem["id"]}, nil, cabaItem)
+	// The batch is applied at once, and the last change to a sucursal wins.
+	rosarioItem := marshalSucursales(testSuite, []models.Sucursal{{ID: "rosario", Address: "Córdoba 1000, S2000 Rosario", Latitude: -32.9468, Longitude: -60.6393}})[0]
+	stream.Put("shard", dynamodbstreams.EventInsert, map[string]*dynamodb.AttributeValue{"id": rosarioItem["id"]}, rosarioItem, nil)
+	stream.Put("shard", dynamodbstreams.EventRemove, map[string]*dynamodb.AttributeValue{"id": rosarioItem["id"]}, nil, rosarioItem)
+	testSuite.Require().NoError(consumer.Poll())
+
+	mockPosition := &models.Position{Latitude: -34.6037, Longitude: -58.3816}
+	request, reqErr := http.NewRequest("GET", fmt.Sprintf("/sucursal/%f/%f", mockPosition.Latitude, mockPosition.Longitude), nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.ClosestSucursalResponse{Sucursal: cordoba, DistanceInKm: calcDistance(mockPosition, &cordoba)},
+	})
+	testSuite.Require().Equal(1, testSuite.controller.index.status().Sucursales)
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestSpatialIndexKeepsChangesAppliedWhileReloading() {
+	caba := models.Sucursal{ID: "caba", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094}
+	cordoba := models.Sucursal{ID: "cordoba", Address: "Av. Colón 500, X5000 Córdoba", Latitude: -31.4135, Longitude: -64.18105}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, []models.Sucursal{caba}), nil).Once()
+	testSuite.useController(WithSpatialIndex(0, 0))
+	index := testSuite.controller.index
+	// The scan reads the table before cordoba is created and caba is deleted.
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, []models.Sucursal{caba}), nil).Run(func(mock.Arguments) {
+		index.put(&cordoba)
+		index.remove(caba.ID)
+	}).Once()
+	testSuite.Require().NoError(index.load(testSuite.documentsMock))
+
+	_, sucursales, ok := index.fresh()
+	testSuite.Require().True(ok)
+	testSuite.Require().Equal(map[string]*models.Sucursal{"cordoba": &cordoba}, sucursales)
+	testSuite.Require().Empty(index.pending)
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestSpatialIndexStopsRefreshingWhenStopIsClosed() {
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, nil), nil).Once()
+	testSuite.useController(WithSpatialIndex(0, 0))
+	testSuite.controller.index.refreshInterval = time.Hour
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		testSuite.controller.index.refreshPeriodically(testSuite.documentsMock, stop)
+		close(done)
+	}()
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		testSuite.FailNow("the refresh did not stop")
+	}
+}
+
+func (testSuite *APIControllerTestSuite) TestGetCacheStatusReportsDocumentsCacheStats() {
+	mockSucursal := models.Sucursal{ID: "caba", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094}
+	marshaledSucursal := marshalSucursales(testSuite, []models.Sucursal{mockSucursal})[0]
+	testSuite.documentsMock.On("Get", models.SucursalKey{ID: mockSucursal.ID}).Return(&dynamodb.GetItemOutput{Item: marshaledSucursal}, nil).Once()
+	cachingClient := documents.NewCachingClient(testSuite.documentsMock, 10, time.Minute)
+	testSuite.useController(WithDocumentsCache(cachingClient))
+	for i := 0; i < 2; i++ {
+		_, err := cachingClient.Get(models.SucursalKey{ID: mockSucursal.ID})
+		testSuite.Require().NoError(err)
+	}
+
+	request, reqErr := http.NewRequest("GET", "/cache/status", nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.CacheStatusResponse{Hits: 1, Misses: 1, Entries: 1},
+	})
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestGetCacheStatusWithoutCacheReturnsZeros() {
+	request, reqErr := http.NewRequest("GET", "/cache/status", nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{http.StatusOK, responses.CacheStatusResponse{}})
+}
+
+func (testSuite *APIControllerTestSuite) TestGetCoverageGapsReturnsCellsFarFromSucursales() {
+	mockSucursales := []models.Sucursal{
+		{ID: "north-west", Address: "Ruta 8 km 60, Pilar", Latitude: -34.2, Longitude: -58.7},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Twice()
+	coverage := requests.CoverageGaps{
+		Polygon:     [][][]float64{{{-59, -34}, {-59, -35}, {-58, -35}, {-58, -34}, {-59, -34}}},
+		CellSizeKm:  50,
+		ThresholdKm: 30,
+	}
+	request, reqErr := http.NewRequest("POST", "/analytics/coverage-gaps", convertStructToBuffer(coverage))
+	testSuite.Require().NoError(reqErr)
+	response := executeRequest(request, testSuite.router)
+	testSuite.Require().Equal(http.StatusOK, response.Code)
+	result := responses.CoverageGapsResponse{}
+	testSuite.Require().NoError(json.Unmarshal(response.Body.Bytes(), &result))
+
+	testSuite.Require().Equal(models.Position{Latitude: -34, Longitude: -59}, result.Origin)
+	testSuite.Require().Equal(3, result.Rows)
+	testSuite.Require().Equal(2, result.Columns)
+	// The cells of the last row have their center south of the polygon.
+	testSuite.Require().Equal(4, result.Cells)
+	testSuite.Require().Len(result.Gaps, 3)
+	for i, cell := range [][]int{{0, 1}, {1, 0}, {1, 1}} {
+		gap := result.Gaps[i]
+		testSuite.Require().Equal(cell, []int{gap.Row, gap.Column})
+		testSuite.Require().Equal("north-west", gap.NearestSucursalID)
+		testSuite.Require().Equal(calcDistance(&gap.Position, &mockSucursales[0]), gap.DistanceInKm)
+		testSuite.Require().Greater(gap.DistanceInKm, coverage.ThresholdKm)
+	}
+
+	request, reqErr = http.NewRequest("POST", "/analytics/coverage-gaps", convertStructToBuffer(coverage))
+	request.Header.Set("Accept", geojson.MediaType)
+	testSuite.Require().NoError(reqErr)
+	response = executeRequest(request, testSuite.router)
+	collection := geojson.FeatureCollection{}
+	testSuite.Require().NoError(json.Unmarshal(response.Body.Bytes(), &collection))
+	testSuite.Require().Len(collection.Features, 3)
+	testSuite.Require().Equal("0/1", collection.Features[0].ID)
+	testSuite.Require().Equal(geojson.TypePolygon, collection.Features[0].Geometry.Type)
+}
+
+func (testSuite *APIControllerTestSuite) TestGetCoverageGapsWithTooManyCellsReturnsBadRequest() {
+	request, reqErr := http.NewRequest("POST", "/analytics/coverage-gaps", convertStructToBuffer(requests.CoverageGaps{
+		Polygon:    [][][]float64{{{-75, -20}, {-75, -55}, {-50, -55}, {-50, -20}, {-75, -20}}},
+		CellSizeKm: 0.1,
+	}))
+
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusBadRequest,
+		responses.ErrorMsg{Message: fmt.Sprintf(tooManyCells, maxCoverageCells)},
+	})
+}
+
+func (testSuite *APIControllerTestSuite) TestGetCatchmentsSplitsBoundaryBetweenSucursales() {
+	mockSucursales := []models.Sucursal{
+		{ID: "west", Address: "Ruta 5 km 60, Luján", Latitude: -34.5, Longitude: -58.75},
+		{ID: "cordoba", Address: "Av. Colón 500, X5000 Córdoba", Latitude: -31.4135, Longitude: -64.18105},
+		{ID: "east", Address: "Florida 296, C1005 CABA", Latitude: -34.5, Longitude: -58.25},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	request, reqErr := http.NewRequest("POST", "/analytics/catchments", convertStructToBuffer(requests.Catchments{
+		Boundary: [][][]float64{{{-59, -34}, {-59, -35}, {-58, -35}, {-58, -34}, {-59, -34}}},
+	}))
+	testSuite.Require().NoError(reqErr)
+	response := executeRequest(request, testSuite.router)
+	testSuite.Require().Equal(http.StatusOK, response.Code)
+	result := responses.CatchmentsResponse{}
+	testSuite.Require().NoError(json.Unmarshal(response.Body.Bytes(), &result))
+
+	// One degree of latitude by one degree of longitude at 34.5°S.
+	testSuite.Require().InDelta(10188.77, result.BoundaryAreaKm2, 0.01)
+	testSuite.Require().Len(result.Catchments, 2)
+	testSuite.Require().Equal("west", result.Catchments[0].SucursalID)
+	testSuite.Require().Equal("east", result.Catchments[1].SucursalID)
+	testSuite.Require().InDelta(result.BoundaryAreaKm2/2, result.Catchments[0].AreaKm2, 1e-6)
+	testSuite.Require().InDelta(result.BoundaryAreaKm2/2, result.Catchments[1].AreaKm2, 1e-6)
+	for _, position := range result.Catchments[0].Coordinates[0] {
+		testSuite.Require().LessOrEqual(position[0], -58.5+1e-9)
+	}
+	ring := result.Catchments[1].Coordinates[0]
+	testSuite.Require().Equal(ring[0], ring[len(ring)-1])
+	signedArea := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		signedArea += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	testSuite.Require().Greater(signedArea, 0.0, "exterior rings must be counterclockwise")
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalNearExistingOnesReturnsConflictWithCandidates() {
+	mockSucursales := []models.Sucursal{
+		{ID: "florida", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094},
+		{ID: "corrientes", Address: "Av. Corrientes 1200, C1043 CABA", Latitude: -34.6035, Longitude: -58.3830},
+		{ID: "cordoba", Address: "Av. Colón 1000, X5000 Córdoba", Latitude: -31.4135, Longitude: -64.1811},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	testSuite.useController(WithDuplicateDetection(0.025, 0.8))
+
+	mockLat := -34.6043
+	mockLon := -58.3751
+	mockPostSucursal := requests.PostSucursal{ID: uuid.NewV4().String(), Address: "Avenida Corrientes 1200, C1043 CABA", Latitude: &mockLat, Longitude: &mockLon}
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(mockPostSucursal))
+	testSuite.Require().NoError(reqErr)
+	position := &models.Position{Latitude: mockLat, Longitude: mockLon}
+	tokens := geocoding.Tokenize(mockPostSucursal.Address)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusConflict,
+		responses.DuplicateSucursalResponse{
+			Message: duplicateSucursal,
+			Candidates: []responses.DuplicateCandidate{
+				{
+					Sucursal:          mockSucursales[0],
+					DistanceInKm:      calcDistance(position, &mockSucursales[0]),
+					AddressSimilarity: geocoding.TokenSimilarity(tokens, geocoding.Tokenize(mockSucursales[0].Address)),
+					Reasons:           []string{duplicateByDistance},
+				},
+				{
+					Sucursal:          mockSucursales[1],
+					DistanceInKm:      calcDistance(position, &mockSucursales[1]),
+					AddressSimilarity: 1,
+					Reasons:           []string{duplicateByAddress},
+				},
+			},
+		},
+	})
+	testSuite.documentsMock.AssertNotCalled(testSuite.T(), "Create", mock.Anything)
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalWithSpatialIndexFindsDuplicatesWithoutScanningTable() {
+	mockSucursales := []models.Sucursal{
+		{ID: "corrientes", Address: "Av. Corrientes 1200, C1043 CABA", Latitude: -34.6035, Longitude: -58.3830},
+		{ID: "cordoba", Address: "Av. Colón 1000, X5000 Córdoba", Latitude: -31.4135, Longitude: -64.1811},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	testSuite.useController(WithSpatialIndex(0, 0), WithDuplicateDetection(0.025, 0.8))
+
+	mockLat := -34.6043
+	mockLon := -58.3751
+	mockPostSucursal := requests.PostSucursal{ID: uuid.NewV4().String(), Address: "Avenida Corrientes 1200, C1043 CABA", Latitude: &mockLat, Longitude: &mockLon}
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(mockPostSucursal))
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusConflict,
+		responses.DuplicateSucursalResponse{
+			Message: duplicateSucursal,
+			Candidates: []responses.DuplicateCandidate{{
+				Sucursal:          mockSucursales[0],
+				DistanceInKm:      calcDistance(&models.Position{Latitude: mockLat, Longitude: mockLon}, &mockSucursales[0]),
+				AddressSimilarity: 1,
+				Reasons:           []string{duplicateByAddress},
+			}},
+		},
+	})
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+	testSuite.documentsMock.AssertNotCalled(testSuite.T(), "Create", mock.Anything)
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalLookingLikeADuplicateWithForceCreatesIt() {
+	testSuite.useController(WithDuplicateDetection(0.025, 0.8))
+
+	mockLat := -34.6043
+	mockLon := -58.3751
+	mockPostSucursal := requests.PostSucursal{ID: uuid.NewV4().String(), Address: "Florida 296, C1005 CABA", Latitude: &mockLat, Longitude: &mockLon, Force: true}
+	mockPostSucursal.NormalizedAddress = normalizedAddress(mockPostSucursal.Address)
+	testSuite.documentsMock.On("Create", &mockPostSucursal).Return(nil, nil).Once()
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(mockPostSucursal))
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.PostSucursal{Message: "Successfully created sucursal", ID: mockPostSucursal.ID, NormalizedAddress: normalizedAddress(mockPostSucursal.Address)},
+	})
+	testSuite.documentsMock.AssertNotCalled(testSuite.T(), "ListAll")
+}
+
+func (testSuite *APIControllerTestSuite) TestCreateSucursalInEmptyTableWithDuplicateDetectionCreatesIt() {
+	testSuite.useController(WithDuplicateDetection(0.025, 0.8))
+
+	mockLat := -34.6043
+	mockLon := -58.3751
+	mockPostSucursal := requests.PostSucursal{ID: uuid.NewV4().String(), Address: "Florida 296, C1005 CABA", Latitude: &mockLat, Longitude: &mockLon}
+	testSuite.documentsMock.On("ListAll").Return([]map[string]*dynamodb.AttributeValue{}, nil).Once()
+	mockPostSucursal.NormalizedAddress = normalizedAddress(mockPostSucursal.Address)
+	testSuite.documentsMock.On("Create", &mockPostSucursal).Return(nil, nil).Once()
+	request, reqErr := http.NewRequest("POST", "/sucursal", convertStructToBuffer(mockPostSucursal))
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.PostSucursal{Message: "Successfully created sucursal", ID: mockPostSucursal.ID, NormalizedAddress: normalizedAddress(mockPostSucursal.Address)},
+	})
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestGetDuplicatesReportReturnsSuspectedPairsClosestFirst() {
+	mockSucursales := []models.Sucursal{
+		{ID: "florida", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094},
+		{ID: "florida bis", Address: "Florida 300, C1005 CABA", Latitude: -34.6043, Longitude: -58.3751},
+		{ID: "corrientes", Address: "Av. Corrientes 1200, C1043 CABA", Latitude: -34.6035, Longitude: -58.3830},
+		{ID: "cordoba", Address: "Av. Colón 1000, X5000 Córdoba", Latitude: -31.4135, Longitude: -64.1811},
+		{ID: "corrientes typo", Address: "Avenida Corrientes 1200, C1043 CABA", Latitude: -34.6100, Longitude: -58.3900},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+
+	request, reqErr := http.NewRequest("GET", "/sucursal/duplicates?maxDistanceM=50", nil)
+	testSuite.Require().NoError(reqErr)
+	similarity := func(first models.Sucursal, second models.Sucursal) float64 {
+		return geocoding.TokenSimilarity(geocoding.Tokenize(first.Address), geocoding.Tokenize(second.Address))
+	}
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.DuplicatesReportResponse{
+			MaxDistanceKm:        0.05,
+			MinAddressSimilarity: defaultDuplicateMinSimilarity,
+			Pairs: []responses.DuplicatePair{
+				{
+					First:             mockSucursales[0],
+					Second:            mockSucursales[1],
+					DistanceInKm:      calcDistance(mockSucursales[0].Position(), &mockSucursales[1]),
+					AddressSimilarity: similarity(mockSucursales[0], mockSucursales[1]),
+					Reasons:           []string{duplicateByDistance},
+				},
+				{
+					First:             mockSucursales[2],
+					Second:            mockSucursales[4],
+					DistanceInKm:      calcDistance(mockSucursales[2].Position(), &mockSucursales[4]),
+					AddressSimilarity: 1,
+					Reasons:           []string{duplicateByAddress},
+				},
+			},
+		},
+	})
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestGetDuplicatesReportWithInvalidSimilarityReturnsBadRequest() {
+	request, reqErr := http.NewRequest("GET", "/sucursal/duplicates?minSimilarity=1.5", nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusBadRequest,
+		responses.ErrorMsg{Message: invalidDuplicateSimilarity},
+	})
+}
+
+func (testSuite *APIControllerTestSuite) TestGetClosestSucursalWithDemandAnalyticsRecordsQuantizedSearch() {
+	mockSucursales := []models.Sucursal{
+		{ID: "caba", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	store := demand.NewMemoryStore()
+	recorder := demand.NewRecorder(store, 10)
+	testSuite.useController(WithDemandAnalytics(recorder, store))
+
+	mockPosition := &models.Position{Latitude: -34.6037, Longitude: -58.3816}
+	request, reqErr := http.NewRequest("GET", fmt.Sprintf("/sucursal/%f/%f", mockPosition.Latitude, mockPosition.Longitude), nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.ClosestSucursalResponse{Sucursal: mockSucursales[0], DistanceInKm: calcDistance(mockPosition, &mockSucursales[0])},
+	})
+	testSuite.Require().Equal(1, recorder.Stats().Pending)
+
+	stop := make(chan struct{})
+	close(stop)
+	recorder.Run(stop)
+	queries, err := store.Range(time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	testSuite.Require().NoError(err)
+	testSuite.Require().Len(queries, 1)
+	testSuite.Require().Equal(-34.605, queries[0].Latitude)
+	testSuite.Require().Equal(-58.385, queries[0].Longitude)
+	testSuite.Require().Equal("caba", queries[0].SucursalID)
+}
+
+func (testSuite *APIControllerTestSuite) TestGetDemandReturnsHeatmapAndCountsOverTimeRange() {
+	from := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	store := demand.NewMemoryStore()
+	testSuite.Require().NoError(store.Put([]demand.Query{
+		{Time: from.Add(-time.Minute), Latitude: -34.605, Longitude: -58.375, SucursalID: "before"},
+		{Time: from, Latitude: -34.605, Longitude: -58.375, SucursalID: "caba"},
+		{Time: from.Add(time.Hour), Latitude: -34.615, Longitude: -58.365, SucursalID: "caba"},
+		{Time: from.Add(2 * time.Hour), Latitude: -31.415, Longitude: -64.185, SucursalID: "cordoba"},
+		{Time: from.Add(24 * time.Hour), Latitude: -31.415, Longitude: -64.185, SucursalID: "after"},
+	}))
+	testSuite.useController(WithDemandAnalytics(demand.NewRecorder(store, 10), store))
+	to := from.Add(24 * time.Hour)
+	timeRange := fmt.Sprintf("from=%s&to=%s", url.QueryEscape(from.Format(time.RFC3339)), url.QueryEscape(to.Format(time.RFC3339)))
+
+	request, reqErr := http.NewRequest("GET", "/analytics/demand/heatmap?cellDegrees=0.02&"+timeRange, nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.DemandHeatmapResponse{
+			From:        from,
+			To:          to,
+			CellDegrees: 0.02,
+			Total:       3,
+			Cells: []responses.DemandCell{
+				{Latitude: -34.61, Longitude: -58.37, Count: 2},
+				{Latitude: -31.41, Longitude: -64.19, Count: 1},
+			},
+		},
+	})
+
+	request, reqErr = http.NewRequest("GET", "/analytics/demand/sucursales?"+timeRange, nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.SucursalDemandResponse{
+			From:       from,
+			To:         to,
+			Total:      3,
+			Sucursales: []responses.SucursalDemand{{ID: "caba", Count: 2}, {ID: "cordoba", Count: 1}},
+		},
+	})
+}
+
+func (testSuite *APIControllerTestSuite) TestGetDemandWithInvalidParamsReturnsBadRequest() {
+	store := demand.NewMemoryStore()
+	testSuite.useController(WithDemandAnalytics(demand.NewRecorder(store, 10), store))
+	cases := map[string]string{
+		"/analytics/demand/sucursales?from=yesterday":                                    fmt.Sprintf(invalidTimeRange, maxDemandRangeDays),
+		"/analytics/demand/sucursales?from=2020-10-02T00:00:00Z&to=2020-10-01T00:00:00Z": fmt.Sprintf(invalidTimeRange, maxDemandRangeDays),
+		"/analytics/demand/sucursales?from=2020-01-01T00:00:00Z&to=2020-10-01T00:00:00Z": fmt.Sprintf(invalidTimeRange, maxDemandRangeDays),
+		"/analytics/demand/heatmap?cellDegrees=0.001":                                    fmt.Sprintf(invalidCellDegrees, demand.DefaultPrecisionDegrees),
+		"/analytics/demand/heatmap?cellDegrees=NaN":                                      fmt.Sprintf(invalidCellDegrees, demand.DefaultPrecisionDegrees),
+		"/analytics/demand/heatmap?cellDegrees=Inf":                                      fmt.Sprintf(invalidCellDegrees, demand.DefaultPrecisionDegrees),
+	}
+	for path, message := range cases {
+		request, reqErr := http.NewRequest("GET", path, nil)
+		testSuite.Require().NoError(reqErr)
+		testSuite.verifyResponse(request, testCaseResult{http.StatusBadRequest, responses.ErrorMsg{Message: message}})
+	}
+}
+
+func (testSuite *APIControllerTestSuite) TestGetDemandWhenNotEnabledReturnsNotFound() {
+	request, reqErr := http.NewRequest("GET", "/analytics/demand/heatmap", nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{http.StatusNotFound, responses.ErrorMsg{Message: demandNotEnabled}})
+}
+
+func (testSuite *APIControllerTestSuite) TestGetSiteSelectionProposesSitesForUploadedDemand() {
+	mockSucursales := []models.Sucursal{
+		{ID: "caba", Address: "Florida 296, C1005 CABA", Latitude: -34.6, Longitude: -58.4},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	weight := 3.0
+	selection := requests.SiteSelection{
+		Count: 1,
+		Demand: []requests.DemandPoint{
+			{Latitude: -34.6, Longitude: -58.4},
+			{Latitude: -31.41, Longitude: -64.19, Weight: &weight},
+			{Latitude: -31.42, Longitude: -64.18},
+		},
+	}
+	request, reqErr := http.NewRequest("POST", "/analytics/site-selection", convertStructToBuffer(selection))
+	testSuite.Require().NoError(reqErr)
+
+	result := siting.Recommend([]siting.Demand{
+		{Latitude: -34.6, Longitude: -58.4, Weight: 1},
+		{Latitude: -31.41, Longitude: -64.19, Weight: 3},
+		{Latitude: -31.42, Longitude: -64.18, Weight: 1},
+	}, []siting.Location{{Latitude: -34.6, Longitude: -58.4}}, 1)
+	testSuite.Require().Len(result.Sites, 1)
+	testSuite.Require().InDelta(-31.41, result.Sites[0].Latitude, 0.005)
+	testSuite.Require().InDelta(-64.19, result.Sites[0].Longitude, 0.005)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.SiteSelectionResponse{
+			DemandPoints: 3,
+			Sites:        []responses.ProposedSite{{Latitude: result.Sites[0].Latitude, Longitude: result.Sites[0].Longitude, DemandWeight: 4}},
+			Before:       &responses.DistanceStats{MeanKm: result.Before.MeanKm, P95Km: result.Before.P95Km},
+			After:        responses.DistanceStats{MeanKm: result.After.MeanKm, P95Km: result.After.P95Km},
+			Improvement: &responses.DistanceStats{
+				MeanKm: result.Before.MeanKm - result.After.MeanKm,
+				P95Km:  result.Before.P95Km - result.After.P95Km,
+			},
+		},
+	})
+}
+
+func (testSuite *APIControllerTestSuite) TestGetSiteSelectionUsesRecordedSearchesWithoutUploadedDemand() {
+	testSuite.documentsMock.On("ListAll").Return([]map[string]*dynamodb.AttributeValue{}, nil).Once()
+	from := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	store := demand.NewMemoryStore()
+	testSuite.Require().NoError(store.Put([]demand.Query{
+		{Time: from, Latitude: -34.605, Longitude: -58.375, SucursalID: "caba"},
+		{Time: from.Add(time.Hour), Latitude: -34.605, Longitude: -58.375, SucursalID: "caba"},
+	}))
+	testSuite.useController(WithDemandAnalytics(demand.NewRecorder(store, 10), store))
+	path := fmt.Sprintf("/analytics/site-selection?from=%s&to=%s", url.QueryEscape(from.Format(time.RFC3339)), url.QueryEscape(from.Add(24*time.Hour).Format(time.RFC3339)))
+	request, reqErr := http.NewRequest("POST", path, convertStructToBuffer(requests.SiteSelection{Count: 2}))
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.SiteSelectionResponse{
+			DemandPoints: 1,
+			Sites:        []responses.ProposedSite{{Latitude: -34.605, Longitude: -58.375, DemandWeight: 2}},
+			After:        responses.DistanceStats{},
+		},
+	})
+}
+
+func (testSuite *APIControllerTestSuite) TestGetSiteSelectionWithoutDemandReturnsBadRequest() {
+	request, reqErr := http.NewRequest("POST", "/analytics/site-selection", convertStructToBuffer(requests.SiteSelection{Count: 2}))
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{http.StatusBadRequest, responses.ErrorMsg{Message: demandRequired}})
+
+	store := demand.NewMemoryStore()
+	testSuite.useController(WithDemandAnalytics(demand.NewRecorder(store, 10), store))
+	request, reqErr = http.NewRequest("POST", "/analytics/site-selection", convertStructToBuffer(requests.SiteSelection{Count: 2}))
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{http.StatusBadRequest, responses.ErrorMsg{Message: noDemandRecorded}})
+}
+
+func (testSuite *APIControllerTestSuite) TestGetClosureSimulationReportsReassignedDemand() {
+	mockSucursales := []models.Sucursal{
+		{ID: "a", Address: "Florida 296, C1005 CABA", Latitude: -34.6, Longitude: -58.4},
+		{ID: "b", Address: "Av. Rivadavia 5000, C1424 CABA", Latitude: -34.7, Longitude: -58.5},
+		{ID: "c", Address: "Av. Colón 1000, X5000 Córdoba", Latitude: -31.4, Longitude: -64.2},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	weight := 2.0
+	simulation := requests.ClosureSimulation{
+		Remove: []string{"a"},
+		Add:    []requests.HypotheticalSucursal{{ID: "d", Latitude: -34.61, Longitude: -58.41}},
+		Demand: []requests.DemandPoint{
+			{Latitude: -34.6, Longitude: -58.4, Weight: &weight},
+			{Latitude: -34.7, Longitude: -58.5},
+			{Latitude: -31.4, Longitude: -64.2},
+		},
+	}
+	request, reqErr := http.NewRequest("POST", "/analytics/closure-simulation", convertStructToBuffer(simulation))
+	testSuite.Require().NoError(reqErr)
+	points := []siting.Demand{{Latitude: -34.6, Longitude: -58.4, Weight: 2}, {Latitude: -34.7, Longitude: -58.5, Weight: 1}, {Latitude: -31.4, Longitude: -64.2, Weight: 1}}
+	before := make([]float64, len(points))
+	after := make([]float64, len(points))
+	for i, point := range points {
+		position := &models.Position{Latitude: point.Latitude, Longitude: point.Longitude}
+		before[i] = calcDistance(position, &mockSucursales[i])
+		after[i] = before[i]
+	}
+	after[0] = calcDistance(&models.Position{Latitude: -34.6, Longitude: -58.4}, &models.Sucursal{Latitude: -34.61, Longitude: -58.41})
+	beforeStats := siting.Summarize(points, before)
+	afterStats := siting.Summarize(points, after)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.ClosureSimulationResponse{
+			DemandPoints:     3,
+			TotalWeight:      4,
+			ReassignedWeight: 2,
+			Before:           responses.DistanceStats{MeanKm: beforeStats.MeanKm, P95Km: beforeStats.P95Km},
+			After:            responses.DistanceStats{MeanKm: afterStats.MeanKm, P95Km: afterStats.P95Km},
+			Sucursales: []responses.SucursalImpact{
+				{ID: "a", Status: sucursalRemoved, DemandBefore: 2, DemandAfter: 0},
+				{ID: "d", Status: sucursalAdded, DemandBefore: 0, DemandAfter: 2},
+				{ID: "b", Status: sucursalKept, DemandBefore: 1, DemandAfter: 1},
+				{ID: "c", Status: sucursalKept, DemandBefore: 1, DemandAfter: 1},
+			},
+			Reassignments: []responses.Reassignment{{From: "a", To: "d", Weight: 2, MeanDistanceIncreaseKm: after[0] - before[0]}},
+		},
+	})
+}
+
+func (testSuite *APIControllerTestSuite) TestGetClosureSimulationWithInvalidSucursalesReturnsBadRequest() {
+	mockSucursales := []models.Sucursal{
+		{ID: "a", Address: "Florida 296, C1005 CABA", Latitude: -34.6, Longitude: -58.4},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Times(3)
+	demandPoints := []requests.DemandPoint{{Latitude: -34.6, Longitude: -58.4}}
+	cases := []struct {
+		simulation requests.ClosureSimulation
+		message    string
+	}{
+		{requests.ClosureSimulation{Remove: []string{"a", "x", "y"}, Demand: demandPoints}, fmt.Sprintf(unknownSucursales, "x, y")},
+		{requests.ClosureSimulation{Remove: []string{"a"}, Demand: demandPoints}, noSucursalesLeft},
+		{requests.ClosureSimulation{Add: []requests.HypotheticalSucursal{{ID: "a"}}, Demand: demandPoints}, fmt.Sprintf(duplicatedSucursalID, "a")},
+	}
+	for _, testCase := range cases {
+		request, reqErr := http.NewRequest("POST", "/analytics/closure-simulation", convertStructToBuffer(testCase.simulation))
+		testSuite.Require().NoError(reqErr)
+		testSuite.verifyResponse(request, testCaseResult{http.StatusBadRequest, responses.ErrorMsg{Message: testCase.message}})
+	}
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestAssignCustomersRespectsSucursalCapacity() {
+	capacity := 1
+	mockSucursales := []models.Sucursal{
+		{ID: "small", Address: "Florida 296, C1005 CABA", Latitude: -34.6, Longitude: -58.4, Capacity: &capacity},
+		{ID: "large", Address: "Av. Rivadavia 5000, C1424 CABA", Latitude: -34.6, Longitude: -58.5},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	assign := requests.AssignCustomers{
+		Customers: []requests.Customer{
+			{ID: "near small", Latitude: -34.6, Longitude: -58.43},
+			{ID: "at small", Latitude: -34.6, Longitude: -58.4},
+		},
+	}
+	request, reqErr := http.NewRequest("POST", "/sucursal/assignments", convertStructToBuffer(assign))
+	testSuite.Require().NoError(reqErr)
+	nearLarge := calcDistance(&models.Position{Latitude: -34.6, Longitude: -58.43}, &mockSucursales[1])
+	atSmall := calcDistance(&models.Position{Latitude: -34.6, Longitude: -58.4}, &mockSucursales[0])
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.AssignCustomersResponse{
+			Assignments: []responses.CustomerAssignment{
+				{CustomerID: "near small", SucursalID: "large", DistanceInKm: nearLarge},
+				{CustomerID: "at small", SucursalID: "small", DistanceInKm: atSmall},
+			},
+			Unassigned:        []string{},
+			TotalDistanceInKm: nearLarge + atSmall,
+			Sucursales: []responses.SucursalLoad{
+				{ID: "large", Assigned: 1},
+				{ID: "small", Capacity: &capacity, Assigned: 1},
+			},
+		},
+	})
+}
+
+func (testSuite *APIControllerTestSuite) TestAssignCustomersReportsUnassignedCustomers() {
+	capacity := 0
+	mockSucursales := []models.Sucursal{
+		{ID: "full", Address: "Florida 296, C1005 CABA", Latitude: -34.6, Longitude: -58.4, Capacity: &capacity},
+		{ID: "far", Address: "Av. Colón 1000, X5000 Córdoba", Latitude: -31.4, Longitude: -64.2},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	assign := requests.AssignCustomers{
+		Customers:     []requests.Customer{{ID: "customer", Latitude: -34.6, Longitude: -58.4}},
+		MaxDistanceKm: 50,
+	}
+	request, reqErr := http.NewRequest("POST", "/sucursal/assignments", convertStructToBuffer(assign))
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.AssignCustomersResponse{
+			Assignments: []responses.CustomerAssignment{},
+			Unassigned:  []string{"customer"},
+			Sucursales:  []responses.SucursalLoad{},
+		},
+	})
+}
+
+func (testSuite *APIControllerTestSuite) TestAssignCustomersWithRepeatedIdsReturnsBadRequest() {
+	assign := requests.AssignCustomers{
+		Customers: []requests.Customer{{ID: "customer"}, {ID: "customer"}},
+	}
+	request, reqErr := http.NewRequest("POST", "/sucursal/assignments", convertStructToBuffer(assign))
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusBadRequest,
+		ApiError{Message: "Error when validating payload", Errors: []string{"Customers must contain unique values"}},
+	})
+}
+
+func (testSuite *APIControllerTestSuite) TestGetVisitRouteOrdersVisits() {
+	mockSucursales := []models.Sucursal{
+		{ID: "a", Address: "Florida 296, C1005 CABA", Latitude: -34.6, Longitude: -58.41},
+		{ID: "b", Address: "Av. Rivadavia 5000, C1424 CABA", Latitude: -34.6, Longitude: -58.42},
+		{ID: "c", Address: "Av. Cabildo 2000, C1428 CABA", Latitude: -34.56, Longitude: -58.45},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	visit := requests.VisitRoute{
+		Start:         &requests.Waypoint{Latitude: -34.6, Longitude: -58.4},
+		SucursalIDs:   []string{"b", "a"},
+		ReturnToStart: true,
+	}
+	request, reqErr := http.NewRequest("POST", "/sucursal/visit-route", convertStructToBuffer(visit))
+	testSuite.Require().NoError(reqErr)
+	start := &models.Position{Latitude: -34.6, Longitude: -58.4}
+	toA := calcDistance(start, &mockSucursales[0])
+	aToB := calcDistance(&models.Position{Latitude: -34.6, Longitude: -58.41}, &mockSucursales[1])
+	bToStart := calcDistance(&models.Position{Latitude: -34.6, Longitude: -58.42}, &models.Sucursal{Latitude: -34.6, Longitude: -58.4})
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.VisitRouteResponse{
+			Routes: []responses.VisitRoute{{
+				Order: []string{"a", "b"},
+				Legs: []responses.RouteLeg{
+					{From: routeStart, To: "a", DistanceInKm: toA},
+					{From: "a", To: "b", DistanceInKm: aToB},
+					{From: "b", To: routeStart, DistanceInKm: bToStart},
+				},
+				DistanceInKm: toA + aToB + bToStart,
+			}},
+			TotalDistanceInKm: toA + aToB + bToStart,
+		},
+	})
+}
+
+func (testSuite *APIControllerTestSuite) TestGetVisitRouteSplitsRoutesByMaxLegs() {
+	mockSucursales := []models.Sucursal{
+		{ID: "a", Address: "Florida 296, C1005 CABA", Latitude: -34.6, Longitude: -58.41},
+		{ID: "b", Address: "Av. Rivadavia 5000, C1424 CABA", Latitude: -34.6, Longitude: -58.42},
+		{ID: "c", Address: "Av. Cabildo 2000, C1428 CABA", Latitude: -34.6, Longitude: -58.43},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	visit := requests.VisitRoute{
+		Start:       &requests.Waypoint{Latitude: -34.6, Longitude: -58.4},
+		SucursalIDs: []string{"c", "b", "a"},
+		End:         &requests.Waypoint{Latitude: -34.6, Longitude: -58.44},
+		MaxLegs:     3,
+	}
+	request, reqErr := http.NewRequest("POST", "/sucursal/visit-route", convertStructToBuffer(visit))
+	testSuite.Require().NoError(reqErr)
+	recorder := executeRequest(request, testSuite.router)
+	testSuite.Require().Equal(http.StatusOK, recorder.Code)
+	response := responses.VisitRouteResponse{}
+	testSuite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	testSuite.Require().Len(response.Routes, 2)
+	visited := []string{}
+	for _, planned := range response.Routes {
+		testSuite.Require().LessOrEqual(len(planned.Legs), 3)
+		testSuite.Require().Equal(routeStart, planned.Legs[0].From)
+		testSuite.Require().Equal(routeEnd, planned.Legs[len(planned.Legs)-1].To)
+		visited = append(visited, planned.Order...)
+	}
+	testSuite.Require().ElementsMatch([]string{"a", "b", "c"}, visited)
+}
+
+func (testSuite *APIControllerTestSuite) TestGetVisitRouteWithInvalidRequestReturnsBadRequest() {
+	mockSucursales := []models.Sucursal{
+		{ID: "a", Address: "Florida 296, C1005 CABA", Latitude: -34.6, Longitude: -58.41},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Twice()
+	start := &requests.Waypoint{Latitude: -34.6, Longitude: -58.4}
+	cases := []struct {
+		visit   requests.VisitRoute
+		message string
+	}{
+		{requests.VisitRoute{Start: start, SucursalIDs: []string{"a", "x", "y"}}, fmt.Sprintf(unknownSucursales, "x, y")},
+		{requests.VisitRoute{Start: start, SucursalIDs: []string{"a"}, ReturnToStart: true, MaxLegs: 1}, fmt.Sprintf(invalidMaxLegs, 2)},
+	}
+	for _, testCase := range cases {
+		request, reqErr := http.NewRequest("POST", "/sucursal/visit-route", convertStructToBuffer(testCase.visit))
+		testSuite.Require().NoError(reqErr)
+		testSuite.verifyResponse(request, testCaseResult{http.StatusBadRequest, responses.ErrorMsg{Message: testCase.message}})
+	}
+	visit := requests.VisitRoute{Start: start, SucursalIDs: []string{"a"}, ReturnToStart: true, End: start}
+	request, reqErr := http.NewRequest("POST", "/sucursal/visit-route", convertStructToBuffer(visit))
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusBadRequest,
+		ApiError{Message: "Error when validating payload", Errors: []string{"ReturnToStart can't be sent together with End"}},
+	})
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+// riverNetwork has the search position and sucursal b on the south bank of a river, and sucursal a
+// right across it, reached over a bridge 4 km west.
+func riverNetwork() *roads.Network {
+	nodes := map[int64]osm.Node{
+		1: {ID: 1, Latitude: -34.6, Longitude: -58.4},
+		2: {ID: 2, Latitude: -34.6, Longitude: -58.43},
+		3: {ID: 3, Latitude: -34.6, Longitude: -58.44},
+		4: {ID: 4, Latitude: -34.59, Longitude: -58.44},
+		5: {ID: 5, Latitude: -34.59, Longitude: -58.4},
+	}
+	ways := []osm.Way{
+		{ID: 1, Nodes: []int64{1, 2, 3}, Tags: map[string]string{"highway": "residential"}},
+		{ID: 2, Nodes: []int64{3, 4}, Tags: map[string]string{"highway": "residential", "bridge": "yes"}},
+		{ID: 3, Nodes: []int64{4, 5}, Tags: map[string]string{"highway": "residential"}},
+	}
+	return roads.Build(&osm.Extract{Nodes: nodes, Ways: ways}, roads.Driving)
+}
+
+func (testSuite *APIControllerTestSuite) TestGetClosestSucursalRanksByRoadDistance() {
+	network := riverNetwork()
+	testSuite.useController(WithRoadNetwork(network, 2))
+	mockSucursales := []models.Sucursal{
+		{ID: "a", Address: "Florida 296, C1005 CABA", Latitude: -34.59, Longitude: -58.4},
+		{ID: "b", Address: "Av. Rivadavia 5000, C1424 CABA", Latitude: -34.6, Longitude: -58.43},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	request, reqErr := http.NewRequest("GET", "/sucursal/-34.6/-58.4", nil)
+	testSuite.Require().NoError(reqErr)
+	route, ok := network.Route(-34.6, -58.4, -34.6, -58.43)
+	testSuite.Require().True(ok)
+	position := &models.Position{Latitude: -34.6, Longitude: -58.4}
+	testSuite.Require().Less(calcDistance(position, &mockSucursales[0]), calcDistance(position, &mockSucursales[1]))
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.ClosestSucursalResponse{
+			Sucursal:     mockSucursales[1],
+			DistanceInKm: calcDistance(position, &mockSucursales[1]),
+			Route:        &models.Route{Profile: "driving", DistanceInKm: route.DistanceKm, DurationInSeconds: route.DurationSeconds},
+		},
+	})
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestGetClosestSucursalOffTheRoadNetworkUsesStraightLine() {
+	testSuite.useController(WithRoadNetwork(riverNetwork(), 2))
+	mockSucursales := []models.Sucursal{
+		{ID: "a", Address: "Florida 296, C1005 CABA", Latitude: -34.59, Longitude: -58.4},
+		{ID: "b", Address: "Av. Rivadavia 5000, C1424 CABA", Latitude: -34.6, Longitude: -58.43},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	request, reqErr := http.NewRequest("GET", "/sucursal/-34.5/-58.4", nil)
+	testSuite.Require().NoError(reqErr)
+	position := &models.Position{Latitude: -34.5, Longitude: -58.4}
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.ClosestSucursalResponse{Sucursal: mockSucursales[0], DistanceInKm: calcDistance(position, &mockSucursales[0])},
+	})
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestSyncSucursalesDryRunPlansChanges() {
+	testSuite.useController(WithSyncMaxDeletePercent(50))
+	mockSucursales := []models.Sucursal{
+		{ID: "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", Address: "Florida 296, C1005 CABA", Latitude: -34.6, Longitude: -58.41},
+		{ID: "8d2e6a1f-3c4b-4a7d-8e9f-0a1b2c3d4e02", Address: "Av. Rivadavia 5000, C1424 CABA", Latitude: -34.6, Longitude: -58.42},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	snapshot := "id,address,latitude,longitude\n4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01,\"Florida 296, C1005 CABA\",-34.6,-58.41\nc61a9b3e-7d2f-4e5a-b8c9-d0e1f2a3b403,\"Av. Cabildo 2000, C1428 CABA\",-34.56,-58.45\n"
+	request, reqErr := http.NewRequest("POST", "/sucursal/sync", strings.NewReader(snapshot))
+	testSuite.Require().NoError(reqErr)
+	request.Header.Set("Content-Type", "text/csv")
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.SyncSucursalesResponse{
+			Changes: []responses.SyncChange{
+				{Action: importer.ActionCreate, Sucursal: &models.Sucursal{ID: "c61a9b3e-7d2f-4e5a-b8c9-d0e1f2a3b403", Address: "Av. Cabildo 2000, C1428 CABA", Latitude: -34.56, Longitude: -58.45}},
+				{Action: importer.ActionDelete, Sucursal: &mockSucursales[1]},
+			},
+			Created:   1,
+			Deleted:   1,
+			Unchanged: 1,
+		},
+	})
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestSyncSucursalesAppliesChanges() {
+	testSuite.useController(WithSyncMaxDeletePercent(50))
+	mockSucursales := []models.Sucursal{
+		{ID: "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", Name: "Centro", Address: "Florida 296, C1005 CABA", Latitude: -34.6, Longitude: -58.41},
+		{ID: "8d2e6a1f-3c4b-4a7d-8e9f-0a1b2c3d4e02", Address: "Av. Rivadavia 5000, C1424 CABA", Latitude: -34.6, Longitude: -58.42},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Once()
+	updated := &models.Sucursal{ID: "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", Name: "Microcentro", Address: "Florida 296, C1005 CABA", Latitude: -34.6, Longitude: -58.41}
+	testSuite.documentsMock.On("Put", updated).Return(&dynamodb.PutItemOutput{}, nil).Once()
+	testSuite.documentsMock.On("Delete", models.SucursalKey{ID: "8d2e6a1f-3c4b-4a7d-8e9f-0a1b2c3d4e02"}).Return(&dynamodb.DeleteItemOutput{}, nil).Once()
+	snapshot := `[{"id": "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", "name": "Microcentro", "address": "Florida 296, C1005 CABA", "latitude": -34.6, "longitude": -58.41}]`
+	request, reqErr := http.NewRequest("POST", "/sucursal/sync?apply=true", strings.NewReader(snapshot))
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.SyncSucursalesResponse{
+			Changes: []responses.SyncChange{
+				{Action: importer.ActionUpdate, Sucursal: updated, Fields: []responses.SyncFieldChange{{Field: "name", Old: `"Centro"`, New: `"Microcentro"`}}},
+				{Action: importer.ActionDelete, Sucursal: &mockSucursales[1]},
+			},
+			Updated: 1,
+			Deleted: 1,
+			Applied: true,
+		},
+	})
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestSyncSucursalesRefusesToDeleteTooMany() {
+	mockSucursales := []models.Sucursal{
+		{ID: "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", Address: "Florida 296, C1005 CABA", Latitude: -34.6, Longitude: -58.41},
+		{ID: "8d2e6a1f-3c4b-4a7d-8e9f-0a1b2c3d4e02", Address: "Av. Rivadavia 5000, C1424 CABA", Latitude: -34.6, Longitude: -58.42},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Times(3)
+	// The limit comes from the server, so neither planning nor the query can get past it.
+	for _, target := range []string{"/sucursal/sync?apply=true", "/sucursal/sync", "/sucursal/sync?apply=true&maxDeletePercent=100"} {
+		request, reqErr := http.NewRequest("POST", target, strings.NewReader("[]"))
+		testSuite.Require().NoError(reqErr)
+		testSuite.verifyResponse(request, testCaseResult{
+			http.StatusConflict,
+			responses.ErrorMsg{Message: fmt.Sprintf(tooManyDeletes, 2, 2, 10.0)},
+		})
+	}
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestSyncSucursalesWithInvalidSnapshotReturnsBadRequest() {
+	request, reqErr := http.NewRequest("POST", "/sucursal/sync", strings.NewReader(`[{"id": "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", "address": "Florida 296"}]`))
+	testSuite.Require().NoError(reqErr)
+	response := executeRequest(request, testSuite.router)
+	testSuite.Require().Equal(http.StatusBadRequest, response.Code)
+	testSuite.Require().Contains(response.Body.String(), "Snapshot could not be read: record 1")
+}
+
+func (testSuite *APIControllerTestSuite) TestDeleteSucursal() {
+	mockSucursal := models.Sucursal{ID: "b309060a-ce7b-4649-abc1-4cf3f6e51d1b", Address: "Florida 296, C1005 CABA", Latitude: -34.604258, Longitude: -58.375094}
+	key := models.SucursalKey{ID: mockSucursal.ID}
+	testSuite.documentsMock.On("Delete", key).Return(&dynamodb.DeleteItemOutput{}, nil).Once()
+	request, reqErr := http.NewRequest("DELETE", "/sucursal/"+mockSucursal.ID, nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.DeleteSucursal{ID: mockSucursal.ID, Message: "Successfully deleted sucursal"},
+	})
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestDeleteSucursalNotFoundReturnsBadRequest() {
+	key := models.SucursalKey{ID: "b309060a-ce7b-4649-abc1-4cf3f6e51d1b"}
+	testSuite.documentsMock.On("Delete", key).Return(nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)).Once()
+	request, reqErr := http.NewRequest("DELETE", "/sucursal/"+key.ID, nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{http.StatusBadRequest, responses.ErrorMsg{Message: idNotFoundError}})
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestListSucursalesPaginatesById() {
+	mockSucursales := []models.Sucursal{
+		{ID: "c", Address: "Av. Cabildo 2000, C1428 CABA", Latitude: -34.56, Longitude: -58.45},
+		{ID: "a", Address: "Florida 296, C1005 CABA", Latitude: -34.6, Longitude: -58.41},
+		{ID: "b", Address: "Av. Rivadavia 5000, C1424 CABA", Latitude: -34.6, Longitude: -58.42},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Twice()
+	request, reqErr := http.NewRequest("GET", "/sucursal?limit=2", nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.ListSucursalesResponse{Sucursales: []models.Sucursal{mockSucursales[1], mockSucursales[2]}, NextCursor: "b"},
+	})
+	request, reqErr = http.NewRequest("GET", "/sucursal?limit=2&cursor=b", nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.ListSucursalesResponse{Sucursales: []models.Sucursal{mockSucursales[0]}},
+	})
+	request, reqErr = http.NewRequest("GET", "/sucursal?limit=0", nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{http.StatusBadRequest, responses.ErrorMsg{Message: invalidLimit}})
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+func (testSuite *APIControllerTestSuite) TestListSucursalesWithinRadiusPaginatesClosestFirst() {
+	mockSucursales := []models.Sucursal{
+		{ID: "a", Address: "Av. Cabildo 2000, C1428 CABA", Latitude: -34.56, Longitude: -58.45},
+		{ID: "b", Address: "Florida 296, C1005 CABA", Latitude: -34.6037, Longitude: -58.3816},
+		{ID: "c", Address: "Av. Rivadavia 5000, C1424 CABA", Latitude: -34.6, Longitude: -58.42},
+	}
+	testSuite.documentsMock.On("ListAll").Return(marshalSucursales(testSuite, mockSucursales), nil).Times(3)
+	position := &models.Position{Latitude: -34.6037, Longitude: -58.3816}
+	request, reqErr := http.NewRequest("GET", "/sucursal?position=-34.6037,-58.3816&radiusKm=10&limit=2", nil)
+	testSuite.Require().NoError(reqErr)
+	cursor := strconv.FormatFloat(calcDistance(position, &mockSucursales[2]), 'g', -1, 64) + "_c"
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.ListSucursalesResponse{Sucursales: []models.Sucursal{mockSucursales[1], mockSucursales[2]}, NextCursor: cursor},
+	})
+	request, reqErr = http.NewRequest("GET", "/sucursal?position=-34.6037,-58.3816&radiusKm=10&limit=2&cursor="+cursor, nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{
+		http.StatusOK,
+		responses.ListSucursalesResponse{Sucursales: []models.Sucursal{mockSucursales[0]}},
+	})
+	request, reqErr = http.NewRequest("GET", "/sucursal?position=-34.6037,-58.3816&radiusKm=10&limit=2&cursor=b", nil)
+	testSuite.Require().NoError(reqErr)
+	testSuite.verifyResponse(request, testCaseResult{http.StatusBadRequest, responses.ErrorMsg{Message: invalidCursor}})
+	testSuite.documentsMock.AssertExpectations(testSuite.T())
+}
+
+// normalizedAddress is the normalized address stored with new sucursales when reverse geocoding is
+// not enabled.
+func normalizedAddress(address string) *models.NormalizedAddress {
+	return &models.NormalizedAddress{Text: geocoding.Normalize(address)}
+}
+
+// useController replaces the controller under test with one built with the given options.
+func (testSuite *APIControllerTestSuite) useController(options ...Option) {
+	controller, _ := NewAPIController(testSuite.documentsMock, options...)
+	testSuite.controller = controller
+	testSuite.router = mux.NewRouter()
+	controller.RegisterRoutes(testSuite.router)
+}
+
+func (testSuite *APIControllerTestSuite) verifyResponse(request *http.Request, expectedResult testCaseResult) *http.Response {
 	response := executeRequest(request, testSuite.router)
 	parsedResult := response.Body.String()
 	parsedExpectedResult := ""
@@ -170,6 +2011,19 @@ func (testSuite *APIControllerTestSuite) verifyResponse(request *http.Request, e
 	responseResult := response.Result()
 	responseResult.Body.Close()
 	testSuite.Require().Equal(expectedResult.status, responseResult.StatusCode, "status code mismatch")
+	return responseResult
+}
+
+func marshalSucursales(testSuite *APIControllerTestSuite, sucursales []models.Sucursal) []map[string]*dynamodb.AttributeValue {
+	marshaledSucursales := []map[string]*dynamodb.AttributeValue{}
+	for _, sucursal := range sucursales {
+		marshaledSucursal, err := dynamodbattribute.MarshalMap(sucursal)
+		if err != nil {
+			testSuite.Fail("Dynamodbattribute MarshalMap Failure")
+		}
+		marshaledSucursales = append(marshaledSucursales, marshaledSucursal)
+	}
+	return marshaledSucursales
 }
 
 func convertStructToBuffer(structure interface{}) *bytes.Buffer {
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/requests"
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/assignment"
+)
+
+func (instance *APIController) AssignCustomers(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error when trying to read request body: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidRequestBody})
+		return
+	}
+	assign := &requests.AssignCustomers{}
+	valErrs, err := ValidateRequest(body, assign)
+	if err != nil {
+		log.Printf("Error when trying to validate requests: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidRequestBody})
+		return
+	}
+	if valErrs != nil {
+		log.Println("Validation error in payload.")
+		writer.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(writer).Encode(valErrs)
+		return
+	}
+	sucursales, err := instance.allSucursales()
+	if err != nil {
+		log.Printf("Error when trying to list sucursales: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: internalServerError})
+		return
+	}
+	if len(sucursales) == 0 {
+		log.Println("No sucursales are loaded in database!")
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: sucursalesNotFoundError})
+		return
+	}
+	response := assignCustomers(assign, sucursales)
+	_ = json.NewEncoder(writer).Encode(&response)
+}
+
+// assignCustomers assigns the customers to the sucursales without going over their capacity.
+func assignCustomers(assign *requests.AssignCustomers, sucursales []*models.Sucursal) responses.AssignCustomersResponse {
+	customers := make([]assignment.Customer, len(assign.Customers))
+	for i, customer := range assign.Customers {
+		customers[i] = assignment.Customer{ID: customer.ID, Latitude: customer.Latitude, Longitude: customer.Longitude}
+	}
+	facilities := make([]assignment.Facility, len(sucursales))
+	byID := map[string]*models.Sucursal{}
+	for i, sucursal := range sucursales {
+		facilities[i] = assignment.Facility{ID: sucursal.ID, Latitude: sucursal.Latitude, Longitude: sucursal.Longitude, Capacity: assignment.Unlimited}
+		if sucursal.Capacity != nil {
+			facilities[i].Capacity = *sucursal.Capacity
+		}
+		byID[sucursal.ID] = sucursal
+	}
+	result := assignment.Assign(customers, facilities, assign.MaxDistanceKm)
+	response := responses.AssignCustomersResponse{
+		Assignments:       []responses.CustomerAssignment{},
+		Unassigned:        result.Unassigned,
+		TotalDistanceInKm: result.TotalDistanceKm,
+		Sucursales:        []responses.SucursalLoad{},
+	}
+	loads := map[string]int{}
+	for _, assigned := range result.Assignments {
+		response.Assignments = append(response.Assignments, responses.CustomerAssignment{
+			CustomerID:   assigned.CustomerID,
+			SucursalID:   assigned.FacilityID,
+			DistanceInKm: assigned.DistanceKm,
+		})
+		loads[assigned.FacilityID]++
+	}
+	for id, assigned := range loads {
+		response.Sucursales = append(response.Sucursales, responses.SucursalLoad{ID: id, Capacity: byID[id].Capacity, Assigned: assigned})
+	}
+	sort.Slice(response.Sucursales, func(i, j int) bool {
+		return response.Sucursales[i].ID < response.Sucursales[j].ID
+	})
+	return response
+}
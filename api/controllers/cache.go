@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+)
+
+// GetCacheStatus reports the statistics of the documents cache, or zeros when it is disabled.
+func (instance *APIController) GetCacheStatus(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	if instance.documentsCache == nil {
+		_ = json.NewEncoder(writer).Encode(&responses.CacheStatusResponse{})
+		return
+	}
+	stats := instance.documentsCache.Stats()
+	_ = json.NewEncoder(writer).Encode(&responses.CacheStatusResponse{
+		Hits:         stats.Hits,
+		Misses:       stats.Misses,
+		Entries:      stats.Entries,
+		Deduplicated: stats.Deduplicated,
+	})
+}
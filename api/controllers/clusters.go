@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/cluster"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Size in pixels of a 256 pixels tile of the cells used to cluster sucursales.
+	clusterRadius = 64
+	// Highest zoom level sucursales can be clustered at.
+	maxClusterZoom = 30
+)
+
+// zoomClusters holds the sucursales clustered at a zoom level.
+type zoomClusters struct {
+	clusters   []*cluster.Cluster
+	sucursales map[string]*models.Sucursal
+}
+
+func (instance *APIController) GetClusters(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	zoom, err := strconv.Atoi(r.URL.Query().Get("zoom"))
+	if err != nil || zoom < 0 || zoom > maxClusterZoom {
+		log.Printf("Invalid zoom: %s", r.URL.Query().Get("zoom"))
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidZoom})
+		return
+	}
+	var bounds *cluster.Bounds
+	if bbox := r.URL.Query().Get("bbox"); bbox != "" {
+		bounds, err = parseBoundingBox(bbox)
+		if err != nil {
+			log.Printf("Invalid bounding box %s: %s", bbox, err)
+			writer.WriteHeader(http.StatusBadRequest)
+			generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidBoundingBox})
+			return
+		}
+	}
+	clustered, ok := instance.clustersAtZoom(writer, zoom)
+	if !ok {
+		return
+	}
+	response := responses.ClustersResponse{Zoom: zoom, Clusters: []responses.ClusterSummary{}}
+	for _, group := range clustered.clusters {
+		if bounds != nil && !bounds.Contains(group.Latitude, group.Longitude) {
+			continue
+		}
+		summary := responses.ClusterSummary{
+			Position: models.Position{Latitude: group.Latitude, Longitude: group.Longitude},
+			Count:    group.Count,
+			Bounds:   group.Bounds,
+		}
+		if group.Count == 1 {
+			summary.SucursalID = group.IDs[0]
+		}
+		response.Clusters = append(response.Clusters, summary)
+	}
+	_ = json.NewEncoder(writer).Encode(&response)
+}
+
+// clustersAtZoom clusters every sucursal at the zoom level. Clusters are cached per zoom level until a
+// sucursal is created. When loading the sucursales fails, the error response has already been written
+// and false is returned.
+func (instance *APIController) clustersAtZoom(writer http.ResponseWriter, zoom int) (*zoomClusters, bool) {
+	key := strconv.Itoa(zoom)
+	if cached, ok := instance.clusterCache.Get(key); ok {
+		return cached.(*zoomClusters), true
+	}
+	sucursales, ok := instance.listSucursales(writer)
+	if !ok {
+		return nil, false
+	}
+	clustered := clusterSucursales(sucursales, zoom)
+	instance.clusterCache.Set(key, clustered)
+	return clustered, true
+}
+
+func clusterSucursales(sucursales []*models.Sucursal, zoom int) *zoomClusters {
+	points := make([]cluster.Point, 0, len(sucursales))
+	byID := map[string]*models.Sucursal{}
+	for _, sucursal := range sucursales {
+		points = append(points, cluster.Point{ID: sucursal.ID, Latitude: sucursal.Latitude, Longitude: sucursal.Longitude})
+		byID[sucursal.ID] = sucursal
+	}
+	return &zoomClusters{clusters: cluster.Grid(points, zoom, clusterRadius), sucursales: byID}
+}
+
+// parseBoundingBox parses a "west,south,east,north" bounding box in decimal degrees. The west edge is
+// greater than the east edge for boxes crossing the antimeridian.
+func parseBoundingBox(value string) (*cluster.Bounds, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return nil, errors.New("bounding box must have four coordinates")
+	}
+	edges := make([]float64, 4)
+	for i, part := range parts {
+		edge, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid bounding box coordinate")
+		}
+		edges[i] = edge
+	}
+	bounds := &cluster.Bounds{West: edges[0], South: edges[1], East: edges[2], North: edges[3]}
+	if bounds.South < -90 || bounds.North > 90 || bounds.South > bounds.North ||
+		bounds.West < -180 || bounds.West > 180 || bounds.East < -180 || bounds.East > 180 {
+		return nil, errors.New("bounding box out of range")
+	}
+	return bounds, nil
+}
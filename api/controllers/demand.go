@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/demand"
+)
+
+const (
+	// Time range of demand queries when the request doesn't set one.
+	defaultDemandRange = 7 * 24 * time.Hour
+	// Longest time range of demand queries read by a single request.
+	maxDemandRangeDays = 31
+)
+
+// recordDemand queues a closest sucursal search to be written to the demand store, if enabled.
+func (instance *APIController) recordDemand(position *models.Position, sucursalID string) {
+	if instance.demandRecorder == nil {
+		return
+	}
+	instance.demandRecorder.Record(position.Latitude, position.Longitude, sucursalID)
+}
+
+func (instance *APIController) GetDemandHeatmap(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	if !instance.demandEnabled(writer) {
+		return
+	}
+	cellDegrees := instance.demandRecorder.PrecisionDegrees
+	if value := r.URL.Query().Get("cellDegrees"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) || parsed < cellDegrees {
+			log.Printf("Invalid demand heatmap cell size: %s", value)
+			writer.WriteHeader(http.StatusBadRequest)
+			generateErrorMessage(writer, &responses.ErrorMsg{Message: fmt.Sprintf(invalidCellDegrees, cellDegrees)})
+			return
+		}
+		cellDegrees = parsed
+	}
+	from, to, queries, ok := instance.demandQueries(writer, r)
+	if !ok {
+		return
+	}
+	response := responses.DemandHeatmapResponse{From: from, To: to, CellDegrees: cellDegrees, Total: len(queries), Cells: []responses.DemandCell{}}
+	for _, cell := range demand.Heatmap(queries, cellDegrees) {
+		response.Cells = append(response.Cells, responses.DemandCell{Latitude: cell.Latitude, Longitude: cell.Longitude, Count: cell.Count})
+	}
+	_ = json.NewEncoder(writer).Encode(&response)
+}
+
+func (instance *APIController) GetDemandBySucursal(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	if !instance.demandEnabled(writer) {
+		return
+	}
+	from, to, queries, ok := instance.demandQueries(writer, r)
+	if !ok {
+		return
+	}
+	response := responses.SucursalDemandResponse{From: from, To: to, Total: len(queries), Sucursales: []responses.SucursalDemand{}}
+	for _, count := range demand.CountBySucursal(queries) {
+		response.Sucursales = append(response.Sucursales, responses.SucursalDemand{ID: count.SucursalID, Count: count.Count})
+	}
+	_ = json.NewEncoder(writer).Encode(&response)
+}
+
+func (instance *APIController) GetDemandStatus(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	if instance.demandRecorder == nil {
+		_ = json.NewEncoder(writer).Encode(&responses.DemandStatusResponse{})
+		return
+	}
+	stats := instance.demandRecorder.Stats()
+	_ = json.NewEncoder(writer).Encode(&responses.DemandStatusResponse{
+		Enabled:          true,
+		PrecisionDegrees: instance.demandRecorder.PrecisionDegrees,
+		Recorded:         stats.Recorded,
+		Dropped:          stats.Dropped,
+		Failed:           stats.Failed,
+		Pending:          stats.Pending,
+	})
+}
+
+func (instance *APIController) demandEnabled(writer http.ResponseWriter) bool {
+	if instance.demandRecorder == nil {
+		log.Println("Demand analytics requested but not enabled.")
+		writer.WriteHeader(http.StatusNotFound)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: demandNotEnabled})
+		return false
+	}
+	return true
+}
+
+// demandQueries reads the demand queries in the from and to query parameters, RFC 3339 times that
+// default to the last week. When it fails, the error response has already been written and false is
+// returned.
+func (instance *APIController) demandQueries(writer http.ResponseWriter, r *http.Request) (time.Time, time.Time, []demand.Query, bool) {
+	to := time.Now().UTC()
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return instance.invalidTimeRange(writer, value)
+		}
+		to = parsed.UTC()
+	}
+	from := to.Add(-defaultDemandRange)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return instance.invalidTimeRange(writer, value)
+		}
+		from = parsed.UTC()
+	}
+	if !from.Before(to) || to.Sub(from) > maxDemandRangeDays*24*time.Hour {
+		return instance.invalidTimeRange(writer, fmt.Sprintf("%s - %s", from, to))
+	}
+	queries, err := instance.demandStore.Range(from, to)
+	if err != nil {
+		log.Printf("Error when trying to read demand queries: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: internalServerError})
+		return from, to, nil, false
+	}
+	return from, to, queries, true
+}
+
+func (instance *APIController) invalidTimeRange(writer http.ResponseWriter, value string) (time.Time, time.Time, []demand.Query, bool) {
+	log.Printf("Invalid demand time range: %s", value)
+	writer.WriteHeader(http.StatusBadRequest)
+	generateErrorMessage(writer, &responses.ErrorMsg{Message: fmt.Sprintf(invalidTimeRange, maxDemandRangeDays)})
+	return time.Time{}, time.Time{}, nil, false
+}
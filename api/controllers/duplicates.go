@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/geocoding"
+	"github.com/NJRodriguez/shiny-waddle/lib/kdtree"
+)
+
+const (
+	// Default thresholds of the duplicates report when duplicate detection is not enabled.
+	defaultDuplicateMaxDistanceKm = 0.025
+	defaultDuplicateMinSimilarity = 0.8
+
+	duplicateByDistance = "distance"
+	duplicateByAddress  = "address"
+)
+
+// duplicateDetection holds the thresholds to consider two sucursales duplicates of each other.
+type duplicateDetection struct {
+	maxDistanceKm        float64
+	minAddressSimilarity float64
+}
+
+// reasons returns why two sucursales are duplicates, if they are.
+func (detection *duplicateDetection) reasons(distance float64, similarity float64) []string {
+	reasons := []string{}
+	if distance <= detection.maxDistanceKm {
+		reasons = append(reasons, duplicateByDistance)
+	}
+	if detection.minAddressSimilarity > 0 && similarity >= detection.minAddressSimilarity {
+		reasons = append(reasons, duplicateByAddress)
+	}
+	return reasons
+}
+
+// duplicateCandidates returns the sucursales that are suspected duplicates of the sucursal, closest first.
+func (instance *APIController) duplicateCandidates(sucursal *models.Sucursal) ([]responses.DuplicateCandidate, error) {
+	addresses, sucursales, err := instance.sucursalAddresses()
+	if err != nil {
+		return nil, err
+	}
+	tokens := geocoding.TokenizeAddress(sucursal.Address)
+	similar := map[string]float64{}
+	if instance.duplicates.minAddressSimilarity > 0 {
+		similar = addresses.Similar(tokens, instance.duplicates.minAddressSimilarity)
+	}
+	candidates := []responses.DuplicateCandidate{}
+	for id, existing := range sucursales {
+		if id == sucursal.ID {
+			continue
+		}
+		distance := calcDistance(sucursal.Position(), existing)
+		similarity, ok := similar[id]
+		if !ok && distance <= instance.duplicates.maxDistanceKm {
+			similarity = tokens.Similarity(addresses.Get(id))
+		}
+		if reasons := instance.duplicates.reasons(distance, similarity); len(reasons) > 0 {
+			candidates = append(candidates, responses.DuplicateCandidate{
+				Sucursal:          *existing,
+				DistanceInKm:      distance,
+				AddressSimilarity: similarity,
+				Reasons:           reasons,
+			})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].DistanceInKm != candidates[j].DistanceInKm {
+			return candidates[i].DistanceInKm < candidates[j].DistanceInKm
+		}
+		return candidates[i].Sucursal.ID < candidates[j].Sucursal.ID
+	})
+	return candidates, nil
+}
+
+// sucursalAddresses returns every sucursal, by id, along with their tokenized addresses. They are
+// taken from the spatial index when it is enabled and fresh, which keeps the addresses tokenized.
+func (instance *APIController) sucursalAddresses() (*geocoding.AddressIndex, map[string]*models.Sucursal, error) {
+	if instance.index != nil {
+		if addresses, sucursales, ok := instance.index.freshAddresses(); ok {
+			return addresses, sucursales, nil
+		}
+	}
+	result, err := instance.documentsClient.ListAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	sucursales, err := models.ToSucursalArray(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[string]*models.Sucursal, len(sucursales))
+	for _, sucursal := range sucursales {
+		byID[sucursal.ID] = sucursal
+	}
+	return indexAddresses(byID, nil, nil), byID, nil
+}
+
+// allSucursales returns every sucursal, from the spatial index when it is enabled and fresh. Unlike
+// listSucursales, an empty table is not an error.
+func (instance *APIController) allSucursales() ([]*models.Sucursal, error) {
+	if instance.index != nil {
+		if _, indexed, ok := instance.index.fresh(); ok {
+			sucursales := make([]*models.Sucursal, 0, len(indexed))
+			for _, sucursal := range indexed {
+				sucursales = append(sucursales, sucursal)
+			}
+			sort.Slice(sucursales, func(i, j int) bool {
+				return sucursales[i].ID < sucursales[j].ID
+			})
+			return sucursales, nil
+		}
+	}
+	result, err := instance.documentsClient.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	return models.ToSucursalArray(result)
+}
+
+func (instance *APIController) GetDuplicatesReport(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	detection := duplicateDetection{maxDistanceKm: defaultDuplicateMaxDistanceKm, minAddressSimilarity: defaultDuplicateMinSimilarity}
+	if instance.duplicates != nil {
+		detection = *instance.duplicates
+	}
+	if value := r.URL.Query().Get("maxDistanceM"); value != "" {
+		meters, err := strconv.ParseFloat(value, 64)
+		if err != nil || meters < 0 {
+			log.Printf("Invalid duplicates max distance: %s", value)
+			writer.WriteHeader(http.StatusBadRequest)
+			generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidDuplicateDistance})
+			return
+		}
+		detection.maxDistanceKm = meters / 1000
+	}
+	if value := r.URL.Query().Get("minSimilarity"); value != "" {
+		similarity, err := strconv.ParseFloat(value, 64)
+		if err != nil || similarity < 0 || similarity > 1 {
+			log.Printf("Invalid duplicates min similarity: %s", value)
+			writer.WriteHeader(http.StatusBadRequest)
+			generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidDuplicateSimilarity})
+			return
+		}
+		detection.minAddressSimilarity = similarity
+	}
+	sucursales, ok := instance.listSucursales(writer)
+	if !ok {
+		return
+	}
+	response := responses.DuplicatesReportResponse{
+		MaxDistanceKm:        detection.maxDistanceKm,
+		MinAddressSimilarity: detection.minAddressSimilarity,
+		Pairs:                duplicatePairs(sucursales, &detection),
+	}
+	_ = json.NewEncoder(writer).Encode(&response)
+}
+
+// duplicatePairs finds every pair of suspected duplicates, closest first. Pairs close to each other are
+// found with a k-d tree, and pairs with alike addresses by bucketing the addresses by their words.
+func duplicatePairs(sucursales []*models.Sucursal, detection *duplicateDetection) []responses.DuplicatePair {
+	byID := make(map[string]*models.Sucursal, len(sucursales))
+	position := make(map[string]int, len(sucursales))
+	points := make([]kdtree.Point, len(sucursales))
+	for i, sucursal := range sucursales {
+		byID[sucursal.ID] = sucursal
+		position[sucursal.ID] = i
+		points[i] = kdtree.Point{ID: sucursal.ID, Latitude: sucursal.Latitude, Longitude: sucursal.Longitude}
+	}
+	addresses := indexAddresses(byID, nil, nil)
+	tree := kdtree.New(points)
+	pairs := []responses.DuplicatePair{}
+	// Sucursales after the first one of the pair, which may be found both close and alike.
+	candidates := []int{}
+	for i, first := range sucursales {
+		candidates = candidates[:0]
+		for _, point := range tree.Within(first.Latitude, first.Longitude, detection.maxDistanceKm) {
+			if j := position[point.ID]; j > i {
+				candidates = append(candidates, j)
+			}
+		}
+		tokens := addresses.Get(first.ID)
+		if detection.minAddressSimilarity > 0 {
+			for id := range addresses.Similar(tokens, detection.minAddressSimilarity) {
+				if j := position[id]; j > i {
+					candidates = append(candidates, j)
+				}
+			}
+		}
+		sort.Ints(candidates)
+		for k, j := range candidates {
+			if k > 0 && candidates[k-1] == j {
+				continue
+			}
+			second := sucursales[j]
+			distance := calcDistance(first.Position(), second)
+			similarity := tokens.Similarity(addresses.Get(second.ID))
+			reasons := detection.reasons(distance, similarity)
+			if len(reasons) == 0 {
+				continue
+			}
+			pairs = append(pairs, responses.DuplicatePair{
+				First:             *first,
+				Second:            *second,
+				DistanceInKm:      distance,
+				AddressSimilarity: similarity,
+				Reasons:           reasons,
+			})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].DistanceInKm < pairs[j].DistanceInKm
+	})
+	return pairs
+}
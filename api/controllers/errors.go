@@ -3,6 +3,11 @@ package controllers
 import (
 	"encoding/json"
 	"log"
+	"strings"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
 
 	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
@@ -11,10 +16,9 @@ import (
 	"github.com/pkg/errors"
 )
 
-type ApiError struct {
-	Message string   `json:"message"`
-	Errors  []string `json:"errors,omitempty"`
-}
+// ApiError is kept as an alias since the error payload moved to the responses package, where
+// clients of the API can use it without depending on the controllers.
+type ApiError = responses.ApiError
 
 func RegisterErrors(instance *validator.Validate) (ut.Translator, error) {
 
@@ -47,9 +51,99 @@ func RegisterErrors(instance *validator.Validate) (ut.Translator, error) {
 		return t
 	})
 
+	_ = instance.RegisterTranslation("required_if", trans, func(ut ut.Translator) error {
+		return ut.Add("required_if", "{0} is a required field when {1}", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("required_if", fe.Field(), strings.Replace(fe.Param(), " ", " is ", 1))
+		return t
+	})
+
+	_ = instance.RegisterTranslation("required_with", trans, func(ut ut.Translator) error {
+		return ut.Add("required_with", "{0} is a required field when {1} is present", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("required_with", fe.Field(), fe.Param())
+		return t
+	})
+
+	if err := instance.RegisterValidation("polygon", validatePolygon); err != nil {
+		return nil, errors.Wrap(err, "register polygon validation error")
+	}
+	_ = instance.RegisterTranslation("polygon", trans, func(ut ut.Translator) error {
+		return ut.Add("polygon", "{0} must be a GeoJSON polygon made of closed rings with at least four positions", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("polygon", fe.Field())
+		return t
+	})
+
+	_ = instance.RegisterTranslation("required_without", trans, func(ut ut.Translator) error {
+		return ut.Add("required_without", "{0} is a required field", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("required_without", fe.Field())
+		return t
+	})
+
+	_ = instance.RegisterTranslation("excluded_with", trans, func(ut ut.Translator) error {
+		return ut.Add("excluded_with", "{0} can't be sent together with {1}", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("excluded_with", fe.Field(), fe.Param())
+		return t
+	})
+
+	instance.RegisterStructValidation(models.ValidateAddressComponents, models.AddressComponents{})
+	_ = instance.RegisterTranslation("street_or_locality", trans, func(ut ut.Translator) error {
+		return ut.Add("street_or_locality", "{0} or Locality is required", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("street_or_locality", fe.Field())
+		return t
+	})
+	_ = instance.RegisterTranslation("cpa", trans, func(ut ut.Translator) error {
+		return ut.Add("cpa", "{0} must be a CPA postal code like C1005AAB or a four digit postal code", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("cpa", fe.Field())
+		return t
+	})
+	_ = instance.RegisterTranslation("cpa_province", trans, func(ut ut.Translator) error {
+		return ut.Add("cpa_province", "{0} must start with the province code {1}", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("cpa_province", fe.Field(), fe.Param())
+		return t
+	})
+	_ = instance.RegisterTranslation("province", trans, func(ut ut.Translator) error {
+		return ut.Add("province", "{0} must be an Argentine province code like C or X", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("province", fe.Field())
+		return t
+	})
+	_ = instance.RegisterTranslation("country", trans, func(ut ut.Translator) error {
+		return ut.Add("country", "{0} must be an ISO 3166-1 alpha-2 country code like AR", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T("country", fe.Field())
+		return t
+	})
+
 	return trans, nil
 }
 
+// validatePolygon checks that the field holds GeoJSON polygon coordinates made of closed rings
+// of valid [longitude, latitude] positions. Empty polygons are left to the required tags.
+func validatePolygon(fl validator.FieldLevel) bool {
+	polygon, ok := fl.Field().Interface().([][][]float64)
+	if !ok {
+		return false
+	}
+	for _, ring := range polygon {
+		if !geo.IsClosedRing(ring) {
+			return false
+		}
+		for _, position := range ring {
+			if position[0] < -180 || position[0] > 180 || position[1] < -90 || position[1] > 90 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func ValidateRequest(body []byte, obj interface{}) (*ApiError, error) {
 	err := json.Unmarshal(body, obj)
 	if err != nil {
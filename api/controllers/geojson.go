@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/geojson"
+)
+
+// wantsGeoJSON reports whether the client asked for GeoJSON through the Accept header.
+func wantsGeoJSON(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			if strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0]) == geojson.MediaType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func setGeoJSONContentType(writer http.ResponseWriter) {
+	writer.Header().Set("Content-Type", geojson.MediaType)
+}
+
+// sucursalFeature renders a sucursal as a GeoJSON point feature. Every sucursal field other than the
+// coordinates becomes a property, along with the extra properties given.
+func sucursalFeature(sucursal *models.Sucursal, extra map[string]interface{}) *geojson.Feature {
+	properties := map[string]interface{}{}
+	marshaled, _ := json.Marshal(sucursal)
+	_ = json.Unmarshal(marshaled, &properties)
+	delete(properties, "latitude")
+	delete(properties, "longitude")
+	for key, value := range extra {
+		properties[key] = value
+	}
+	return geojson.NewFeature(sucursal.ID, geojson.NewPoint(sucursal.Latitude, sucursal.Longitude), properties)
+}
+
+func writeFeature(writer http.ResponseWriter, feature *geojson.Feature) {
+	setGeoJSONContentType(writer)
+	_ = json.NewEncoder(writer).Encode(feature)
+}
+
+func writeFeatureCollection(writer http.ResponseWriter, features []*geojson.Feature) {
+	setGeoJSONContentType(writer)
+	_ = json.NewEncoder(writer).Encode(geojson.NewFeatureCollection(features))
+}
+
+// closestSucursalFeature renders a closest sucursal response as a GeoJSON feature with the distance
+// and geocoding details as properties.
+func closestSucursalFeature(response *responses.ClosestSucursalResponse) *geojson.Feature {
+	extra := map[string]interface{}{"distanceInKm": response.DistanceInKm}
+	if response.Geocoding != nil {
+		extra["geocoding"] = response.Geocoding
+	}
+	if response.Route != nil {
+		extra["route"] = response.Route
+	}
+	return sucursalFeature(&response.Sucursal, extra)
+}
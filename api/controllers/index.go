@@ -0,0 +1,379 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/requests"
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb"
+	"github.com/NJRodriguez/shiny-waddle/lib/geocoding"
+	"github.com/NJRodriguez/shiny-waddle/lib/kdtree"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// spatialIndex keeps every sucursal in memory in a k-d tree, so that position queries don't need to
+// scan the whole table. It is safe for concurrent use.
+type spatialIndex struct {
+	// Read and written atomically, so that queries only take the read lock. It comes first to be
+	// 64-bit aligned.
+	fallbacks uint64
+
+	refreshInterval time.Duration
+	maxAge          time.Duration
+	now             func() time.Time
+
+	// Serializes the changes to the index, which are built before taking mutex to swap them in.
+	updates sync.Mutex
+	// Changes applied while the table is scanned, to replay on top of the scan. Guarded by updates.
+	loading    bool
+	pending    []indexChange
+	mutex      sync.RWMutex
+	tree       *kdtree.Tree
+	sucursales map[string]*models.Sucursal
+	addresses  *geocoding.AddressIndex
+	loadedAt   time.Time
+	lastError  error
+}
+
+// indexChange is a batch of changes passed to apply.
+type indexChange struct {
+	puts    []*models.Sucursal
+	removes []string
+}
+
+func newSpatialIndex(refreshInterval time.Duration, maxAge time.Duration) *spatialIndex {
+	return &spatialIndex{refreshInterval: refreshInterval, maxAge: maxAge, now: time.Now}
+}
+
+// load replaces the indexed sucursales with every sucursal in the table. The changes applied while
+// the table is scanned are kept, since the scan may have read the table before them.
+func (index *spatialIndex) load(documentsClient dynamodb.DocumentsClient) error {
+	index.updates.Lock()
+	index.loading = true
+	index.pending = nil
+	index.updates.Unlock()
+	result, err := documentsClient.ListAll()
+	if err == nil {
+		var sucursales []*models.Sucursal
+		sucursales, err = models.ToSucursalArray(result)
+		if err == nil {
+			index.replace(sucursales)
+			return nil
+		}
+	}
+	index.updates.Lock()
+	index.loading = false
+	index.pending = nil
+	index.updates.Unlock()
+	index.mutex.Lock()
+	index.lastError = err
+	index.mutex.Unlock()
+	return err
+}
+
+// replace indexes the scanned sucursales, replaying the changes applied since the scan started.
+func (index *spatialIndex) replace(sucursales []*models.Sucursal) {
+	index.updates.Lock()
+	defer index.updates.Unlock()
+	byID := make(map[string]*models.Sucursal, len(sucursales))
+	for _, sucursal := range sucursales {
+		byID[sucursal.ID] = sucursal
+	}
+	for _, change := range index.pending {
+		for _, id := range change.removes {
+			delete(byID, id)
+		}
+		for _, sucursal := range change.puts {
+			byID[sucursal.ID] = sucursal
+		}
+	}
+	index.loading = false
+	index.pending = nil
+	tree := buildTree(byID)
+	addresses := indexAddresses(byID, nil, nil)
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	index.tree = tree
+	index.sucursales = byID
+	index.addresses = addresses
+	index.loadedAt = index.now()
+	index.lastError = nil
+}
+
+// put adds or replaces a sucursal written by this process, without waiting for the next refresh.
+func (index *spatialIndex) put(sucursal *models.Sucursal) {
+	index.apply([]*models.Sucursal{sucursal}, nil)
+}
+
+// remove drops a sucursal from the index.
+func (index *spatialIndex) remove(id string) {
+	index.apply(nil, []string{id})
+}
+
+// apply drops the removed sucursales and then adds or replaces the put ones, rebuilding the tree
+// once for the whole batch. The tree is built before taking the write lock, so queries only wait for
+// it to be swapped in. While the table is scanned, the changes are also kept for the load to replay.
+// It does nothing else until the index is loaded.
+func (index *spatialIndex) apply(puts []*models.Sucursal, removes []string) {
+	index.updates.Lock()
+	defer index.updates.Unlock()
+	if index.loading {
+		index.pending = append(index.pending, indexChange{puts: puts, removes: removes})
+	}
+	// Only writers change the index, and they hold updates.
+	if index.tree == nil {
+		return
+	}
+	current := index.sucursales
+	changed := len(puts) > 0
+	for _, id := range removes {
+		if _, ok := current[id]; ok {
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	byID := make(map[string]*models.Sucursal, len(current)+len(puts))
+	for id, indexed := range current {
+		byID[id] = indexed
+	}
+	for _, id := range removes {
+		delete(byID, id)
+	}
+	for _, sucursal := range puts {
+		byID[sucursal.ID] = sucursal
+	}
+	tree := buildTree(byID)
+	addresses := indexAddresses(byID, current, index.addresses)
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	index.tree = tree
+	index.sucursales = byID
+	index.addresses = addresses
+}
+
+func buildTree(sucursales map[string]*models.Sucursal) *kdtree.Tree {
+	points := make([]kdtree.Point, 0, len(sucursales))
+	for _, sucursal := range sucursales {
+		points = append(points, kdtree.Point{ID: sucursal.ID, Latitude: sucursal.Latitude, Longitude: sucursal.Longitude})
+	}
+	return kdtree.New(points)
+}
+
+// indexAddresses tokenizes the addresses of the sucursales, reusing the tokens in previous of those
+// whose address is the same as in previousSucursales.
+func indexAddresses(sucursales map[string]*models.Sucursal, previousSucursales map[string]*models.Sucursal, previous *geocoding.AddressIndex) *geocoding.AddressIndex {
+	tokenized := make(map[string]*geocoding.AddressTokens, len(sucursales))
+	for id, sucursal := range sucursales {
+		if old, ok := previousSucursales[id]; ok && previous != nil && old.Address == sucursal.Address {
+			tokenized[id] = previous.Get(id)
+			continue
+		}
+		tokenized[id] = geocoding.TokenizeAddress(sucursal.Address)
+	}
+	return geocoding.NewAddressIndex(tokenized)
+}
+
+// fresh returns the indexed sucursales if they were loaded less than maxAge ago. Otherwise it counts
+// a fallback to scanning the table and returns false.
+func (index *spatialIndex) fresh() (*kdtree.Tree, map[string]*models.Sucursal, bool) {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+	if !index.isFresh() {
+		return nil, nil, false
+	}
+	return index.tree, index.sucursales, true
+}
+
+// freshAddresses is like fresh, but returns the tokenized addresses of the sucursales.
+func (index *spatialIndex) freshAddresses() (*geocoding.AddressIndex, map[string]*models.Sucursal, bool) {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+	if !index.isFresh() {
+		return nil, nil, false
+	}
+	return index.addresses, index.sucursales, true
+}
+
+// isFresh must be called holding the read lock.
+func (index *spatialIndex) isFresh() bool {
+	if index.tree == nil || index.tree.Len() == 0 || (index.maxAge > 0 && index.now().Sub(index.loadedAt) > index.maxAge) {
+		atomic.AddUint64(&index.fallbacks, 1)
+		return false
+	}
+	return true
+}
+
+// refreshPeriodically reloads the index every refreshInterval until stop is closed.
+func (index *spatialIndex) refreshPeriodically(documentsClient dynamodb.DocumentsClient, stop <-chan struct{}) {
+	ticker := time.NewTicker(index.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if err := index.load(documentsClient); err != nil {
+			log.Printf("Error when trying to refresh the spatial index: %s", err)
+		}
+	}
+}
+
+func (index *spatialIndex) status() responses.IndexStatusResponse {
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+	status := responses.IndexStatusResponse{
+		Enabled:                true,
+		RefreshIntervalSeconds: index.refreshInterval.Seconds(),
+		MaxAgeSeconds:          index.maxAge.Seconds(),
+		Fallbacks:              atomic.LoadUint64(&index.fallbacks),
+	}
+	if index.tree != nil {
+		loadedAt := index.loadedAt
+		status.Sucursales = len(index.sucursales)
+		status.LoadedAt = &loadedAt
+		status.StalenessSeconds = index.now().Sub(loadedAt).Seconds()
+		status.Stale = index.maxAge > 0 && index.now().Sub(loadedAt) > index.maxAge
+	}
+	if index.lastError != nil {
+		status.LastError = index.lastError.Error()
+	}
+	return status
+}
+
+// indexCreatedSucursal adds a sucursal created by this process to the spatial index, if enabled.
+func (instance *APIController) indexCreatedSucursal(sucursal *requests.PostSucursal) {
+	if instance.index == nil {
+		return
+	}
+	item, err := dynamodbattribute.MarshalMap(sucursal)
+	if err == nil {
+		var created *models.Sucursal
+		created, err = models.ToSucursal(item)
+		if err == nil {
+			instance.index.put(created)
+			return
+		}
+	}
+	log.Printf("Error when trying to add Sucursal %s to the spatial index: %s", sucursal.ID, err)
+}
+
+func (instance *APIController) GetIndexStatus(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	if instance.index == nil {
+		_ = json.NewEncoder(writer).Encode(&responses.IndexStatusResponse{})
+		return
+	}
+	status := instance.index.status()
+	_ = json.NewEncoder(writer).Encode(&status)
+}
+
+// closestSucursal finds the sucursal closest to the position, using the spatial index when it is
+// enabled and fresh and scanning the table otherwise. When it fails, the error response has already
+// been written and false is returned.
+func (instance *APIController) closestSucursal(writer http.ResponseWriter, position *models.Position) (*models.SucursalWithDistance, bool) {
+	if instance.roads != nil {
+		return instance.closestSucursalByRoad(writer, position)
+	}
+	if instance.index != nil {
+		if tree, sucursales, ok := instance.index.fresh(); ok {
+			nearest, _ := tree.Nearest(position.Latitude, position.Longitude)
+			sucursal := sucursales[nearest.ID]
+			return &models.SucursalWithDistance{Sucursal: sucursal, Distance: calcDistance(position, sucursal)}, true
+		}
+	}
+	sucursales, ok := instance.listSucursales(writer)
+	if !ok {
+		return nil, false
+	}
+	return findClosestSucursal(position, sucursales), true
+}
+
+// nearestSucursales finds the count sucursales closest to the position, closest first, using the
+// spatial index when it is enabled and fresh and scanning the table otherwise. When it fails, the
+// error response has already been written and false is returned.
+func (instance *APIController) nearestSucursales(writer http.ResponseWriter, position *models.Position, count int) ([]*models.Sucursal, bool) {
+	if instance.index != nil {
+		if tree, indexed, ok := instance.index.fresh(); ok {
+			sucursales := []*models.Sucursal{}
+			for _, point := range tree.NearestN(position.Latitude, position.Longitude, count) {
+				sucursales = append(sucursales, indexed[point.ID])
+			}
+			return sucursales, true
+		}
+	}
+	sucursales, ok := instance.listSucursales(writer)
+	if !ok {
+		return nil, false
+	}
+	sort.SliceStable(sucursales, func(i, j int) bool {
+		return calcDistance(position, sucursales[i]) < calcDistance(position, sucursales[j])
+	})
+	if len(sucursales) > count {
+		sucursales = sucursales[:count]
+	}
+	return sucursales, true
+}
+
+// sucursalesWithin finds the sucursales at most radiusKm away from the position, closest first, using
+// the spatial index when it is enabled and fresh and scanning the table otherwise. When it fails, the
+// error response has already been written and false is returned.
+func (instance *APIController) sucursalesWithin(writer http.ResponseWriter, position *models.Position, radiusKm float64) ([]*models.Sucursal, bool) {
+	if instance.index != nil {
+		if tree, indexed, ok := instance.index.fresh(); ok {
+			sucursales := []*models.Sucursal{}
+			for _, point := range tree.Within(position.Latitude, position.Longitude, radiusKm) {
+				sucursales = append(sucursales, indexed[point.ID])
+			}
+			return sucursales, true
+		}
+	}
+	all, ok := instance.listSucursales(writer)
+	if !ok {
+		return nil, false
+	}
+	within := []*models.SucursalWithDistance{}
+	for _, sucursal := range all {
+		if distance := calcDistance(position, sucursal); distance <= radiusKm {
+			within = append(within, &models.SucursalWithDistance{Sucursal: sucursal, Distance: distance})
+		}
+	}
+	sort.SliceStable(within, func(i, j int) bool {
+		return within[i].Distance < within[j].Distance
+	})
+	sucursales := make([]*models.Sucursal, len(within))
+	for i, sucursal := range within {
+		sucursales[i] = sucursal.Sucursal
+	}
+	return sucursales, true
+}
+
+// sucursalTree returns a k-d tree of every sucursal, taken from the spatial index when it is enabled
+// and fresh and built from a scan of the table otherwise. It is meant for handlers answering many
+// position queries at once. When it fails, the error response has already been written and false is
+// returned.
+func (instance *APIController) sucursalTree(writer http.ResponseWriter) (*kdtree.Tree, map[string]*models.Sucursal, bool) {
+	if instance.index != nil {
+		if tree, sucursales, ok := instance.index.fresh(); ok {
+			return tree, sucursales, true
+		}
+	}
+	sucursales, ok := instance.listSucursales(writer)
+	if !ok {
+		return nil, nil, false
+	}
+	byID := make(map[string]*models.Sucursal, len(sucursales))
+	for _, sucursal := range sucursales {
+		byID[sucursal.ID] = sucursal
+	}
+	return buildTree(byID), byID, true
+}
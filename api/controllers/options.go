@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb"
+	"github.com/NJRodriguez/shiny-waddle/lib/cache"
+	"github.com/NJRodriguez/shiny-waddle/lib/demand"
+	"github.com/NJRodriguez/shiny-waddle/lib/geocoding"
+	"github.com/NJRodriguez/shiny-waddle/lib/roads"
+)
+
+const (
+	// AddressValidationOff skips checking the address against the coordinates.
+	AddressValidationOff = "off"
+	// AddressValidationWarn creates the sucursal but returns a warning when the address is far from the coordinates.
+	AddressValidationWarn = "warn"
+	// AddressValidationReject refuses to create the sucursal when the address is far from the coordinates.
+	AddressValidationReject = "reject"
+)
+
+// Option configures optional features of the APIController.
+type Option func(*APIController)
+
+// WithGeocoder enables resolving sucursal coordinates from addresses.
+func WithGeocoder(geocoder geocoding.Geocoder) Option {
+	return func(instance *APIController) {
+		instance.geocoder = geocoder
+	}
+}
+
+// WithReverseGeocoder enables storing the normalized address of new sucursales.
+func WithReverseGeocoder(reverseGeocoder geocoding.ReverseGeocoder) Option {
+	return func(instance *APIController) {
+		instance.reverseGeocoder = reverseGeocoder
+	}
+}
+
+// WithAddressValidation checks that the address of new sucursales is within maxDistanceKm of the
+// given coordinates. Requires a geocoder.
+func WithAddressValidation(mode string, maxDistanceKm float64) Option {
+	return func(instance *APIController) {
+		instance.addressValidation = mode
+		instance.addressMaxDistanceKm = maxDistanceKm
+	}
+}
+
+// WithTileCache caches up to size generated map tiles for ttl, along with the sucursales clustered at
+// each zoom level. Cached tiles and clusters are dropped whenever a sucursal is created.
+func WithTileCache(size int, ttl time.Duration) Option {
+	return func(instance *APIController) {
+		instance.tileCache = cache.NewLRU(size, ttl)
+		instance.clusterCache = cache.NewLRU(maxClusterZoom+1, ttl)
+	}
+}
+
+// WithSpatialIndex loads every sucursal into memory when the controller is created and answers
+// position queries from a spatial index. The index is reloaded every refreshInterval, if positive,
+// and queries scan the table while it is older than maxAge, if positive.
+func WithSpatialIndex(refreshInterval time.Duration, maxAge time.Duration) Option {
+	return func(instance *APIController) {
+		instance.index = newSpatialIndex(refreshInterval, maxAge)
+	}
+}
+
+// WithIndexSource loads the spatial index from documentsClient instead of the client of the
+// controller. When the controller reads through a cache, it keeps the index from being reloaded
+// with cached reads older than it claims to be.
+func WithIndexSource(documentsClient dynamodb.DocumentsClient) Option {
+	return func(instance *APIController) {
+		instance.indexSource = documentsClient
+	}
+}
+
+// WithDocumentsCache reports the statistics of the cache the controller reads sucursales through.
+func WithDocumentsCache(cachingClient *dynamodb.CachingClient) Option {
+	return func(instance *APIController) {
+		instance.documentsCache = cachingClient
+	}
+}
+
+// WithDuplicateDetection rejects new sucursales within maxDistanceKm of an existing one or whose
+// normalized address is at least minAddressSimilarity alike, unless the request sets force. A zero
+// minAddressSimilarity only compares distances.
+func WithDuplicateDetection(maxDistanceKm float64, minAddressSimilarity float64) Option {
+	return func(instance *APIController) {
+		instance.duplicates = &duplicateDetection{maxDistanceKm: maxDistanceKm, minAddressSimilarity: minAddressSimilarity}
+	}
+}
+
+// WithRoadNetwork finds the closest sucursal along the road network, among the candidates sucursales
+// closest to the position in a straight line, or DefaultRoadCandidates when not positive.
+func WithRoadNetwork(network *roads.Network, candidates int) Option {
+	return func(instance *APIController) {
+		if candidates <= 0 {
+			candidates = DefaultRoadCandidates
+		}
+		instance.roads = &roadRanking{network: network, candidates: candidates}
+	}
+}
+
+// WithDemandAnalytics records the position and result of closest sucursal searches with the recorder
+// and serves the demand read back from the store.
+func WithDemandAnalytics(recorder *demand.Recorder, store demand.Store) Option {
+	return func(instance *APIController) {
+		instance.demandRecorder = recorder
+		instance.demandStore = store
+	}
+}
+
+// WithSyncMaxDeletePercent refuses syncs that delete more than percent of the sucursales, instead of
+// importer.DefaultMaxDeletePercent. Requests can't raise it.
+func WithSyncMaxDeletePercent(percent float64) Option {
+	return func(instance *APIController) {
+		instance.syncMaxDeletePercent = percent
+	}
+}
@@ -0,0 +1,13 @@
+package requests
+
+type AssignCustomers struct {
+	Customers []Customer `json:"customers" validate:"required,min=1,max=1000,unique=ID,dive"`
+	// Customers are only assigned to sucursales at most this far, when positive.
+	MaxDistanceKm float64 `json:"maxDistanceKm" validate:"gte=0"`
+}
+
+type Customer struct {
+	ID        string  `json:"id" validate:"required"`
+	Latitude  float64 `json:"latitude" validate:"min=-90,max=90"`
+	Longitude float64 `json:"longitude" validate:"min=-180,max=180"`
+}
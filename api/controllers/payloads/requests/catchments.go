@@ -0,0 +1,7 @@
+package requests
+
+type Catchments struct {
+	// GeoJSON polygon coordinates in [longitude, latitude] order the catchments are clipped to. Only the
+	// outer ring is used.
+	Boundary [][][]float64 `json:"boundary" validate:"required,polygon"`
+}
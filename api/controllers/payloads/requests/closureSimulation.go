@@ -0,0 +1,16 @@
+package requests
+
+type ClosureSimulation struct {
+	// Ids of the sucursales to close.
+	Remove []string `json:"remove" validate:"required_without=Add,dive,required"`
+	// Sucursales to open.
+	Add []HypotheticalSucursal `json:"add" validate:"dive"`
+	// Where customers are. The searches recorded by demand analytics are used when omitted.
+	Demand []DemandPoint `json:"demand" validate:"max=10000,dive"`
+}
+
+type HypotheticalSucursal struct {
+	ID        string  `json:"id" validate:"required"`
+	Latitude  float64 `json:"latitude" validate:"min=-90,max=90"`
+	Longitude float64 `json:"longitude" validate:"min=-180,max=180"`
+}
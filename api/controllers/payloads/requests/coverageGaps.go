@@ -0,0 +1,10 @@
+package requests
+
+type CoverageGaps struct {
+	// GeoJSON polygon coordinates in [longitude, latitude] order of the area to analyse.
+	Polygon [][][]float64 `json:"polygon" validate:"required,polygon"`
+	// Side of the square grid cells in kilometers.
+	CellSizeKm float64 `json:"cellSizeKm" validate:"required,gt=0"`
+	// Cells whose center is farther than this from every sucursal are returned.
+	ThresholdKm float64 `json:"thresholdKm" validate:"gte=0"`
+}
@@ -1,8 +1,26 @@
 package requests
 
+import "github.com/NJRodriguez/shiny-waddle/api/models"
+
 type PostSucursal struct {
-	ID        string   `json:"id" validate:"required,uuid4"`
-	Address   string   `json:"address" validate:"required"`
-	Latitude  *float64 `json:"latitude" validate:"required,min=-90,max=90"`
-	Longitude *float64 `json:"longitude" validate:"required,min=-180,max=180"`
+	ID          string              `json:"id" validate:"required,uuid4"`
+	Name        string              `json:"name,omitempty"`
+	Address     string              `json:"address" validate:"required_without=StructuredAddress"`
+	Latitude    *float64            `json:"latitude" validate:"required_with=Longitude,omitempty,min=-90,max=90"`
+	Longitude   *float64            `json:"longitude" validate:"required_with=Latitude,omitempty,min=-180,max=180"`
+	ServiceArea *models.ServiceArea `json:"serviceArea,omitempty" validate:"omitempty"`
+	Capacity    *int                `json:"capacity,omitempty" validate:"omitempty,gte=0"`
+	// Opening hours in the OpenStreetMap syntax, such as "Mo-Fr 10:00-15:00".
+	OpeningHours string `json:"openingHours,omitempty"`
+	// Alternative to latitude and longitude in any format supported by geo.ParsePosition, such as
+	// DMS, plus codes, geohashes or projected coordinates. Converted to WGS84 before storing.
+	Position string `json:"position,omitempty" dynamodbav:"-" validate:"excluded_with=Latitude"`
+	// Alternative to the single-line address. The single-line address is rendered from it when omitted.
+	StructuredAddress *models.AddressComponents `json:"structuredAddress,omitempty" validate:"omitempty"`
+	// Creates the sucursal even if it looks like a duplicate of an existing one.
+	Force bool `json:"force,omitempty" dynamodbav:"-"`
+	// Filled in by the API when the coordinates are resolved from the address.
+	Geocoding *models.Geocoding `json:"-" dynamodbav:"geocoding,omitempty"`
+	// Filled in by the API, with the components when reverse geocoding is enabled.
+	NormalizedAddress *models.NormalizedAddress `json:"-" dynamodbav:"normalizedAddress,omitempty"`
 }
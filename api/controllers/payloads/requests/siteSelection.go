@@ -0,0 +1,15 @@
+package requests
+
+type SiteSelection struct {
+	// Number of new sucursales to propose.
+	Count int `json:"count" validate:"required,gt=0,lte=20"`
+	// Where customers are. The searches recorded by demand analytics are used when omitted.
+	Demand []DemandPoint `json:"demand" validate:"max=10000,dive"`
+}
+
+type DemandPoint struct {
+	Latitude  float64 `json:"latitude" validate:"min=-90,max=90"`
+	Longitude float64 `json:"longitude" validate:"min=-180,max=180"`
+	// Number of customers at the point, 1 when omitted.
+	Weight *float64 `json:"weight,omitempty" validate:"omitempty,gt=0"`
+}
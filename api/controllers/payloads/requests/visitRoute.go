@@ -0,0 +1,18 @@
+package requests
+
+type VisitRoute struct {
+	Start *Waypoint `json:"start" validate:"required"`
+	// Ids of the sucursales to visit.
+	SucursalIDs []string `json:"sucursalIds" validate:"required,min=1,max=200,unique,dive,required"`
+	// Whether to come back to the start after the last visit.
+	ReturnToStart bool `json:"returnToStart" validate:"excluded_with=End"`
+	// Where to finish after the last visit. Routes end at the last sucursal visited when omitted.
+	End *Waypoint `json:"end"`
+	// Routes are split so that none has more legs than this, when positive.
+	MaxLegs int `json:"maxLegs" validate:"gte=0"`
+}
+
+type Waypoint struct {
+	Latitude  float64 `json:"latitude" validate:"min=-90,max=90"`
+	Longitude float64 `json:"longitude" validate:"min=-180,max=180"`
+}
@@ -0,0 +1,23 @@
+package responses
+
+type CustomerAssignment struct {
+	CustomerID   string  `json:"customerId"`
+	SucursalID   string  `json:"sucursalId"`
+	DistanceInKm float64 `json:"distanceInKm"`
+}
+
+type SucursalLoad struct {
+	ID string `json:"id"`
+	// Omitted for sucursales without a capacity limit.
+	Capacity *int `json:"capacity,omitempty"`
+	Assigned int  `json:"assigned"`
+}
+
+type AssignCustomersResponse struct {
+	// In the order the customers were sent.
+	Assignments []CustomerAssignment `json:"assignments"`
+	// Ids of the customers that could not be assigned without going over capacity or the max distance.
+	Unassigned        []string       `json:"unassigned"`
+	TotalDistanceInKm float64        `json:"totalDistanceInKm"`
+	Sucursales        []SucursalLoad `json:"sucursales"`
+}
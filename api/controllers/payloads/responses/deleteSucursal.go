@@ -0,0 +1,6 @@
+package responses
+
+type DeleteSucursal struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,30 @@
+package responses
+
+import "github.com/NJRodriguez/shiny-waddle/api/models"
+
+type DuplicateCandidate struct {
+	Sucursal          models.Sucursal `json:"sucursal"`
+	DistanceInKm      float64         `json:"distanceInKm"`
+	AddressSimilarity float64         `json:"addressSimilarity"`
+	// Why the sucursal is a suspected duplicate: "distance", "address" or both.
+	Reasons []string `json:"reasons"`
+}
+
+type DuplicateSucursalResponse struct {
+	Message    string               `json:"message"`
+	Candidates []DuplicateCandidate `json:"candidates"`
+}
+
+type DuplicatePair struct {
+	First             models.Sucursal `json:"first"`
+	Second            models.Sucursal `json:"second"`
+	DistanceInKm      float64         `json:"distanceInKm"`
+	AddressSimilarity float64         `json:"addressSimilarity"`
+	Reasons           []string        `json:"reasons"`
+}
+
+type DuplicatesReportResponse struct {
+	MaxDistanceKm        float64         `json:"maxDistanceKm"`
+	MinAddressSimilarity float64         `json:"minAddressSimilarity"`
+	Pairs                []DuplicatePair `json:"pairs"`
+}
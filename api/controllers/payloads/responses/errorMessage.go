@@ -3,3 +3,9 @@ package responses
 type ErrorMsg struct {
 	Message string `json:"message"`
 }
+
+// ApiError is the error payload of requests that fail validation, listing every failed check.
+type ApiError struct {
+	Message string   `json:"message"`
+	Errors  []string `json:"errors,omitempty"`
+}
@@ -0,0 +1,9 @@
+package responses
+
+type CacheStatusResponse struct {
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Entries int    `json:"entries"`
+	// Reads answered by waiting for an identical read already in progress.
+	Deduplicated uint64 `json:"deduplicated"`
+}
@@ -0,0 +1,13 @@
+package responses
+
+type Catchment struct {
+	SucursalID string  `json:"sucursalId"`
+	AreaKm2    float64 `json:"areaKm2"`
+	// GeoJSON polygon coordinates in [longitude, latitude] order.
+	Coordinates [][][]float64 `json:"coordinates"`
+}
+
+type CatchmentsResponse struct {
+	BoundaryAreaKm2 float64     `json:"boundaryAreaKm2"`
+	Catchments      []Catchment `json:"catchments"`
+}
@@ -5,4 +5,8 @@ import "github.com/NJRodriguez/shiny-waddle/api/models"
 type ClosestSucursalResponse struct {
 	Sucursal     models.Sucursal
 	DistanceInKm float64
+	// Set when the search position was resolved from an address.
+	Geocoding *models.Geocoding `json:",omitempty"`
+	// Set when sucursales are ranked by their distance along the road network.
+	Route *models.Route `json:",omitempty"`
 }
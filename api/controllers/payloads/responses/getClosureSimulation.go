@@ -0,0 +1,29 @@
+package responses
+
+type SucursalImpact struct {
+	ID string `json:"id"`
+	// Either kept, removed or added.
+	Status string `json:"status"`
+	// Weight of the demand closest to the sucursal before and after the changes.
+	DemandBefore float64 `json:"demandBefore"`
+	DemandAfter  float64 `json:"demandAfter"`
+}
+
+type Reassignment struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Weight float64 `json:"weight"`
+	// Weighted mean of how much farther the reassigned demand is from its closest sucursal.
+	MeanDistanceIncreaseKm float64 `json:"meanDistanceIncreaseKm"`
+}
+
+type ClosureSimulationResponse struct {
+	DemandPoints int     `json:"demandPoints"`
+	TotalWeight  float64 `json:"totalWeight"`
+	// Weight of the demand whose closest sucursal changes.
+	ReassignedWeight float64          `json:"reassignedWeight"`
+	Before           DistanceStats    `json:"before"`
+	After            DistanceStats    `json:"after"`
+	Sucursales       []SucursalImpact `json:"sucursales"`
+	Reassignments    []Reassignment   `json:"reassignments"`
+}
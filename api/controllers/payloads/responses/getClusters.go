@@ -0,0 +1,21 @@
+package responses
+
+import (
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/cluster"
+)
+
+type ClusterSummary struct {
+	// Centroid of the sucursales in the cluster.
+	Position models.Position `json:"position"`
+	Count    int             `json:"count"`
+	// Smallest box containing every sucursal of the cluster.
+	Bounds cluster.Bounds `json:"bounds"`
+	// Set when the cluster holds a single sucursal.
+	SucursalID string `json:"sucursalId,omitempty"`
+}
+
+type ClustersResponse struct {
+	Zoom     int              `json:"zoom"`
+	Clusters []ClusterSummary `json:"clusters"`
+}
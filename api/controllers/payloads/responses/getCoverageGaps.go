@@ -0,0 +1,24 @@
+package responses
+
+import "github.com/NJRodriguez/shiny-waddle/api/models"
+
+type CoverageGap struct {
+	Row    int `json:"row"`
+	Column int `json:"column"`
+	// Center of the cell.
+	Position          models.Position `json:"position"`
+	DistanceInKm      float64         `json:"distanceInKm"`
+	NearestSucursalID string          `json:"nearestSucursalId"`
+}
+
+type CoverageGapsResponse struct {
+	// North-west corner of the grid. Rows grow to the south and columns to the east.
+	Origin            models.Position `json:"origin"`
+	Rows              int             `json:"rows"`
+	Columns           int             `json:"columns"`
+	CellHeightDegrees float64         `json:"cellHeightDegrees"`
+	CellWidthDegrees  float64         `json:"cellWidthDegrees"`
+	// Number of cells whose center is inside the polygon.
+	Cells int           `json:"cells"`
+	Gaps  []CoverageGap `json:"gaps"`
+}
@@ -0,0 +1,43 @@
+package responses
+
+import "time"
+
+type DemandCell struct {
+	// Center of the cell.
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Count     int     `json:"count"`
+}
+
+type DemandHeatmapResponse struct {
+	From        time.Time    `json:"from"`
+	To          time.Time    `json:"to"`
+	CellDegrees float64      `json:"cellDegrees"`
+	Total       int          `json:"total"`
+	Cells       []DemandCell `json:"cells"`
+}
+
+type SucursalDemand struct {
+	ID    string `json:"id"`
+	Count int    `json:"count"`
+}
+
+type SucursalDemandResponse struct {
+	From       time.Time        `json:"from"`
+	To         time.Time        `json:"to"`
+	Total      int              `json:"total"`
+	Sucursales []SucursalDemand `json:"sucursales"`
+}
+
+type DemandStatusResponse struct {
+	Enabled          bool    `json:"enabled"`
+	PrecisionDegrees float64 `json:"precisionDegrees,omitempty"`
+	// Searches written to the demand table.
+	Recorded uint64 `json:"recorded"`
+	// Searches discarded because the buffer was full.
+	Dropped uint64 `json:"dropped"`
+	// Searches discarded because the demand table failed to save them.
+	Failed uint64 `json:"failed"`
+	// Searches waiting in the buffer.
+	Pending int `json:"pending"`
+}
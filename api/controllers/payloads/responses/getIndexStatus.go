@@ -0,0 +1,18 @@
+package responses
+
+import "time"
+
+type IndexStatusResponse struct {
+	Enabled                bool    `json:"enabled"`
+	Sucursales             int     `json:"sucursales"`
+	RefreshIntervalSeconds float64 `json:"refreshIntervalSeconds"`
+	MaxAgeSeconds          float64 `json:"maxAgeSeconds"`
+	// Time the index was last loaded from the table, if ever.
+	LoadedAt         *time.Time `json:"loadedAt,omitempty"`
+	StalenessSeconds float64    `json:"stalenessSeconds"`
+	// Set when the index is older than the max age and queries fall back to scanning the table.
+	Stale bool `json:"stale"`
+	// Number of queries answered by scanning the table because the index was stale or not loaded.
+	Fallbacks uint64 `json:"fallbacks"`
+	LastError string `json:"lastError,omitempty"`
+}
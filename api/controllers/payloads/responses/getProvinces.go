@@ -0,0 +1,13 @@
+package responses
+
+type ProvinceSummary struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	Sucursales int    `json:"sucursales"`
+}
+
+type ProvincesResponse struct {
+	Provinces []ProvinceSummary `json:"provinces"`
+	// Sucursales whose province could not be determined from their address.
+	Unknown int `json:"unknown"`
+}
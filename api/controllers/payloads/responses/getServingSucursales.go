@@ -0,0 +1,5 @@
+package responses
+
+type ServingSucursalesResponse struct {
+	Sucursales []ClosestSucursalResponse `json:"sucursales"`
+}
@@ -0,0 +1,24 @@
+package responses
+
+type DistanceStats struct {
+	MeanKm float64 `json:"meanKm"`
+	P95Km  float64 `json:"p95Km"`
+}
+
+type ProposedSite struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// Weight of the demand that would be closer to this site than to any other sucursal.
+	DemandWeight float64 `json:"demandWeight"`
+}
+
+type SiteSelectionResponse struct {
+	DemandPoints int            `json:"demandPoints"`
+	Sites        []ProposedSite `json:"sites"`
+	// Distance from the demand to the closest sucursal, omitted when there are no sucursales yet.
+	Before *DistanceStats `json:"before,omitempty"`
+	// Distance from the demand to the closest sucursal or proposed site.
+	After DistanceStats `json:"after"`
+	// How much closer the demand gets with the proposed sites, omitted along with before.
+	Improvement *DistanceStats `json:"improvement,omitempty"`
+}
@@ -0,0 +1,21 @@
+package responses
+
+type RouteLeg struct {
+	// Sucursal id, or start or end for the endpoints of the route.
+	From         string  `json:"from"`
+	To           string  `json:"to"`
+	DistanceInKm float64 `json:"distanceInKm"`
+}
+
+type VisitRoute struct {
+	// Ids of the sucursales in visiting order.
+	Order        []string   `json:"order"`
+	Legs         []RouteLeg `json:"legs"`
+	DistanceInKm float64    `json:"distanceInKm"`
+}
+
+type VisitRouteResponse struct {
+	// A single route unless max legs splits the visits.
+	Routes            []VisitRoute `json:"routes"`
+	TotalDistanceInKm float64      `json:"totalDistanceInKm"`
+}
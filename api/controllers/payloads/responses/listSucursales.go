@@ -0,0 +1,9 @@
+package responses
+
+import "github.com/NJRodriguez/shiny-waddle/api/models"
+
+type ListSucursalesResponse struct {
+	Sucursales []models.Sucursal `json:"sucursales"`
+	// Cursor of the next page when the sucursales are paginated, empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
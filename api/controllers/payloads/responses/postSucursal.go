@@ -1,6 +1,13 @@
 package responses
 
+import "github.com/NJRodriguez/shiny-waddle/api/models"
+
 type PostSucursal struct {
-	ID      string `json:"id"`
-	Message string `json:"message"`
+	ID                string                    `json:"id"`
+	Message           string                    `json:"message"`
+	Latitude          *float64                  `json:"latitude,omitempty"`
+	Longitude         *float64                  `json:"longitude,omitempty"`
+	Geocoding         *models.Geocoding         `json:"geocoding,omitempty"`
+	NormalizedAddress *models.NormalizedAddress `json:"normalizedAddress,omitempty"`
+	Warnings          []string                  `json:"warnings,omitempty"`
 }
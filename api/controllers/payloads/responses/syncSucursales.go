@@ -0,0 +1,30 @@
+package responses
+
+import "github.com/NJRodriguez/shiny-waddle/api/models"
+
+// SyncFieldChange is a field of a sucursal that an update changes, with its old and new values as
+// JSON. Values are empty when the field is missing.
+type SyncFieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// SyncChange is a write of a sync: create, update or delete. Sucursal is the new version for creates
+// and updates and the existing one for deletes.
+type SyncChange struct {
+	Action   string           `json:"action"`
+	Sucursal *models.Sucursal `json:"sucursal"`
+	// Only set for updates.
+	Fields []SyncFieldChange `json:"fields,omitempty"`
+}
+
+type SyncSucursalesResponse struct {
+	Changes   []SyncChange `json:"changes"`
+	Created   int          `json:"created"`
+	Updated   int          `json:"updated"`
+	Deleted   int          `json:"deleted"`
+	Unchanged int          `json:"unchanged"`
+	// Set when the changes were made, rather than only planned.
+	Applied bool `json:"applied"`
+}
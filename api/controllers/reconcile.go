@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/importer"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+)
+
+// SyncSucursales reconciles the table with the snapshot in the body: a JSON array, JSON lines or CSV
+// depending on the content type. It only plans the changes unless apply is set, and refuses them when
+// they delete more of the sucursales than the server allows, which only sucursal-sync -force overrides.
+func (instance *APIController) SyncSucursales(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	apply := false
+	if value := r.URL.Query().Get("apply"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Printf("Invalid apply: %s", value)
+			writer.WriteHeader(http.StatusBadRequest)
+			generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidApply})
+			return
+		}
+		apply = parsed
+	}
+	snapshot, err := importer.ReadSnapshot(r.Body, snapshotFormat(r.Header.Get("Content-Type")))
+	if err != nil {
+		log.Printf("Error when trying to read the snapshot: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: fmt.Sprintf(invalidSnapshot, err)})
+		return
+	}
+	items, err := instance.documentsClient.ListAll()
+	if err != nil {
+		log.Printf("Error when trying to list sucursales: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: internalServerError})
+		return
+	}
+	existing, err := models.ToSucursalArray(items)
+	if err != nil {
+		log.Printf("Error when trying to read sucursales: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: internalServerError})
+		return
+	}
+	plan, err := importer.PlanSync(snapshot, existing)
+	if err != nil {
+		log.Printf("Error when trying to plan the sync: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: internalServerError})
+		return
+	}
+	response := responses.SyncSucursalesResponse{
+		Changes:   syncChanges(plan.Changes),
+		Created:   plan.Count(importer.ActionCreate),
+		Updated:   plan.Count(importer.ActionUpdate),
+		Deleted:   plan.Count(importer.ActionDelete),
+		Unchanged: plan.Unchanged,
+	}
+	if err := plan.CheckLimits(len(existing), instance.syncMaxDeletePercent); err != nil {
+		limit := err.(*importer.LimitError)
+		log.Printf("Refusing to sync: %s", err)
+		writer.WriteHeader(http.StatusConflict)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: fmt.Sprintf(tooManyDeletes, limit.Deletes, limit.Existing, limit.MaxDeletePercent)})
+		return
+	}
+	if apply {
+		applied, err := importer.Apply(instance.documentsClient, plan)
+		instance.indexSyncedChanges(plan.Changes[:applied])
+		if err != nil {
+			log.Printf("Error when trying to sync sucursales: %s", err)
+			writer.WriteHeader(http.StatusBadRequest)
+			generateErrorMessage(writer, &responses.ErrorMsg{Message: fmt.Sprintf(syncFailed, applied, len(plan.Changes))})
+			return
+		}
+		log.Printf("Synced sucursales with %d changes.", applied)
+		response.Applied = true
+	}
+	_ = json.NewEncoder(writer).Encode(&response)
+}
+
+// snapshotFormat returns the snapshot format of a content type, JSON unless it is CSV or JSON lines.
+func snapshotFormat(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return importer.FormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return importer.FormatJSONL
+	}
+	return importer.FormatJSON
+}
+
+func syncChanges(changes []importer.Change) []responses.SyncChange {
+	converted := make([]responses.SyncChange, len(changes))
+	for i, change := range changes {
+		converted[i] = responses.SyncChange{Action: change.Action, Sucursal: change.Sucursal}
+		for _, field := range change.Fields {
+			converted[i].Fields = append(converted[i].Fields, responses.SyncFieldChange{Field: field.Field, Old: field.Old, New: field.New})
+		}
+	}
+	return converted
+}
+
+// indexSyncedChanges brings the spatial index and the caches up to date with the changes made.
+func (instance *APIController) indexSyncedChanges(changes []importer.Change) {
+	if len(changes) == 0 {
+		return
+	}
+	if instance.index != nil {
+		puts := []*models.Sucursal{}
+		removes := []string{}
+		for _, change := range changes {
+			if change.Action == importer.ActionDelete {
+				removes = append(removes, change.Sucursal.ID)
+			} else {
+				puts = append(puts, change.Sucursal)
+			}
+		}
+		instance.index.apply(puts, removes)
+	}
+	instance.tileCache.Purge()
+	instance.clusterCache.Purge()
+}
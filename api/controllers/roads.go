@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/roads"
+)
+
+// DefaultRoadCandidates is how many of the sucursales closest in a straight line are routed to by
+// default.
+const DefaultRoadCandidates = 5
+
+type roadRanking struct {
+	network    *roads.Network
+	candidates int
+}
+
+// closestSucursalByRoad routes to the sucursales closest to the position in a straight line and
+// returns the one with the shortest route. When none of them can be routed to, as when the position
+// is off the network, the one closest in a straight line is returned without a route. When it fails,
+// the error response has already been written and false is returned.
+func (instance *APIController) closestSucursalByRoad(writer http.ResponseWriter, position *models.Position) (*models.SucursalWithDistance, bool) {
+	candidates, ok := instance.nearestSucursales(writer, position, instance.roads.candidates)
+	if !ok {
+		return nil, false
+	}
+	var closest *models.SucursalWithDistance
+	for _, sucursal := range candidates {
+		route, ok := instance.roads.network.Route(position.Latitude, position.Longitude, sucursal.Latitude, sucursal.Longitude)
+		if !ok || (closest != nil && route.DistanceKm >= closest.Route.DistanceInKm) {
+			continue
+		}
+		closest = &models.SucursalWithDistance{
+			Sucursal: sucursal,
+			Distance: calcDistance(position, sucursal),
+			Route: &models.Route{
+				Profile:           instance.roads.network.Profile.Name,
+				DistanceInKm:      route.DistanceKm,
+				DurationInSeconds: route.DurationSeconds,
+			},
+		}
+	}
+	if closest == nil {
+		return &models.SucursalWithDistance{Sucursal: candidates[0], Distance: calcDistance(position, candidates[0])}, true
+	}
+	return closest, true
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/requests"
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+	"github.com/NJRodriguez/shiny-waddle/lib/route"
+	"github.com/pkg/errors"
+)
+
+const (
+	routeStart = "start"
+	routeEnd   = "end"
+)
+
+func (instance *APIController) GetVisitRoute(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error when trying to read request body: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidRequestBody})
+		return
+	}
+	visit := &requests.VisitRoute{}
+	valErrs, err := ValidateRequest(body, visit)
+	if err != nil {
+		log.Printf("Error when trying to validate requests: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidRequestBody})
+		return
+	}
+	if valErrs != nil {
+		log.Println("Validation error in payload.")
+		writer.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(writer).Encode(valErrs)
+		return
+	}
+	sucursales, err := instance.allSucursales()
+	if err != nil {
+		log.Printf("Error when trying to list sucursales: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: internalServerError})
+		return
+	}
+	response, err := planVisits(visit, sucursales)
+	if err != nil {
+		log.Printf("Invalid visit route: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: err.Error()})
+		return
+	}
+	_ = json.NewEncoder(writer).Encode(&response)
+}
+
+// planVisits orders the visits to the sucursales of the request and reports the legs of every route.
+func planVisits(visit *requests.VisitRoute, sucursales []*models.Sucursal) (*responses.VisitRouteResponse, error) {
+	byID := make(map[string]*models.Sucursal, len(sucursales))
+	for _, sucursal := range sucursales {
+		byID[sucursal.ID] = sucursal
+	}
+	stops := make([]route.Point, len(visit.SucursalIDs))
+	unknown := []string{}
+	for i, id := range visit.SucursalIDs {
+		sucursal, ok := byID[id]
+		if !ok {
+			unknown = append(unknown, id)
+			continue
+		}
+		stops[i] = route.Point{Latitude: sucursal.Latitude, Longitude: sucursal.Longitude}
+	}
+	if len(unknown) > 0 {
+		return nil, errors.Errorf(unknownSucursales, strings.Join(unknown, ", "))
+	}
+	start := route.Point{Latitude: visit.Start.Latitude, Longitude: visit.Start.Longitude}
+	var end *route.Point
+	endName := routeEnd
+	switch {
+	case visit.ReturnToStart:
+		end = &start
+		endName = routeStart
+	case visit.End != nil:
+		end = &route.Point{Latitude: visit.End.Latitude, Longitude: visit.End.Longitude}
+	}
+	// Every route has a leg per stop, plus the one to its end.
+	maxStops := visit.MaxLegs
+	if maxStops > 0 && end != nil {
+		if maxStops < 2 {
+			return nil, errors.Errorf(invalidMaxLegs, 2)
+		}
+		maxStops--
+	}
+	response := &responses.VisitRouteResponse{Routes: []responses.VisitRoute{}}
+	for _, order := range route.Plan(start, stops, end, maxStops) {
+		planned := responses.VisitRoute{Order: []string{}, Legs: []responses.RouteLeg{}}
+		from, previous := routeStart, start
+		for _, stop := range order {
+			id := visit.SucursalIDs[stop]
+			planned.Order = append(planned.Order, id)
+			planned.Legs = append(planned.Legs, routeLeg(from, previous, id, stops[stop]))
+			from, previous = id, stops[stop]
+		}
+		if end != nil {
+			planned.Legs = append(planned.Legs, routeLeg(from, previous, endName, *end))
+		}
+		for _, leg := range planned.Legs {
+			planned.DistanceInKm += leg.DistanceInKm
+		}
+		response.Routes = append(response.Routes, planned)
+		response.TotalDistanceInKm += planned.DistanceInKm
+	}
+	return response, nil
+}
+
+func routeLeg(from string, fromPoint route.Point, to string, toPoint route.Point) responses.RouteLeg {
+	return responses.RouteLeg{
+		From:         from,
+		To:           to,
+		DistanceInKm: geo.Distance(fromPoint.Latitude, fromPoint.Longitude, toPoint.Latitude, toPoint.Longitude),
+	}
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/requests"
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/siting"
+	"github.com/pkg/errors"
+)
+
+const (
+	sucursalKept    = "kept"
+	sucursalRemoved = "removed"
+	sucursalAdded   = "added"
+)
+
+func (instance *APIController) GetClosureSimulation(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error when trying to read request body: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidRequestBody})
+		return
+	}
+	simulation := &requests.ClosureSimulation{}
+	valErrs, err := ValidateRequest(body, simulation)
+	if err != nil {
+		log.Printf("Error when trying to validate requests: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidRequestBody})
+		return
+	}
+	if valErrs != nil {
+		log.Println("Validation error in payload.")
+		writer.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(writer).Encode(valErrs)
+		return
+	}
+	points, ok := instance.requestDemand(writer, r, simulation.Demand)
+	if !ok {
+		return
+	}
+	current, ok := instance.listSucursales(writer)
+	if !ok {
+		return
+	}
+	scenario, statuses, err := closureScenario(current, simulation)
+	if err != nil {
+		log.Printf("Invalid closure simulation: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: err.Error()})
+		return
+	}
+	response := simulateClosures(points, current, scenario, statuses)
+	_ = json.NewEncoder(writer).Encode(&response)
+}
+
+// closureScenario returns the sucursales left after closing and opening those of the simulation,
+// along with the status of every sucursal in either set.
+func closureScenario(current []*models.Sucursal, simulation *requests.ClosureSimulation) ([]*models.Sucursal, map[string]string, error) {
+	statuses := map[string]string{}
+	for _, sucursal := range current {
+		statuses[sucursal.ID] = sucursalKept
+	}
+	unknown := []string{}
+	for _, id := range simulation.Remove {
+		if _, ok := statuses[id]; !ok {
+			unknown = append(unknown, id)
+			continue
+		}
+		statuses[id] = sucursalRemoved
+	}
+	if len(unknown) > 0 {
+		return nil, nil, errors.Errorf(unknownSucursales, strings.Join(unknown, ", "))
+	}
+	scenario := []*models.Sucursal{}
+	for _, sucursal := range current {
+		if statuses[sucursal.ID] == sucursalKept {
+			scenario = append(scenario, sucursal)
+		}
+	}
+	for _, added := range simulation.Add {
+		if _, ok := statuses[added.ID]; ok {
+			return nil, nil, errors.Errorf(duplicatedSucursalID, added.ID)
+		}
+		statuses[added.ID] = sucursalAdded
+		scenario = append(scenario, &models.Sucursal{ID: added.ID, Latitude: added.Latitude, Longitude: added.Longitude})
+	}
+	if len(scenario) == 0 {
+		return nil, nil, errors.New(noSucursalesLeft)
+	}
+	return scenario, statuses, nil
+}
+
+// simulateClosures assigns every demand point to its closest sucursal before and after the changes
+// and reports how the assignments and distances change.
+func simulateClosures(points []siting.Demand, current []*models.Sucursal, scenario []*models.Sucursal, statuses map[string]string) responses.ClosureSimulationResponse {
+	type flow struct{ from, to string }
+	before := make([]float64, len(points))
+	after := make([]float64, len(points))
+	demandBefore := map[string]float64{}
+	demandAfter := map[string]float64{}
+	flowWeights := map[flow]float64{}
+	flowIncreases := map[flow]float64{}
+	response := responses.ClosureSimulationResponse{DemandPoints: len(points), Sucursales: []responses.SucursalImpact{}, Reassignments: []responses.Reassignment{}}
+	for i, point := range points {
+		position := &models.Position{Latitude: point.Latitude, Longitude: point.Longitude}
+		closestBefore := findClosestSucursal(position, current)
+		closestAfter := findClosestSucursal(position, scenario)
+		before[i], after[i] = closestBefore.Distance, closestAfter.Distance
+		demandBefore[closestBefore.Sucursal.ID] += point.Weight
+		demandAfter[closestAfter.Sucursal.ID] += point.Weight
+		response.TotalWeight += point.Weight
+		if closestBefore.Sucursal.ID != closestAfter.Sucursal.ID {
+			key := flow{closestBefore.Sucursal.ID, closestAfter.Sucursal.ID}
+			flowWeights[key] += point.Weight
+			flowIncreases[key] += point.Weight * (closestAfter.Distance - closestBefore.Distance)
+			response.ReassignedWeight += point.Weight
+		}
+	}
+	beforeStats := siting.Summarize(points, before)
+	afterStats := siting.Summarize(points, after)
+	response.Before = responses.DistanceStats{MeanKm: beforeStats.MeanKm, P95Km: beforeStats.P95Km}
+	response.After = responses.DistanceStats{MeanKm: afterStats.MeanKm, P95Km: afterStats.P95Km}
+	for id, status := range statuses {
+		response.Sucursales = append(response.Sucursales, responses.SucursalImpact{
+			ID:           id,
+			Status:       status,
+			DemandBefore: demandBefore[id],
+			DemandAfter:  demandAfter[id],
+		})
+	}
+	sort.Slice(response.Sucursales, func(i, j int) bool {
+		first, second := response.Sucursales[i], response.Sucursales[j]
+		if change := math.Abs(first.DemandAfter-first.DemandBefore) - math.Abs(second.DemandAfter-second.DemandBefore); change != 0 {
+			return change > 0
+		}
+		return first.ID < second.ID
+	})
+	for key, weight := range flowWeights {
+		response.Reassignments = append(response.Reassignments, responses.Reassignment{
+			From:                   key.from,
+			To:                     key.to,
+			Weight:                 weight,
+			MeanDistanceIncreaseKm: flowIncreases[key] / weight,
+		})
+	}
+	sort.Slice(response.Reassignments, func(i, j int) bool {
+		first, second := response.Reassignments[i], response.Reassignments[j]
+		if first.Weight != second.Weight {
+			return first.Weight > second.Weight
+		}
+		if first.From != second.From {
+			return first.From < second.From
+		}
+		return first.To < second.To
+	})
+	return response
+}
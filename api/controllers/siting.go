@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/requests"
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/lib/demand"
+	"github.com/NJRodriguez/shiny-waddle/lib/siting"
+)
+
+func (instance *APIController) GetSiteSelection(writer http.ResponseWriter, r *http.Request) {
+	setJSONContentType(writer)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error when trying to read request body: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidRequestBody})
+		return
+	}
+	selection := &requests.SiteSelection{}
+	valErrs, err := ValidateRequest(body, selection)
+	if err != nil {
+		log.Printf("Error when trying to validate requests: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidRequestBody})
+		return
+	}
+	if valErrs != nil {
+		log.Println("Validation error in payload.")
+		writer.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(writer).Encode(valErrs)
+		return
+	}
+	points, ok := instance.requestDemand(writer, r, selection.Demand)
+	if !ok {
+		return
+	}
+	sucursales, err := instance.allSucursales()
+	if err != nil {
+		log.Printf("Error when trying to list sucursales: %s", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: internalServerError})
+		return
+	}
+	existing := make([]siting.Location, len(sucursales))
+	for i, sucursal := range sucursales {
+		existing[i] = siting.Location{Latitude: sucursal.Latitude, Longitude: sucursal.Longitude}
+	}
+	result := siting.Recommend(points, existing, selection.Count)
+	response := responses.SiteSelectionResponse{
+		DemandPoints: len(points),
+		Sites:        []responses.ProposedSite{},
+		After:        responses.DistanceStats{MeanKm: result.After.MeanKm, P95Km: result.After.P95Km},
+	}
+	for _, site := range result.Sites {
+		response.Sites = append(response.Sites, responses.ProposedSite{Latitude: site.Latitude, Longitude: site.Longitude, DemandWeight: site.Weight})
+	}
+	if !math.IsInf(result.Before.MeanKm, 1) {
+		response.Before = &responses.DistanceStats{MeanKm: result.Before.MeanKm, P95Km: result.Before.P95Km}
+		response.Improvement = &responses.DistanceStats{
+			MeanKm: result.Before.MeanKm - result.After.MeanKm,
+			P95Km:  result.Before.P95Km - result.After.P95Km,
+		}
+	}
+	_ = json.NewEncoder(writer).Encode(&response)
+}
+
+// requestDemand returns the demand points of the request or, when it has none, the recorded
+// searches in the time range of the from and to query parameters, aggregated by their quantized
+// position. When it fails, the error response has already been written and false is returned.
+func (instance *APIController) requestDemand(writer http.ResponseWriter, r *http.Request, demandPoints []requests.DemandPoint) ([]siting.Demand, bool) {
+	if len(demandPoints) > 0 {
+		points := make([]siting.Demand, len(demandPoints))
+		for i, point := range demandPoints {
+			points[i] = siting.Demand{Latitude: point.Latitude, Longitude: point.Longitude, Weight: 1}
+			if point.Weight != nil {
+				points[i].Weight = *point.Weight
+			}
+		}
+		return points, true
+	}
+	if instance.demandRecorder == nil {
+		log.Println("Demand points are required since demand analytics are not enabled.")
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: demandRequired})
+		return nil, false
+	}
+	_, _, queries, ok := instance.demandQueries(writer, r)
+	if !ok {
+		return nil, false
+	}
+	if len(queries) == 0 {
+		log.Println("No searches were recorded in the time range.")
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: noDemandRecorded})
+		return nil, false
+	}
+	cells := demand.Heatmap(queries, instance.demandRecorder.PrecisionDegrees)
+	points := make([]siting.Demand, len(cells))
+	for i, cell := range cells {
+		points[i] = siting.Demand{Latitude: cell.Latitude, Longitude: cell.Longitude, Weight: float64(cell.Count)}
+	}
+	return points, true
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"log"
+
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/pkg/errors"
+)
+
+// ApplyStreamRecords keeps the caches and the spatial index of the controller in sync with the
+// changes made to the table by any instance, as read from its DynamoDB stream.
+// The spatial index is updated once for the whole batch.
+func (instance *APIController) ApplyStreamRecords(records []dynamodbstreams.Record) error {
+	// The last change to each sucursal wins.
+	puts := map[string]*models.Sucursal{}
+	removes := map[string]bool{}
+	for _, record := range records {
+		switch record.EventName {
+		case dynamodbstreams.EventInsert, dynamodbstreams.EventModify:
+			sucursal, err := models.ToSucursal(record.NewImage)
+			if err != nil {
+				return errors.Wrapf(err, "converting record %s to sucursal", record.SequenceNumber)
+			}
+			puts[sucursal.ID] = sucursal
+			delete(removes, sucursal.ID)
+		case dynamodbstreams.EventRemove:
+			key := models.SucursalKey{}
+			if err := dynamodbattribute.UnmarshalMap(record.Keys, &key); err != nil {
+				return errors.Wrapf(err, "converting keys of record %s", record.SequenceNumber)
+			}
+			removes[key.ID] = true
+			delete(puts, key.ID)
+		default:
+			log.Printf("Ignoring stream record %s with unknown event %s", record.SequenceNumber, record.EventName)
+		}
+	}
+	if instance.index != nil {
+		putList := make([]*models.Sucursal, 0, len(puts))
+		for _, sucursal := range puts {
+			putList = append(putList, sucursal)
+		}
+		removeList := make([]string, 0, len(removes))
+		for id := range removes {
+			removeList = append(removeList, id)
+		}
+		instance.index.apply(putList, removeList)
+	}
+	if len(records) > 0 {
+		instance.tileCache.Purge()
+		instance.clusterCache.Purge()
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+	"github.com/NJRodriguez/shiny-waddle/lib/mvt"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// Name of the vector tile layer holding the sucursales.
+	tileLayerName = "sucursales"
+	// Sucursales are clustered at zoom levels below this one.
+	tileClusterMaxZoom = 15
+	// Units of the tile extent around the tile where points are still included, so that the
+	// symbols of points close to the edges are not cut.
+	tileMargin = 128
+	// Default number of tiles cached and how long they are cached for.
+	defaultTileCacheSize       = 1024
+	defaultTileCacheTTLSeconds = 300
+)
+
+func (instance *APIController) GetTile(writer http.ResponseWriter, r *http.Request) {
+	pathVars := mux.Vars(r)
+	z, _ := strconv.Atoi(pathVars["z"])
+	x, _ := strconv.Atoi(pathVars["x"])
+	y, _ := strconv.Atoi(pathVars["y"])
+	if !geo.ValidTile(z, x, y) {
+		log.Printf("Invalid tile %s/%s/%s", pathVars["z"], pathVars["x"], pathVars["y"])
+		setJSONContentType(writer)
+		writer.WriteHeader(http.StatusBadRequest)
+		generateErrorMessage(writer, &responses.ErrorMsg{Message: invalidTile})
+		return
+	}
+	key := fmt.Sprintf("tile/%d/%d/%d", z, x, y)
+	if cached, ok := instance.tileCache.Get(key); ok {
+		writeTile(writer, cached.([]byte))
+		return
+	}
+	var encoded []byte
+	if z < tileClusterMaxZoom {
+		clustered, ok := instance.clustersAtZoom(writer, z)
+		if !ok {
+			return
+		}
+		encoded = encodeClusterTile(clustered, z, x, y)
+	} else {
+		sucursales, ok := instance.listSucursales(writer)
+		if !ok {
+			return
+		}
+		encoded = encodeTile(sucursales, z, x, y)
+	}
+	instance.tileCache.Set(key, encoded)
+	writeTile(writer, encoded)
+}
+
+// encodeTile renders the sucursales of a tile as a Mapbox Vector Tile.
+func encodeTile(sucursales []*models.Sucursal, z int, x int, y int) []byte {
+	tile := mvt.NewTile(z, x, y)
+	layer := tile.AddLayer(tileLayerName, mvt.DefaultExtent)
+	for _, sucursal := range sucursales {
+		layer.AddPoint(sucursal.Latitude, sucursal.Longitude, tileMargin, sucursalTileProperties(sucursal))
+	}
+	return tile.Marshal()
+}
+
+// encodeClusterTile renders the clusters of a tile as a Mapbox Vector Tile. Clusters of a single
+// sucursal are rendered as that sucursal, others as points with the cluster and point_count properties.
+func encodeClusterTile(clustered *zoomClusters, z int, x int, y int) []byte {
+	tile := mvt.NewTile(z, x, y)
+	layer := tile.AddLayer(tileLayerName, mvt.DefaultExtent)
+	for _, group := range clustered.clusters {
+		if group.Count == 1 {
+			sucursal := clustered.sucursales[group.IDs[0]]
+			layer.AddPoint(sucursal.Latitude, sucursal.Longitude, tileMargin, sucursalTileProperties(sucursal))
+			continue
+		}
+		layer.AddPoint(group.Latitude, group.Longitude, tileMargin, map[string]interface{}{
+			"cluster":     true,
+			"point_count": group.Count,
+		})
+	}
+	return tile.Marshal()
+}
+
+func sucursalTileProperties(sucursal *models.Sucursal) map[string]interface{} {
+	return map[string]interface{}{
+		"id":      sucursal.ID,
+		"address": sucursal.Address,
+	}
+}
+
+func writeTile(writer http.ResponseWriter, encoded []byte) {
+	writer.Header().Set("Content-Type", mvt.MediaType)
+	_, _ = writer.Write(encoded)
+}
@@ -0,0 +1,63 @@
+// Package importer loads sucursales from external sources into the table, showing what changes
+// before writing them.
+package importer
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Condition matches the features with a tag, holding any of the values if there are any.
+type Condition struct {
+	Key    string
+	Values []string
+}
+
+// Filter matches the features that meet every condition.
+type Filter []Condition
+
+// ParseCondition parses conditions written as key=value, key=value1|value2 or just key, which
+// matches any value.
+func ParseCondition(value string) (Condition, error) {
+	parts := strings.SplitN(value, "=", 2)
+	key := strings.TrimSpace(parts[0])
+	if key == "" {
+		return Condition{}, errors.Errorf("tag filter %q has no key", value)
+	}
+	condition := Condition{Key: key}
+	if len(parts) == 2 {
+		for _, option := range strings.Split(parts[1], "|") {
+			if option = strings.TrimSpace(option); option != "" {
+				condition.Values = append(condition.Values, option)
+			}
+		}
+		if len(condition.Values) == 0 {
+			return Condition{}, errors.Errorf("tag filter %q has no value", value)
+		}
+	}
+	return condition, nil
+}
+
+// Matches reports whether the tags meet every condition of the filter.
+func (filter Filter) Matches(tags map[string]string) bool {
+	for _, condition := range filter {
+		value, ok := tags[condition.Key]
+		if !ok || !condition.matches(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (condition Condition) matches(value string) bool {
+	if len(condition.Values) == 0 {
+		return true
+	}
+	for _, option := range condition.Values {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
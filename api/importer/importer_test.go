@@ -0,0 +1,293 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb/mocks"
+	"github.com/NJRodriguez/shiny-waddle/lib/osm"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCondition(t *testing.T) {
+	condition, err := ParseCondition("operator=Banco Galicia | Banco de Galicia")
+	require.NoError(t, err)
+	require.Equal(t, Condition{Key: "operator", Values: []string{"Banco Galicia", "Banco de Galicia"}}, condition)
+	condition, err = ParseCondition("atm")
+	require.NoError(t, err)
+	require.Equal(t, Condition{Key: "atm"}, condition)
+	for _, invalid := range []string{"", "=bank", "amenity=", "amenity=|"} {
+		_, err := ParseCondition(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestFilterMatchesEveryCondition(t *testing.T) {
+	filter := Filter{{Key: "amenity", Values: []string{"bank"}}, {Key: "operator"}}
+	require.True(t, filter.Matches(map[string]string{"amenity": "bank", "operator": "Banco Galicia"}))
+	require.False(t, filter.Matches(map[string]string{"amenity": "bank"}))
+	require.False(t, filter.Matches(map[string]string{"amenity": "atm", "operator": "Banco Galicia"}))
+	require.True(t, Filter{}.Matches(map[string]string{}))
+}
+
+func TestFromOSMMapsTags(t *testing.T) {
+	extract := &osm.Extract{
+		Nodes: map[int64]osm.Node{
+			1: {ID: 1, Latitude: -34.6, Longitude: -58.4},
+			2: {ID: 2, Latitude: -34.6, Longitude: -58.402},
+			3: {ID: 3, Latitude: -34.602, Longitude: -58.402},
+			4: {ID: 4, Latitude: -34.602, Longitude: -58.4},
+		},
+		Ways: []osm.Way{
+			{ID: 7, Nodes: []int64{1, 2, 3, 4, 1}, Tags: map[string]string{"amenity": "bank", "brand": "Banco Galicia", "branch": "Florida"}},
+			// Node 5 was left out of the extract.
+			{ID: 8, Nodes: []int64{1, 5}, Tags: map[string]string{"amenity": "bank"}},
+			{ID: 9, Nodes: []int64{1, 2}, Tags: map[string]string{"amenity": "atm"}},
+		},
+		Points: []osm.Node{{ID: 3, Latitude: -34.604258, Longitude: -58.375094, Tags: map[string]string{
+			"amenity":          "bank",
+			"name":             "Banco Nación",
+			"addr:street":      "Florida",
+			"addr:housenumber": "296",
+			"addr:postcode":    "C1005",
+			"addr:city":        "CABA",
+			"addr:province":    "Ciudad Autónoma de Buenos Aires",
+			"opening_hours":    "Mo-Fr 10:00-15:00",
+		}}},
+	}
+	sucursales := FromOSM(extract, Filter{{Key: "amenity", Values: []string{"bank"}}})
+	require.Equal(t, []*models.Sucursal{
+		{
+			ID:        SucursalID("node/3"),
+			Name:      "Banco Nación",
+			Address:   "Florida 296, C1005 CABA, Ciudad Autónoma de Buenos Aires",
+			Latitude:  -34.604258,
+			Longitude: -58.375094,
+			StructuredAddress: &models.AddressComponents{
+				Street:     "Florida",
+				Number:     "296",
+				Locality:   "CABA",
+				Province:   "C",
+				PostalCode: "C1005",
+			},
+			OpeningHours: "Mo-Fr 10:00-15:00",
+			OSMID:        "node/3",
+		},
+		{
+			ID:        SucursalID("way/7"),
+			Name:      "Banco Galicia Florida",
+			Latitude:  -34.601,
+			Longitude: -58.401,
+			OSMID:     "way/7",
+		},
+	}, sucursales)
+	require.Equal(t, SucursalID("node/3"), SucursalID("node/3"))
+	require.NotEqual(t, SucursalID("node/3"), SucursalID("way/3"))
+}
+
+func TestPlanOSMImportUpdatesImportedFieldsOnly(t *testing.T) {
+	capacity := 10
+	kept := &models.Sucursal{ID: SucursalID("node/1"), Name: "Banco", Latitude: -34.6, Longitude: -58.4, OSMID: "node/1", Capacity: &capacity}
+	moved := &models.Sucursal{
+		ID: SucursalID("node/2"), Name: "Banco", Latitude: -34.6, Longitude: -58.5, OSMID: "node/2",
+		Capacity: &capacity, Geocoding: &models.Geocoding{Provider: "gazetteer"},
+	}
+	gone := &models.Sucursal{ID: SucursalID("node/3"), Name: "Banco", OSMID: "node/3"}
+	manual := &models.Sucursal{ID: "b309060a-ce7b-4649-abc1-4cf3f6e51d1b", Name: "Manual"}
+	florida := &models.AddressComponents{Street: "Florida", Number: "296"}
+	kept.Address, kept.StructuredAddress = florida.String(), florida
+	moved.Address, moved.StructuredAddress = florida.String(), florida
+	imported := []*models.Sucursal{
+		{ID: SucursalID("node/1"), Name: "Banco", Address: florida.String(), StructuredAddress: florida, Latitude: -34.6, Longitude: -58.4, OSMID: "node/1"},
+		{ID: SucursalID("node/2"), Name: "Banco", Address: florida.String(), StructuredAddress: florida, Latitude: -34.61, Longitude: -58.5, OSMID: "node/2"},
+		{ID: SucursalID("node/4"), Name: "Nuevo", Address: florida.String(), StructuredAddress: florida, OSMID: "node/4"},
+	}
+	existing := []*models.Sucursal{kept, moved, gone, manual}
+
+	plan, err := PlanOSMImport(imported, existing, false)
+	require.NoError(t, err)
+	require.Equal(t, 1, plan.Unchanged)
+	require.Equal(t, 1, plan.Count(ActionCreate))
+	require.Equal(t, 1, plan.Count(ActionUpdate))
+	require.Equal(t, 0, plan.Count(ActionDelete))
+	update := plan.Changes[1]
+	require.Equal(t, ActionUpdate, update.Action)
+	require.Equal(t, &capacity, update.Sucursal.Capacity)
+	require.Nil(t, update.Sucursal.Geocoding)
+	require.Equal(t, []FieldChange{
+		{Field: "geocoding", Old: `{"confidence":0,"matchedAddress":"","provider":"gazetteer"}`},
+		{Field: "latitude", Old: "-34.6", New: "-34.61"},
+	}, update.Fields)
+
+	plan, err = PlanOSMImport(imported, existing, true)
+	require.NoError(t, err)
+	require.Equal(t, 1, plan.Count(ActionDelete))
+	require.Equal(t, gone, plan.Changes[2].Sucursal)
+}
+
+func TestPlanOSMImportSkipsFeaturesWithoutAnAddress(t *testing.T) {
+	addressed := &models.Sucursal{
+		ID: SucursalID("node/1"), Name: "Banco", Address: "Florida 296, CABA", Latitude: -34.6, Longitude: -58.4, OSMID: "node/1",
+		StructuredAddress: &models.AddressComponents{Street: "Florida", Number: "296", Locality: "CABA"},
+	}
+	bare := &models.Sucursal{ID: SucursalID("node/2"), Name: "Banco", Latitude: -34.6, Longitude: -58.5, OSMID: "node/2"}
+	countryOnly := &models.Sucursal{
+		ID: SucursalID("node/3"), Latitude: -34.6, Longitude: -58.6, OSMID: "node/3",
+		StructuredAddress: &models.AddressComponents{Country: "AR"},
+	}
+	badPostcode := &models.Sucursal{
+		ID: SucursalID("node/4"), Address: "Florida 296, 12", OSMID: "node/4",
+		StructuredAddress: &models.AddressComponents{Street: "Florida", Number: "296", PostalCode: "12"},
+	}
+	stored := &models.Sucursal{ID: SucursalID("node/2"), Name: "Banco", Address: "Corrientes 1000", Latitude: -34.6, Longitude: -58.5, OSMID: "node/2"}
+
+	plan, err := PlanOSMImport([]*models.Sucursal{addressed, bare, countryOnly, badPostcode}, []*models.Sucursal{stored}, true)
+	require.NoError(t, err)
+	require.Equal(t, []Change{{Action: ActionCreate, Sucursal: addressed}}, plan.Changes)
+	require.Equal(t, []Skip{
+		{Sucursal: bare, Reason: "no addr:* tags"},
+		{Sucursal: countryOnly, Reason: "no street or locality tags"},
+		{Sucursal: badPostcode, Reason: "invalid addr:postcode"},
+	}, plan.Skipped)
+
+	output := &bytes.Buffer{}
+	require.NoError(t, plan.Write(output))
+	require.Contains(t, output.String(), `! skip `+bare.ID+` "Banco" (-34.600000, -58.500000): no addr:* tags`)
+	require.Contains(t, output.String(), "1 to create, 0 to update, 0 to delete, 0 unchanged, 3 skipped\n")
+}
+
+func TestDiffRejectsRepeatedSucursales(t *testing.T) {
+	sucursal := &models.Sucursal{ID: "a"}
+	_, err := Diff([]*models.Sucursal{sucursal, sucursal}, nil, false)
+	require.Error(t, err)
+}
+
+func TestPlanWrite(t *testing.T) {
+	plan := &Plan{
+		Changes: []Change{
+			{Action: ActionCreate, Sucursal: &models.Sucursal{ID: "a", Name: "Banco", Latitude: -34.6, Longitude: -58.4}},
+			{Action: ActionUpdate, Sucursal: &models.Sucursal{ID: "b", Address: "Florida 296"}, Fields: []FieldChange{
+				{Field: "name", Old: `"Banco"`},
+				{Field: "openingHours", New: `"24/7"`},
+			}},
+			{Action: ActionDelete, Sucursal: &models.Sucursal{ID: "c"}},
+		},
+		Unchanged: 4,
+	}
+	output := &bytes.Buffer{}
+	require.NoError(t, plan.Write(output))
+	require.Equal(t, `+ create a "Banco" (-34.600000, -58.400000)
+~ update b "Florida 296" (0.000000, 0.000000)
+    name: "Banco" -> (none)
+    openingHours: (none) -> "24/7"
+- delete c "" (0.000000, 0.000000)
+1 to create, 1 to update, 1 to delete, 4 unchanged
+`, output.String())
+}
+
+func TestApplyStopsAtTheFirstFailure(t *testing.T) {
+	created := &models.Sucursal{ID: "a"}
+	updated := &models.Sucursal{ID: "b"}
+	deleted := &models.Sucursal{ID: "c"}
+	plan := &Plan{Changes: []Change{
+		{Action: ActionCreate, Sucursal: created},
+		{Action: ActionUpdate, Sucursal: updated},
+		{Action: ActionDelete, Sucursal: deleted},
+		{Action: ActionCreate, Sucursal: &models.Sucursal{ID: "d"}},
+	}}
+	documents := &mocks.DocumentsClient{}
+	documents.On("Create", created).Return(&dynamodb.PutItemOutput{}, nil).Once()
+	documents.On("Put", updated).Return(&dynamodb.PutItemOutput{}, nil).Once()
+	documents.On("Delete", models.SucursalKey{ID: "c"}).Return(nil, errors.New("throttled")).Once()
+	applied, err := Apply(documents, plan)
+	require.Error(t, err)
+	require.Equal(t, 2, applied)
+	documents.AssertExpectations(t)
+}
+
+func TestReadSnapshotFormats(t *testing.T) {
+	capacity := 20
+	expected := []*models.Sucursal{
+		{ID: "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", Name: "Centro", Address: "Florida 296, C1005 CABA", Latitude: -34.6, Longitude: -58.4, Capacity: &capacity, OpeningHours: "Mo-Fr 10:00-15:00"},
+		{
+			ID: "8d2e6a1f-3c4b-4a7d-8e9f-0a1b2c3d4e02", Address: "Av. Colón 100, 5000 Córdoba", Latitude: -31.4, Longitude: -64.2,
+			StructuredAddress: &models.AddressComponents{Street: "Av. Colón", Number: "100", Locality: "Córdoba", Province: "X", PostalCode: "5000"},
+		},
+	}
+	snapshots := map[string]string{
+		FormatJSON: `[
+			{"id": "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", "name": "Centro", "address": "Florida 296, C1005 CABA", "latitude": -34.6, "longitude": -58.4, "capacity": 20, "openingHours": "Mo-Fr 10:00-15:00", "erpCode": 7},
+			{"id": "8d2e6a1f-3c4b-4a7d-8e9f-0a1b2c3d4e02", "structuredAddress": {"street": "Av. Colón", "number": "100", "locality": "Córdoba", "province": "X", "postalCode": "5000"}, "latitude": -31.4, "longitude": -64.2}
+		]`,
+		FormatJSONL: `{"id": "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", "name": "Centro", "address": "Florida 296, C1005 CABA", "latitude": -34.6, "longitude": -58.4, "capacity": 20, "openingHours": "Mo-Fr 10:00-15:00"}
+
+{"id": "8d2e6a1f-3c4b-4a7d-8e9f-0a1b2c3d4e02", "structuredAddress": {"street": "Av. Colón", "number": "100", "locality": "Córdoba", "province": "X", "postalCode": "5000"}, "latitude": -31.4, "longitude": -64.2}
+`,
+		FormatCSV: "\ufeffID,Name,Address,Latitude,Longitude,Capacity,Opening_Hours,Street,Number,Locality,Province,Postal_Code\n" +
+			"4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01,Centro,\"Florida 296, C1005 CABA\",-34.6,-58.4,20,Mo-Fr 10:00-15:00,,,,,\n" +
+			"8d2e6a1f-3c4b-4a7d-8e9f-0a1b2c3d4e02,,,-31.4,-64.2,,,Av. Colón,100,Córdoba,Córdoba,5000\n",
+	}
+	for format, snapshot := range snapshots {
+		sucursales, err := ReadSnapshot(strings.NewReader(snapshot), format)
+		require.NoError(t, err, format)
+		require.Equal(t, expected, sucursales, format)
+	}
+}
+
+func TestReadSnapshotRejectsInvalidRecords(t *testing.T) {
+	invalid := map[string]string{
+		"missing coordinates":    `[{"id": "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", "address": "Florida 296"}]`,
+		"latitude range":         `[{"id": "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", "address": "Florida 296", "latitude": -134.6, "longitude": -58.4}]`,
+		"missing address":        `[{"id": "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", "latitude": -34.6, "longitude": -58.4}]`,
+		"repeated id":            `[{"id": "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", "address": "Florida 296", "latitude": -34.6, "longitude": -58.4}, {"id": "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", "address": "Florida 296", "latitude": -34.6, "longitude": -58.4}]`,
+		"postal code":            `[{"id": "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", "structuredAddress": {"street": "Florida", "number": "296", "postalCode": "12"}, "latitude": -34.6, "longitude": -58.4}]`,
+		"postal code province":   `[{"id": "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", "structuredAddress": {"street": "Florida", "postalCode": "X5000ABC", "province": "C"}, "latitude": -34.6, "longitude": -58.4}]`,
+		"unknown province":       `[{"id": "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", "structuredAddress": {"street": "Florida", "province": "O"}, "latitude": -34.6, "longitude": -58.4}]`,
+		"blank address":          `[{"id": "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", "address": "  ", "latitude": -34.6, "longitude": -58.4}]`,
+		"empty rendered address": `[{"id": "4f0c7c4e-5b1a-4f8e-9d2a-1c3b5e7f9a01", "structuredAddress": {"country": "AR"}, "latitude": -34.6, "longitude": -58.4}]`,
+		"erp id":                 `[{"id": "7", "address": "Florida 296", "latitude": -34.6, "longitude": -58.4}]`,
+	}
+	for name, snapshot := range invalid {
+		_, err := ReadSnapshot(strings.NewReader(snapshot), FormatJSON)
+		require.Error(t, err, name)
+	}
+	_, err := ReadSnapshot(strings.NewReader("id,branch_code\na,7\n"), FormatCSV)
+	require.EqualError(t, err, `unknown snapshot column "branch_code"`)
+	_, err = ReadSnapshot(strings.NewReader("id,address,latitude,longitude\na,Florida 296,south,-58.4\n"), FormatCSV)
+	require.EqualError(t, err, `line 2: latitude "south" is not a number`)
+}
+
+func TestPlanSyncKeepsFieldsManagedThroughTheAPI(t *testing.T) {
+	serviceArea := &models.ServiceArea{Type: "radius", RadiusKm: 5}
+	kept := &models.Sucursal{ID: "a", Address: "Florida 296", Latitude: -34.6, Longitude: -58.4, ServiceArea: serviceArea}
+	renamed := &models.Sucursal{ID: "b", Name: "Centro", Address: "Av. Colón 100", Latitude: -31.4, Longitude: -64.2, ServiceArea: serviceArea}
+	gone := &models.Sucursal{ID: "c", Address: "Av. Cabildo 2000"}
+	snapshot := []*models.Sucursal{
+		{ID: "a", Address: "Florida 296", Latitude: -34.6, Longitude: -58.4},
+		{ID: "b", Name: "Córdoba Centro", Address: "Av. Colón 100", Latitude: -31.4, Longitude: -64.2},
+		{ID: "d", Address: "Av. Rivadavia 5000", Latitude: -34.62, Longitude: -58.44},
+	}
+
+	plan, err := PlanSync(snapshot, []*models.Sucursal{kept, renamed, gone})
+	require.NoError(t, err)
+	require.Equal(t, 1, plan.Unchanged)
+	require.Equal(t, []string{ActionCreate, ActionUpdate, ActionDelete}, []string{plan.Changes[0].Action, plan.Changes[1].Action, plan.Changes[2].Action})
+	require.Equal(t, serviceArea, plan.Changes[1].Sucursal.ServiceArea)
+	require.Equal(t, []FieldChange{{Field: "name", Old: `"Centro"`, New: `"Córdoba Centro"`}}, plan.Changes[1].Fields)
+	require.Equal(t, gone, plan.Changes[2].Sucursal)
+}
+
+func TestCheckLimits(t *testing.T) {
+	plan := &Plan{Changes: []Change{
+		{Action: ActionUpdate, Sucursal: &models.Sucursal{ID: "a"}},
+		{Action: ActionDelete, Sucursal: &models.Sucursal{ID: "b"}},
+	}}
+	require.NoError(t, plan.CheckLimits(10, 10))
+	require.Equal(t, &LimitError{Deletes: 1, Existing: 9, MaxDeletePercent: 10}, plan.CheckLimits(9, 10))
+	require.EqualError(t, plan.CheckLimits(9, 10), "the sync would delete 1 of 9 sucursales, more than the 10% allowed")
+	require.NoError(t, (&Plan{}).CheckLimits(0, 0))
+}
@@ -0,0 +1,204 @@
+package importer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/osm"
+	validator "github.com/go-playground/validator/v10"
+	uuid "github.com/satori/go.uuid"
+)
+
+// FromOSM maps the tagged nodes and the ways of the extract that match the filter to sucursales,
+// sorted by OpenStreetMap id. Ways, such as bank buildings, are placed at the average of their nodes.
+func FromOSM(extract *osm.Extract, filter Filter) []*models.Sucursal {
+	sucursales := []*models.Sucursal{}
+	for _, node := range extract.Points {
+		if filter.Matches(node.Tags) {
+			sucursales = append(sucursales, fromTags(fmt.Sprintf("node/%d", node.ID), node.Latitude, node.Longitude, node.Tags))
+		}
+	}
+	for _, way := range extract.Ways {
+		if !filter.Matches(way.Tags) {
+			continue
+		}
+		if latitude, longitude, ok := wayCenter(extract, way); ok {
+			sucursales = append(sucursales, fromTags(fmt.Sprintf("way/%d", way.ID), latitude, longitude, way.Tags))
+		}
+	}
+	sort.Slice(sucursales, func(i, j int) bool {
+		return sucursales[i].OSMID < sucursales[j].OSMID
+	})
+	return sucursales
+}
+
+// SucursalID returns the id of the sucursal imported from an OpenStreetMap element, which is the
+// same on every import so that imports can be repeated.
+func SucursalID(osmID string) string {
+	return uuid.NewV5(uuid.NamespaceURL, "https://www.openstreetmap.org/"+osmID).String()
+}
+
+func fromTags(osmID string, latitude float64, longitude float64, tags map[string]string) *models.Sucursal {
+	sucursal := &models.Sucursal{
+		ID:           SucursalID(osmID),
+		Name:         name(tags),
+		Latitude:     latitude,
+		Longitude:    longitude,
+		OpeningHours: tags["opening_hours"],
+		OSMID:        osmID,
+	}
+	components := &models.AddressComponents{
+		Street:     tags["addr:street"],
+		Number:     tags["addr:housenumber"],
+		Locality:   firstTag(tags, "addr:city", "addr:suburb"),
+		Province:   models.ProvinceCode(firstTag(tags, "addr:province", "addr:state")),
+		PostalCode: tags["addr:postcode"],
+		Country:    strings.ToUpper(tags["addr:country"]),
+	}
+	if *components != (models.AddressComponents{}) {
+		sucursal.StructuredAddress = components
+		sucursal.Address = components.String()
+	}
+	return sucursal
+}
+
+// name returns the name of the feature, or its brand or operator when it has none, followed by the
+// branch if it is tagged.
+func name(tags map[string]string) string {
+	name := firstTag(tags, "name", "brand", "operator")
+	if branch := tags["branch"]; branch != "" && !strings.Contains(name, branch) {
+		name = strings.TrimSpace(name + " " + branch)
+	}
+	return name
+}
+
+func firstTag(tags map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := strings.TrimSpace(tags[key]); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// wayCenter returns the average position of the nodes of the way, counting the first node of closed
+// ways once. It returns false when any node is missing from the extract.
+func wayCenter(extract *osm.Extract, way osm.Way) (float64, float64, bool) {
+	nodes := way.Nodes
+	if len(nodes) > 1 && nodes[0] == nodes[len(nodes)-1] {
+		nodes = nodes[:len(nodes)-1]
+	}
+	if len(nodes) == 0 {
+		return 0, 0, false
+	}
+	var latitude, longitude float64
+	for _, id := range nodes {
+		node, ok := extract.Nodes[id]
+		if !ok {
+			return 0, 0, false
+		}
+		latitude += node.Latitude
+		longitude += node.Longitude
+	}
+	return latitude / float64(len(nodes)), longitude / float64(len(nodes)), true
+}
+
+// Merge returns the existing sucursal with the fields imported from OpenStreetMap replaced by those
+// of the imported one, keeping the fields that only the API sets, such as the capacity. The geocoding
+// and normalized address are dropped when the address or coordinates change, since they no longer
+// describe them.
+func Merge(existing *models.Sucursal, imported *models.Sucursal) *models.Sucursal {
+	merged := *existing
+	merged.Name = imported.Name
+	merged.Address = imported.Address
+	merged.StructuredAddress = imported.StructuredAddress
+	merged.Latitude = imported.Latitude
+	merged.Longitude = imported.Longitude
+	merged.OpeningHours = imported.OpeningHours
+	merged.OSMID = imported.OSMID
+	if merged.Address != existing.Address || merged.Latitude != existing.Latitude || merged.Longitude != existing.Longitude {
+		merged.Geocoding = nil
+		merged.NormalizedAddress = nil
+	}
+	return &merged
+}
+
+// PlanOSMImport plans the writes that load the imported sucursales into the table holding the
+// existing ones. Sucursales imported before are updated with the imported fields, see Merge. When
+// deleteMissing is set, the sucursales imported before that aren't imported anymore are deleted;
+// sucursales that weren't imported from OpenStreetMap are never deleted. Imported sucursales the API
+// wouldn't accept, such as features without address tags, are skipped: they are reported in the
+// plan and their existing versions are left as they are.
+func PlanOSMImport(imported []*models.Sucursal, existing []*models.Sucursal, deleteMissing bool) (*Plan, error) {
+	byID := make(map[string]*models.Sucursal, len(existing))
+	for _, sucursal := range existing {
+		byID[sucursal.ID] = sucursal
+	}
+	desired := []*models.Sucursal{}
+	skipped := []Skip{}
+	importedIDs := make(map[string]bool, len(imported))
+	for _, sucursal := range imported {
+		importedIDs[sucursal.ID] = true
+		if reason := invalidAddress(sucursal); reason != "" {
+			skipped = append(skipped, Skip{Sucursal: sucursal, Reason: reason})
+			continue
+		}
+		if current, ok := byID[sucursal.ID]; ok {
+			desired = append(desired, Merge(current, sucursal))
+			continue
+		}
+		desired = append(desired, sucursal)
+	}
+	skippedIDs := make(map[string]bool, len(skipped))
+	for _, skip := range skipped {
+		skippedIDs[skip.Sucursal.ID] = true
+	}
+	compared := []*models.Sucursal{}
+	for _, sucursal := range existing {
+		if (sucursal.OSMID != "" || importedIDs[sucursal.ID]) && !skippedIDs[sucursal.ID] {
+			compared = append(compared, sucursal)
+		}
+	}
+	plan, err := Diff(desired, compared, deleteMissing)
+	if err != nil {
+		return nil, err
+	}
+	if len(skipped) > 0 {
+		plan.Skipped = skipped
+	}
+	return plan, nil
+}
+
+// invalidAddress returns why the API would reject the address of an imported sucursal, or "" when
+// it would accept it.
+func invalidAddress(sucursal *models.Sucursal) string {
+	if sucursal.StructuredAddress == nil {
+		return "no addr:* tags"
+	}
+	err := validate.Struct(sucursal.StructuredAddress)
+	if err == nil {
+		return ""
+	}
+	fields := []string{}
+	if invalid, ok := err.(validator.ValidationErrors); ok {
+		for _, field := range invalid {
+			if field.Tag() == "street_or_locality" {
+				return "no street or locality tags"
+			}
+			fields = append(fields, osmTags[field.Field()])
+		}
+	}
+	return "invalid " + strings.Join(fields, ", ")
+}
+
+// osmTags are the tags each address component is imported from.
+var osmTags = map[string]string{
+	"Street":     "addr:street",
+	"Number":     "addr:housenumber",
+	"Locality":   "addr:city",
+	"Province":   "addr:province",
+	"PostalCode": "addr:postcode",
+	"Country":    "addr:country",
+}
@@ -0,0 +1,216 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb"
+	"github.com/pkg/errors"
+)
+
+// Actions of a plan.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// FieldChange is a field of a sucursal that an update changes, with its old and new values as JSON.
+// Values are empty when the field is missing.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// Change is a write to the table. Sucursal is the new version for creates and updates and the
+// existing one for deletes.
+type Change struct {
+	Action   string           `json:"action"`
+	Sucursal *models.Sucursal `json:"sucursal"`
+	// Only set for updates.
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// Skip is an imported sucursal left out of a plan because the API wouldn't accept it, with the
+// reason.
+type Skip struct {
+	Sucursal *models.Sucursal `json:"sucursal"`
+	Reason   string           `json:"reason"`
+}
+
+// Plan holds the writes that make the table hold the desired sucursales.
+type Plan struct {
+	Changes   []Change `json:"changes"`
+	Unchanged int      `json:"unchanged"`
+	// Imported sucursales that are neither written nor deleted.
+	Skipped []Skip `json:"skipped,omitempty"`
+}
+
+// Diff compares the desired sucursales with the existing ones, matched by id. Desired sucursales
+// missing from the table are created and those that differ are updated. When deleteMissing is set,
+// existing sucursales missing from the desired ones are deleted. Changes are sorted by action and id.
+func Diff(desired []*models.Sucursal, existing []*models.Sucursal, deleteMissing bool) (*Plan, error) {
+	byID := make(map[string]*models.Sucursal, len(existing))
+	for _, sucursal := range existing {
+		byID[sucursal.ID] = sucursal
+	}
+	plan := &Plan{Changes: []Change{}}
+	wanted := make(map[string]bool, len(desired))
+	for _, sucursal := range desired {
+		if wanted[sucursal.ID] {
+			return nil, errors.Errorf("sucursal %s is repeated", sucursal.ID)
+		}
+		wanted[sucursal.ID] = true
+		current, ok := byID[sucursal.ID]
+		if !ok {
+			plan.Changes = append(plan.Changes, Change{Action: ActionCreate, Sucursal: sucursal})
+			continue
+		}
+		fields, err := fieldChanges(current, sucursal)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			plan.Unchanged++
+			continue
+		}
+		plan.Changes = append(plan.Changes, Change{Action: ActionUpdate, Sucursal: sucursal, Fields: fields})
+	}
+	if deleteMissing {
+		for _, sucursal := range existing {
+			if !wanted[sucursal.ID] {
+				plan.Changes = append(plan.Changes, Change{Action: ActionDelete, Sucursal: sucursal})
+			}
+		}
+	}
+	order := map[string]int{ActionCreate: 0, ActionUpdate: 1, ActionDelete: 2}
+	sort.SliceStable(plan.Changes, func(i, j int) bool {
+		first, second := plan.Changes[i], plan.Changes[j]
+		if first.Action != second.Action {
+			return order[first.Action] < order[second.Action]
+		}
+		return first.Sucursal.ID < second.Sucursal.ID
+	})
+	return plan, nil
+}
+
+// fieldChanges compares the JSON fields of two versions of a sucursal.
+func fieldChanges(old *models.Sucursal, new *models.Sucursal) ([]FieldChange, error) {
+	oldFields, err := jsonFields(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := jsonFields(new)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	changes := []FieldChange{}
+	for _, name := range names {
+		if !bytes.Equal(oldFields[name], newFields[name]) {
+			changes = append(changes, FieldChange{Field: name, Old: string(oldFields[name]), New: string(newFields[name])})
+		}
+	}
+	return changes, nil
+}
+
+func jsonFields(sucursal *models.Sucursal) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(sucursal)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling sucursal")
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling sucursal fields")
+	}
+	return fields, nil
+}
+
+// Count returns the number of changes with the action.
+func (plan *Plan) Count(action string) int {
+	count := 0
+	for _, change := range plan.Changes {
+		if change.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Write writes the plan as a diff, with a line per change, per changed field and per skipped
+// sucursal, followed by a summary line.
+func (plan *Plan) Write(writer io.Writer) error {
+	symbols := map[string]string{ActionCreate: "+", ActionUpdate: "~", ActionDelete: "-"}
+	for _, change := range plan.Changes {
+		sucursal := change.Sucursal
+		if _, err := fmt.Fprintf(writer, "%s %s %s %q (%f, %f)\n", symbols[change.Action], change.Action, sucursal.ID, describe(sucursal), sucursal.Latitude, sucursal.Longitude); err != nil {
+			return err
+		}
+		for _, field := range change.Fields {
+			if _, err := fmt.Fprintf(writer, "    %s: %s -> %s\n", field.Field, orNone(field.Old), orNone(field.New)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, skip := range plan.Skipped {
+		sucursal := skip.Sucursal
+		if _, err := fmt.Fprintf(writer, "! skip %s %q (%f, %f): %s\n", sucursal.ID, describe(sucursal), sucursal.Latitude, sucursal.Longitude, skip.Reason); err != nil {
+			return err
+		}
+	}
+	summary := fmt.Sprintf("%d to create, %d to update, %d to delete, %d unchanged",
+		plan.Count(ActionCreate), plan.Count(ActionUpdate), plan.Count(ActionDelete), plan.Unchanged)
+	if len(plan.Skipped) > 0 {
+		summary += fmt.Sprintf(", %d skipped", len(plan.Skipped))
+	}
+	_, err := fmt.Fprintln(writer, summary)
+	return err
+}
+
+func describe(sucursal *models.Sucursal) string {
+	if sucursal.Name != "" {
+		return sucursal.Name
+	}
+	return sucursal.Address
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+// Apply makes the changes of the plan through the client, in order, and returns how many were made.
+// It stops at the first change that fails.
+func Apply(client dynamodb.DocumentsClient, plan *Plan) (int, error) {
+	for i, change := range plan.Changes {
+		var err error
+		switch change.Action {
+		case ActionCreate:
+			_, err = client.Create(change.Sucursal)
+		case ActionUpdate:
+			_, err = client.Put(change.Sucursal)
+		case ActionDelete:
+			_, err = client.Delete(models.SucursalKey{ID: change.Sucursal.ID})
+		}
+		if err != nil {
+			return i, errors.Wrapf(err, "trying to %s sucursal %s", change.Action, change.Sucursal.ID)
+		}
+	}
+	return len(plan.Changes), nil
+}
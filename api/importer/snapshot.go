@@ -0,0 +1,307 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	validator "github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+)
+
+// Formats of a snapshot.
+const (
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// DefaultMaxDeletePercent is the largest share of the existing sucursales a sync deletes unless
+// told otherwise.
+const DefaultMaxDeletePercent = 10
+
+// Record is a sucursal of a snapshot. It holds the fields owned by the source of the snapshot,
+// such as the ERP; the rest are managed through the API.
+type Record struct {
+	ID                string                    `json:"id" validate:"required,uuid4"`
+	Name              string                    `json:"name,omitempty"`
+	Address           string                    `json:"address" validate:"required_without=StructuredAddress"`
+	StructuredAddress *models.AddressComponents `json:"structuredAddress,omitempty" validate:"omitempty"`
+	Latitude          *float64                  `json:"latitude" validate:"required,min=-90,max=90"`
+	Longitude         *float64                  `json:"longitude" validate:"required,min=-180,max=180"`
+	Capacity          *int                      `json:"capacity,omitempty" validate:"omitempty,gte=0"`
+	OpeningHours      string                    `json:"openingHours,omitempty"`
+}
+
+// validate checks records with the same address rules as the API.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	instance := validator.New()
+	instance.RegisterStructValidation(models.ValidateAddressComponents, models.AddressComponents{})
+	return instance
+}
+
+// FormatOf returns the format of a snapshot file from its extension: .csv, .jsonl or .ndjson, and
+// JSON otherwise.
+func FormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	}
+	return FormatJSON
+}
+
+// LoadSnapshot reads the snapshot file at path, see ReadSnapshot.
+func LoadSnapshot(path string) ([]*models.Sucursal, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening snapshot")
+	}
+	defer file.Close()
+	return ReadSnapshot(file, FormatOf(path))
+}
+
+// ReadSnapshot reads every sucursal of a snapshot, which is a JSON array of records, a record per
+// line or a CSV file with a header row naming the record fields. CSV columns may also be written in
+// snake case, such as opening_hours, and the structured address is given by the street, number,
+// locality, province, postal_code and country columns. Records are validated and must have unique
+// ids.
+func ReadSnapshot(reader io.Reader, format string) ([]*models.Sucursal, error) {
+	var records []Record
+	var err error
+	switch format {
+	case FormatJSON:
+		err = json.NewDecoder(reader).Decode(&records)
+		if err != nil {
+			err = errors.Wrap(err, "decoding snapshot")
+		}
+	case FormatJSONL:
+		records, err = readJSONL(reader)
+	case FormatCSV:
+		records, err = readCSV(reader)
+	default:
+		err = errors.Errorf("unknown snapshot format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sucursales := make([]*models.Sucursal, 0, len(records))
+	seen := make(map[string]int, len(records))
+	for i, record := range records {
+		if err := validate.Struct(&record); err != nil {
+			return nil, errors.Wrapf(err, "record %d", i+1)
+		}
+		if first, ok := seen[record.ID]; ok {
+			return nil, errors.Errorf("record %d repeats id %s of record %d", i+1, record.ID, first)
+		}
+		seen[record.ID] = i + 1
+		sucursal := record.sucursal()
+		if strings.TrimSpace(sucursal.Address) == "" {
+			return nil, errors.Errorf("record %d has an empty address", i+1)
+		}
+		sucursales = append(sucursales, sucursal)
+	}
+	return sucursales, nil
+}
+
+func (record *Record) sucursal() *models.Sucursal {
+	sucursal := &models.Sucursal{
+		ID:                record.ID,
+		Name:              record.Name,
+		Address:           record.Address,
+		StructuredAddress: record.StructuredAddress,
+		Latitude:          *record.Latitude,
+		Longitude:         *record.Longitude,
+		Capacity:          record.Capacity,
+		OpeningHours:      record.OpeningHours,
+	}
+	if sucursal.Address == "" && sucursal.StructuredAddress != nil {
+		sucursal.Address = sucursal.StructuredAddress.String()
+	}
+	return sucursal
+}
+
+func readJSONL(reader io.Reader) ([]Record, error) {
+	records := []Record{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		record := Record{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, errors.Wrapf(err, "decoding line %d", line)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading snapshot")
+	}
+	return records, nil
+}
+
+// readCSV maps the columns of the CSV file to record fields by their normalized names.
+func readCSV(reader io.Reader) ([]Record, error) {
+	rows := csv.NewReader(reader)
+	rows.TrimLeadingSpace = true
+	header, err := rows.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading snapshot header")
+	}
+	columns := make([]string, len(header))
+	for i, name := range header {
+		column := strings.ToLower(strings.NewReplacer("_", "", "-", "", " ", "").Replace(strings.TrimSpace(name)))
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		if !csvColumns[column] {
+			return nil, errors.Errorf("unknown snapshot column %q", name)
+		}
+		columns[i] = column
+	}
+	records := []Record{}
+	for line := 2; ; line++ {
+		row, err := rows.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading snapshot")
+		}
+		record, err := csvRecord(columns, row)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", line)
+		}
+		records = append(records, record)
+	}
+}
+
+var csvColumns = map[string]bool{
+	"id": true, "name": true, "address": true, "latitude": true, "longitude": true, "capacity": true, "openinghours": true,
+	"street": true, "number": true, "locality": true, "province": true, "postalcode": true, "country": true,
+}
+
+func csvRecord(columns []string, row []string) (Record, error) {
+	record := Record{}
+	components := models.AddressComponents{}
+	for i, value := range row {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		switch columns[i] {
+		case "id":
+			record.ID = value
+		case "name":
+			record.Name = value
+		case "address":
+			record.Address = value
+		case "latitude", "longitude":
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return record, errors.Errorf("%s %q is not a number", columns[i], value)
+			}
+			if columns[i] == "latitude" {
+				record.Latitude = &number
+			} else {
+				record.Longitude = &number
+			}
+		case "capacity":
+			capacity, err := strconv.Atoi(value)
+			if err != nil {
+				return record, errors.Errorf("capacity %q is not an integer", value)
+			}
+			record.Capacity = &capacity
+		case "openinghours":
+			record.OpeningHours = value
+		case "street":
+			components.Street = value
+		case "number":
+			components.Number = value
+		case "locality":
+			components.Locality = value
+		case "province":
+			components.Province = models.ProvinceCode(value)
+		case "postalcode":
+			components.PostalCode = value
+		case "country":
+			components.Country = strings.ToUpper(value)
+		}
+	}
+	if components != (models.AddressComponents{}) {
+		record.StructuredAddress = &components
+	}
+	return record, nil
+}
+
+// MergeSnapshot returns the existing sucursal with the fields owned by the snapshot replaced by
+// those of the snapshot one, keeping the fields managed through the API, such as the service area.
+// The geocoding and normalized address are dropped when the address or coordinates change.
+func MergeSnapshot(existing *models.Sucursal, snapshot *models.Sucursal) *models.Sucursal {
+	merged := *existing
+	merged.Name = snapshot.Name
+	merged.Address = snapshot.Address
+	merged.StructuredAddress = snapshot.StructuredAddress
+	merged.Latitude = snapshot.Latitude
+	merged.Longitude = snapshot.Longitude
+	merged.Capacity = snapshot.Capacity
+	merged.OpeningHours = snapshot.OpeningHours
+	if merged.Address != existing.Address || merged.Latitude != existing.Latitude || merged.Longitude != existing.Longitude {
+		merged.Geocoding = nil
+		merged.NormalizedAddress = nil
+	}
+	return &merged
+}
+
+// PlanSync plans the writes that make the table hold exactly the sucursales of the snapshot:
+// sucursales missing from the table are created, those that differ are updated, see MergeSnapshot,
+// and those missing from the snapshot are deleted.
+func PlanSync(snapshot []*models.Sucursal, existing []*models.Sucursal) (*Plan, error) {
+	byID := make(map[string]*models.Sucursal, len(existing))
+	for _, sucursal := range existing {
+		byID[sucursal.ID] = sucursal
+	}
+	desired := make([]*models.Sucursal, len(snapshot))
+	for i, sucursal := range snapshot {
+		desired[i] = sucursal
+		if current, ok := byID[sucursal.ID]; ok {
+			desired[i] = MergeSnapshot(current, sucursal)
+		}
+	}
+	return Diff(desired, existing, true)
+}
+
+// LimitError is returned when a plan deletes a larger share of the existing sucursales than allowed.
+type LimitError struct {
+	Deletes          int
+	Existing         int
+	MaxDeletePercent float64
+}
+
+func (err *LimitError) Error() string {
+	return fmt.Sprintf("the sync would delete %d of %d sucursales, more than the %g%% allowed", err.Deletes, err.Existing, err.MaxDeletePercent)
+}
+
+// CheckLimits returns a *LimitError when the plan deletes more than maxDeletePercent of the existing
+// sucursales, which usually means the snapshot is truncated or was exported from the wrong source.
+func (plan *Plan) CheckLimits(existing int, maxDeletePercent float64) error {
+	deletes := plan.Count(ActionDelete)
+	if deletes > 0 && float64(deletes)*100 > maxDeletePercent*float64(existing) {
+		return &LimitError{Deletes: deletes, Existing: existing, MaxDeletePercent: maxDeletePercent}
+	}
+	return nil
+}
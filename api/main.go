@@ -18,7 +18,8 @@ func main() {
 	region := os.Getenv("AWS_REGION")
 
 	server := &setup.Server{
-		Router: mux.NewRouter(),
+		Router:  mux.NewRouter(),
+		Options: setup.OptionsFromEnv(),
 	}
 	err := server.Initialize(tableName, region)
 	if err != nil {
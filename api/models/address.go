@@ -0,0 +1,144 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/geocoding"
+	validator "github.com/go-playground/validator/v10"
+)
+
+// AddressComponents are the structured parts of an address.
+type AddressComponents struct {
+	Street     string `json:"street,omitempty"`
+	Number     string `json:"number,omitempty"`
+	Locality   string `json:"locality,omitempty"`
+	Province   string `json:"province,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+// NormalizedAddress is the normalized form of the address of a Sucursal, stored alongside the raw address.
+type NormalizedAddress struct {
+	// Lowercase address without accents, punctuation or abbreviations.
+	Text string `json:"text"`
+	// Components of the known address closest to the coordinates, if any was found.
+	Components *AddressComponents `json:"components,omitempty"`
+	// Address closest to the coordinates as written in the reverse geocoding data.
+	MatchedAddress string `json:"matchedAddress,omitempty"`
+	// Distance in kilometers between the coordinates and the matched address.
+	DistanceKm float64 `json:"distanceKm,omitempty"`
+}
+
+var countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+var streetNumberPattern = regexp.MustCompile(`^(.*\S)\s+([0-9]+[A-Za-z]?)$`)
+
+// ParseAddress splits a single-line address such as "Florida 296, C1005 CABA" into its components.
+// Parsing is best effort: parts that are not recognized are left empty.
+func ParseAddress(line string) *AddressComponents {
+	components := &AddressComponents{}
+	parts := strings.Split(line, ",")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if i == 0 {
+			if match := streetNumberPattern.FindStringSubmatch(part); match != nil {
+				components.Street = match[1]
+				components.Number = match[2]
+			} else {
+				components.Street = part
+			}
+			continue
+		}
+		if code := provinceByName(part); code != "" && components.Province == "" {
+			components.Province = code
+			continue
+		}
+		fields := strings.Fields(part)
+		if components.PostalCode == "" && (IsArgentinePostalCode(fields[0]) || ProvinceFromPostalCode(fields[0]) != "") {
+			components.PostalCode = fields[0]
+			fields = fields[1:]
+		}
+		if components.Locality == "" && len(fields) > 0 {
+			components.Locality = strings.Join(fields, " ")
+		}
+	}
+	if components.Province == "" {
+		components.Province = ProvinceFromPostalCode(components.PostalCode)
+	}
+	if components.Province != "" {
+		components.Country = CountryArgentina
+	}
+	return components
+}
+
+// String renders the components as a single-line address, e.g. "Florida 296, C1005AAB Ciudad Autónoma de Buenos Aires".
+func (components *AddressComponents) String() string {
+	parts := []string{}
+	if street := strings.TrimSpace(components.Street + " " + components.Number); street != "" {
+		parts = append(parts, street)
+	}
+	if locality := strings.TrimSpace(components.PostalCode + " " + components.Locality); locality != "" {
+		parts = append(parts, locality)
+	}
+	if name, ok := Provinces[components.Province]; ok && name != components.Locality {
+		parts = append(parts, name)
+	}
+	if components.Country != "" && components.Country != CountryArgentina {
+		parts = append(parts, components.Country)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ProvinceCode returns the province code for either a province code or a province name. Returns an
+// empty string when it does not match any province.
+func ProvinceCode(codeOrName string) string {
+	if code := strings.ToUpper(strings.TrimSpace(codeOrName)); IsProvinceCode(code) {
+		return code
+	}
+	return provinceByName(codeOrName)
+}
+
+func provinceByName(name string) string {
+	normalized := geocoding.Normalize(name)
+	for code, provinceName := range Provinces {
+		if geocoding.Normalize(provinceName) == normalized {
+			return code
+		}
+	}
+	return ""
+}
+
+// ValidateAddressComponents is the struct validation of AddressComponents, shared by the API and the
+// importer. Every address needs a street or a locality, so that it doesn't render empty. It checks
+// the province and postal code of Argentine addresses. Addresses from other countries only get
+// their country code checked.
+func ValidateAddressComponents(sl validator.StructLevel) {
+	address := sl.Current().Interface().(AddressComponents)
+	if strings.TrimSpace(address.Street) == "" && strings.TrimSpace(address.Locality) == "" {
+		sl.ReportError(address.Street, "Street", "Street", "street_or_locality", "")
+	}
+	if address.Country != "" && !countryCodePattern.MatchString(address.Country) {
+		sl.ReportError(address.Country, "Country", "Country", "country", "")
+		return
+	}
+	if address.Country != "" && address.Country != CountryArgentina {
+		return
+	}
+	if address.Province != "" && !IsProvinceCode(address.Province) {
+		sl.ReportError(address.Province, "Province", "Province", "province", "")
+	}
+	if address.PostalCode == "" {
+		return
+	}
+	if !IsArgentinePostalCode(address.PostalCode) {
+		sl.ReportError(address.PostalCode, "PostalCode", "PostalCode", "cpa", "")
+		return
+	}
+	if IsProvinceCode(address.Province) && IsCPA(address.PostalCode) && ProvinceFromPostalCode(address.PostalCode) != address.Province {
+		sl.ReportError(address.PostalCode, "PostalCode", "PostalCode", "cpa_province", address.Province)
+	}
+}
@@ -0,0 +1,11 @@
+package models
+
+// Geocoding stores how the coordinates of a Sucursal were resolved from its address.
+type Geocoding struct {
+	// Confidence of the match, between 0 and 1.
+	Confidence float64 `json:"confidence"`
+	// Address that was matched by the geocoder.
+	MatchedAddress string `json:"matchedAddress"`
+	// Geocoding provider that resolved the address.
+	Provider string `json:"provider"`
+}
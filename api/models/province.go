@@ -0,0 +1,69 @@
+package models
+
+import "regexp"
+
+// CountryArgentina is the ISO 3166-1 alpha-2 code of Argentina.
+const CountryArgentina = "AR"
+
+// Provinces maps the Argentine province codes (ISO 3166-2:AR, also used as the first letter of the
+// CPA postal code) to their names.
+var Provinces = map[string]string{
+	"A": "Salta",
+	"B": "Buenos Aires",
+	"C": "Ciudad Autónoma de Buenos Aires",
+	"D": "San Luis",
+	"E": "Entre Ríos",
+	"F": "La Rioja",
+	"G": "Santiago del Estero",
+	"H": "Chaco",
+	"J": "San Juan",
+	"K": "Catamarca",
+	"L": "La Pampa",
+	"M": "Mendoza",
+	"N": "Misiones",
+	"P": "Formosa",
+	"Q": "Neuquén",
+	"R": "Río Negro",
+	"S": "Santa Fe",
+	"T": "Tucumán",
+	"U": "Chubut",
+	"V": "Tierra del Fuego",
+	"W": "Corrientes",
+	"X": "Córdoba",
+	"Y": "Jujuy",
+	"Z": "Santa Cruz",
+}
+
+var (
+	// cpaPattern matches the CPA postal code format, e.g. C1005AAB.
+	cpaPattern = regexp.MustCompile(`^[A-HJ-NP-Z][0-9]{4}[A-Z]{3}$`)
+	// legacyPostalCodePattern matches the four digit postal codes used before CPA, e.g. 1005.
+	legacyPostalCodePattern = regexp.MustCompile(`^[0-9]{4}$`)
+	// shortCPAPattern matches the province letter and the four digits of a CPA, e.g. C1005.
+	shortCPAPattern = regexp.MustCompile(`^[A-HJ-NP-Z][0-9]{4}$`)
+)
+
+// IsCPA reports whether the postal code is in the Argentine CPA format, e.g. C1005AAB.
+func IsCPA(postalCode string) bool {
+	return cpaPattern.MatchString(postalCode)
+}
+
+// IsArgentinePostalCode reports whether the postal code is either a CPA or a legacy four digit code.
+func IsArgentinePostalCode(postalCode string) bool {
+	return IsCPA(postalCode) || legacyPostalCodePattern.MatchString(postalCode)
+}
+
+// IsProvinceCode reports whether the code is a valid Argentine province code.
+func IsProvinceCode(code string) bool {
+	_, ok := Provinces[code]
+	return ok
+}
+
+// ProvinceFromPostalCode returns the province code encoded in the first letter of a CPA. Returns an
+// empty string for legacy postal codes.
+func ProvinceFromPostalCode(postalCode string) string {
+	if IsCPA(postalCode) || shortCPAPattern.MatchString(postalCode) {
+		return postalCode[:1]
+	}
+	return ""
+}
@@ -0,0 +1,9 @@
+package models
+
+// Route is the way to a sucursal along the road network.
+type Route struct {
+	// Driving or walking.
+	Profile           string  `json:"profile"`
+	DistanceInKm      float64 `json:"distanceInKm"`
+	DurationInSeconds float64 `json:"durationInSeconds"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "github.com/NJRodriguez/shiny-waddle/lib/geo"
+
+const (
+	// ServiceAreaRadius is a circular service area around the Sucursal.
+	ServiceAreaRadius = "radius"
+	// ServiceAreaPolygon is a GeoJSON polygon service area.
+	ServiceAreaPolygon = "polygon"
+)
+
+// ServiceArea defines the delivery/service area of a Sucursal.
+type ServiceArea struct {
+	// Either "radius" or "polygon".
+	Type string `json:"type" validate:"required,oneof=radius polygon"`
+	// Radius in kilometers around the Sucursal. Only used by "radius" areas.
+	RadiusKm float64 `json:"radiusKm,omitempty" validate:"required_if=Type radius,gte=0"`
+	// GeoJSON polygon coordinates in [longitude, latitude] order. Only used by "polygon" areas.
+	Coordinates [][][]float64 `json:"coordinates,omitempty" validate:"required_if=Type polygon,polygon"`
+}
+
+// Contains reports whether the position is inside the service area of the Sucursal located at the given center.
+func (area *ServiceArea) Contains(center *Position, position *Position) bool {
+	switch area.Type {
+	case ServiceAreaRadius:
+		return geo.Distance(center.Latitude, center.Longitude, position.Latitude, position.Longitude) <= area.RadiusKm
+	case ServiceAreaPolygon:
+		return geo.PointInPolygon(position.Latitude, position.Longitude, area.Coordinates)
+	}
+	return false
+}
@@ -10,17 +10,72 @@ import (
 type Sucursal struct {
 	// Used for DynamoDB lookup.
 	ID string `json:"id"`
-	// Address of Sucursal.
+	// Name of Sucursal, such as the branch name.
+	Name string `json:"name,omitempty"`
+	// Address of Sucursal in a single line.
 	Address string `json:"address"`
+	// Structured address of Sucursal, when it was created with one.
+	StructuredAddress *AddressComponents `json:"structuredAddress,omitempty"`
 	// Latitude of Sucursal in decimal degrees.
 	Latitude float64 `json:"latitude"`
 	// Longitude of Sucursal in decimal degrees.
 	Longitude float64 `json:"longitude"`
+	// Optional delivery/service area of Sucursal.
+	ServiceArea *ServiceArea `json:"serviceArea,omitempty"`
+	// Most customers assigned to Sucursal at once. Unlimited when omitted.
+	Capacity *int `json:"capacity,omitempty"`
+	// Opening hours of Sucursal in the OpenStreetMap syntax, such as "Mo-Fr 10:00-15:00".
+	OpeningHours string `json:"openingHours,omitempty"`
+	// OpenStreetMap element Sucursal was imported from, such as node/123.
+	OSMID string `json:"osmId,omitempty"`
+	// Set when the coordinates were resolved from the address.
+	Geocoding *Geocoding `json:"geocoding,omitempty"`
+	// Normalized address, set when reverse geocoding is enabled.
+	NormalizedAddress *NormalizedAddress `json:"normalizedAddress,omitempty"`
+}
+
+// Position returns the location of Sucursal.
+func (sucursal *Sucursal) Position() *Position {
+	return &Position{Latitude: sucursal.Latitude, Longitude: sucursal.Longitude}
+}
+
+// Province returns the province code of Sucursal, looking at the structured address, then at the
+// normalized address and finally at the postal code of the single-line address.
+func (sucursal *Sucursal) Province() string {
+	if sucursal.StructuredAddress != nil && sucursal.StructuredAddress.Province != "" {
+		return sucursal.StructuredAddress.Province
+	}
+	if sucursal.NormalizedAddress != nil && sucursal.NormalizedAddress.Components != nil && sucursal.NormalizedAddress.Components.Province != "" {
+		return sucursal.NormalizedAddress.Components.Province
+	}
+	return ParseAddress(sucursal.Address).Province
+}
+
+// Locality returns the locality of Sucursal, looking at the same sources as Province.
+func (sucursal *Sucursal) Locality() string {
+	if sucursal.StructuredAddress != nil && sucursal.StructuredAddress.Locality != "" {
+		return sucursal.StructuredAddress.Locality
+	}
+	if sucursal.NormalizedAddress != nil && sucursal.NormalizedAddress.Components != nil && sucursal.NormalizedAddress.Components.Locality != "" {
+		return sucursal.NormalizedAddress.Components.Locality
+	}
+	return ParseAddress(sucursal.Address).Locality
+}
+
+// Serves reports whether the position falls inside the service area of Sucursal.
+// Sucursales without a service area do not serve any position.
+func (sucursal *Sucursal) Serves(position *Position) bool {
+	if sucursal.ServiceArea == nil {
+		return false
+	}
+	return sucursal.ServiceArea.Contains(sucursal.Position(), position)
 }
 
 type SucursalWithDistance struct {
 	Sucursal *Sucursal
 	Distance float64
+	// Set when sucursales are ranked along the road network.
+	Route *Route
 }
 
 type SucursalKey struct {
@@ -0,0 +1,133 @@
+package setup
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/NJRodriguez/shiny-waddle/api/controllers"
+	"github.com/NJRodriguez/shiny-waddle/api/importer"
+	"github.com/NJRodriguez/shiny-waddle/lib/demand"
+	"github.com/NJRodriguez/shiny-waddle/lib/roads"
+)
+
+// Options holds the optional features of the server, read from environment variables.
+type Options struct {
+	// Path to a CSV gazetteer used to geocode addresses. Geocoding is disabled when empty.
+	GazetteerFile string
+	// Minimum confidence accepted from the gazetteer.
+	GeocodingMinConfidence float64
+	// Farthest distance in km between coordinates and the address returned by reverse geocoding.
+	ReverseGeocodingMaxDistanceKm float64
+	// Either off, warn or reject. Checks the address of new sucursales against their coordinates.
+	AddressValidation string
+	// Farthest distance in km allowed between the address and the coordinates of new sucursales.
+	AddressMaxDistanceKm float64
+	// Number of map tiles cached and how long in seconds they are cached for.
+	TileCacheSize       int
+	TileCacheTTLSeconds float64
+	// Answers position queries from an in-memory spatial index instead of scanning the table.
+	SpatialIndex bool
+	// Seconds between reloads of the spatial index and age in seconds after which it is no longer used.
+	SpatialIndexRefreshSeconds float64
+	SpatialIndexMaxAgeSeconds  float64
+	// Number of table reads cached in memory, and how long in seconds they are cached for. Disabled when 0.
+	DocumentsCacheSize       int
+	DocumentsCacheTTLSeconds float64
+	// Applies the changes read from the DynamoDB stream of the table to the caches and the spatial index.
+	StreamSync bool
+	// JSON file where the stream position is saved. The stream is read from its latest records when empty.
+	StreamCheckpointFile string
+	// Seconds between polls of the stream.
+	StreamPollSeconds float64
+	// Rejects new sucursales that look like duplicates of existing ones unless the request sets force.
+	DuplicateDetection bool
+	// Closest distance in meters and highest address similarity, from 0 to 1, allowed between sucursales.
+	DuplicateMaxDistanceMeters float64
+	DuplicateAddressSimilarity float64
+	// DynamoDB table where closest sucursal searches are recorded. Demand analytics are disabled when empty.
+	DemandTableName string
+	// Size in degrees of the grid recorded positions are snapped to.
+	DemandPrecisionDegrees float64
+	// Number of searches waiting to be recorded before new ones are dropped.
+	DemandBufferSize int
+	// Seconds between writes of the recorded searches.
+	DemandFlushSeconds float64
+	// Days the recorded searches are kept for. Kept forever when 0.
+	DemandRetentionDays float64
+	// OpenStreetMap extract, in PBF or XML format, whose roads rank the closest sucursales. Disabled when empty.
+	RoadNetworkFile string
+	// Either driving or walking.
+	RoadNetworkProfile string
+	// Number of sucursales closest in a straight line that are routed to.
+	RoadNetworkCandidates int
+	// Farthest distance in km between a position and the road network for it to be routed.
+	RoadNetworkMaxSnapKm float64
+	// Largest percentage of the sucursales a sync through the API may delete.
+	SyncMaxDeletePercent float64
+}
+
+// OptionsFromEnv reads the server options from environment variables.
+func OptionsFromEnv() Options {
+	return Options{
+		GazetteerFile:                 os.Getenv("GAZETTEER_FILE"),
+		GeocodingMinConfidence:        floatFromEnv("GEOCODING_MIN_CONFIDENCE", 0),
+		ReverseGeocodingMaxDistanceKm: floatFromEnv("REVERSE_GEOCODING_MAX_DISTANCE_KM", 0),
+		AddressValidation:             stringFromEnv("ADDRESS_VALIDATION", controllers.AddressValidationOff),
+		AddressMaxDistanceKm:          floatFromEnv("ADDRESS_MAX_DISTANCE_KM", 0.5),
+		TileCacheSize:                 intFromEnv("TILE_CACHE_SIZE", 1024),
+		TileCacheTTLSeconds:           floatFromEnv("TILE_CACHE_TTL_SECONDS", 300),
+		SpatialIndex:                  boolFromEnv("SPATIAL_INDEX", false),
+		SpatialIndexRefreshSeconds:    floatFromEnv("SPATIAL_INDEX_REFRESH_SECONDS", 60),
+		SpatialIndexMaxAgeSeconds:     floatFromEnv("SPATIAL_INDEX_MAX_AGE_SECONDS", 300),
+		DocumentsCacheSize:            intFromEnv("DOCUMENTS_CACHE_SIZE", 0),
+		DocumentsCacheTTLSeconds:      floatFromEnv("DOCUMENTS_CACHE_TTL_SECONDS", 30),
+		StreamSync:                    boolFromEnv("STREAM_SYNC", false),
+		StreamCheckpointFile:          os.Getenv("STREAM_CHECKPOINT_FILE"),
+		StreamPollSeconds:             floatFromEnv("STREAM_POLL_SECONDS", 1),
+		DuplicateDetection:            boolFromEnv("DUPLICATE_DETECTION", false),
+		DuplicateMaxDistanceMeters:    floatFromEnv("DUPLICATE_MAX_DISTANCE_METERS", 25),
+		DuplicateAddressSimilarity:    floatFromEnv("DUPLICATE_ADDRESS_SIMILARITY", 0.8),
+		DemandTableName:               os.Getenv("DEMAND_TABLE_NAME"),
+		DemandPrecisionDegrees:        floatFromEnv("DEMAND_PRECISION_DEGREES", demand.DefaultPrecisionDegrees),
+		DemandBufferSize:              intFromEnv("DEMAND_BUFFER_SIZE", 10000),
+		DemandFlushSeconds:            floatFromEnv("DEMAND_FLUSH_SECONDS", 5),
+		DemandRetentionDays:           floatFromEnv("DEMAND_RETENTION_DAYS", 90),
+		RoadNetworkFile:               os.Getenv("ROAD_NETWORK_FILE"),
+		RoadNetworkProfile:            stringFromEnv("ROAD_NETWORK_PROFILE", roads.Driving.Name),
+		RoadNetworkCandidates:         intFromEnv("ROAD_NETWORK_CANDIDATES", controllers.DefaultRoadCandidates),
+		RoadNetworkMaxSnapKm:          floatFromEnv("ROAD_NETWORK_MAX_SNAP_KM", roads.DefaultMaxSnapDistanceKm),
+		SyncMaxDeletePercent:          floatFromEnv("SYNC_MAX_DELETE_PERCENT", importer.DefaultMaxDeletePercent),
+	}
+}
+
+func stringFromEnv(name string, defaultValue string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func floatFromEnv(name string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(name), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func intFromEnv(name string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func boolFromEnv(name string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
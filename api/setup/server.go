@@ -3,33 +3,178 @@ package setup
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/NJRodriguez/shiny-waddle/api/controllers"
 	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb"
+	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodbstreams"
+	"github.com/NJRodriguez/shiny-waddle/lib/demand"
+	"github.com/NJRodriguez/shiny-waddle/lib/geocoding"
+	"github.com/NJRodriguez/shiny-waddle/lib/roads"
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 )
 
 type Server struct {
-	Router *mux.Router
+	Router  *mux.Router
+	Options Options
 }
 
 func (server *Server) Initialize(tableName string, region string) error {
 	log.Println("Starting API Controller...")
-	client, err := dynamodb.New(tableName, region)
+	documentsClient, err := dynamodb.New(tableName, region)
 	if err != nil {
 		log.Fatalln("Error when trying to start DynamoDB Client.")
 		return err
 	}
-	apiController, err := controllers.NewAPIController(client)
+	var client dynamodb.DocumentsClient = documentsClient
+	var cachingClient *dynamodb.CachingClient
+	if server.Options.DocumentsCacheSize > 0 {
+		ttl := time.Duration(server.Options.DocumentsCacheTTLSeconds * float64(time.Second))
+		cachingClient = dynamodb.NewCachingClient(documentsClient, server.Options.DocumentsCacheSize, ttl)
+		client = cachingClient
+	}
+	controllerOptions, err := server.controllerOptions()
+	if err != nil {
+		log.Println("Error when trying to load API Controller options.")
+		return err
+	}
+	if cachingClient != nil {
+		// The spatial index records when it was loaded, so it reads the table rather than the cache.
+		controllerOptions = append(controllerOptions, controllers.WithIndexSource(documentsClient), controllers.WithDocumentsCache(cachingClient))
+	}
+	if server.Options.DemandTableName != "" {
+		demandOption, err := server.startDemandRecorder(region)
+		if err != nil {
+			log.Println("Error when trying to start the demand recorder.")
+			return err
+		}
+		controllerOptions = append(controllerOptions, demandOption)
+	}
+	apiController, err := controllers.NewAPIController(client, controllerOptions...)
 	if err != nil {
 		log.Fatal("Error when trying to start API Controller!")
 		return err
 	}
 	log.Println("Registering API Routes...")
 	apiController.RegisterRoutes(server.Router)
+	if server.Options.StreamSync {
+		if err := server.startStreamSync(apiController, cachingClient, tableName, region); err != nil {
+			log.Println("Error when trying to start the DynamoDB stream consumer.")
+			return err
+		}
+	}
+	return nil
+}
+
+// startStreamSync reads the stream of the table in the background, applying every change to the
+// caches and the spatial index of the controller and purging the documents cache, if any.
+func (server *Server) startStreamSync(apiController *controllers.APIController, cachingClient *dynamodb.CachingClient, tableName string, region string) error {
+	var checkpoints dynamodbstreams.Checkpoints = dynamodbstreams.NewMemoryCheckpoints()
+	if server.Options.StreamCheckpointFile != "" {
+		fileCheckpoints, err := dynamodbstreams.LoadFileCheckpoints(server.Options.StreamCheckpointFile)
+		if err != nil {
+			return err
+		}
+		checkpoints = fileCheckpoints
+	}
+	handler := apiController.ApplyStreamRecords
+	if cachingClient != nil {
+		handler = func(records []dynamodbstreams.Record) error {
+			cachingClient.Purge()
+			return apiController.ApplyStreamRecords(records)
+		}
+	}
+	consumer, err := dynamodbstreams.New(tableName, region, handler, checkpoints)
+	if err != nil {
+		return err
+	}
+	if server.Options.StreamPollSeconds > 0 {
+		consumer.PollInterval = time.Duration(server.Options.StreamPollSeconds * float64(time.Second))
+	}
+	log.Println("Reading DynamoDB stream...")
+	go consumer.Run(nil)
 	return nil
 }
 
+// startDemandRecorder writes the closest sucursal searches to the demand table in the background.
+func (server *Server) startDemandRecorder(region string) (controllers.Option, error) {
+	retention := time.Duration(server.Options.DemandRetentionDays * float64(24*time.Hour))
+	store, err := demand.NewDynamoDBStore(server.Options.DemandTableName, region, retention)
+	if err != nil {
+		return nil, err
+	}
+	recorder := demand.NewRecorder(store, server.Options.DemandBufferSize)
+	if server.Options.DemandPrecisionDegrees > 0 {
+		recorder.PrecisionDegrees = server.Options.DemandPrecisionDegrees
+	}
+	if server.Options.DemandFlushSeconds > 0 {
+		recorder.FlushInterval = time.Duration(server.Options.DemandFlushSeconds * float64(time.Second))
+	}
+	log.Printf("Recording demand to %s...", server.Options.DemandTableName)
+	go recorder.Run(nil)
+	return controllers.WithDemandAnalytics(recorder, store), nil
+}
+
+func (server *Server) controllerOptions() ([]controllers.Option, error) {
+	options := []controllers.Option{}
+	if server.Options.GazetteerFile != "" {
+		log.Printf("Loading gazetteer from %s...", server.Options.GazetteerFile)
+		gazetteer, err := geocoding.LoadGazetteer(server.Options.GazetteerFile)
+		if err != nil {
+			return nil, err
+		}
+		if server.Options.GeocodingMinConfidence > 0 {
+			gazetteer.MinConfidence = server.Options.GeocodingMinConfidence
+		}
+		if server.Options.ReverseGeocodingMaxDistanceKm > 0 {
+			gazetteer.MaxReverseDistanceKm = server.Options.ReverseGeocodingMaxDistanceKm
+		}
+		options = append(options, controllers.WithGeocoder(geocoding.Chain{gazetteer}), controllers.WithReverseGeocoder(gazetteer))
+	}
+	switch server.Options.AddressValidation {
+	case controllers.AddressValidationOff:
+	case controllers.AddressValidationWarn, controllers.AddressValidationReject:
+		if server.Options.GazetteerFile == "" {
+			return nil, errors.Errorf("address validation %s requires GAZETTEER_FILE to geocode the addresses", server.Options.AddressValidation)
+		}
+		options = append(options, controllers.WithAddressValidation(server.Options.AddressValidation, server.Options.AddressMaxDistanceKm))
+	default:
+		return nil, errors.Errorf("unknown address validation mode %s", server.Options.AddressValidation)
+	}
+	if server.Options.TileCacheSize > 0 {
+		ttl := time.Duration(server.Options.TileCacheTTLSeconds * float64(time.Second))
+		options = append(options, controllers.WithTileCache(server.Options.TileCacheSize, ttl))
+	}
+	if server.Options.SpatialIndex {
+		refreshInterval := time.Duration(server.Options.SpatialIndexRefreshSeconds * float64(time.Second))
+		maxAge := time.Duration(server.Options.SpatialIndexMaxAgeSeconds * float64(time.Second))
+		options = append(options, controllers.WithSpatialIndex(refreshInterval, maxAge))
+	}
+	if server.Options.DuplicateDetection {
+		options = append(options, controllers.WithDuplicateDetection(server.Options.DuplicateMaxDistanceMeters/1000, server.Options.DuplicateAddressSimilarity))
+	}
+	if server.Options.RoadNetworkFile != "" {
+		profile, err := roads.ProfileByName(server.Options.RoadNetworkProfile)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Loading %s road network from %s...", profile.Name, server.Options.RoadNetworkFile)
+		network, err := roads.Load(server.Options.RoadNetworkFile, profile)
+		if err != nil {
+			return nil, err
+		}
+		network.MaxSnapDistanceKm = server.Options.RoadNetworkMaxSnapKm
+		log.Printf("Loaded road network with %d nodes and %d edges.", network.Nodes(), network.Edges())
+		options = append(options, controllers.WithRoadNetwork(network, server.Options.RoadNetworkCandidates))
+	}
+	if server.Options.SyncMaxDeletePercent < 0 || server.Options.SyncMaxDeletePercent > 100 {
+		return nil, errors.Errorf("sync max delete percent must be between 0 and 100, got %g", server.Options.SyncMaxDeletePercent)
+	}
+	options = append(options, controllers.WithSyncMaxDeletePercent(server.Options.SyncMaxDeletePercent))
+	return options, nil
+}
+
 func (server *Server) Run(addr string) {
 	log.Println("Listening to port 80")
 	log.Fatal(http.ListenAndServe(addr, server.Router))
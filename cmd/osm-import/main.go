@@ -0,0 +1,92 @@
+// Command osm-import loads sucursales from an OpenStreetMap extract into the sucursales table.
+//
+// It selects the nodes and ways of the extract matching every -filter, maps their name, address
+// tags, coordinates and opening hours to sucursales and prints the changes against the table before
+// making them. Sucursales keep the same id on every import, so importing again updates them.
+//
+//	osm-import -file argentina.osm.pbf -filter amenity=bank -filter "operator=Banco de la Nación Argentina" -dry-run
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/NJRodriguez/shiny-waddle/api/importer"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb"
+	"github.com/NJRodriguez/shiny-waddle/lib/osm"
+)
+
+// filterFlags collects the repeated -filter flags.
+type filterFlags []importer.Condition
+
+func (filters *filterFlags) String() string {
+	values := []string{}
+	for _, condition := range *filters {
+		values = append(values, condition.Key+"="+strings.Join(condition.Values, "|"))
+	}
+	return strings.Join(values, " ")
+}
+
+func (filters *filterFlags) Set(value string) error {
+	condition, err := importer.ParseCondition(value)
+	if err != nil {
+		return err
+	}
+	*filters = append(*filters, condition)
+	return nil
+}
+
+func main() {
+	var filters filterFlags
+	file := flag.String("file", "", "OpenStreetMap extract in PBF (.pbf) or XML format")
+	table := flag.String("table", os.Getenv("TABLE_NAME"), "DynamoDB table of the sucursales")
+	region := flag.String("region", os.Getenv("AWS_REGION"), "AWS region of the table")
+	dryRun := flag.Bool("dry-run", false, "only print the changes")
+	deleteMissing := flag.Bool("delete", false, "delete the sucursales imported before that are no longer in the extract or no longer match the filters")
+	flag.Var(&filters, "filter", "tag filter as key=value, key=value1|value2 or key; repeat to require several")
+	flag.Parse()
+	if *file == "" || len(filters) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	filter := importer.Filter(filters)
+
+	log.Printf("Reading %s...", *file)
+	extract, err := osm.Load(*file, filter.Matches)
+	if err != nil {
+		log.Fatalf("Error when trying to read the extract: %s", err)
+	}
+	imported := importer.FromOSM(extract, filter)
+	log.Printf("Found %d sucursales matching %s.", len(imported), filters.String())
+
+	documentsClient, err := dynamodb.New(*table, *region)
+	if err != nil {
+		log.Fatalf("Error when trying to start DynamoDB Client: %s", err)
+	}
+	items, err := documentsClient.ListAll()
+	if err != nil {
+		log.Fatalf("Error when trying to list the sucursales: %s", err)
+	}
+	existing, err := models.ToSucursalArray(items)
+	if err != nil {
+		log.Fatalf("Error when trying to read the sucursales: %s", err)
+	}
+	plan, err := importer.PlanOSMImport(imported, existing, *deleteMissing)
+	if err != nil {
+		log.Fatalf("Error when trying to compare the sucursales: %s", err)
+	}
+	if err := plan.Write(os.Stdout); err != nil {
+		log.Fatalf("Error when trying to print the changes: %s", err)
+	}
+	if *dryRun {
+		return
+	}
+	applied, err := importer.Apply(documentsClient, plan)
+	if err != nil {
+		log.Fatalf("Error after making %d of %d changes: %s", applied, len(plan.Changes), err)
+	}
+	log.Printf("Made %d changes.", applied)
+}
@@ -0,0 +1,74 @@
+// Command sucursal-sync reconciles the sucursales table with a full snapshot exported from the
+// source of truth, such as the ERP.
+//
+// It reads the snapshot, a JSON array (.json), JSON lines (.jsonl) or CSV (.csv) file, and prints
+// the creates, updates and deletes that make the table hold exactly its sucursales before making
+// them. It refuses to make them when they delete more than -max-delete-percent of the table, which
+// usually means the snapshot is truncated, unless -force is set.
+//
+//	sucursal-sync -file erp-export.csv -dry-run
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/NJRodriguez/shiny-waddle/api/importer"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb"
+)
+
+func main() {
+	file := flag.String("file", "", "snapshot of every sucursal as a JSON array (.json), JSON lines (.jsonl) or CSV (.csv)")
+	table := flag.String("table", os.Getenv("TABLE_NAME"), "DynamoDB table of the sucursales")
+	region := flag.String("region", os.Getenv("AWS_REGION"), "AWS region of the table")
+	dryRun := flag.Bool("dry-run", false, "only print the changes")
+	maxDeletePercent := flag.Float64("max-delete-percent", importer.DefaultMaxDeletePercent, "largest percentage of the sucursales the sync may delete")
+	force := flag.Bool("force", false, "make the changes even if they delete more than -max-delete-percent of the sucursales")
+	flag.Parse()
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	snapshot, err := importer.LoadSnapshot(*file)
+	if err != nil {
+		log.Fatalf("Error when trying to read the snapshot: %s", err)
+	}
+	log.Printf("Read %d sucursales from %s.", len(snapshot), *file)
+
+	documentsClient, err := dynamodb.New(*table, *region)
+	if err != nil {
+		log.Fatalf("Error when trying to start DynamoDB Client: %s", err)
+	}
+	items, err := documentsClient.ListAll()
+	if err != nil {
+		log.Fatalf("Error when trying to list the sucursales: %s", err)
+	}
+	existing, err := models.ToSucursalArray(items)
+	if err != nil {
+		log.Fatalf("Error when trying to read the sucursales: %s", err)
+	}
+	plan, err := importer.PlanSync(snapshot, existing)
+	if err != nil {
+		log.Fatalf("Error when trying to compare the sucursales: %s", err)
+	}
+	if err := plan.Write(os.Stdout); err != nil {
+		log.Fatalf("Error when trying to print the changes: %s", err)
+	}
+	if err := plan.CheckLimits(len(existing), *maxDeletePercent); err != nil {
+		if !*force {
+			log.Fatalf("Aborting: %s. Check the snapshot or set -force.", err)
+		}
+		log.Printf("Continuing since -force is set: %s.", err)
+	}
+	if *dryRun {
+		return
+	}
+	applied, err := importer.Apply(documentsClient, plan)
+	if err != nil {
+		log.Fatalf("Error after making %d of %d changes: %s", applied, len(plan.Changes), err)
+	}
+	log.Printf("Made %d changes.", applied)
+}
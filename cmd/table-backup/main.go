@@ -0,0 +1,108 @@
+// Command table-backup dumps a DynamoDB table to a local backup file and restores such backups into
+// any table, such as a copy of production into development.
+//
+//	table-backup dump -table sucursal_table -dir backups -segments 4
+//	table-backup verify -file backups/sucursal_table-20261019T135700Z.jsonl.gz
+//	table-backup restore -file backups/sucursal_table-20261019T135700Z.jsonl.gz -table sucursal_table_dev
+//
+// An interrupted dump is finished with -resume and the path it printed, and an interrupted restore
+// by running it again with -resume.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb"
+	"github.com/NJRodriguez/shiny-waddle/lib/backup"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "dump":
+		dump(os.Args[2:])
+	case "restore":
+		restore(os.Args[2:])
+	case "verify":
+		verify(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: table-backup dump|restore|verify [flags]")
+	os.Exit(2)
+}
+
+func dump(args []string) {
+	flags := flag.NewFlagSet("dump", flag.ExitOnError)
+	table := flags.String("table", os.Getenv("TABLE_NAME"), "DynamoDB table to back up")
+	region := flags.String("region", os.Getenv("AWS_REGION"), "AWS region of the table")
+	dir := flags.String("dir", ".", "directory the backup is written to")
+	segments := flags.Int("segments", 1, "segments of the parallel scan, read concurrently")
+	resume := flags.String("resume", "", "path of an interrupted backup to finish")
+	_ = flags.Parse(args)
+	if *table == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	documentsClient, err := dynamodb.New(*table, *region)
+	if err != nil {
+		log.Fatalf("Error when trying to start DynamoDB Client: %s", err)
+	}
+	path, manifest, err := backup.Dump(documentsClient, *table, backup.DumpOptions{Dir: *dir, Segments: *segments, Resume: *resume})
+	if err != nil {
+		if path != "" {
+			log.Fatalf("Error when trying to back up %s: %s. Finish it with -resume %s.", *table, err, path)
+		}
+		log.Fatalf("Error when trying to back up %s: %s", *table, err)
+	}
+	log.Printf("Backed up %d items of %s to %s, SHA-256 %s.", manifest.Items, *table, path, manifest.SHA256)
+}
+
+func restore(args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	file := flags.String("file", "", "backup to restore")
+	table := flags.String("table", os.Getenv("TABLE_NAME"), "DynamoDB table to restore the backup into")
+	region := flags.String("region", os.Getenv("AWS_REGION"), "AWS region of the table")
+	resume := flags.Bool("resume", false, "continue an interrupted restore of the backup into the table")
+	_ = flags.Parse(args)
+	if *file == "" || *table == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	documentsClient, err := dynamodb.New(*table, *region)
+	if err != nil {
+		log.Fatalf("Error when trying to start DynamoDB Client: %s", err)
+	}
+	written, err := backup.Restore(documentsClient, *table, *file, backup.RestoreOptions{Resume: *resume})
+	if err != nil {
+		log.Fatalf("Error after writing %d items into %s: %s", written, *table, err)
+	}
+	log.Printf("Restored %d items of %s into %s.", written, *file, *table)
+}
+
+func verify(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	file := flags.String("file", "", "backup to verify")
+	_ = flags.Parse(args)
+	if *file == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	manifest, err := backup.Verify(*file)
+	if err != nil {
+		log.Fatalf("Error when verifying %s: %s", *file, err)
+	}
+	log.Printf("%s holds %d items of %s, backed up from %s to %s.", *file, manifest.Items, manifest.Table,
+		manifest.StartedAt.Format("2006-01-02 15:04:05 MST"), manifest.FinishedAt.Format("2006-01-02 15:04:05 MST"))
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/NJRodriguez/shiny-waddle/api/client"
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/requests"
+	"github.com/NJRodriguez/shiny-waddle/api/controllers/payloads/responses"
+	"github.com/NJRodriguez/shiny-waddle/api/importer"
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+// pageSize is how many sucursales are fetched at once when listing them.
+const pageSize = 500
+
+func runCreate(api *client.Client, args []string) error {
+	flags := newFlagSet("create")
+	file := flags.String("file", "", "JSON request body of POST /sucursal, or - to read it from the standard input")
+	id := flags.String("id", "", "id of the sucursal, a random UUID when omitted")
+	name := flags.String("name", "", "name of the sucursal")
+	address := flags.String("address", "", "single-line address of the sucursal")
+	latitude := flags.String("lat", "", "latitude in decimal degrees, geocoded from the address when omitted")
+	longitude := flags.String("lon", "", "longitude in decimal degrees")
+	position := flags.String("position", "", "position in any format the API accepts, instead of -lat and -lon")
+	capacity := flags.Int("capacity", -1, "most customers assigned to the sucursal at once")
+	openingHours := flags.String("opening-hours", "", "opening hours in the OpenStreetMap syntax, such as \"Mo-Fr 10:00-15:00\"")
+	force := flags.Bool("force", false, "create the sucursal even if it looks like a duplicate")
+	output := flags.String("o", outputTable, "output format: table or json")
+	_ = flags.Parse(args)
+	if err := checkOutput(*output, outputTable, outputJSON); err != nil {
+		return err
+	}
+
+	sucursal := &requests.PostSucursal{}
+	if *file != "" {
+		if err := decodeFile(*file, sucursal); err != nil {
+			return err
+		}
+	} else {
+		sucursal.ID = *id
+		sucursal.Name = *name
+		sucursal.Address = *address
+		sucursal.Position = *position
+		sucursal.OpeningHours = *openingHours
+		sucursal.Force = *force
+		if *capacity >= 0 {
+			sucursal.Capacity = capacity
+		}
+		if *latitude != "" || *longitude != "" {
+			var err error
+			if sucursal.Latitude, sucursal.Longitude, err = parseCoordinates(*latitude, *longitude); err != nil {
+				return err
+			}
+		}
+	}
+	if sucursal.ID == "" {
+		sucursal.ID = uuid.NewV4().String()
+	}
+	created, err := api.CreateSucursal(context.Background(), sucursal)
+	if err != nil {
+		return err
+	}
+	if *output == outputJSON {
+		return writeJSON(os.Stdout, created)
+	}
+	fmt.Printf("%s %s\n", created.Message, created.ID)
+	if created.Latitude != nil && created.Longitude != nil {
+		fmt.Printf("Coordinates: %v, %v\n", *created.Latitude, *created.Longitude)
+	}
+	for _, warning := range created.Warnings {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+	return nil
+}
+
+func runGet(api *client.Client, args []string) error {
+	flags := newFlagSet("get")
+	output := flags.String("o", outputTable, "output format: table, json or csv")
+	_ = flags.Parse(args)
+	if err := checkOutput(*output, outputTable, outputJSON, outputCSV); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	sucursal, err := api.GetSucursal(context.Background(), flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *output == outputJSON {
+		return writeJSON(os.Stdout, sucursal)
+	}
+	return writeSucursales(os.Stdout, *output, []models.Sucursal{*sucursal})
+}
+
+func runList(api *client.Client, args []string) error {
+	flags := newFlagSet("list")
+	province := flags.String("province", "", "only sucursales in the province, by code or name")
+	locality := flags.String("locality", "", "only sucursales in the locality")
+	position := flags.String("position", "", "center of -radius-km")
+	radiusKm := flags.Float64("radius-km", 0, "only sucursales within this many km of -position")
+	output := flags.String("o", outputTable, "output format: table, json or csv")
+	_ = flags.Parse(args)
+	if err := checkOutput(*output, outputTable, outputJSON, outputCSV); err != nil {
+		return err
+	}
+	sucursales, err := api.ListAllSucursales(context.Background(), &client.ListOptions{
+		Province: *province,
+		Locality: *locality,
+		Position: *position,
+		RadiusKm: *radiusKm,
+		Limit:    pageSize,
+	})
+	if err != nil {
+		return err
+	}
+	return writeSucursales(os.Stdout, *output, sucursales)
+}
+
+func runDelete(api *client.Client, args []string) error {
+	flags := newFlagSet("delete")
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	for _, id := range flags.Args() {
+		deleted, err := api.DeleteSucursal(context.Background(), id)
+		if err != nil {
+			return errors.Wrapf(err, "deleting %s", id)
+		}
+		fmt.Printf("%s %s\n", deleted.Message, deleted.ID)
+	}
+	return nil
+}
+
+func runNearest(api *client.Client, args []string) error {
+	flags := newFlagSet("nearest")
+	address := flags.String("address", "", "address to geocode instead of a latitude and longitude")
+	output := flags.String("o", outputTable, "output format: table, json or csv")
+	flagArgs, coordinates := splitCoordinates(args, "-address", "--address", "-o", "--o")
+	_ = flags.Parse(flagArgs)
+	if err := checkOutput(*output, outputTable, outputJSON, outputCSV); err != nil {
+		return err
+	}
+	coordinates = append(coordinates, flags.Args()...)
+	var closest *responses.ClosestSucursalResponse
+	var err error
+	switch {
+	case *address != "" && len(coordinates) == 0:
+		closest, err = api.GetNearestSucursal(context.Background(), *address)
+	case *address == "" && len(coordinates) == 2:
+		latitude, longitude, parseErr := parseCoordinates(coordinates[0], coordinates[1])
+		if parseErr != nil {
+			return parseErr
+		}
+		closest, err = api.GetClosestSucursal(context.Background(), *latitude, *longitude)
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		return err
+	}
+	distance := strconv.FormatFloat(closest.DistanceInKm, 'f', 3, 64)
+	switch *output {
+	case outputJSON:
+		return writeJSON(os.Stdout, closest)
+	case outputCSV:
+		rows := csv.NewWriter(os.Stdout)
+		_ = rows.Write(append(csvHeader, "distanceInKm"))
+		_ = rows.Write(append(sucursalColumns(&closest.Sucursal), closest.Sucursal.OpeningHours, distance))
+		rows.Flush()
+		return rows.Error()
+	}
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tNAME\tADDRESS\tDISTANCE_KM")
+	fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", closest.Sucursal.ID, closest.Sucursal.Name, closest.Sucursal.Address, distance)
+	return table.Flush()
+}
+
+// splitCoordinates takes the numbers out of the arguments, except for the values of the given flags,
+// since negative coordinates would otherwise be parsed as flags.
+func splitCoordinates(args []string, valueFlags ...string) ([]string, []string) {
+	flagArgs, coordinates := []string{}, []string{}
+	for i := 0; i < len(args); i++ {
+		if _, err := strconv.ParseFloat(args[i], 64); err == nil {
+			coordinates = append(coordinates, args[i])
+			continue
+		}
+		flagArgs = append(flagArgs, args[i])
+		for _, name := range valueFlags {
+			if args[i] == name && i+1 < len(args) {
+				i++
+				flagArgs = append(flagArgs, args[i])
+			}
+		}
+	}
+	return flagArgs, coordinates
+}
+
+func runImport(api *client.Client, args []string) error {
+	flags := newFlagSet("import")
+	file := flags.String("file", "", "sucursales to create as a JSON array (.json), JSON lines (.jsonl) or CSV (.csv), in the format of sucursal-sync snapshots")
+	force := flags.Bool("force", false, "create sucursales even if they look like duplicates")
+	_ = flags.Parse(args)
+	if *file == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	sucursales, err := importer.LoadSnapshot(*file)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, sucursal := range sucursales {
+		request := &requests.PostSucursal{
+			ID:                sucursal.ID,
+			Name:              sucursal.Name,
+			Address:           sucursal.Address,
+			StructuredAddress: sucursal.StructuredAddress,
+			Latitude:          &sucursal.Latitude,
+			Longitude:         &sucursal.Longitude,
+			Capacity:          sucursal.Capacity,
+			OpeningHours:      sucursal.OpeningHours,
+			Force:             *force,
+		}
+		if _, err := api.CreateSucursal(context.Background(), request); err != nil {
+			log.Printf("Error when trying to create sucursal %s: %s", sucursal.ID, err)
+			failed++
+			continue
+		}
+		fmt.Printf("Created sucursal %s\n", sucursal.ID)
+	}
+	if failed > 0 {
+		return errors.Errorf("%d of %d sucursales could not be created", failed, len(sucursales))
+	}
+	log.Printf("Created %d sucursales.", len(sucursales))
+	return nil
+}
+
+func runExport(api *client.Client, args []string) error {
+	flags := newFlagSet("export")
+	file := flags.String("file", "", "file to write, the standard output when omitted")
+	format := flags.String("format", "", "json, jsonl or csv; taken from the extension of -file when omitted, json otherwise")
+	_ = flags.Parse(args)
+	if *format == "" {
+		*format = outputJSON
+		if *file != "" {
+			*format = importer.FormatOf(*file)
+		}
+	}
+	if err := checkOutput(*format, outputJSON, outputJSONL, outputCSV); err != nil {
+		return err
+	}
+	sucursales, err := api.ListAllSucursales(context.Background(), &client.ListOptions{Limit: pageSize})
+	if err != nil {
+		return err
+	}
+	var writer io.Writer = os.Stdout
+	if *file != "" {
+		created, err := os.Create(*file)
+		if err != nil {
+			return errors.Wrap(err, "creating export")
+		}
+		defer created.Close()
+		writer = created
+	}
+	if err := writeSucursales(writer, *format, sucursales); err != nil {
+		return errors.Wrap(err, "writing export")
+	}
+	if *file != "" {
+		log.Printf("Exported %d sucursales to %s.", len(sucursales), *file)
+	}
+	return nil
+}
+
+// decodeFile decodes the JSON file at path, or the standard input when path is -.
+func decodeFile(path string, value interface{}) error {
+	var reader io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return errors.Wrap(err, "opening request")
+		}
+		defer file.Close()
+		reader = file
+	}
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	return errors.Wrap(decoder.Decode(value), "decoding request")
+}
+
+func parseCoordinates(latitude string, longitude string) (*float64, *float64, error) {
+	parsedLatitude, err := strconv.ParseFloat(latitude, 64)
+	if err != nil {
+		return nil, nil, errors.Errorf("latitude %q is not a number in decimal degrees", latitude)
+	}
+	parsedLongitude, err := strconv.ParseFloat(longitude, 64)
+	if err != nil {
+		return nil, nil, errors.Errorf("longitude %q is not a number in decimal degrees", longitude)
+	}
+	return &parsedLatitude, &parsedLongitude, nil
+}
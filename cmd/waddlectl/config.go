@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const defaultURL = "http://localhost"
+
+// config is the configuration file of waddlectl, holding a profile per API deployment:
+//
+//	{
+//	    "defaultProfile": "prod",
+//	    "profiles": {
+//	        "prod": {"url": "https://sucursales.example.com", "token": "..."},
+//	        "dev": {"url": "http://localhost:8080"}
+//	    }
+//	}
+type config struct {
+	DefaultProfile string             `json:"defaultProfile,omitempty"`
+	Profiles       map[string]profile `json:"profiles"`
+}
+
+// profile holds the base URL of an API deployment and the token sent as a bearer token, if the
+// deployment sits behind a gateway that requires one.
+type profile struct {
+	URL   string `json:"url"`
+	Token string `json:"token,omitempty"`
+}
+
+// configPath returns the path of the configuration file, $WADDLECTL_CONFIG or waddlectl/config.json
+// in the user configuration directory.
+func configPath() (string, error) {
+	if path := os.Getenv("WADDLECTL_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", errors.Wrap(err, "finding the configuration directory")
+	}
+	return filepath.Join(dir, "waddlectl", "config.json"), nil
+}
+
+// loadConfig reads the configuration file, which is optional.
+func loadConfig(path string) (*config, error) {
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &config{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading configuration")
+	}
+	loaded := &config{}
+	if err := json.Unmarshal(contents, loaded); err != nil {
+		return nil, errors.Wrapf(err, "parsing configuration %s", path)
+	}
+	return loaded, nil
+}
+
+// resolve returns the profile to use, named by name, $WADDLECTL_PROFILE or the default profile, in
+// that order, with the URL and token replaced by $WADDLECTL_URL and $WADDLECTL_TOKEN when set. The
+// URL defaults to the API running locally.
+func (loaded *config) resolve(name string) (profile, error) {
+	if name == "" {
+		name = os.Getenv("WADDLECTL_PROFILE")
+	}
+	if name == "" {
+		name = loaded.DefaultProfile
+	}
+	resolved := profile{}
+	if name != "" {
+		var ok bool
+		if resolved, ok = loaded.Profiles[name]; !ok {
+			return resolved, errors.Errorf("profile %q is not configured", name)
+		}
+	}
+	if url := os.Getenv("WADDLECTL_URL"); url != "" {
+		resolved.URL = url
+	}
+	if token := os.Getenv("WADDLECTL_TOKEN"); token != "" {
+		resolved.Token = token
+	}
+	if resolved.URL == "" {
+		resolved.URL = defaultURL
+	}
+	return resolved, nil
+}
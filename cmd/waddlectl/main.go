@@ -0,0 +1,115 @@
+// Command waddlectl manages sucursales through the HTTP API.
+//
+//	waddlectl [-profile name] [-url url] [-token token] <command> [flags] [arguments]
+//
+// The base URL and token come from the profile of the configuration file, see config, unless given
+// with -url and -token. Commands that print sucursales take -o table, json or csv.
+//
+//	waddlectl create -address "Florida 296, C1005 CABA" -name Centro -capacity 40
+//	waddlectl list -province C -o csv
+//	waddlectl nearest -address "Av. Corrientes 1200"
+//	waddlectl export -file sucursales.csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/NJRodriguez/shiny-waddle/api/client"
+)
+
+// command is a subcommand of waddlectl.
+type command struct {
+	usage   string
+	summary string
+	run     func(api *client.Client, args []string) error
+}
+
+// commands is filled in by init, since the commands look up their own usage.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"create":  {"create [-file sucursal.json | -address address ...] [-o format]", "create a sucursal", runCreate},
+		"get":     {"get [-o format] id", "print a sucursal", runGet},
+		"list":    {"list [-province code] [-locality name] [-position position -radius-km km] [-o format]", "print the sucursales", runList},
+		"delete":  {"delete id...", "delete sucursales", runDelete},
+		"nearest": {"nearest [-o format] (-address address | latitude longitude)", "print the closest sucursal to a position or address", runNearest},
+		"import":  {"import -file sucursales.csv [-force]", "create the sucursales of a JSON, JSON lines or CSV file", runImport},
+		"export":  {"export [-file sucursales.csv] [-format format]", "write every sucursal to a JSON, JSON lines or CSV file", runExport},
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	profileName := flag.String("profile", "", "profile of the configuration file to use")
+	baseURL := flag.String("url", "", "base URL of the API, overriding the profile")
+	token := flag.String("token", "", "bearer token sent to the API, overriding the profile")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	selected, ok := commands[flag.Arg(0)]
+	if !ok {
+		log.Printf("Unknown command %q.", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	path, err := configPath()
+	if err != nil {
+		log.Fatalf("Error when trying to find the configuration: %s", err)
+	}
+	loaded, err := loadConfig(path)
+	if err != nil {
+		log.Fatalf("Error when trying to load the configuration: %s", err)
+	}
+	resolved, err := loaded.resolve(*profileName)
+	if err != nil {
+		log.Fatalf("Error when trying to load the profile: %s", err)
+	}
+	if *baseURL != "" {
+		resolved.URL = *baseURL
+	}
+	if *token != "" {
+		resolved.Token = *token
+	}
+	api, err := client.New(resolved.URL, client.WithToken(resolved.Token))
+	if err != nil {
+		log.Fatalf("Error when trying to create the API client: %s", err)
+	}
+	if err := selected.run(api, flag.Args()[1:]); err != nil {
+		log.Fatalf("Error: %s", err)
+	}
+}
+
+func usage() {
+	output := flag.CommandLine.Output()
+	fmt.Fprintln(output, "Usage: waddlectl [-profile name] [-url url] [-token token] <command> [flags] [arguments]")
+	fmt.Fprintln(output, "\nCommands:")
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(output, "  %-8s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(output, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+// newFlagSet returns the flags of a command, printing its usage on errors.
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: waddlectl %s\n", commands[name].usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/NJRodriguez/shiny-waddle/api/models"
+	"github.com/pkg/errors"
+)
+
+// Output formats.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputJSONL = "jsonl"
+	outputCSV   = "csv"
+)
+
+// csvHeader names the columns of the CSV output, which sucursal-sync and the import command read back.
+var csvHeader = []string{"id", "name", "address", "latitude", "longitude", "capacity", "openingHours"}
+
+func checkOutput(format string, allowed ...string) error {
+	for _, name := range allowed {
+		if format == name {
+			return nil
+		}
+	}
+	return errors.Errorf("unknown output format %q", format)
+}
+
+// writeJSON writes the value as indented JSON.
+func writeJSON(writer io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(value)
+}
+
+// writeSucursales writes the sucursales in the output format.
+func writeSucursales(writer io.Writer, format string, sucursales []models.Sucursal) error {
+	switch format {
+	case outputJSON:
+		return writeJSON(writer, sucursales)
+	case outputJSONL:
+		encoder := json.NewEncoder(writer)
+		for i := range sucursales {
+			if err := encoder.Encode(&sucursales[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputCSV:
+		rows := csv.NewWriter(writer)
+		if err := rows.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, sucursal := range sucursales {
+			if err := rows.Write(append(sucursalColumns(&sucursal), sucursal.OpeningHours)); err != nil {
+				return err
+			}
+		}
+		rows.Flush()
+		return rows.Error()
+	}
+	table := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tNAME\tADDRESS\tLATITUDE\tLONGITUDE\tCAPACITY")
+	for _, sucursal := range sucursales {
+		columns := sucursalColumns(&sucursal)
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", columns[0], columns[1], columns[2], columns[3], columns[4], columns[5])
+	}
+	return table.Flush()
+}
+
+// sucursalColumns returns the id, name, address, coordinates and capacity of the sucursal.
+func sucursalColumns(sucursal *models.Sucursal) []string {
+	capacity := ""
+	if sucursal.Capacity != nil {
+		capacity = strconv.Itoa(*sucursal.Capacity)
+	}
+	return []string{
+		sucursal.ID,
+		sucursal.Name,
+		sucursal.Address,
+		strconv.FormatFloat(sucursal.Latitude, 'f', -1, 64),
+		strconv.FormatFloat(sucursal.Longitude, 'f', -1, 64),
+		capacity,
+	}
+}
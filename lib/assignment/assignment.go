@@ -0,0 +1,155 @@
+package assignment
+
+import (
+	"sort"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+)
+
+// CandidatesPerCustomer is the number of closest facilities each customer is first offered. Farther
+// facilities are only offered to the customers that don't fit in them, see Assign.
+const CandidatesPerCustomer = 20
+
+// Unlimited is the capacity of facilities that can take any number of customers. Any negative
+// capacity is unlimited.
+const Unlimited = -1
+
+// Customer is someone to be assigned to a facility.
+type Customer struct {
+	ID        string
+	Latitude  float64
+	Longitude float64
+}
+
+// Facility is where customers are assigned, up to its capacity.
+type Facility struct {
+	ID        string
+	Latitude  float64
+	Longitude float64
+	// Most customers assigned to the facility, or Unlimited.
+	Capacity int
+}
+
+// Assignment is a customer assigned to a facility.
+type Assignment struct {
+	CustomerID string
+	FacilityID string
+	DistanceKm float64
+}
+
+// Result holds the assigned customers, in the order they were given, and the ids of those that could
+// not be assigned.
+type Result struct {
+	Assignments     []Assignment
+	Unassigned      []string
+	TotalDistanceKm float64
+}
+
+// Assign assigns as many customers as possible to facilities without going over their capacity and,
+// among those assignments, looks for the one with the lowest total distance. If maxDistanceKm is
+// positive, customers are only assigned to facilities at most that far. The problem is solved as a
+// min-cost flow from the customers to the facilities they are offered.
+//
+// To keep the problem small for large catalogues, customers are first offered their
+// CandidatesPerCustomer closest facilities only. Customers left unassigned are then offered every
+// facility and the problem is solved again. If some are still left, every customer is offered every
+// facility, since those holding the facilities they can reach may have room farther away. So the
+// most customers possible are always assigned, but the total distance is only the lowest among the
+// facilities offered, which misses the lowest overall when a customer would be better off farther
+// than its CandidatesPerCustomer closest facilities.
+func Assign(customers []Customer, facilities []Facility, maxDistanceKm float64) Result {
+	everyFacility := make([]bool, len(customers))
+	for {
+		result, unassigned := solve(customers, facilities, maxDistanceKm, everyFacility)
+		if len(unassigned) == 0 {
+			return result
+		}
+		offered := false
+		for _, i := range unassigned {
+			if !everyFacility[i] {
+				everyFacility[i] = true
+				offered = true
+			}
+		}
+		if !offered {
+			for i := range everyFacility {
+				if !everyFacility[i] {
+					everyFacility[i] = true
+					offered = true
+				}
+			}
+		}
+		if !offered {
+			return result
+		}
+	}
+}
+
+// solve assigns the customers, offering every facility to those with everyFacility set, and returns
+// the result along with the positions of the unassigned customers.
+func solve(customers []Customer, facilities []Facility, maxDistanceKm float64, everyFacility []bool) (Result, []int) {
+	// Nodes are the source, the customers, the facilities and the sink, in that order.
+	source := 0
+	firstFacility := 1 + len(customers)
+	sink := firstFacility + len(facilities)
+	network := newFlowNetwork(sink + 1)
+	type candidate struct {
+		facility int
+		distance float64
+		edge     int
+	}
+	candidates := make([][]candidate, len(customers))
+	for i, customer := range customers {
+		network.addEdge(source, 1+i, 1, 0)
+		all := make([]candidate, 0, len(facilities))
+		for j, facility := range facilities {
+			distance := geo.Distance(customer.Latitude, customer.Longitude, facility.Latitude, facility.Longitude)
+			if maxDistanceKm > 0 && distance > maxDistanceKm {
+				continue
+			}
+			all = append(all, candidate{facility: j, distance: distance})
+		}
+		sort.SliceStable(all, func(a, b int) bool {
+			return all[a].distance < all[b].distance
+		})
+		if len(all) > CandidatesPerCustomer && !everyFacility[i] {
+			all = all[:CandidatesPerCustomer]
+		}
+		for c := range all {
+			all[c].edge = network.addEdge(1+i, firstFacility+all[c].facility, 1, all[c].distance)
+		}
+		candidates[i] = all
+	}
+	for j, facility := range facilities {
+		capacity := facility.Capacity
+		if capacity < 0 || capacity > len(customers) {
+			capacity = len(customers)
+		}
+		network.addEdge(firstFacility+j, sink, capacity, 0)
+	}
+	network.minCostMaxFlow(source, sink)
+
+	result := Result{Assignments: []Assignment{}, Unassigned: []string{}}
+	unassigned := []int{}
+	for i, customer := range customers {
+		assigned := false
+		for _, option := range candidates[i] {
+			// The edge is saturated when the customer was sent through it.
+			if network.edges[1+i][option.edge].capacity == 0 {
+				result.Assignments = append(result.Assignments, Assignment{
+					CustomerID: customer.ID,
+					FacilityID: facilities[option.facility].ID,
+					DistanceKm: option.distance,
+				})
+				result.TotalDistanceKm += option.distance
+				assigned = true
+				break
+			}
+		}
+		if !assigned {
+			result.Unassigned = append(result.Unassigned, customer.ID)
+			unassigned = append(unassigned, i)
+		}
+	}
+	return result, unassigned
+}
@@ -0,0 +1,143 @@
+package assignment
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+	"github.com/stretchr/testify/require"
+)
+
+// Longitude degrees per kilometer at the equator.
+var degreesPerKm = 1 / geo.Distance(0, 0, 0, 1)
+
+func TestAssignMinimizesTotalDistanceWithinCapacity(t *testing.T) {
+	facilities := []Facility{
+		{ID: "a", Longitude: 0, Capacity: 1},
+		{ID: "b", Longitude: 10 * degreesPerKm, Capacity: Unlimited},
+	}
+	// Assigning the first customer to its closest facility, as a greedy assignment would, sends the
+	// second one 10 km away.
+	customers := []Customer{
+		{ID: "near a", Longitude: 4 * degreesPerKm},
+		{ID: "at a", Longitude: 0},
+	}
+	result := Assign(customers, facilities, 0)
+	require.Equal(t, []string{}, result.Unassigned)
+	require.Len(t, result.Assignments, 2)
+	require.Equal(t, "near a", result.Assignments[0].CustomerID)
+	require.Equal(t, "b", result.Assignments[0].FacilityID)
+	require.Equal(t, "at a", result.Assignments[1].CustomerID)
+	require.Equal(t, "a", result.Assignments[1].FacilityID)
+	require.InDelta(t, 6, result.TotalDistanceKm, 0.01)
+}
+
+func TestAssignReportsCustomersThatDontFit(t *testing.T) {
+	facilities := []Facility{
+		{ID: "a", Longitude: 0, Capacity: 1},
+		{ID: "far", Longitude: 100 * degreesPerKm, Capacity: Unlimited},
+		{ID: "closed", Longitude: 1 * degreesPerKm, Capacity: 0},
+	}
+	customers := []Customer{
+		{ID: "first", Longitude: 2 * degreesPerKm},
+		{ID: "second", Longitude: 0.5 * degreesPerKm},
+	}
+	result := Assign(customers, facilities, 50)
+	require.Equal(t, []string{"first"}, result.Unassigned)
+	require.Len(t, result.Assignments, 1)
+	require.Equal(t, Assignment{CustomerID: "second", FacilityID: "a", DistanceKm: result.Assignments[0].DistanceKm}, result.Assignments[0])
+}
+
+func TestAssignOffersFartherFacilitiesToCustomersThatDontFit(t *testing.T) {
+	facilities := []Facility{{ID: "far", Longitude: 50 * degreesPerKm, Capacity: Unlimited}}
+	for i := 0; i < CandidatesPerCustomer; i++ {
+		facilities = append(facilities, Facility{ID: string(rune('a' + i)), Longitude: float64(i) * degreesPerKm, Capacity: 1})
+	}
+	customers := make([]Customer, CandidatesPerCustomer+1)
+	for i := range customers {
+		customers[i] = Customer{ID: string(rune('A' + i))}
+	}
+	result := Assign(customers, facilities, 0)
+	require.Equal(t, []string{}, result.Unassigned)
+	require.Len(t, result.Assignments, len(customers))
+	far := 0
+	for _, assignment := range result.Assignments {
+		if assignment.FacilityID == "far" {
+			far++
+		}
+	}
+	require.Equal(t, 1, far)
+}
+
+func TestAssignOffersFartherFacilitiesToCustomersHoldingTheOnlyReachableOne(t *testing.T) {
+	// "near" is the only facility "B" can reach, and the closest to "A", whose other closest
+	// facilities are full.
+	facilities := []Facility{{ID: "near", Longitude: 1 * degreesPerKm, Capacity: 1}}
+	for i := 1; i < CandidatesPerCustomer; i++ {
+		facilities = append(facilities, Facility{ID: string(rune('a' + i)), Longitude: -4.5 * degreesPerKm, Capacity: 0})
+	}
+	facilities = append(facilities, Facility{ID: "far", Longitude: -5 * degreesPerKm, Capacity: 1})
+	customers := []Customer{{ID: "A"}, {ID: "B", Longitude: 3 * degreesPerKm}}
+	result := Assign(customers, facilities, 7)
+	require.Equal(t, []string{}, result.Unassigned)
+	require.Equal(t, "far", result.Assignments[0].FacilityID)
+	require.Equal(t, "near", result.Assignments[1].FacilityID)
+}
+
+func TestAssignMatchesBruteForce(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for round := 0; round < 50; round++ {
+		customers := make([]Customer, 6)
+		for i := range customers {
+			customers[i] = Customer{ID: string(rune('a' + i)), Latitude: random.Float64(), Longitude: random.Float64()}
+		}
+		facilities := make([]Facility, 3)
+		for i := range facilities {
+			facilities[i] = Facility{ID: string(rune('x' + i)), Latitude: random.Float64(), Longitude: random.Float64(), Capacity: random.Intn(4) - 1}
+		}
+		maxDistanceKm := 80.0
+		result := Assign(customers, facilities, maxDistanceKm)
+
+		bestAssigned, bestDistance := bruteForce(customers, facilities, maxDistanceKm)
+		require.Equal(t, bestAssigned, len(result.Assignments), "round %d", round)
+		require.InDelta(t, bestDistance, result.TotalDistanceKm, 1e-6, "round %d", round)
+		loads := map[string]int{}
+		for _, assigned := range result.Assignments {
+			loads[assigned.FacilityID]++
+		}
+		for _, facility := range facilities {
+			if facility.Capacity >= 0 {
+				require.LessOrEqual(t, loads[facility.ID], facility.Capacity)
+			}
+		}
+	}
+}
+
+// bruteForce tries every assignment, returning the most customers that can be assigned and the lowest
+// total distance of doing so.
+func bruteForce(customers []Customer, facilities []Facility, maxDistanceKm float64) (int, float64) {
+	loads := make([]int, len(facilities))
+	bestAssigned, bestDistance := -1, math.Inf(1)
+	var search func(customer int, assigned int, total float64)
+	search = func(customer int, assigned int, total float64) {
+		if customer == len(customers) {
+			if assigned > bestAssigned || (assigned == bestAssigned && total < bestDistance) {
+				bestAssigned, bestDistance = assigned, total
+			}
+			return
+		}
+		search(customer+1, assigned, total)
+		for j, facility := range facilities {
+			distance := geo.Distance(customers[customer].Latitude, customers[customer].Longitude, facility.Latitude, facility.Longitude)
+			if distance > maxDistanceKm || (facility.Capacity >= 0 && loads[j] >= facility.Capacity) {
+				continue
+			}
+			loads[j]++
+			search(customer+1, assigned+1, total+distance)
+			loads[j]--
+		}
+	}
+	search(0, 0, 0)
+	return bestAssigned, bestDistance
+}
@@ -0,0 +1,107 @@
+package assignment
+
+import (
+	"container/heap"
+	"math"
+)
+
+type edge struct {
+	to       int
+	capacity int
+	cost     float64
+	// Index of the reverse edge in the adjacency list of to.
+	reverse int
+}
+
+// flowNetwork solves min-cost max-flow problems with successive shortest paths, using Dijkstra with
+// node potentials since every edge of the problems solved here has a non-negative cost.
+type flowNetwork struct {
+	edges [][]edge
+}
+
+func newFlowNetwork(nodes int) *flowNetwork {
+	return &flowNetwork{edges: make([][]edge, nodes)}
+}
+
+// addEdge adds an edge along with its residual reverse edge and returns its index in the adjacency
+// list of from.
+func (network *flowNetwork) addEdge(from int, to int, capacity int, cost float64) int {
+	network.edges[from] = append(network.edges[from], edge{to: to, capacity: capacity, cost: cost, reverse: len(network.edges[to])})
+	network.edges[to] = append(network.edges[to], edge{to: from, capacity: 0, cost: -cost, reverse: len(network.edges[from]) - 1})
+	return len(network.edges[from]) - 1
+}
+
+// minCostMaxFlow sends as much flow as possible from source to sink at the lowest cost. The
+// augmenting paths are found one unit at a time, enough for the unit capacities of the customers.
+func (network *flowNetwork) minCostMaxFlow(source int, sink int) int {
+	nodes := len(network.edges)
+	potential := make([]float64, nodes)
+	distance := make([]float64, nodes)
+	previousNode := make([]int, nodes)
+	previousEdge := make([]int, nodes)
+	flow := 0
+	for {
+		for i := range distance {
+			distance[i] = math.Inf(1)
+		}
+		distance[source] = 0
+		queue := &priorityQueue{{node: source}}
+		for queue.Len() > 0 {
+			current := heap.Pop(queue).(queueItem)
+			if current.distance > distance[current.node] {
+				continue
+			}
+			for i, next := range network.edges[current.node] {
+				if next.capacity == 0 {
+					continue
+				}
+				reduced := distance[current.node] + next.cost + potential[current.node] - potential[next.to]
+				// Rounding can make reduced costs slightly negative, which doesn't change the result.
+				if reduced < distance[next.to]-1e-12 {
+					distance[next.to] = reduced
+					previousNode[next.to] = current.node
+					previousEdge[next.to] = i
+					heap.Push(queue, queueItem{node: next.to, distance: reduced})
+				}
+			}
+		}
+		if math.IsInf(distance[sink], 1) {
+			return flow
+		}
+		for i := range potential {
+			if !math.IsInf(distance[i], 1) {
+				potential[i] += distance[i]
+			}
+		}
+		amount := math.MaxInt32
+		for node := sink; node != source; node = previousNode[node] {
+			if capacity := network.edges[previousNode[node]][previousEdge[node]].capacity; capacity < amount {
+				amount = capacity
+			}
+		}
+		for node := sink; node != source; node = previousNode[node] {
+			current := &network.edges[previousNode[node]][previousEdge[node]]
+			current.capacity -= amount
+			network.edges[node][current.reverse].capacity += amount
+		}
+		flow += amount
+	}
+}
+
+type queueItem struct {
+	node     int
+	distance float64
+}
+
+type priorityQueue []queueItem
+
+func (queue priorityQueue) Len() int            { return len(queue) }
+func (queue priorityQueue) Less(i, j int) bool  { return queue[i].distance < queue[j].distance }
+func (queue priorityQueue) Swap(i, j int)       { queue[i], queue[j] = queue[j], queue[i] }
+func (queue *priorityQueue) Push(x interface{}) { *queue = append(*queue, x.(queueItem)) }
+func (queue *priorityQueue) Pop() interface{} {
+	old := *queue
+	item := old[len(old)-1]
+	*queue = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,61 @@
+package dynamodb
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxBatchWriteItems is the most items DynamoDB accepts in a single batch write.
+	MaxBatchWriteItems = 25
+	// Times a batch write is retried when DynamoDB leaves items unprocessed.
+	maxBatchWriteRetries = 8
+)
+
+// BatchPutFunc writes a batch of items and returns those DynamoDB left unprocessed, as BatchPut does.
+type BatchPutFunc func(items []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error)
+
+// TableBatchPut returns a BatchPutFunc that writes the items into the table with client.
+func TableBatchPut(client dynamodbiface.DynamoDBAPI, table string) BatchPutFunc {
+	return func(items []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error) {
+		requests := make([]*dynamodb.WriteRequest, len(items))
+		for i, item := range items {
+			requests[i] = &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}}
+		}
+		result, err := client.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]*dynamodb.WriteRequest{table: requests},
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "writing a batch of %d items", len(items))
+		}
+		unprocessed := []map[string]*dynamodb.AttributeValue{}
+		for _, request := range result.UnprocessedItems[table] {
+			if request.PutRequest != nil {
+				unprocessed = append(unprocessed, request.PutRequest.Item)
+			}
+		}
+		return unprocessed, nil
+	}
+}
+
+// WriteBatch writes up to MaxBatchWriteItems items with put, retrying those DynamoDB leaves
+// unprocessed. It waits backoff before the first retry, twice as long before the next one and so on.
+func WriteBatch(put BatchPutFunc, items []map[string]*dynamodb.AttributeValue, backoff time.Duration) error {
+	for retries := 0; ; retries++ {
+		unprocessed, err := put(items)
+		if err != nil {
+			return err
+		}
+		if len(unprocessed) == 0 {
+			return nil
+		}
+		if retries == maxBatchWriteRetries {
+			return errors.Errorf("DynamoDB left %d items unprocessed after %d retries", len(unprocessed), retries)
+		}
+		time.Sleep(backoff << uint(retries))
+		items = unprocessed
+	}
+}
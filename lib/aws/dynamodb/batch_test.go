@@ -0,0 +1,46 @@
+package dynamodb
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBatchRetriesUnprocessedItems(t *testing.T) {
+	items := []map[string]*dynamodb.AttributeValue{
+		{"id": {S: aws.String("a")}},
+		{"id": {S: aws.String("b")}},
+		{"id": {S: aws.String("c")}},
+	}
+	written := []string{}
+	put := func(batch []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error) {
+		// Every write leaves its last item unprocessed, except for the last item.
+		if len(batch) > 1 {
+			batch, unprocessed := batch[:len(batch)-1], batch[len(batch)-1:]
+			for _, item := range batch {
+				written = append(written, *item["id"].S)
+			}
+			return unprocessed, nil
+		}
+		written = append(written, *batch[0]["id"].S)
+		return nil, nil
+	}
+	require.NoError(t, WriteBatch(put, items, 0))
+	require.Equal(t, []string{"a", "b", "c"}, written)
+
+	writes := 0
+	unprocessed := func(batch []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error) {
+		writes++
+		return batch, nil
+	}
+	require.EqualError(t, WriteBatch(unprocessed, items, 0), "DynamoDB left 3 items unprocessed after 8 retries")
+	require.Equal(t, maxBatchWriteRetries+1, writes)
+
+	failing := func(batch []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error) {
+		return nil, errors.New("throttled")
+	}
+	require.EqualError(t, WriteBatch(failing, items, 0), "throttled")
+}
@@ -0,0 +1,129 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/cache"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pkg/errors"
+)
+
+const listAllKey = "list-all"
+
+// CacheStats holds the counters of a CachingClient.
+type CacheStats struct {
+	cache.Stats
+	// Reads answered by waiting for an identical read already in progress.
+	Deduplicated uint64 `json:"deduplicated"`
+}
+
+// CachingClient is a DocumentsClient that caches the results of Get and ListAll of another client.
+// Concurrent identical reads are sent to the wrapped client once, and every write drops the whole
+// cache. Reads of List are not cached.
+type CachingClient struct {
+	client DocumentsClient
+	lru    *cache.LRU
+	group  cache.Group
+
+	mutex sync.Mutex
+	// Incremented on every invalidation, so that reads started before it are neither cached nor shared.
+	generation   uint64
+	deduplicated uint64
+}
+
+// NewCachingClient wraps the client caching up to size results for ttl.
+func NewCachingClient(client DocumentsClient, size int, ttl time.Duration) *CachingClient {
+	return &CachingClient{client: client, lru: cache.NewLRU(size, ttl)}
+}
+
+func (instance *CachingClient) Get(key interface{}) (*dynamodb.GetItemOutput, error) {
+	marshaledKey, err := json.Marshal(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling key to cache")
+	}
+	value, err := instance.read("get/"+string(marshaledKey), func() (interface{}, error) {
+		return instance.client.Get(key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*dynamodb.GetItemOutput), nil
+}
+
+func (instance *CachingClient) ListAll() ([]map[string]*dynamodb.AttributeValue, error) {
+	value, err := instance.read(listAllKey, func() (interface{}, error) {
+		return instance.client.ListAll()
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Copy the snapshot so that callers appending to it don't change the cached one.
+	items := value.([]map[string]*dynamodb.AttributeValue)
+	return append([]map[string]*dynamodb.AttributeValue{}, items...), nil
+}
+
+func (instance *CachingClient) List(exclusiveStartKey map[string]*dynamodb.AttributeValue, limit int64) (*dynamodb.ScanOutput, error) {
+	return instance.client.List(exclusiveStartKey, limit)
+}
+
+func (instance *CachingClient) Create(item interface{}) (*dynamodb.PutItemOutput, error) {
+	defer instance.Purge()
+	return instance.client.Create(item)
+}
+
+func (instance *CachingClient) Put(item interface{}) (*dynamodb.PutItemOutput, error) {
+	defer instance.Purge()
+	return instance.client.Put(item)
+}
+
+func (instance *CachingClient) Delete(key interface{}) (*dynamodb.DeleteItemOutput, error) {
+	defer instance.Purge()
+	return instance.client.Delete(key)
+}
+
+// Purge drops every cached result. Reads in progress are not cached once they finish.
+func (instance *CachingClient) Purge() {
+	instance.mutex.Lock()
+	instance.generation++
+	instance.mutex.Unlock()
+	instance.lru.Purge()
+}
+
+// Stats returns the hit, miss and de-duplication counters of the cache.
+func (instance *CachingClient) Stats() CacheStats {
+	instance.mutex.Lock()
+	defer instance.mutex.Unlock()
+	return CacheStats{Stats: instance.lru.Stats(), Deduplicated: instance.deduplicated}
+}
+
+// read returns the cached result for the key or loads it, sharing the load with concurrent reads of
+// the same key. Errors are not cached.
+func (instance *CachingClient) read(key string, load func() (interface{}, error)) (interface{}, error) {
+	if value, ok := instance.lru.Get(key); ok {
+		return value, nil
+	}
+	instance.mutex.Lock()
+	generation := instance.generation
+	instance.mutex.Unlock()
+	value, err, shared := instance.group.Do(fmt.Sprintf("%d/%s", generation, key), func() (interface{}, error) {
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+		instance.mutex.Lock()
+		defer instance.mutex.Unlock()
+		if instance.generation == generation {
+			instance.lru.Set(key, value)
+		}
+		return value, nil
+	})
+	if shared {
+		instance.mutex.Lock()
+		instance.deduplicated++
+		instance.mutex.Unlock()
+	}
+	return value, err
+}
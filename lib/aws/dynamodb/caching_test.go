@@ -0,0 +1,108 @@
+package dynamodb
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type testKey struct {
+	ID string `json:"id"`
+}
+
+func TestCachingClientCachesReadsUntilWrite(t *testing.T) {
+	documents := &mocks.DocumentsClient{}
+	item := map[string]*dynamodb.AttributeValue{"id": {S: aws.String("a")}}
+	documents.On("Get", testKey{"a"}).Return(&dynamodb.GetItemOutput{Item: item}, nil).Twice()
+	documents.On("ListAll").Return([]map[string]*dynamodb.AttributeValue{item}, nil).Twice()
+	documents.On("Create", item).Return(&dynamodb.PutItemOutput{}, nil).Once()
+	client := NewCachingClient(documents, 10, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		result, err := client.Get(testKey{"a"})
+		require.NoError(t, err)
+		require.Equal(t, item, result.Item)
+		items, err := client.ListAll()
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+	}
+	require.Equal(t, CacheStats{}.Deduplicated, client.Stats().Deduplicated)
+	require.Equal(t, uint64(2), client.Stats().Hits)
+	require.Equal(t, uint64(2), client.Stats().Misses)
+
+	_, err := client.Create(item)
+	require.NoError(t, err)
+	require.Equal(t, 0, client.Stats().Entries)
+	_, err = client.Get(testKey{"a"})
+	require.NoError(t, err)
+	items, err := client.ListAll()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	documents.AssertExpectations(t)
+}
+
+func TestCachingClientPurgesOnPutAndDelete(t *testing.T) {
+	documents := &mocks.DocumentsClient{}
+	item := map[string]*dynamodb.AttributeValue{"id": {S: aws.String("a")}}
+	documents.On("ListAll").Return([]map[string]*dynamodb.AttributeValue{item}, nil).Times(3)
+	documents.On("Put", item).Return(&dynamodb.PutItemOutput{}, nil).Once()
+	documents.On("Delete", testKey{"a"}).Return(&dynamodb.DeleteItemOutput{}, nil).Once()
+	client := NewCachingClient(documents, 10, time.Minute)
+
+	_, err := client.ListAll()
+	require.NoError(t, err)
+	_, err = client.Put(item)
+	require.NoError(t, err)
+	_, err = client.ListAll()
+	require.NoError(t, err)
+	_, err = client.Delete(testKey{"a"})
+	require.NoError(t, err)
+	_, err = client.ListAll()
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), client.Stats().Hits)
+	documents.AssertExpectations(t)
+}
+
+func TestCachingClientDoesNotCacheErrors(t *testing.T) {
+	documents := &mocks.DocumentsClient{}
+	documents.On("ListAll").Return(nil, errors.New("scan failure")).Once()
+	documents.On("ListAll").Return([]map[string]*dynamodb.AttributeValue{}, nil).Once()
+	client := NewCachingClient(documents, 10, time.Minute)
+
+	_, err := client.ListAll()
+	require.Error(t, err)
+	items, err := client.ListAll()
+	require.NoError(t, err)
+	require.Empty(t, items)
+	documents.AssertExpectations(t)
+}
+
+func TestCachingClientDeduplicatesConcurrentReads(t *testing.T) {
+	documents := &mocks.DocumentsClient{}
+	release := make(chan time.Time)
+	documents.On("ListAll").Return([]map[string]*dynamodb.AttributeValue{}, nil).WaitUntil(release).Once()
+	client := NewCachingClient(documents, 10, time.Minute)
+
+	var wait sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			_, errs[i] = client.ListAll()
+		}(i)
+	}
+	// Give every reader time to join the first one before it completes.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wait.Wait()
+	require.Equal(t, make([]error, 5), errs)
+	documents.AssertExpectations(t)
+	require.Equal(t, uint64(4), client.Stats().Deduplicated)
+}
@@ -15,6 +15,8 @@ import (
 type DocumentsClient interface {
 	Get(key interface{}) (*dynamodb.GetItemOutput, error)
 	Create(item interface{}) (*dynamodb.PutItemOutput, error)
+	Put(item interface{}) (*dynamodb.PutItemOutput, error)
+	Delete(key interface{}) (*dynamodb.DeleteItemOutput, error)
 	List(exclusiveStartKey map[string]*dynamodb.AttributeValue, limit int64) (*dynamodb.ScanOutput, error)
 	ListAll() ([]map[string]*dynamodb.AttributeValue, error)
 }
@@ -71,6 +73,42 @@ func (instance *documents) Create(document interface{}) (*dynamodb.PutItemOutput
 	return result, err
 }
 
+// Put creates the document or replaces the one with the same key.
+func (instance *documents) Put(document interface{}) (*dynamodb.PutItemOutput, error) {
+	item, err := dynamodbattribute.MarshalMap(document)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling interface to dynamodb readable")
+	}
+	result, err := instance.awsDynamodbClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(instance.table),
+		Item:      item,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Delete removes the document with the key. It fails with a ConditionalCheckFailedException when
+// there is none, as Create does when the document already exists.
+func (instance *documents) Delete(key interface{}) (*dynamodb.DeleteItemOutput, error) {
+	item, err := dynamodbattribute.MarshalMap(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling interface to dynamodb readable")
+	}
+	condition := "attribute_exists(id)"
+	result, err := instance.awsDynamodbClient.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:           aws.String(instance.table),
+		ConditionExpression: &condition,
+		Key:                 item,
+	})
+	if err != nil {
+		log.Println("Failed to delete item from table.")
+		return nil, err
+	}
+	return result, nil
+}
+
 func (instance *documents) List(exclusiveStartKey map[string]*dynamodb.AttributeValue, limit int64) (*dynamodb.ScanOutput, error) {
 	result, err := instance.awsDynamodbClient.Scan(&dynamodb.ScanInput{
 		TableName:         aws.String(instance.table),
@@ -100,3 +138,25 @@ func (instance *documents) ListAll() ([]map[string]*dynamodb.AttributeValue, err
 	}
 	return result, nil
 }
+
+// ScanSegment reads a page of one of the segments of a parallel scan of the table, starting after
+// exclusiveStartKey. The page is complete when its LastEvaluatedKey is empty.
+func (instance *documents) ScanSegment(segment int64, totalSegments int64, exclusiveStartKey map[string]*dynamodb.AttributeValue) (*dynamodb.ScanOutput, error) {
+	result, err := instance.awsDynamodbClient.Scan(&dynamodb.ScanInput{
+		TableName:         aws.String(instance.table),
+		Segment:           aws.Int64(segment),
+		TotalSegments:     aws.Int64(totalSegments),
+		ExclusiveStartKey: exclusiveStartKey,
+		ConsistentRead:    aws.Bool(true),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "scanning segment %d of %d", segment, totalSegments)
+	}
+	return result, nil
+}
+
+// BatchPut creates or replaces up to 25 items, the most DynamoDB writes in a single batch, and returns
+// those it left unprocessed, which should be sent again after a while.
+func (instance *documents) BatchPut(items []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error) {
+	return TableBatchPut(instance.awsDynamodbClient, instance.table)(items)
+}
@@ -35,6 +35,29 @@ func (_m *DocumentsClient) Create(item interface{}) (*dynamodb.PutItemOutput, er
 	return r0, r1
 }
 
+// Delete provides a mock function with given fields: key
+func (_m *DocumentsClient) Delete(key interface{}) (*dynamodb.DeleteItemOutput, error) {
+	ret := _m.Called(key)
+
+	var r0 *dynamodb.DeleteItemOutput
+	if rf, ok := ret.Get(0).(func(interface{}) *dynamodb.DeleteItemOutput); ok {
+		r0 = rf(key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dynamodb.DeleteItemOutput)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(interface{}) error); ok {
+		r1 = rf(key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Get provides a mock function with given fields: key
 func (_m *DocumentsClient) Get(key interface{}) (*dynamodb.GetItemOutput, error) {
 	ret := _m.Called(key)
@@ -103,3 +126,26 @@ func (_m *DocumentsClient) ListAll() ([]map[string]*dynamodb.AttributeValue, err
 
 	return r0, r1
 }
+
+// Put provides a mock function with given fields: item
+func (_m *DocumentsClient) Put(item interface{}) (*dynamodb.PutItemOutput, error) {
+	ret := _m.Called(item)
+
+	var r0 *dynamodb.PutItemOutput
+	if rf, ok := ret.Get(0).(func(interface{}) *dynamodb.PutItemOutput); ok {
+		r0 = rf(item)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*dynamodb.PutItemOutput)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(interface{}) error); ok {
+		r1 = rf(item)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
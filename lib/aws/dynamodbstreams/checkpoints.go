@@ -0,0 +1,86 @@
+package dynamodbstreams
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Checkpoints stores the sequence number of the last record handled of each shard.
+type Checkpoints interface {
+	// Get returns the checkpoint of the shard, or an empty string if it has none.
+	Get(shardID string) (string, error)
+	Set(shardID string, sequenceNumber string) error
+}
+
+// MemoryCheckpoints keeps checkpoints in memory, so every process starts reading the stream anew.
+type MemoryCheckpoints struct {
+	mutex       sync.Mutex
+	checkpoints map[string]string
+}
+
+// NewMemoryCheckpoints creates empty in-memory checkpoints.
+func NewMemoryCheckpoints() *MemoryCheckpoints {
+	return &MemoryCheckpoints{checkpoints: map[string]string{}}
+}
+
+func (memory *MemoryCheckpoints) Get(shardID string) (string, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+	return memory.checkpoints[shardID], nil
+}
+
+func (memory *MemoryCheckpoints) Set(shardID string, sequenceNumber string) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+	memory.checkpoints[shardID] = sequenceNumber
+	return nil
+}
+
+// FileCheckpoints keeps checkpoints in a JSON file, so that a restarted process resumes reading the
+// stream where it left off.
+type FileCheckpoints struct {
+	path        string
+	mutex       sync.Mutex
+	checkpoints map[string]string
+}
+
+// LoadFileCheckpoints reads the checkpoints saved in the file. A missing file has no checkpoints.
+func LoadFileCheckpoints(path string) (*FileCheckpoints, error) {
+	checkpoints := map[string]string{}
+	content, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "reading checkpoints file")
+	}
+	if err == nil {
+		if err := json.Unmarshal(content, &checkpoints); err != nil {
+			return nil, errors.Wrap(err, "parsing checkpoints file")
+		}
+	}
+	return &FileCheckpoints{path: path, checkpoints: checkpoints}, nil
+}
+
+func (file *FileCheckpoints) Get(shardID string) (string, error) {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+	return file.checkpoints[shardID], nil
+}
+
+// Set saves the checkpoint, replacing the file atomically.
+func (file *FileCheckpoints) Set(shardID string, sequenceNumber string) error {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+	file.checkpoints[shardID] = sequenceNumber
+	content, err := json.MarshalIndent(file.checkpoints, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshalling checkpoints")
+	}
+	temporary := file.path + ".tmp"
+	if err := ioutil.WriteFile(temporary, content, 0644); err != nil {
+		return errors.Wrap(err, "writing checkpoints file")
+	}
+	return errors.Wrap(os.Rename(temporary, file.path), "replacing checkpoints file")
+}
@@ -0,0 +1,269 @@
+package dynamodbstreams
+
+import (
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+	"github.com/pkg/errors"
+)
+
+// Names of the events of stream records.
+const (
+	EventInsert = dynamodbstreams.OperationTypeInsert
+	EventModify = dynamodbstreams.OperationTypeModify
+	EventRemove = dynamodbstreams.OperationTypeRemove
+)
+
+// Record is a change made to an item of the table.
+type Record struct {
+	// Either EventInsert, EventModify or EventRemove.
+	EventName      string
+	SequenceNumber string
+	Keys           map[string]*dynamodb.AttributeValue
+	// Item after the change, unless it was removed.
+	NewImage map[string]*dynamodb.AttributeValue
+	// Item before the change, unless it was inserted.
+	OldImage map[string]*dynamodb.AttributeValue
+}
+
+// Handler applies a batch of records read from a shard, in order. When it returns an error, the
+// records are read again on the next poll.
+type Handler func(records []Record) error
+
+var newAwsSession = session.NewSession
+
+type shard struct {
+	id       string
+	parentID string
+	// Position to start reading from when the shard has no checkpoint.
+	initialPosition string
+	// Sequence number of the first record of a batch the handler failed, to read again.
+	retryFrom string
+	iterator  *string
+	finished  bool
+}
+
+// Consumer reads the records of every shard of a DynamoDB stream, processing parent shards before
+// their children, and checkpoints the last record handled of each shard.
+type Consumer struct {
+	// Time between polls of the stream in Run.
+	PollInterval time.Duration
+	// Maximum number of records read at once from a shard.
+	BatchSize int64
+	// Where to start reading the shards that exist when the consumer starts and have no checkpoint.
+	// Either LATEST (the default) or TRIM_HORIZON. Shards created afterwards are always read from
+	// their first record.
+	InitialPosition string
+
+	client      dynamodbstreamsiface.DynamoDBStreamsAPI
+	streamArn   string
+	handler     Handler
+	checkpoints Checkpoints
+	shards      map[string]*shard
+	order       []string
+}
+
+// New creates a consumer of the stream of the table.
+func New(table string, awsRegion string, handler Handler, checkpoints Checkpoints) (*Consumer, error) {
+	session, err := newAwsSession(&aws.Config{Region: aws.String(awsRegion)})
+	if err != nil {
+		return nil, errors.Wrap(err, "starting new aws sessions")
+	}
+	description, err := dynamodb.New(session).DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(table)})
+	if err != nil {
+		return nil, errors.Wrap(err, "describing dynamodb table")
+	}
+	if description.Table.LatestStreamArn == nil {
+		return nil, errors.Errorf("table %s has no stream enabled", table)
+	}
+	return NewConsumer(dynamodbstreams.New(session), *description.Table.LatestStreamArn, handler, checkpoints), nil
+}
+
+// NewConsumer creates a consumer of the stream using the given client.
+func NewConsumer(client dynamodbstreamsiface.DynamoDBStreamsAPI, streamArn string, handler Handler, checkpoints Checkpoints) *Consumer {
+	return &Consumer{
+		PollInterval:    time.Second,
+		BatchSize:       1000,
+		InitialPosition: dynamodbstreams.ShardIteratorTypeLatest,
+		client:          client,
+		streamArn:       streamArn,
+		handler:         handler,
+		checkpoints:     checkpoints,
+	}
+}
+
+// Run polls the stream every PollInterval until stop is closed. Errors are logged and the failed
+// records are read again on the next poll.
+func (consumer *Consumer) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(consumer.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := consumer.Poll(); err != nil {
+			log.Printf("Error when trying to read stream %s: %s", consumer.streamArn, err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll reads the records available in every shard whose parent has been read completely.
+func (consumer *Consumer) Poll() error {
+	if err := consumer.describeShards(); err != nil {
+		return err
+	}
+	for _, id := range consumer.order {
+		current := consumer.shards[id]
+		if current.finished || !consumer.parentFinished(current) {
+			continue
+		}
+		if err := consumer.readShard(current); err != nil {
+			return errors.Wrapf(err, "reading shard %s", current.id)
+		}
+	}
+	return nil
+}
+
+func (consumer *Consumer) describeShards() error {
+	initialPosition := consumer.InitialPosition
+	if consumer.shards == nil {
+		consumer.shards = map[string]*shard{}
+	} else {
+		initialPosition = dynamodbstreams.ShardIteratorTypeTrimHorizon
+	}
+	var lastShardID *string
+	for {
+		output, err := consumer.client.DescribeStream(&dynamodbstreams.DescribeStreamInput{
+			StreamArn:             aws.String(consumer.streamArn),
+			ExclusiveStartShardId: lastShardID,
+		})
+		if err != nil {
+			return errors.Wrap(err, "describing stream")
+		}
+		for _, described := range output.StreamDescription.Shards {
+			id := aws.StringValue(described.ShardId)
+			if _, ok := consumer.shards[id]; ok {
+				continue
+			}
+			consumer.shards[id] = &shard{id: id, parentID: aws.StringValue(described.ParentShardId), initialPosition: initialPosition}
+			consumer.order = append(consumer.order, id)
+		}
+		lastShardID = output.StreamDescription.LastEvaluatedShardId
+		if lastShardID == nil {
+			return nil
+		}
+	}
+}
+
+// parentFinished reports whether the parent of the shard has been read completely. Parents that are
+// no longer in the stream have been trimmed and can't be read anymore.
+func (consumer *Consumer) parentFinished(current *shard) bool {
+	parent, ok := consumer.shards[current.parentID]
+	return !ok || parent.finished
+}
+
+func (consumer *Consumer) readShard(current *shard) error {
+	for {
+		if current.iterator == nil {
+			iterator, err := consumer.shardIterator(current)
+			if err != nil {
+				return err
+			}
+			current.iterator = iterator
+		}
+		output, err := consumer.client.GetRecords(&dynamodbstreams.GetRecordsInput{
+			ShardIterator: current.iterator,
+			Limit:         aws.Int64(consumer.BatchSize),
+		})
+		if err != nil {
+			if isAWSError(err, dynamodbstreams.ErrCodeExpiredIteratorException) {
+				current.iterator = nil
+				continue
+			}
+			return errors.Wrap(err, "getting records")
+		}
+		if len(output.Records) > 0 {
+			records := toRecords(output.Records)
+			if err := consumer.handler(records); err != nil {
+				// Read the records again on the next poll, even if the shard has no checkpoint yet.
+				current.retryFrom = records[0].SequenceNumber
+				current.iterator = nil
+				return errors.Wrap(err, "handling records")
+			}
+			if err := consumer.checkpoints.Set(current.id, records[len(records)-1].SequenceNumber); err != nil {
+				return errors.Wrap(err, "saving checkpoint")
+			}
+			current.retryFrom = ""
+		}
+		current.iterator = output.NextShardIterator
+		if current.iterator == nil {
+			current.finished = true
+			return nil
+		}
+		if len(output.Records) == 0 {
+			return nil
+		}
+	}
+}
+
+// shardIterator returns an iterator at the batch of the shard to read again, if any, right after the
+// checkpoint of the shard, if any, or at the initial position of the shard otherwise.
+func (consumer *Consumer) shardIterator(current *shard) (*string, error) {
+	input := &dynamodbstreams.GetShardIteratorInput{
+		StreamArn:         aws.String(consumer.streamArn),
+		ShardId:           aws.String(current.id),
+		ShardIteratorType: aws.String(current.initialPosition),
+	}
+	checkpoint, err := consumer.checkpoints.Get(current.id)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading checkpoint")
+	}
+	switch {
+	case current.retryFrom != "":
+		input.ShardIteratorType = aws.String(dynamodbstreams.ShardIteratorTypeAtSequenceNumber)
+		input.SequenceNumber = aws.String(current.retryFrom)
+	case checkpoint != "":
+		input.ShardIteratorType = aws.String(dynamodbstreams.ShardIteratorTypeAfterSequenceNumber)
+		input.SequenceNumber = aws.String(checkpoint)
+	}
+	output, err := consumer.client.GetShardIterator(input)
+	if err != nil && input.SequenceNumber != nil && isAWSError(err, dynamodbstreams.ErrCodeTrimmedDataAccessException) {
+		// The checkpoint is older than the retention of the stream, read what is left.
+		log.Printf("Record %s of shard %s was trimmed from the stream, reading from its oldest record.", aws.StringValue(input.SequenceNumber), current.id)
+		input.ShardIteratorType = aws.String(dynamodbstreams.ShardIteratorTypeTrimHorizon)
+		input.SequenceNumber = nil
+		output, err = consumer.client.GetShardIterator(input)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "getting shard iterator")
+	}
+	return output.ShardIterator, nil
+}
+
+func toRecords(streamRecords []*dynamodbstreams.Record) []Record {
+	records := make([]Record, 0, len(streamRecords))
+	for _, streamRecord := range streamRecords {
+		record := Record{EventName: aws.StringValue(streamRecord.EventName)}
+		if streamRecord.Dynamodb != nil {
+			record.SequenceNumber = aws.StringValue(streamRecord.Dynamodb.SequenceNumber)
+			record.Keys = streamRecord.Dynamodb.Keys
+			record.NewImage = streamRecord.Dynamodb.NewImage
+			record.OldImage = streamRecord.Dynamodb.OldImage
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func isAWSError(err error, code string) bool {
+	aerr, ok := errors.Cause(err).(awserr.Error)
+	return ok && aerr.Code() == code
+}
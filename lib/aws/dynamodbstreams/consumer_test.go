@@ -0,0 +1,131 @@
+package dynamodbstreams
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodbstreams/fake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+const testStreamArn = "arn:aws:dynamodb:us-east-1:000000000000:table/sucursal_table/stream/2020-10-19T00:00:00.000"
+
+func item(id string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{"id": {S: aws.String(id)}}
+}
+
+// recorder is a handler keeping the ids of the items of the records handled.
+type recorder struct {
+	ids  []string
+	fail bool
+}
+
+func (handler *recorder) handle(records []Record) error {
+	if handler.fail {
+		return errors.New("handler failure")
+	}
+	for _, record := range records {
+		handler.ids = append(handler.ids, record.EventName+" "+aws.StringValue(record.Keys["id"].S))
+	}
+	return nil
+}
+
+func TestPollReadsParentShardsBeforeChildren(t *testing.T) {
+	stream := fake.NewStream(testStreamArn)
+	stream.PageSize = 1
+	stream.AddShard("parent", "")
+	stream.Put("parent", EventInsert, item("a"), item("a"), nil)
+	stream.AddShard("child", "parent")
+	stream.Put("child", EventRemove, item("a"), nil, item("a"))
+	handler := &recorder{}
+	consumer := NewConsumer(stream, testStreamArn, handler.handle, NewMemoryCheckpoints())
+	consumer.InitialPosition = "TRIM_HORIZON"
+	consumer.BatchSize = 1
+
+	require.NoError(t, consumer.Poll())
+	require.Equal(t, []string{"INSERT a"}, handler.ids, "the child must wait for the parent to be closed")
+
+	stream.Put("parent", EventModify, item("a"), item("a"), item("a"))
+	stream.CloseShard("parent")
+	require.NoError(t, consumer.Poll())
+	require.Equal(t, []string{"INSERT a", "MODIFY a", "REMOVE a"}, handler.ids)
+
+	stream.Put("child", EventInsert, item("b"), item("b"), nil)
+	require.NoError(t, consumer.Poll())
+	require.Equal(t, []string{"INSERT a", "MODIFY a", "REMOVE a", "INSERT b"}, handler.ids)
+}
+
+func TestPollStartsExistingShardsAtLatestAndNewShardsAtTrimHorizon(t *testing.T) {
+	stream := fake.NewStream(testStreamArn)
+	stream.AddShard("first", "")
+	stream.Put("first", EventInsert, item("old"), item("old"), nil)
+	handler := &recorder{}
+	consumer := NewConsumer(stream, testStreamArn, handler.handle, NewMemoryCheckpoints())
+
+	require.NoError(t, consumer.Poll())
+	stream.Put("first", EventInsert, item("a"), item("a"), nil)
+	stream.CloseShard("first")
+	stream.AddShard("second", "first")
+	stream.Put("second", EventInsert, item("b"), item("b"), nil)
+	require.NoError(t, consumer.Poll())
+	require.Equal(t, []string{"INSERT a", "INSERT b"}, handler.ids)
+}
+
+func TestPollRetriesFailedRecordsAndResumesFromCheckpoints(t *testing.T) {
+	stream := fake.NewStream(testStreamArn)
+	stream.AddShard("shard", "")
+	first := stream.Put("shard", EventInsert, item("a"), item("a"), nil)
+	directory, err := ioutil.TempDir("", "checkpoints")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+	checkpoints, err := LoadFileCheckpoints(filepath.Join(directory, "checkpoints.json"))
+	require.NoError(t, err)
+	handler := &recorder{}
+	consumer := NewConsumer(stream, testStreamArn, handler.handle, checkpoints)
+	consumer.InitialPosition = "TRIM_HORIZON"
+	require.NoError(t, consumer.Poll())
+
+	stream.Put("shard", EventInsert, item("b"), item("b"), nil)
+	handler.fail = true
+	require.Error(t, consumer.Poll())
+	handler.fail = false
+	require.NoError(t, consumer.Poll())
+	require.Equal(t, []string{"INSERT a", "INSERT b"}, handler.ids)
+
+	// A new consumer resumes after the saved checkpoint.
+	stream.Put("shard", EventInsert, item("c"), item("c"), nil)
+	reloaded, err := LoadFileCheckpoints(checkpoints.path)
+	require.NoError(t, err)
+	resumed := &recorder{}
+	require.NoError(t, NewConsumer(stream, testStreamArn, resumed.handle, reloaded).Poll())
+	require.Equal(t, []string{"INSERT c"}, resumed.ids)
+
+	// Checkpoints trimmed from the stream restart from the oldest record left.
+	stream.Trim("shard", 3)
+	stream.Put("shard", EventInsert, item("d"), item("d"), nil)
+	trimmed := &recorder{}
+	require.NoError(t, NewConsumer(stream, testStreamArn, trimmed.handle, &MemoryCheckpoints{checkpoints: map[string]string{"shard": first}}).Poll())
+	require.Equal(t, []string{"INSERT d"}, trimmed.ids)
+}
+
+func TestPollRetriesFailedRecordsOfShardsWithoutCheckpoint(t *testing.T) {
+	stream := fake.NewStream(testStreamArn)
+	stream.AddShard("shard", "")
+	stream.Put("shard", EventInsert, item("old"), item("old"), nil)
+	handler := &recorder{}
+	consumer := NewConsumer(stream, testStreamArn, handler.handle, NewMemoryCheckpoints())
+	require.NoError(t, consumer.Poll())
+
+	stream.Put("shard", EventInsert, item("a"), item("a"), nil)
+	stream.Put("shard", EventModify, item("a"), item("a"), item("a"))
+	handler.fail = true
+	require.Error(t, consumer.Poll())
+	handler.fail = false
+	require.NoError(t, consumer.Poll())
+	require.Equal(t, []string{"INSERT a", "MODIFY a"}, handler.ids)
+}
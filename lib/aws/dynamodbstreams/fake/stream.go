@@ -0,0 +1,179 @@
+// Package fake provides an in-memory DynamoDB stream to test stream consumers without AWS.
+package fake
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+)
+
+type shard struct {
+	id       string
+	parentID string
+	records  []*dynamodbstreams.Record
+	// Number of records removed from the start of the shard by Trim.
+	trimmed int
+	closed  bool
+}
+
+// Stream implements the DescribeStream, GetShardIterator and GetRecords operations of the DynamoDB
+// Streams API over shards kept in memory. Other operations panic.
+type Stream struct {
+	dynamodbstreamsiface.DynamoDBStreamsAPI
+
+	// Maximum number of shards returned by a DescribeStream call.
+	PageSize int
+
+	mutex    sync.Mutex
+	arn      string
+	shards   []*shard
+	sequence int
+}
+
+// NewStream creates a stream without shards.
+func NewStream(arn string) *Stream {
+	return &Stream{arn: arn, PageSize: 100}
+}
+
+// AddShard opens a new shard. The parent may be empty.
+func (stream *Stream) AddShard(id string, parentID string) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.shards = append(stream.shards, &shard{id: id, parentID: parentID})
+}
+
+// CloseShard stops a shard from receiving records. Readers reaching its end get no next iterator.
+func (stream *Stream) CloseShard(id string) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.shard(id).closed = true
+}
+
+// Trim drops the oldest records of a shard, as the stream does when they exceed its retention.
+func (stream *Stream) Trim(id string, count int) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	current := stream.shard(id)
+	current.records = current.records[count:]
+	current.trimmed += count
+}
+
+// Put appends a record to a shard and returns its sequence number.
+func (stream *Stream) Put(shardID string, eventName string, keys map[string]*dynamodb.AttributeValue, newImage map[string]*dynamodb.AttributeValue, oldImage map[string]*dynamodb.AttributeValue) string {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.sequence++
+	sequenceNumber := fmt.Sprintf("%021d", stream.sequence)
+	current := stream.shard(shardID)
+	current.records = append(current.records, &dynamodbstreams.Record{
+		EventID:   aws.String(sequenceNumber),
+		EventName: aws.String(eventName),
+		Dynamodb: &dynamodbstreams.StreamRecord{
+			Keys:           keys,
+			NewImage:       newImage,
+			OldImage:       oldImage,
+			SequenceNumber: aws.String(sequenceNumber),
+			StreamViewType: aws.String(dynamodbstreams.StreamViewTypeNewAndOldImages),
+		},
+	})
+	return sequenceNumber
+}
+
+func (stream *Stream) shard(id string) *shard {
+	for _, current := range stream.shards {
+		if current.id == id {
+			return current
+		}
+	}
+	panic("unknown shard " + id)
+}
+
+func (stream *Stream) DescribeStream(input *dynamodbstreams.DescribeStreamInput) (*dynamodbstreams.DescribeStreamOutput, error) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if aws.StringValue(input.StreamArn) != stream.arn {
+		return nil, awserr.New(dynamodbstreams.ErrCodeResourceNotFoundException, "stream not found", nil)
+	}
+	start := 0
+	if input.ExclusiveStartShardId != nil {
+		for i, current := range stream.shards {
+			if current.id == *input.ExclusiveStartShardId {
+				start = i + 1
+			}
+		}
+	}
+	description := &dynamodbstreams.StreamDescription{
+		StreamArn:    aws.String(stream.arn),
+		StreamStatus: aws.String(dynamodbstreams.StreamStatusEnabled),
+		Shards:       []*dynamodbstreams.Shard{},
+	}
+	for i := start; i < len(stream.shards) && i < start+stream.PageSize; i++ {
+		current := stream.shards[i]
+		described := &dynamodbstreams.Shard{ShardId: aws.String(current.id)}
+		if current.parentID != "" {
+			described.ParentShardId = aws.String(current.parentID)
+		}
+		description.Shards = append(description.Shards, described)
+		if i == start+stream.PageSize-1 && i < len(stream.shards)-1 {
+			description.LastEvaluatedShardId = aws.String(current.id)
+		}
+	}
+	return &dynamodbstreams.DescribeStreamOutput{StreamDescription: description}, nil
+}
+
+func (stream *Stream) GetShardIterator(input *dynamodbstreams.GetShardIteratorInput) (*dynamodbstreams.GetShardIteratorOutput, error) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	current := stream.shard(aws.StringValue(input.ShardId))
+	position := current.trimmed
+	switch aws.StringValue(input.ShardIteratorType) {
+	case dynamodbstreams.ShardIteratorTypeTrimHorizon:
+	case dynamodbstreams.ShardIteratorTypeLatest:
+		position = current.trimmed + len(current.records)
+	case dynamodbstreams.ShardIteratorTypeAtSequenceNumber, dynamodbstreams.ShardIteratorTypeAfterSequenceNumber:
+		found := false
+		for i, record := range current.records {
+			if aws.StringValue(record.Dynamodb.SequenceNumber) == aws.StringValue(input.SequenceNumber) {
+				position, found = current.trimmed+i, true
+			}
+		}
+		if !found {
+			return nil, awserr.New(dynamodbstreams.ErrCodeTrimmedDataAccessException, "sequence number not in shard", nil)
+		}
+		if aws.StringValue(input.ShardIteratorType) == dynamodbstreams.ShardIteratorTypeAfterSequenceNumber {
+			position++
+		}
+	}
+	return &dynamodbstreams.GetShardIteratorOutput{ShardIterator: aws.String(iterator(current.id, position))}, nil
+}
+
+func (stream *Stream) GetRecords(input *dynamodbstreams.GetRecordsInput) (*dynamodbstreams.GetRecordsOutput, error) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	parts := strings.SplitN(aws.StringValue(input.ShardIterator), "/", 2)
+	position, _ := strconv.Atoi(parts[1])
+	current := stream.shard(parts[0])
+	if position < current.trimmed {
+		return nil, awserr.New(dynamodbstreams.ErrCodeTrimmedDataAccessException, "records were trimmed", nil)
+	}
+	end := current.trimmed + len(current.records)
+	if limit := int(aws.Int64Value(input.Limit)); limit > 0 && position+limit < end {
+		end = position + limit
+	}
+	output := &dynamodbstreams.GetRecordsOutput{Records: current.records[position-current.trimmed : end-current.trimmed]}
+	if !current.closed || end < current.trimmed+len(current.records) {
+		output.NextShardIterator = aws.String(iterator(current.id, end))
+	}
+	return output, nil
+}
+
+func iterator(shardID string, position int) string {
+	return fmt.Sprintf("%s/%d", shardID, position)
+}
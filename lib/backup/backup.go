@@ -0,0 +1,356 @@
+// Package backup dumps DynamoDB tables to local files and restores them into any table, so that
+// data can be copied from production to development or recovered after a bad bulk edit.
+//
+// A backup is a gzip file of DynamoDB JSON lines, one item per line, named after the table and the
+// time it started, such as sucursal_table-20261019T135700Z.jsonl.gz. Next to it, a manifest records
+// how many items it holds and its SHA-256, which are checked before restoring it. Both backups and
+// restores save their progress as they go, so that an interrupted one resumes where it stopped.
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Extension of backup files.
+	Extension = ".jsonl.gz"
+	// Format of the backups, as recorded in their manifest.
+	Format = "dynamodb-json-lines+gzip"
+	// UTC time in backup names, which sorts them by time.
+	timeLayout     = "20060102T150405Z"
+	manifestSuffix = ".manifest.json"
+	progressSuffix = ".progress.json"
+)
+
+var now = time.Now
+
+// Table is the DynamoDB table a backup is made of or restored into. The documents client of
+// lib/aws/dynamodb implements it.
+type Table interface {
+	ScanSegment(segment int64, totalSegments int64, exclusiveStartKey map[string]*dynamodb.AttributeValue) (*dynamodb.ScanOutput, error)
+	BatchPut(items []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error)
+}
+
+// Manifest describes a backup.
+type Manifest struct {
+	Table      string    `json:"table"`
+	Format     string    `json:"format"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	// Segments of the parallel scan that read the table.
+	Segments int `json:"segments"`
+	Items    int `json:"items"`
+	// Hex SHA-256 of the backup file.
+	SHA256 string `json:"sha256"`
+}
+
+// FileName returns the name of a backup of the table started at the time.
+func FileName(table string, startedAt time.Time) string {
+	return table + "-" + startedAt.UTC().Format(timeLayout) + Extension
+}
+
+// ManifestPath returns the path of the manifest of the backup at path.
+func ManifestPath(path string) string {
+	return strings.TrimSuffix(path, Extension) + manifestSuffix
+}
+
+// ReadManifest reads the manifest of the backup at path.
+func ReadManifest(path string) (*Manifest, error) {
+	manifest := &Manifest{}
+	if err := readJSON(ManifestPath(path), manifest); err != nil {
+		return nil, errors.Wrap(err, "reading manifest")
+	}
+	return manifest, nil
+}
+
+// DumpOptions configures a backup.
+type DumpOptions struct {
+	// Directory the backup is written to, the working directory when empty.
+	Dir string
+	// Segments of the parallel scan of the table, which are read concurrently. One when zero.
+	Segments int
+	// Path of an interrupted backup to finish instead of starting a new one.
+	Resume string
+}
+
+// dumpProgress is the state of a backup being made. Each segment of the scan is written to its own
+// part file, and the parts are joined into the backup once every segment is read.
+type dumpProgress struct {
+	Table     string             `json:"table"`
+	StartedAt time.Time          `json:"startedAt"`
+	Segments  []*segmentProgress `json:"segments"`
+}
+
+type segmentProgress struct {
+	// Bytes of the part file holding complete pages. Anything after them is dropped on resume.
+	Size  int64 `json:"size"`
+	Items int   `json:"items"`
+	// Key the scan continues after, in DynamoDB JSON.
+	LastEvaluatedKey json.RawMessage `json:"lastEvaluatedKey,omitempty"`
+	Done             bool            `json:"done"`
+}
+
+type dumper struct {
+	table    Table
+	path     string
+	mutex    sync.Mutex
+	progress *dumpProgress
+}
+
+// Dump makes a backup of the table named tableName and returns its path and manifest. The path is
+// also returned when reading the table fails, so that the backup can be resumed with
+// DumpOptions.Resume.
+func Dump(table Table, tableName string, options DumpOptions) (string, *Manifest, error) {
+	path, progress, err := startDump(tableName, options)
+	if err != nil {
+		return "", nil, err
+	}
+	dump := &dumper{table: table, path: path, progress: progress}
+	failures := make(chan error, len(progress.Segments))
+	for segment := range progress.Segments {
+		go func(segment int) {
+			failures <- dump.scan(segment)
+		}(segment)
+	}
+	var failure error
+	for range progress.Segments {
+		if err := <-failures; err != nil && failure == nil {
+			failure = err
+		}
+	}
+	if failure != nil {
+		return path, nil, failure
+	}
+	manifest, err := dump.finish()
+	return path, manifest, err
+}
+
+func startDump(tableName string, options DumpOptions) (string, *dumpProgress, error) {
+	if options.Resume != "" {
+		progress := &dumpProgress{}
+		if err := readJSON(options.Resume+progressSuffix, progress); err != nil {
+			return options.Resume, nil, errors.Wrapf(err, "reading the progress of %s", options.Resume)
+		}
+		if progress.Table != tableName {
+			return options.Resume, nil, errors.Errorf("%s is a backup of %s, not of %s", options.Resume, progress.Table, tableName)
+		}
+		return options.Resume, progress, nil
+	}
+	segments := options.Segments
+	if segments == 0 {
+		segments = 1
+	}
+	if segments < 0 {
+		return "", nil, errors.Errorf("segments must be positive, got %d", segments)
+	}
+	progress := &dumpProgress{Table: tableName, StartedAt: now().UTC(), Segments: make([]*segmentProgress, segments)}
+	for i := range progress.Segments {
+		progress.Segments[i] = &segmentProgress{}
+	}
+	dir := options.Dir
+	if dir == "" {
+		dir = "."
+	}
+	path := filepath.Join(dir, FileName(tableName, progress.StartedAt))
+	if _, err := os.Stat(path); err == nil {
+		return path, nil, errors.Errorf("%s already exists", path)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return path, nil, errors.Wrap(err, "creating backup directory")
+	}
+	return path, progress, writeJSON(path+progressSuffix, progress)
+}
+
+func (dump *dumper) partPath(segment int) string {
+	return dump.path + ".part-" + strconv.Itoa(segment)
+}
+
+// scan reads a segment of the table into its part file, saving the progress after every page.
+func (dump *dumper) scan(segment int) error {
+	dump.mutex.Lock()
+	state := *dump.progress.Segments[segment]
+	total := len(dump.progress.Segments)
+	dump.mutex.Unlock()
+	if state.Done {
+		return nil
+	}
+	part, err := os.OpenFile(dump.partPath(segment), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.Wrap(err, "opening part file")
+	}
+	defer part.Close()
+	if err := part.Truncate(state.Size); err != nil {
+		return errors.Wrap(err, "dropping incomplete pages")
+	}
+	if _, err := part.Seek(state.Size, io.SeekStart); err != nil {
+		return errors.Wrap(err, "seeking part file")
+	}
+	var startKey map[string]*dynamodb.AttributeValue
+	if len(state.LastEvaluatedKey) > 0 {
+		if startKey, err = DecodeItem(state.LastEvaluatedKey); err != nil {
+			return err
+		}
+	}
+	for {
+		page, err := dump.table.ScanSegment(int64(segment), int64(total), startKey)
+		if err != nil {
+			return err
+		}
+		written, err := writePage(part, page.Items)
+		if err != nil {
+			return err
+		}
+		state.Size += written
+		state.Items += len(page.Items)
+		state.Done = len(page.LastEvaluatedKey) == 0
+		state.LastEvaluatedKey = nil
+		if !state.Done {
+			if state.LastEvaluatedKey, err = EncodeItem(page.LastEvaluatedKey); err != nil {
+				return err
+			}
+		}
+		if err := dump.save(segment, state); err != nil {
+			return err
+		}
+		if state.Done {
+			return nil
+		}
+		startKey = page.LastEvaluatedKey
+	}
+}
+
+// writePage appends the items to the part as a gzip member of their own, so that a part holding
+// whole pages is always valid gzip, and returns how many bytes it wrote.
+func writePage(part *os.File, items []map[string]*dynamodb.AttributeValue) (int64, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+	compressed := &bytes.Buffer{}
+	writer := gzip.NewWriter(compressed)
+	for _, item := range items {
+		line, err := EncodeItem(item)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := writer.Write(append(line, '\n')); err != nil {
+			return 0, errors.Wrap(err, "compressing page")
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return 0, errors.Wrap(err, "compressing page")
+	}
+	written, err := part.Write(compressed.Bytes())
+	if err != nil {
+		return int64(written), errors.Wrap(err, "writing page")
+	}
+	return int64(written), errors.Wrap(part.Sync(), "writing page")
+}
+
+func (dump *dumper) save(segment int, state segmentProgress) error {
+	dump.mutex.Lock()
+	defer dump.mutex.Unlock()
+	dump.progress.Segments[segment] = &state
+	return writeJSON(dump.path+progressSuffix, dump.progress)
+}
+
+// finish joins the parts into the backup, since concatenated gzip members are a valid gzip file,
+// and writes its manifest.
+func (dump *dumper) finish() (*Manifest, error) {
+	temporary := dump.path + ".tmp"
+	file, err := os.Create(temporary)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating backup file")
+	}
+	defer file.Close()
+	hash := sha256.New()
+	writer := io.MultiWriter(file, hash)
+	var size int64
+	items := 0
+	for segment, state := range dump.progress.Segments {
+		if err := copyPart(writer, dump.partPath(segment), state.Size); err != nil {
+			return nil, err
+		}
+		size += state.Size
+		items += state.Items
+	}
+	if size == 0 {
+		// An empty table still makes a readable backup.
+		if err := gzip.NewWriter(writer).Close(); err != nil {
+			return nil, errors.Wrap(err, "writing backup file")
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return nil, errors.Wrap(err, "writing backup file")
+	}
+	if err := os.Rename(temporary, dump.path); err != nil {
+		return nil, errors.Wrap(err, "replacing backup file")
+	}
+	manifest := &Manifest{
+		Table:      dump.progress.Table,
+		Format:     Format,
+		StartedAt:  dump.progress.StartedAt,
+		FinishedAt: now().UTC(),
+		Segments:   len(dump.progress.Segments),
+		Items:      items,
+		SHA256:     hex.EncodeToString(hash.Sum(nil)),
+	}
+	if err := writeJSON(ManifestPath(dump.path), manifest); err != nil {
+		return nil, errors.Wrap(err, "writing manifest")
+	}
+	for segment := range dump.progress.Segments {
+		if err := os.Remove(dump.partPath(segment)); err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrap(err, "removing part file")
+		}
+	}
+	return manifest, errors.Wrap(os.Remove(dump.path+progressSuffix), "removing progress file")
+}
+
+func copyPart(writer io.Writer, path string, size int64) error {
+	if size == 0 {
+		return nil
+	}
+	part, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "opening part file")
+	}
+	defer part.Close()
+	_, err = io.CopyN(writer, part, size)
+	return errors.Wrap(err, "copying part file")
+}
+
+func readJSON(path string, value interface{}) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(json.Unmarshal(content, value), "parsing %s", path)
+}
+
+// writeJSON replaces the file atomically, so that an interruption leaves either the old or the new
+// contents.
+func writeJSON(path string, value interface{}) error {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "marshalling %s", path)
+	}
+	temporary := path + ".tmp"
+	if err := ioutil.WriteFile(temporary, content, 0644); err != nil {
+		return errors.Wrapf(err, "writing %s", path)
+	}
+	return errors.Wrapf(os.Rename(temporary, path), "replacing %s", path)
+}
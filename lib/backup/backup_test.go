@@ -0,0 +1,244 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	documents "github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryTable is a table keyed by id that scans pageSize items at a time.
+type memoryTable struct {
+	mutex    sync.Mutex
+	items    map[string]map[string]*dynamodb.AttributeValue
+	pageSize int
+	// Calls that fail once the count reaches zero, unless it is negative.
+	scansLeft int
+	putsLeft  int
+	// Items left unprocessed by the next batch write.
+	unprocessed int
+}
+
+func newMemoryTable(items int) *memoryTable {
+	table := &memoryTable{items: map[string]map[string]*dynamodb.AttributeValue{}, pageSize: 7, scansLeft: -1, putsLeft: -1}
+	for i := 0; i < items; i++ {
+		id := fmt.Sprintf("%03d", i)
+		table.items[id] = map[string]*dynamodb.AttributeValue{
+			"id":       {S: aws.String(id)},
+			"capacity": {N: aws.String(fmt.Sprint(i))},
+			"serviceAreas": {L: []*dynamodb.AttributeValue{
+				{M: map[string]*dynamodb.AttributeValue{"radiusKm": {N: aws.String("2.5")}}},
+			}},
+		}
+	}
+	return table
+}
+
+func segmentOf(id string, totalSegments int64) int64 {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(id))
+	return int64(hash.Sum32()) % totalSegments
+}
+
+func (table *memoryTable) ScanSegment(segment int64, totalSegments int64, exclusiveStartKey map[string]*dynamodb.AttributeValue) (*dynamodb.ScanOutput, error) {
+	table.mutex.Lock()
+	defer table.mutex.Unlock()
+	if table.scansLeft == 0 {
+		return nil, errors.New("throttled")
+	}
+	table.scansLeft--
+	ids := []string{}
+	for id := range table.items {
+		if segmentOf(id, totalSegments) == segment && (exclusiveStartKey == nil || id > *exclusiveStartKey["id"].S) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	output := &dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{}}
+	for _, id := range ids {
+		if len(output.Items) == table.pageSize {
+			output.LastEvaluatedKey = map[string]*dynamodb.AttributeValue{"id": {S: aws.String(ids[table.pageSize-1])}}
+			break
+		}
+		output.Items = append(output.Items, table.items[id])
+	}
+	return output, nil
+}
+
+func (table *memoryTable) BatchPut(items []map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue, error) {
+	table.mutex.Lock()
+	defer table.mutex.Unlock()
+	if len(items) > documents.MaxBatchWriteItems {
+		return nil, errors.New("too many items")
+	}
+	if table.putsLeft == 0 {
+		return nil, errors.New("throttled")
+	}
+	table.putsLeft--
+	left := table.unprocessed
+	if left > len(items) {
+		left = len(items)
+	}
+	table.unprocessed = 0
+	for _, item := range items[:len(items)-left] {
+		table.items[*item["id"].S] = item
+	}
+	return items[len(items)-left:], nil
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func useClock(t *testing.T, at time.Time) {
+	now = func() time.Time { return at }
+	wait := retryWait
+	retryWait = 0
+	t.Cleanup(func() {
+		now = time.Now
+		retryWait = wait
+	})
+}
+
+func TestEncodeItemKeepsEveryAttributeType(t *testing.T) {
+	item := map[string]*dynamodb.AttributeValue{
+		"id":        {S: aws.String("a")},
+		"latitude":  {N: aws.String("-34.6037")},
+		"open":      {BOOL: aws.Bool(false)},
+		"missing":   {NULL: aws.Bool(true)},
+		"photo":     {B: []byte{0, 1, 2}},
+		"tags":      {SS: []*string{aws.String("centro")}},
+		"codes":     {NS: []*string{aws.String("1"), aws.String("2")}},
+		"blobs":     {BS: [][]byte{{3}}},
+		"emptyList": {L: []*dynamodb.AttributeValue{}},
+		"emptyMap":  {M: map[string]*dynamodb.AttributeValue{}},
+		"nested": {M: map[string]*dynamodb.AttributeValue{
+			"list": {L: []*dynamodb.AttributeValue{{S: aws.String("x")}, {N: aws.String("0")}}},
+		}},
+	}
+	encoded, err := EncodeItem(item)
+	require.NoError(t, err)
+	require.NotContains(t, string(encoded), "\n")
+	require.Contains(t, string(encoded), `"emptyList":{"L":[]}`)
+	require.Contains(t, string(encoded), `"open":{"BOOL":false}`)
+	decoded, err := DecodeItem(encoded)
+	require.NoError(t, err)
+	require.Equal(t, item, decoded)
+}
+
+func TestDumpAndRestore(t *testing.T) {
+	useClock(t, time.Date(2026, 10, 19, 13, 57, 0, 0, time.UTC))
+	dir := tempDir(t)
+	source := newMemoryTable(60)
+
+	path, manifest, err := Dump(source, "sucursal_table", DumpOptions{Dir: dir, Segments: 3})
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "sucursal_table-20261019T135700Z.jsonl.gz"), path)
+	require.Equal(t, "sucursal_table", manifest.Table)
+	require.Equal(t, Format, manifest.Format)
+	require.Equal(t, 3, manifest.Segments)
+	require.Equal(t, 60, manifest.Items)
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2, "only the backup and its manifest are left")
+
+	verified, err := Verify(path)
+	require.NoError(t, err)
+	require.Equal(t, manifest.SHA256, verified.SHA256)
+
+	target := newMemoryTable(0)
+	target.unprocessed = 3
+	written, err := Restore(target, "sucursal_table_dev", path, RestoreOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 60, written)
+	require.Equal(t, source.items, target.items)
+	_, err = os.Stat(RestoreProgressPath(path, "sucursal_table_dev"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestDumpOfAnEmptyTable(t *testing.T) {
+	dir := tempDir(t)
+	path, manifest, err := Dump(newMemoryTable(0), "empty", DumpOptions{Dir: dir, Segments: 2})
+	require.NoError(t, err)
+	require.Equal(t, 0, manifest.Items)
+	_, err = Verify(path)
+	require.NoError(t, err)
+	written, err := Restore(newMemoryTable(0), "empty", path, RestoreOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 0, written)
+}
+
+func TestDumpResumesAfterFailure(t *testing.T) {
+	dir := tempDir(t)
+	source := newMemoryTable(60)
+	source.scansLeft = 4
+
+	path, _, err := Dump(source, "sucursal_table", DumpOptions{Dir: dir, Segments: 2})
+	require.EqualError(t, err, "throttled")
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+
+	_, _, err = Dump(source, "other_table", DumpOptions{Resume: path})
+	require.Error(t, err)
+
+	source.scansLeft = -1
+	resumed, manifest, err := Dump(source, "sucursal_table", DumpOptions{Resume: path})
+	require.NoError(t, err)
+	require.Equal(t, path, resumed)
+	require.Equal(t, 60, manifest.Items)
+	target := newMemoryTable(0)
+	_, err = Restore(target, "sucursal_table", path, RestoreOptions{})
+	require.NoError(t, err)
+	require.Equal(t, source.items, target.items)
+}
+
+func TestRestoreResumesAfterFailure(t *testing.T) {
+	dir := tempDir(t)
+	source := newMemoryTable(60)
+	path, _, err := Dump(source, "sucursal_table", DumpOptions{Dir: dir})
+	require.NoError(t, err)
+
+	target := newMemoryTable(0)
+	target.putsLeft = 1
+	written, err := Restore(target, "sucursal_table", path, RestoreOptions{})
+	require.EqualError(t, err, "throttled")
+	require.Equal(t, 25, written)
+
+	target.putsLeft = -1
+	_, err = Restore(target, "sucursal_table", path, RestoreOptions{})
+	require.Error(t, err, "an interrupted restore is only continued when asked to")
+
+	written, err = Restore(target, "sucursal_table", path, RestoreOptions{Resume: true})
+	require.NoError(t, err)
+	require.Equal(t, 35, written)
+	require.Equal(t, source.items, target.items)
+}
+
+func TestRestoreRejectsCorruptedBackups(t *testing.T) {
+	dir := tempDir(t)
+	path, _, err := Dump(newMemoryTable(10), "sucursal_table", DumpOptions{Dir: dir})
+	require.NoError(t, err)
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	content[len(content)/2] ^= 0xff
+	require.NoError(t, ioutil.WriteFile(path, content, 0644))
+
+	target := newMemoryTable(0)
+	_, err = Restore(target, "sucursal_table", path, RestoreOptions{})
+	require.Error(t, err)
+	require.Empty(t, target.items)
+}
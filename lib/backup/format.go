@@ -0,0 +1,69 @@
+package backup
+
+import (
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pkg/errors"
+)
+
+// attributeJSON is an attribute value in DynamoDB JSON, such as {"S": "Florida 296"}. Lists and maps
+// are pointers so that empty ones are kept.
+type attributeJSON struct {
+	B    []byte                     `json:"B,omitempty"`
+	BOOL *bool                      `json:"BOOL,omitempty"`
+	BS   [][]byte                   `json:"BS,omitempty"`
+	L    *[]*attributeJSON          `json:"L,omitempty"`
+	M    *map[string]*attributeJSON `json:"M,omitempty"`
+	N    *string                    `json:"N,omitempty"`
+	NS   []*string                  `json:"NS,omitempty"`
+	NULL *bool                      `json:"NULL,omitempty"`
+	S    *string                    `json:"S,omitempty"`
+	SS   []*string                  `json:"SS,omitempty"`
+}
+
+func toJSON(value *dynamodb.AttributeValue) *attributeJSON {
+	if value == nil {
+		return nil
+	}
+	converted := &attributeJSON{
+		B: value.B, BOOL: value.BOOL, BS: value.BS, N: value.N, NS: value.NS, NULL: value.NULL, S: value.S, SS: value.SS,
+	}
+	if value.L != nil {
+		list := make([]*attributeJSON, len(value.L))
+		for i, element := range value.L {
+			list[i] = toJSON(element)
+		}
+		converted.L = &list
+	}
+	if value.M != nil {
+		converted.M = toJSONMap(value.M)
+	}
+	return converted
+}
+
+func toJSONMap(values map[string]*dynamodb.AttributeValue) *map[string]*attributeJSON {
+	converted := make(map[string]*attributeJSON, len(values))
+	for name, value := range values {
+		converted[name] = toJSON(value)
+	}
+	return &converted
+}
+
+// EncodeItem returns the item in DynamoDB JSON, the format of the DynamoDB API, on a single line.
+func EncodeItem(item map[string]*dynamodb.AttributeValue) ([]byte, error) {
+	encoded, err := json.Marshal(toJSONMap(item))
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding item")
+	}
+	return encoded, nil
+}
+
+// DecodeItem reads an item in DynamoDB JSON.
+func DecodeItem(encoded []byte) (map[string]*dynamodb.AttributeValue, error) {
+	item := map[string]*dynamodb.AttributeValue{}
+	if err := json.Unmarshal(encoded, &item); err != nil {
+		return nil, errors.Wrap(err, "decoding item")
+	}
+	return item, nil
+}
@@ -0,0 +1,170 @@
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	documents "github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/pkg/errors"
+)
+
+// Longest line of a backup. DynamoDB items are at most 400 KB, which base64 and JSON enlarge.
+const maxLineBytes = 4 << 20
+
+// Wait before retrying unprocessed items, which doubles on every retry.
+var retryWait = 100 * time.Millisecond
+
+// Verify checks that the backup at path has the checksum and the number of items its manifest
+// records and that every item can be read. It returns the manifest.
+func Verify(path string) (*Manifest, error) {
+	manifest, err := ReadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening backup")
+	}
+	defer file.Close()
+	hash := sha256.New()
+	items, err := eachItem(io.TeeReader(file, hash), func(item map[string]*dynamodb.AttributeValue) error {
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, errors.Wrap(err, "reading backup")
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != manifest.SHA256 {
+		return nil, errors.Errorf("the SHA-256 of %s is %s, but the manifest says %s", path, sum, manifest.SHA256)
+	}
+	if items != manifest.Items {
+		return nil, errors.Errorf("%s has %d items, but the manifest says %d", path, items, manifest.Items)
+	}
+	return manifest, nil
+}
+
+// eachItem calls visit with every item of a backup, until it returns an error, and returns how
+// many items were read.
+func eachItem(reader io.Reader, visit func(item map[string]*dynamodb.AttributeValue) error) (int, error) {
+	decompressed, err := gzip.NewReader(reader)
+	if err != nil {
+		return 0, errors.Wrap(err, "decompressing backup")
+	}
+	defer decompressed.Close()
+	scanner := bufio.NewScanner(decompressed)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	items := 0
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		item, err := DecodeItem(scanner.Bytes())
+		if err != nil {
+			return items, errors.Wrapf(err, "line %d", line)
+		}
+		if err := visit(item); err != nil {
+			return items, err
+		}
+		items++
+	}
+	return items, errors.Wrap(scanner.Err(), "reading backup")
+}
+
+// RestoreOptions configures a restore.
+type RestoreOptions struct {
+	// Continue an interrupted restore of the backup into the same table, skipping the items it
+	// already wrote.
+	Resume bool
+}
+
+// restoreProgress is the state of a restore, saved after every batch.
+type restoreProgress struct {
+	Table  string `json:"table"`
+	SHA256 string `json:"sha256"`
+	// Items at the start of the backup that are already written.
+	Items int `json:"items"`
+}
+
+// RestoreProgressPath returns the path of the progress of restoring the backup at path into the
+// table. It only exists while the restore is unfinished.
+func RestoreProgressPath(path string, tableName string) string {
+	return strings.TrimSuffix(path, Extension) + ".restore-" + tableName + progressSuffix
+}
+
+// Restore writes the items of the backup at path into the table named tableName, after checking it
+// with Verify, and returns how many items it wrote. Items replace those with the same key, and
+// items of the table that are not in the backup are left as they are, so restoring into an empty
+// table gives an exact copy.
+//
+// When the restore fails, what it wrote is recorded so that it can be finished with
+// RestoreOptions.Resume. Restoring again without it is refused until the progress file is removed.
+func Restore(table Table, tableName string, path string, options RestoreOptions) (int, error) {
+	manifest, err := Verify(path)
+	if err != nil {
+		return 0, err
+	}
+	progressPath := RestoreProgressPath(path, tableName)
+	progress := &restoreProgress{Table: tableName, SHA256: manifest.SHA256}
+	if content, err := ioutil.ReadFile(progressPath); err == nil && len(content) > 0 {
+		if !options.Resume {
+			return 0, errors.Errorf("a restore of %s into %s was interrupted, resume it or remove %s", path, tableName, progressPath)
+		}
+		if err := readJSON(progressPath, progress); err != nil {
+			return 0, errors.Wrap(err, "reading restore progress")
+		}
+		if progress.SHA256 != manifest.SHA256 {
+			return 0, errors.Errorf("%s belongs to another backup, remove it to restore %s", progressPath, path)
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		return 0, errors.Wrap(err, "reading restore progress")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, errors.Wrap(err, "opening backup")
+	}
+	defer file.Close()
+	skip := progress.Items
+	written := 0
+	batch := []map[string]*dynamodb.AttributeValue{}
+	flush := func() error {
+		if err := documents.WriteBatch(table.BatchPut, batch, retryWait); err != nil {
+			return err
+		}
+		written += len(batch)
+		progress.Items += len(batch)
+		batch = batch[:0]
+		return writeJSON(progressPath, progress)
+	}
+	_, err = eachItem(file, func(item map[string]*dynamodb.AttributeValue) error {
+		if skip > 0 {
+			skip--
+			return nil
+		}
+		batch = append(batch, item)
+		if len(batch) < documents.MaxBatchWriteItems {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil && len(batch) > 0 {
+		err = flush()
+	}
+	if err != nil {
+		return written, err
+	}
+	if err := os.Remove(progressPath); err != nil && !os.IsNotExist(err) {
+		return written, errors.Wrap(err, "removing restore progress")
+	}
+	return written, nil
+}
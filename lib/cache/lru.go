@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a fixed size cache that evicts the least recently used entries first. Entries also expire
+// after a time to live. It is safe for concurrent use.
+type LRU struct {
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	hits    uint64
+	misses  uint64
+}
+
+type entry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// Stats holds the counters of a cache.
+type Stats struct {
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Entries int    `json:"entries"`
+}
+
+// NewLRU creates a cache holding up to size entries for at most ttl. A zero ttl never expires entries.
+func NewLRU(size int, ttl time.Duration) *LRU {
+	return &LRU{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// Get returns the value cached for the key, if any and not expired.
+func (lru *LRU) Get(key string) (interface{}, bool) {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+	element, ok := lru.entries[key]
+	if !ok {
+		lru.misses++
+		return nil, false
+	}
+	cached := element.Value.(*entry)
+	if lru.ttl > 0 && lru.now().After(cached.expires) {
+		lru.removeElement(element)
+		lru.misses++
+		return nil, false
+	}
+	lru.order.MoveToFront(element)
+	lru.hits++
+	return cached.value, true
+}
+
+// Set caches the value for the key, evicting the least recently used entry when the cache is full.
+func (lru *LRU) Set(key string, value interface{}) {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+	expires := lru.now().Add(lru.ttl)
+	if element, ok := lru.entries[key]; ok {
+		element.Value = &entry{key, value, expires}
+		lru.order.MoveToFront(element)
+		return
+	}
+	lru.entries[key] = lru.order.PushFront(&entry{key, value, expires})
+	for lru.size > 0 && lru.order.Len() > lru.size {
+		lru.removeElement(lru.order.Back())
+	}
+}
+
+// Remove deletes the entry for the key.
+func (lru *LRU) Remove(key string) {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+	if element, ok := lru.entries[key]; ok {
+		lru.removeElement(element)
+	}
+}
+
+// Purge deletes every entry.
+func (lru *LRU) Purge() {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+	lru.entries = map[string]*list.Element{}
+	lru.order.Init()
+}
+
+// Stats returns the hit and miss counters of the cache.
+func (lru *LRU) Stats() Stats {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+	return Stats{Hits: lru.hits, Misses: lru.misses, Entries: lru.order.Len()}
+}
+
+func (lru *LRU) removeElement(element *list.Element) {
+	lru.order.Remove(element)
+	delete(lru.entries, element.Value.(*entry).key)
+}
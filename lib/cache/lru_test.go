@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	lru := NewLRU(2, 0)
+	lru.Set("a", 1)
+	lru.Set("b", 2)
+	_, _ = lru.Get("a")
+	lru.Set("c", 3)
+
+	_, ok := lru.Get("b")
+	require.False(t, ok)
+	value, ok := lru.Get("a")
+	require.True(t, ok)
+	require.Equal(t, 1, value)
+	require.Equal(t, Stats{Hits: 2, Misses: 1, Entries: 2}, lru.Stats())
+}
+
+func TestLRUExpiresEntries(t *testing.T) {
+	now := time.Date(2020, 10, 19, 0, 0, 0, 0, time.UTC)
+	lru := NewLRU(10, time.Minute)
+	lru.now = func() time.Time { return now }
+	lru.Set("a", 1)
+
+	now = now.Add(59 * time.Second)
+	_, ok := lru.Get("a")
+	require.True(t, ok)
+	now = now.Add(2 * time.Second)
+	_, ok = lru.Get("a")
+	require.False(t, ok)
+	require.Equal(t, 0, lru.Stats().Entries)
+}
@@ -0,0 +1,44 @@
+package cache
+
+import "sync"
+
+// call is a load in progress or completed.
+type call struct {
+	done  sync.WaitGroup
+	value interface{}
+	err   error
+}
+
+// Group de-duplicates concurrent loads of the same key: while a load runs, callers asking for the
+// same key wait for it and share its result instead of starting their own. It is safe for concurrent use.
+type Group struct {
+	mutex sync.Mutex
+	calls map[string]*call
+}
+
+// Do runs load for the key, unless a load of the key is already running, in which case it waits for it
+// and returns its result. shared reports whether the result came from another caller's load.
+func (group *Group) Do(key string, load func() (interface{}, error)) (value interface{}, err error, shared bool) {
+	group.mutex.Lock()
+	if group.calls == nil {
+		group.calls = map[string]*call{}
+	}
+	if running, ok := group.calls[key]; ok {
+		group.mutex.Unlock()
+		running.done.Wait()
+		return running.value, running.err, true
+	}
+	current := &call{}
+	current.done.Add(1)
+	group.calls[key] = current
+	group.mutex.Unlock()
+
+	defer func() {
+		group.mutex.Lock()
+		delete(group.calls, key)
+		group.mutex.Unlock()
+		current.done.Done()
+	}()
+	current.value, current.err = load()
+	return current.value, current.err, false
+}
@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"math"
+	"sort"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+)
+
+// TileSize is the size in pixels of the map tiles the clusters are computed for.
+const TileSize = 256
+
+// Point is a location to be clustered.
+type Point struct {
+	ID        string
+	Latitude  float64
+	Longitude float64
+}
+
+// Bounds is a latitude/longitude bounding box.
+type Bounds struct {
+	South float64 `json:"south"`
+	West  float64 `json:"west"`
+	North float64 `json:"north"`
+	East  float64 `json:"east"`
+}
+
+// Contains reports whether the position is inside the bounds. Bounds crossing the antimeridian have
+// their west edge greater than their east edge.
+func (bounds *Bounds) Contains(latitude float64, longitude float64) bool {
+	if latitude < bounds.South || latitude > bounds.North {
+		return false
+	}
+	if bounds.West <= bounds.East {
+		return longitude >= bounds.West && longitude <= bounds.East
+	}
+	return longitude >= bounds.West || longitude <= bounds.East
+}
+
+// Cluster is a group of nearby points.
+type Cluster struct {
+	// Centroid of the points in the cluster.
+	Latitude  float64
+	Longitude float64
+	// Number of points in the cluster.
+	Count int
+	// Smallest box containing every point of the cluster.
+	Bounds Bounds
+	// IDs of the points in the cluster.
+	IDs []string
+}
+
+type cell struct {
+	x int
+	y int
+}
+
+// Grid groups the points that fall in the same square of radius x radius pixels of the Web Mercator map
+// at the given zoom level. Clusters are returned ordered from north-west to south-east.
+func Grid(points []Point, zoom int, radius float64) []*Cluster {
+	clusters := map[cell]*Cluster{}
+	for _, point := range points {
+		x, y := geo.WorldPixel(point.Latitude, point.Longitude, zoom, TileSize)
+		key := cell{int(math.Floor(x / radius)), int(math.Floor(y / radius))}
+		cluster, ok := clusters[key]
+		if !ok {
+			cluster = &Cluster{Bounds: Bounds{South: point.Latitude, West: point.Longitude, North: point.Latitude, East: point.Longitude}}
+			clusters[key] = cluster
+		}
+		cluster.Count++
+		cluster.Latitude += point.Latitude
+		cluster.Longitude += point.Longitude
+		cluster.IDs = append(cluster.IDs, point.ID)
+		cluster.Bounds.South = math.Min(cluster.Bounds.South, point.Latitude)
+		cluster.Bounds.North = math.Max(cluster.Bounds.North, point.Latitude)
+		cluster.Bounds.West = math.Min(cluster.Bounds.West, point.Longitude)
+		cluster.Bounds.East = math.Max(cluster.Bounds.East, point.Longitude)
+	}
+	keys := make([]cell, 0, len(clusters))
+	for key := range clusters {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].y != keys[j].y {
+			return keys[i].y < keys[j].y
+		}
+		return keys[i].x < keys[j].x
+	})
+	result := make([]*Cluster, 0, len(keys))
+	for _, key := range keys {
+		cluster := clusters[key]
+		cluster.Latitude /= float64(cluster.Count)
+		cluster.Longitude /= float64(cluster.Count)
+		sort.Strings(cluster.IDs)
+		result = append(result, cluster)
+	}
+	return result
+}
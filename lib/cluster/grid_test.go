@@ -0,0 +1,33 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGridMergesNearbyPoints(t *testing.T) {
+	points := []Point{
+		{ID: "florida", Latitude: -34.604258, Longitude: -58.375094},
+		{ID: "corrientes", Latitude: -34.6035, Longitude: -58.383},
+		{ID: "cordoba", Latitude: -31.4135, Longitude: -64.18105},
+	}
+
+	clusters := Grid(points, 4, 64)
+	require.Len(t, clusters, 2)
+	require.Equal(t, []string{"cordoba"}, clusters[0].IDs)
+	require.Equal(t, 2, clusters[1].Count)
+	require.Equal(t, []string{"corrientes", "florida"}, clusters[1].IDs)
+	require.InDelta(t, -34.603879, clusters[1].Latitude, 0.000001)
+	require.Equal(t, Bounds{South: -34.604258, West: -58.383, North: -34.6035, East: -58.375094}, clusters[1].Bounds)
+
+	require.Len(t, Grid(points, 18, 64), 3)
+}
+
+func TestBoundsContainsAcrossAntimeridian(t *testing.T) {
+	bounds := Bounds{South: -10, West: 170, North: 10, East: -170}
+	require.True(t, bounds.Contains(0, 175))
+	require.True(t, bounds.Contains(0, -175))
+	require.False(t, bounds.Contains(0, 0))
+	require.False(t, bounds.Contains(20, 175))
+}
@@ -0,0 +1,129 @@
+package demand
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultPrecisionDegrees is the size of the grid query positions are snapped to when none is
+// configured, roughly a kilometer.
+const DefaultPrecisionDegrees = 0.01
+
+// Query is a closest sucursal search, with its position already quantized.
+type Query struct {
+	Time       time.Time
+	Latitude   float64
+	Longitude  float64
+	SucursalID string
+}
+
+// Store saves queries and reads them back by time.
+type Store interface {
+	Put(queries []Query) error
+	// Range returns the queries made from the from time, inclusive, to the to time, exclusive.
+	Range(from time.Time, to time.Time) ([]Query, error)
+}
+
+// Cell is a square of a heatmap, located by its center.
+type Cell struct {
+	Latitude  float64
+	Longitude float64
+	Count     int
+}
+
+// SucursalCount is the number of queries that resolved to a sucursal.
+type SucursalCount struct {
+	SucursalID string
+	Count      int
+}
+
+// Quantize snaps a position to the center of its square of a grid of precisionDegrees, so that
+// individual positions are not stored.
+func Quantize(latitude float64, longitude float64, precisionDegrees float64) (float64, float64) {
+	return snap(latitude, precisionDegrees), snap(longitude, precisionDegrees)
+}
+
+func snap(value float64, precisionDegrees float64) float64 {
+	center := (math.Floor(value/precisionDegrees) + 0.5) * precisionDegrees
+	// Drop the floating point noise of the multiplication, the positions are far coarser.
+	return math.Round(center*1e9) / 1e9
+}
+
+// Heatmap counts the queries in every square of a grid of cellDegrees, most queried first. The cell
+// size should be a multiple of the precision the queries were quantized with.
+func Heatmap(queries []Query, cellDegrees float64) []Cell {
+	type key struct{ row, column int64 }
+	counts := map[key]int{}
+	for _, query := range queries {
+		counts[key{int64(math.Floor(query.Latitude / cellDegrees)), int64(math.Floor(query.Longitude / cellDegrees))}]++
+	}
+	cells := make([]Cell, 0, len(counts))
+	for cell, count := range counts {
+		cells = append(cells, Cell{
+			Latitude:  snap((float64(cell.row)+0.5)*cellDegrees, cellDegrees),
+			Longitude: snap((float64(cell.column)+0.5)*cellDegrees, cellDegrees),
+			Count:     count,
+		})
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Count != cells[j].Count {
+			return cells[i].Count > cells[j].Count
+		}
+		if cells[i].Latitude != cells[j].Latitude {
+			return cells[i].Latitude < cells[j].Latitude
+		}
+		return cells[i].Longitude < cells[j].Longitude
+	})
+	return cells
+}
+
+// CountBySucursal counts the queries that resolved to each sucursal, most queried first.
+func CountBySucursal(queries []Query) []SucursalCount {
+	counts := map[string]int{}
+	for _, query := range queries {
+		counts[query.SucursalID]++
+	}
+	result := make([]SucursalCount, 0, len(counts))
+	for id, count := range counts {
+		result = append(result, SucursalCount{SucursalID: id, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].SucursalID < result[j].SucursalID
+	})
+	return result
+}
+
+// MemoryStore keeps the queries in memory, for tests and single instance deployments.
+type MemoryStore struct {
+	mutex   sync.Mutex
+	queries []Query
+}
+
+// NewMemoryStore creates an empty store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (store *MemoryStore) Put(queries []Query) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.queries = append(store.queries, queries...)
+	return nil
+}
+
+func (store *MemoryStore) Range(from time.Time, to time.Time) ([]Query, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	result := []Query{}
+	for _, query := range store.queries {
+		if !query.Time.Before(from) && query.Time.Before(to) {
+			result = append(result, query)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,54 @@
+package demand
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuantizeSnapsToCellCenter(t *testing.T) {
+	latitude, longitude := Quantize(-34.604258, -58.375094, 0.01)
+	require.Equal(t, -34.605, latitude)
+	require.Equal(t, -58.375, longitude)
+	latitude, longitude = Quantize(-34.6001, -58.3799, 0.01)
+	require.Equal(t, -34.605, latitude)
+	require.Equal(t, -58.375, longitude)
+}
+
+func TestHeatmapCountsQueriesPerCellMostQueriedFirst(t *testing.T) {
+	queries := []Query{
+		{Latitude: -34.605, Longitude: -58.375},
+		{Latitude: -34.615, Longitude: -58.365},
+		{Latitude: -34.605, Longitude: -58.365},
+		{Latitude: -31.415, Longitude: -64.185},
+	}
+	require.Equal(t, []Cell{
+		{Latitude: -34.61, Longitude: -58.37, Count: 3},
+		{Latitude: -31.41, Longitude: -64.19, Count: 1},
+	}, Heatmap(queries, 0.02))
+	require.Equal(t, []Cell{
+		{Latitude: -34.615, Longitude: -58.365, Count: 1},
+		{Latitude: -34.605, Longitude: -58.375, Count: 1},
+		{Latitude: -34.605, Longitude: -58.365, Count: 1},
+		{Latitude: -31.415, Longitude: -64.185, Count: 1},
+	}, Heatmap(queries, 0.01))
+}
+
+func TestCountBySucursalMostQueriedFirst(t *testing.T) {
+	queries := []Query{{SucursalID: "b"}, {SucursalID: "a"}, {SucursalID: "c"}, {SucursalID: "c"}}
+	require.Equal(t, []SucursalCount{{"c", 2}, {"a", 1}, {"b", 1}}, CountBySucursal(queries))
+}
+
+func TestMemoryStoreRangeIncludesFromAndExcludesTo(t *testing.T) {
+	from := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	require.NoError(t, store.Put([]Query{
+		{Time: from.Add(-time.Second), SucursalID: "before"},
+		{Time: from, SucursalID: "from"},
+		{Time: from.Add(time.Hour), SucursalID: "to"},
+	}))
+	queries, err := store.Range(from, from.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []Query{{Time: from, SucursalID: "from"}}, queries)
+}
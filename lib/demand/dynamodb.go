@@ -0,0 +1,146 @@
+package demand
+
+import (
+	"strings"
+	"time"
+
+	documents "github.com/NJRodriguez/shiny-waddle/lib/aws/dynamodb"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	dayLayout = "2006-01-02"
+	// Fixed width, so that the ids of an item sort by time.
+	timeLayout = "2006-01-02T15:04:05.000000000Z"
+)
+
+var newAwsSession = session.NewSession
+
+// item is a query as stored in DynamoDB. Items are partitioned by day and sorted by time, followed by
+// a random suffix so that queries made at the same time don't overwrite each other.
+type item struct {
+	Day        string  `dynamodbav:"day"`
+	ID         string  `dynamodbav:"id"`
+	Latitude   float64 `dynamodbav:"latitude"`
+	Longitude  float64 `dynamodbav:"longitude"`
+	SucursalID string  `dynamodbav:"sucursalId"`
+	// Unix time when DynamoDB deletes the item, if the table has TTL enabled.
+	ExpiresAt int64 `dynamodbav:"expiresAt,omitempty"`
+}
+
+// DynamoDBStore saves queries in a DynamoDB table with a day hash key and an id range key.
+type DynamoDBStore struct {
+	client    dynamodbiface.DynamoDBAPI
+	table     string
+	retention time.Duration
+	backoff   time.Duration
+}
+
+// NewDynamoDBStore creates a store in the table. Queries expire after retention, if positive.
+func NewDynamoDBStore(table string, awsRegion string, retention time.Duration) (*DynamoDBStore, error) {
+	session, err := newAwsSession(&aws.Config{Region: aws.String(awsRegion)})
+	if err != nil {
+		return nil, errors.Wrap(err, "starting new aws sessions")
+	}
+	return newDynamoDBStore(dynamodb.New(session), table, retention), nil
+}
+
+func newDynamoDBStore(client dynamodbiface.DynamoDBAPI, table string, retention time.Duration) *DynamoDBStore {
+	return &DynamoDBStore{client: client, table: table, retention: retention, backoff: 100 * time.Millisecond}
+}
+
+func (store *DynamoDBStore) Put(queries []Query) error {
+	for start := 0; start < len(queries); start += documents.MaxBatchWriteItems {
+		end := start + documents.MaxBatchWriteItems
+		if end > len(queries) {
+			end = len(queries)
+		}
+		items := make([]map[string]*dynamodb.AttributeValue, 0, end-start)
+		for _, query := range queries[start:end] {
+			marshaled, err := dynamodbattribute.MarshalMap(store.item(query))
+			if err != nil {
+				return errors.Wrap(err, "marshalling demand query to dynamodb readable")
+			}
+			items = append(items, marshaled)
+		}
+		if err := documents.WriteBatch(documents.TableBatchPut(store.client, store.table), items, store.backoff); err != nil {
+			return errors.Wrap(err, "writing demand queries to dynamodb")
+		}
+	}
+	return nil
+}
+
+func (store *DynamoDBStore) Range(from time.Time, to time.Time) ([]Query, error) {
+	from = from.UTC()
+	to = to.UTC()
+	queries := []Query{}
+	for day := from.Truncate(24 * time.Hour); day.Before(to); day = day.Add(24 * time.Hour) {
+		var exclusiveStartKey map[string]*dynamodb.AttributeValue
+		for {
+			result, err := store.client.Query(&dynamodb.QueryInput{
+				TableName:                aws.String(store.table),
+				KeyConditionExpression:   aws.String("#day = :day AND id BETWEEN :from AND :to"),
+				ExpressionAttributeNames: map[string]*string{"#day": aws.String("day")},
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":day":  {S: aws.String(day.Format(dayLayout))},
+					":from": {S: aws.String(from.Format(timeLayout))},
+					// Ids start with the time and have a suffix, so those made exactly at the to time sort after it.
+					":to": {S: aws.String(to.Format(timeLayout))},
+				},
+				ExclusiveStartKey: exclusiveStartKey,
+			})
+			if err != nil {
+				return nil, errors.Wrap(err, "querying demand queries from dynamodb")
+			}
+			for _, marshaled := range result.Items {
+				query, err := unmarshalQuery(marshaled)
+				if err != nil {
+					return nil, err
+				}
+				queries = append(queries, query)
+			}
+			if len(result.LastEvaluatedKey) == 0 {
+				break
+			}
+			exclusiveStartKey = result.LastEvaluatedKey
+		}
+	}
+	return queries, nil
+}
+
+func (store *DynamoDBStore) item(query Query) item {
+	queryTime := query.Time.UTC()
+	stored := item{
+		Day:        queryTime.Format(dayLayout),
+		ID:         queryTime.Format(timeLayout) + "#" + uuid.NewV4().String(),
+		Latitude:   query.Latitude,
+		Longitude:  query.Longitude,
+		SucursalID: query.SucursalID,
+	}
+	if store.retention > 0 {
+		stored.ExpiresAt = queryTime.Add(store.retention).Unix()
+	}
+	return stored
+}
+
+func unmarshalQuery(marshaled map[string]*dynamodb.AttributeValue) (Query, error) {
+	stored := item{}
+	if err := dynamodbattribute.UnmarshalMap(marshaled, &stored); err != nil {
+		return Query{}, errors.Wrap(err, "unmarshalling demand query")
+	}
+	separator := strings.Index(stored.ID, "#")
+	if separator < 0 {
+		return Query{}, errors.Errorf("demand query id %s has no time", stored.ID)
+	}
+	queryTime, err := time.Parse(timeLayout, stored.ID[:separator])
+	if err != nil {
+		return Query{}, errors.Wrapf(err, "parsing time of demand query %s", stored.ID)
+	}
+	return Query{Time: queryTime, Latitude: stored.Latitude, Longitude: stored.Longitude, SucursalID: stored.SucursalID}, nil
+}
@@ -0,0 +1,78 @@
+package demand
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDynamoDB keeps the items of a day/id table in memory. The first batch write leaves its last
+// item unprocessed and queries return a single item per page.
+type fakeDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	items       []map[string]*dynamodb.AttributeValue
+	batchWrites int
+}
+
+func (fake *fakeDynamoDB) BatchWriteItem(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	fake.batchWrites++
+	output := &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]*dynamodb.WriteRequest{}}
+	for table, requests := range input.RequestItems {
+		if fake.batchWrites == 1 {
+			output.UnprocessedItems[table] = requests[len(requests)-1:]
+			requests = requests[:len(requests)-1]
+		}
+		for _, request := range requests {
+			fake.items = append(fake.items, request.PutRequest.Item)
+		}
+	}
+	return output, nil
+}
+
+func (fake *fakeDynamoDB) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	values := input.ExpressionAttributeValues
+	matching := []map[string]*dynamodb.AttributeValue{}
+	for _, stored := range fake.items {
+		id := *stored["id"].S
+		if *stored["day"].S == *values[":day"].S && id >= *values[":from"].S && id <= *values[":to"].S {
+			matching = append(matching, stored)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool { return *matching[i]["id"].S < *matching[j]["id"].S })
+	for len(matching) > 0 && input.ExclusiveStartKey != nil && *matching[0]["id"].S <= *input.ExclusiveStartKey["id"].S {
+		matching = matching[1:]
+	}
+	if len(matching) == 0 {
+		return &dynamodb.QueryOutput{}, nil
+	}
+	return &dynamodb.QueryOutput{
+		Items:            matching[:1],
+		LastEvaluatedKey: map[string]*dynamodb.AttributeValue{"day": matching[0]["day"], "id": matching[0]["id"]},
+	}, nil
+}
+
+func TestDynamoDBStoreReadsBackQueriesAcrossDays(t *testing.T) {
+	fake := &fakeDynamoDB{}
+	store := newDynamoDBStore(fake, "demand_table", 24*time.Hour)
+	store.backoff = 0
+	midnight := time.Date(2020, 10, 2, 0, 0, 0, 0, time.UTC)
+	queries := []Query{
+		{Time: midnight.Add(-time.Hour), Latitude: -34.605, Longitude: -58.375, SucursalID: "a"},
+		{Time: midnight, Latitude: -34.605, Longitude: -58.375, SucursalID: "b"},
+		{Time: midnight.Add(time.Hour), Latitude: -31.415, Longitude: -64.185, SucursalID: "c"},
+		{Time: midnight.Add(2 * time.Hour), Latitude: -31.415, Longitude: -64.185, SucursalID: "d"},
+	}
+	require.NoError(t, store.Put(queries))
+	require.Equal(t, 2, fake.batchWrites)
+	require.Len(t, fake.items, 4)
+	require.Equal(t, aws.String("1601683200"), fake.items[1]["expiresAt"].N)
+
+	read, err := store.Range(midnight.Add(-time.Hour), midnight.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, queries[:3], read)
+}
@@ -0,0 +1,129 @@
+package demand
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// RecorderStats holds the counters of a Recorder.
+type RecorderStats struct {
+	// Queries written to the store.
+	Recorded uint64 `json:"recorded"`
+	// Queries discarded because the buffer was full.
+	Dropped uint64 `json:"dropped"`
+	// Queries discarded because the store failed to save them.
+	Failed uint64 `json:"failed"`
+	// Queries waiting in the buffer.
+	Pending int `json:"pending"`
+}
+
+// Recorder buffers queries in memory and writes them to a store in batches from Run, so that
+// recording a query never waits for the store. Queries are dropped when the buffer is full.
+type Recorder struct {
+	// Most queries written to the store at once.
+	BatchSize int
+	// Longest time a query waits in the buffer before it is written.
+	FlushInterval time.Duration
+	// Size of the grid positions are snapped to. Must be set before recording.
+	PrecisionDegrees float64
+
+	store   Store
+	queries chan Query
+	now     func() time.Time
+
+	mutex    sync.Mutex
+	recorded uint64
+	dropped  uint64
+	failed   uint64
+}
+
+// NewRecorder creates a recorder buffering up to bufferSize queries for the store.
+func NewRecorder(store Store, bufferSize int) *Recorder {
+	return &Recorder{
+		BatchSize:        100,
+		FlushInterval:    5 * time.Second,
+		PrecisionDegrees: DefaultPrecisionDegrees,
+		store:            store,
+		queries:          make(chan Query, bufferSize),
+		now:              time.Now,
+	}
+}
+
+// Record quantizes the position and queues the query to be written. It never blocks and returns false
+// when the query was dropped.
+func (recorder *Recorder) Record(latitude float64, longitude float64, sucursalID string) bool {
+	latitude, longitude = Quantize(latitude, longitude, recorder.PrecisionDegrees)
+	select {
+	case recorder.queries <- Query{Time: recorder.now().UTC(), Latitude: latitude, Longitude: longitude, SucursalID: sucursalID}:
+		return true
+	default:
+		recorder.mutex.Lock()
+		recorder.dropped++
+		recorder.mutex.Unlock()
+		return false
+	}
+}
+
+// Run writes the queued queries every FlushInterval, or as soon as a batch is full, until stop is
+// closed. The queries still queued are written before returning.
+func (recorder *Recorder) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(recorder.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]Query, 0, recorder.BatchSize)
+	for {
+		select {
+		case query := <-recorder.queries:
+			batch = append(batch, query)
+			if len(batch) >= recorder.BatchSize {
+				batch = recorder.flush(batch)
+			}
+		case <-ticker.C:
+			batch = recorder.flush(batch)
+		case <-stop:
+			for {
+				select {
+				case query := <-recorder.queries:
+					batch = append(batch, query)
+					if len(batch) >= recorder.BatchSize {
+						batch = recorder.flush(batch)
+					}
+				default:
+					recorder.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// Stats returns the counters of the recorder.
+func (recorder *Recorder) Stats() RecorderStats {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	return RecorderStats{
+		Recorded: recorder.recorded,
+		Dropped:  recorder.dropped,
+		Failed:   recorder.failed,
+		Pending:  len(recorder.queries),
+	}
+}
+
+// flush writes the batch and returns it emptied. Failed batches are logged and discarded.
+func (recorder *Recorder) flush(batch []Query) []Query {
+	if len(batch) == 0 {
+		return batch
+	}
+	err := recorder.store.Put(batch)
+	recorder.mutex.Lock()
+	if err != nil {
+		recorder.failed += uint64(len(batch))
+	} else {
+		recorder.recorded += uint64(len(batch))
+	}
+	recorder.mutex.Unlock()
+	if err != nil {
+		log.Printf("Error when trying to record %d demand queries: %s", len(batch), err)
+	}
+	return batch[:0]
+}
@@ -0,0 +1,76 @@
+package demand
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type failingStore struct{}
+
+func (failingStore) Put(queries []Query) error {
+	return errors.New("write failure")
+}
+
+func (failingStore) Range(from time.Time, to time.Time) ([]Query, error) {
+	return nil, errors.New("read failure")
+}
+
+func TestRecorderDropsQueriesWhenBufferIsFullWithoutBlocking(t *testing.T) {
+	recorder := NewRecorder(NewMemoryStore(), 2)
+	require.True(t, recorder.Record(-34.6, -58.3, "a"))
+	require.True(t, recorder.Record(-34.6, -58.3, "b"))
+	require.False(t, recorder.Record(-34.6, -58.3, "c"))
+	require.Equal(t, RecorderStats{Dropped: 1, Pending: 2}, recorder.Stats())
+}
+
+func TestRecorderWritesQuantizedQueriesInBatchesAndFlushesOnStop(t *testing.T) {
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	recorder := NewRecorder(store, 10)
+	recorder.BatchSize = 2
+	recorder.FlushInterval = time.Hour
+	recorder.now = func() time.Time { return now }
+	for _, id := range []string{"a", "b", "c"} {
+		require.True(t, recorder.Record(-34.604258, -58.375094, id))
+	}
+	stop := make(chan struct{})
+	close(stop)
+	recorder.Run(stop)
+
+	queries, err := store.Range(now, now.Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, []Query{
+		{Time: now, Latitude: -34.605, Longitude: -58.375, SucursalID: "a"},
+		{Time: now, Latitude: -34.605, Longitude: -58.375, SucursalID: "b"},
+		{Time: now, Latitude: -34.605, Longitude: -58.375, SucursalID: "c"},
+	}, queries)
+	require.Equal(t, RecorderStats{Recorded: 3}, recorder.Stats())
+}
+
+func TestRecorderFlushesEveryInterval(t *testing.T) {
+	store := NewMemoryStore()
+	recorder := NewRecorder(store, 10)
+	recorder.FlushInterval = 10 * time.Millisecond
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		recorder.Run(stop)
+		close(done)
+	}()
+	require.True(t, recorder.Record(-34.6, -58.3, "a"))
+	require.Eventually(t, func() bool { return recorder.Stats().Recorded == 1 }, time.Second, 5*time.Millisecond)
+	close(stop)
+	<-done
+}
+
+func TestRecorderCountsFailedWrites(t *testing.T) {
+	recorder := NewRecorder(failingStore{}, 10)
+	require.True(t, recorder.Record(-34.6, -58.3, "a"))
+	stop := make(chan struct{})
+	close(stop)
+	recorder.Run(stop)
+	require.Equal(t, RecorderStats{Failed: 1}, recorder.Stats())
+}
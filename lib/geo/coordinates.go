@@ -0,0 +1,256 @@
+package geo
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInvalidCoordinate is returned when a coordinate can't be parsed.
+	ErrInvalidCoordinate = errors.New("invalid coordinate")
+	// ErrCoordinateOutOfRange is returned when a parsed coordinate is out of its valid range.
+	ErrCoordinateOutOfRange = errors.New("coordinate out of range")
+
+	numberPattern = regexp.MustCompile(`[0-9]+(?:\.[0-9]*)?|\.[0-9]+`)
+	// Characters allowed between the numbers of a DMS or DDM coordinate.
+	separatorPattern = regexp.MustCompile(`^[\s°º˚:'’′"”″]*$`)
+)
+
+// ParseLatitude parses a latitude in decimal degrees (-34.604), degrees minutes seconds (34°36'15"S)
+// or degrees decimal minutes (34 36.25 S) into decimal degrees.
+func ParseLatitude(value string) (float64, error) {
+	latitude, err := parseAxis(value, "NS")
+	if err != nil {
+		return 0, err
+	}
+	if latitude < -90 || latitude > 90 {
+		return 0, ErrCoordinateOutOfRange
+	}
+	return latitude, nil
+}
+
+// ParseLongitude parses a longitude in the same formats as ParseLatitude. West can be written
+// either as W or as O (oeste).
+func ParseLongitude(value string) (float64, error) {
+	longitude, err := parseAxis(value, "EWO")
+	if err != nil {
+		return 0, err
+	}
+	if longitude < -180 || longitude > 180 {
+		return 0, ErrCoordinateOutOfRange
+	}
+	return longitude, nil
+}
+
+func parseAxis(value string, hemispheres string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if decimal, err := strconv.ParseFloat(value, 64); err == nil {
+		if math.IsNaN(decimal) || math.IsInf(decimal, 0) {
+			return 0, ErrInvalidCoordinate
+		}
+		return decimal, nil
+	}
+	if value == "" {
+		return 0, ErrInvalidCoordinate
+	}
+	sign := 1.0
+	switch value[0] {
+	case '-':
+		sign = -1
+		value = value[1:]
+	case '+':
+		value = value[1:]
+	}
+	var hemisphere rune
+	if first, size := utf8.DecodeRuneInString(value); strings.ContainsRune(hemispheres, unicode.ToUpper(first)) {
+		hemisphere, value = unicode.ToUpper(first), value[size:]
+	} else if last, size := utf8.DecodeLastRuneInString(value); strings.ContainsRune(hemispheres, unicode.ToUpper(last)) {
+		hemisphere, value = unicode.ToUpper(last), value[:len(value)-size]
+	}
+	if hemisphere != 0 && sign < 0 {
+		return 0, ErrInvalidCoordinate
+	}
+	if hemisphere == 'S' || hemisphere == 'W' || hemisphere == 'O' {
+		sign = -1
+	}
+	numbers := numberPattern.FindAllString(value, -1)
+	if len(numbers) == 0 || len(numbers) > 3 || !separatorPattern.MatchString(numberPattern.ReplaceAllString(value, "")) {
+		return 0, ErrInvalidCoordinate
+	}
+	parts := make([]float64, 3)
+	for i, number := range numbers {
+		parsed, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0, ErrInvalidCoordinate
+		}
+		// Only the last component may have decimals and minutes and seconds must be below 60.
+		if (i < len(numbers)-1 && parsed != float64(int64(parsed))) || (i > 0 && parsed >= 60) {
+			return 0, ErrInvalidCoordinate
+		}
+		parts[i] = parsed
+	}
+	return sign * (parts[0] + parts[1]/60 + parts[2]/3600), nil
+}
+
+// ParsePosition parses a position written in one of the following formats and converts it to WGS84
+// latitude and longitude in decimal degrees:
+//
+//	-34.6037,-58.3816              latitude and longitude in decimal degrees, DMS or DDM
+//	34°36'13"S 58°22'54"W          same as above separated by spaces when using hemispheres
+//	48Q39JW9+G9                    Open Location Code (plus code), full codes only
+//	geohash:69y7pkxfd              geohash
+//	utm:21H 373318 6170034         UTM with zone number and latitude band
+//	utm:21 S 373318 6170034        UTM with zone number and N/S hemisphere
+//	epsg:32721:373318,6170034      UTM, Web Mercator (3857) or POSGAR Gauss-Krüger zones
+//	webmercator:-6499009,-4110158  Web Mercator meters
+//	posgar:5:5648457,6170144       POSGAR Gauss-Krüger zone followed by easting and northing
+func ParsePosition(value string) (float64, float64, error) {
+	value = strings.TrimSpace(value)
+	scheme, rest := "", value
+	if i := strings.Index(value, ":"); i > 0 && isScheme(value[:i]) {
+		scheme, rest = strings.ToLower(value[:i]), strings.TrimSpace(value[i+1:])
+	}
+	switch scheme {
+	case "geohash":
+		return DecodeGeohash(rest)
+	case "olc", "pluscode":
+		return DecodePlusCode(rest)
+	case "utm":
+		return parseUTM(rest)
+	case "webmercator":
+		x, y, err := parseXY(rest)
+		if err != nil {
+			return 0, 0, err
+		}
+		latitude, longitude := FromWebMercator(x, y)
+		return latitude, longitude, nil
+	case "posgar", "gk":
+		parts := strings.SplitN(rest, ":", 2)
+		if len(parts) != 2 {
+			return 0, 0, ErrInvalidCoordinate
+		}
+		zone, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return 0, 0, ErrInvalidCoordinate
+		}
+		x, y, err := parseXY(parts[1])
+		if err != nil {
+			return 0, 0, err
+		}
+		return FromPosgar(zone, x, y)
+	case "epsg":
+		parts := strings.SplitN(rest, ":", 2)
+		if len(parts) != 2 {
+			return 0, 0, ErrInvalidCoordinate
+		}
+		code, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return 0, 0, ErrInvalidCoordinate
+		}
+		x, y, err := parseXY(parts[1])
+		if err != nil {
+			return 0, 0, err
+		}
+		return FromEPSG(code, x, y)
+	}
+	if strings.Contains(value, "+") && !strings.ContainsAny(value, ", ") {
+		return DecodePlusCode(value)
+	}
+	return parseLatLonPair(value)
+}
+
+func isScheme(value string) bool {
+	switch strings.ToLower(value) {
+	case "geohash", "olc", "pluscode", "utm", "webmercator", "posgar", "gk", "epsg":
+		return true
+	}
+	return false
+}
+
+// parseLatLonPair parses "latitude,longitude" or, when both have hemispheres, "latitude longitude".
+func parseLatLonPair(value string) (float64, float64, error) {
+	var latitude, longitude string
+	if parts := strings.Split(value, ","); len(parts) == 2 {
+		latitude, longitude = parts[0], parts[1]
+	} else {
+		upper := strings.ToUpper(value)
+		if strings.IndexAny(upper, "NS") == 0 {
+			// N34 36 15 W58 22 54: the longitude starts at its hemisphere.
+			i := strings.IndexAny(upper, "EWO")
+			if i < 0 {
+				return 0, 0, ErrInvalidCoordinate
+			}
+			latitude, longitude = value[:i], value[i:]
+		} else {
+			// 34 36 15 S 58 22 54 W: the latitude ends at its hemisphere.
+			i := strings.IndexAny(upper, "NS")
+			if i < 0 {
+				return 0, 0, ErrInvalidCoordinate
+			}
+			latitude, longitude = value[:i+1], value[i+1:]
+		}
+	}
+	lat, err := ParseLatitude(latitude)
+	if err != nil {
+		return 0, 0, err
+	}
+	lon, err := ParseLongitude(longitude)
+	if err != nil {
+		return 0, 0, err
+	}
+	return lat, lon, nil
+}
+
+func parseXY(value string) (float64, float64, error) {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	if len(fields) != 2 {
+		return 0, 0, ErrInvalidCoordinate
+	}
+	x, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, 0, ErrInvalidCoordinate
+	}
+	y, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return 0, 0, ErrInvalidCoordinate
+	}
+	return x, y, nil
+}
+
+// parseUTM parses "21H 373317 6170187" or "21 S 373317 6170187". A letter right after the zone is
+// read as the latitude band, where C to M are south and N to X are north, as in 33S for Spain. A
+// separate N or S is read as the hemisphere.
+func parseUTM(value string) (float64, float64, error) {
+	fields := strings.Fields(strings.ToUpper(strings.ReplaceAll(value, ",", " ")))
+	var zoneField string
+	var south bool
+	switch {
+	case len(fields) == 4 && (fields[1] == "N" || fields[1] == "S"):
+		zoneField, south = fields[0], fields[1] == "S"
+	case len(fields) == 3 && len(fields[0]) >= 2:
+		band := fields[0][len(fields[0])-1]
+		if band < 'C' || band > 'X' || band == 'I' || band == 'O' {
+			return 0, 0, ErrInvalidCoordinate
+		}
+		zoneField, south = fields[0][:len(fields[0])-1], band < 'N'
+	default:
+		return 0, 0, ErrInvalidCoordinate
+	}
+	zone, err := strconv.Atoi(zoneField)
+	if err != nil {
+		return 0, 0, ErrInvalidCoordinate
+	}
+	x, y, err := parseXY(fields[len(fields)-2] + " " + fields[len(fields)-1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return FromUTM(zone, south, x, y)
+}
@@ -0,0 +1,89 @@
+package geo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Obelisco de Buenos Aires.
+const (
+	testLatitude  = -34.603722
+	testLongitude = -58.381592
+)
+
+func TestParseLatitudeFormats(t *testing.T) {
+	// ſ uppercases to S, with a different length in UTF-8.
+	for _, value := range []string{"-34.603722", `34°36'13.4"S`, "34°36′13.4″S", "S 34 36 13.4", "34 36.22332 S", "-34:36:13.4", "34 36.22332 ſ"} {
+		latitude, err := ParseLatitude(value)
+		require.NoError(t, err, value)
+		require.InDelta(t, testLatitude, latitude, 0.00001, value)
+	}
+	for _, value := range []string{"invalid", "NaN", "34°36'13\"W", "34°61'S", "-34°36'S"} {
+		_, err := ParseLatitude(value)
+		require.Error(t, err, value)
+	}
+	_, err := ParseLatitude("91°N")
+	require.Equal(t, ErrCoordinateOutOfRange, err)
+}
+
+func TestParsePositionFormats(t *testing.T) {
+	for _, value := range []string{
+		"-34.603722,-58.381592",
+		`34°36'13.4"S 58°22'53.7"W`,
+		"S34 36.2233 O58 22.8955",
+		"utm:21H 373318.27 6170033.74",
+		"utm:21 S 373318.27 6170033.74",
+		"epsg:32721:373318.27,6170033.74",
+		"posgar:5:5648457.30,6170143.80",
+		"posgar:5:648457.30,6170143.80",
+		"epsg:22185:5648457.30,6170143.80",
+		"webmercator:-6499009.09,-4110158.10",
+		"epsg:3857:-6499009.09,-4110158.10",
+	} {
+		latitude, longitude, err := ParsePosition(value)
+		require.NoError(t, err, value)
+		require.InDelta(t, testLatitude, latitude, 0.00001, value)
+		require.InDelta(t, testLongitude, longitude, 0.00001, value)
+	}
+
+	// Geohashes and plus codes resolve to the center of their cell.
+	latitude, longitude, err := ParsePosition("geohash:69y7pkxfd")
+	require.NoError(t, err)
+	require.InDelta(t, testLatitude, latitude, 0.0001)
+	require.InDelta(t, testLongitude, longitude, 0.0001)
+	latitude, longitude, err = ParsePosition("48Q39JW9+G9")
+	require.NoError(t, err)
+	require.InDelta(t, testLatitude, latitude, 0.0001)
+	require.InDelta(t, testLongitude, longitude, 0.0001)
+
+	// Latitude band S is north of the equator: 33S covers Sicily.
+	zone, _, easting, northing := ToUTM(38.1157, 13.3615)
+	latitude, longitude, err = ParsePosition(fmt.Sprintf("utm:%dS %f %f", zone, easting, northing))
+	require.NoError(t, err)
+	require.InDelta(t, 38.1157, latitude, 0.00001)
+	require.InDelta(t, 13.3615, longitude, 0.00001)
+
+	for _, value := range []string{"", "geohash:aaaa", "9G8F+6X", "utm:99H 1 1", "utm:21 H 1 1", "utm:21I 1 1", "epsg:4326:1,1", "posgar:9:1,1"} {
+		_, _, err := ParsePosition(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestProjectionsRoundTrip(t *testing.T) {
+	zone, south, easting, northing := ToUTM(testLatitude, testLongitude)
+	require.Equal(t, 21, zone)
+	require.True(t, south)
+	latitude, longitude, err := FromUTM(zone, south, easting, northing)
+	require.NoError(t, err)
+	require.InDelta(t, testLatitude, latitude, 1e-8)
+	require.InDelta(t, testLongitude, longitude, 1e-8)
+
+	// The equator at the central meridian of zone 31 is the UTM false origin.
+	_, _, easting, northing = ToUTM(0, 3)
+	require.InDelta(t, 500000, easting, 1e-6)
+	require.InDelta(t, 0, northing, 1e-6)
+
+	require.Equal(t, "69y7pkxfd", EncodeGeohash(testLatitude, testLongitude, 9))
+}
@@ -0,0 +1,68 @@
+package geo
+
+import "math"
+
+// Distance returns the great-circle distance in kilometers between two points given in decimal degrees.
+func Distance(lat1 float64, lon1 float64, lat2 float64, lon2 float64) float64 {
+	radlat1 := math.Pi * lat1 / 180
+	radlat2 := math.Pi * lat2 / 180
+
+	theta := lon1 - lon2
+	radtheta := math.Pi * theta / 180
+
+	dist := math.Sin(radlat1)*math.Sin(radlat2) + math.Cos(radlat1)*math.Cos(radlat2)*math.Cos(radtheta)
+
+	if dist > 1 {
+		dist = 1
+	}
+
+	dist = math.Acos(dist)
+	dist = dist * 180 / math.Pi
+	dist = dist * 60 * 1.1515
+	dist = dist * 1.609344
+
+	return dist
+}
+
+// PointInRing reports whether the point is inside a closed ring of [longitude, latitude] pairs,
+// following the GeoJSON coordinate order. Uses the even-odd ray casting rule.
+func PointInRing(lat float64, lon float64, ring [][]float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lon < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// PointInPolygon reports whether the point is inside a GeoJSON polygon. The first ring is the
+// exterior boundary and any following rings are holes.
+func PointInPolygon(lat float64, lon float64, polygon [][][]float64) bool {
+	if len(polygon) == 0 || !PointInRing(lat, lon, polygon[0]) {
+		return false
+	}
+	for _, hole := range polygon[1:] {
+		if PointInRing(lat, lon, hole) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsClosedRing reports whether the ring has at least four positions and its first and last
+// positions are equal, as required by GeoJSON.
+func IsClosedRing(ring [][]float64) bool {
+	if len(ring) < 4 {
+		return false
+	}
+	for _, position := range ring {
+		if len(position) < 2 {
+			return false
+		}
+	}
+	first, last := ring[0], ring[len(ring)-1]
+	return first[0] == last[0] && first[1] == last[1]
+}
@@ -0,0 +1,78 @@
+package geo
+
+import "strings"
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// DecodeGeohash returns the latitude and longitude at the center of the geohash cell.
+func DecodeGeohash(geohash string) (float64, float64, error) {
+	geohash = strings.ToLower(strings.TrimSpace(geohash))
+	if geohash == "" || len(geohash) > 22 {
+		return 0, 0, ErrInvalidCoordinate
+	}
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+	even := true
+	for _, char := range geohash {
+		value := strings.IndexRune(geohashAlphabet, char)
+		if value < 0 {
+			return 0, 0, ErrInvalidCoordinate
+		}
+		for bit := 4; bit >= 0; bit-- {
+			set := value&(1<<uint(bit)) != 0
+			if even {
+				middle := (minLon + maxLon) / 2
+				if set {
+					minLon = middle
+				} else {
+					maxLon = middle
+				}
+			} else {
+				middle := (minLat + maxLat) / 2
+				if set {
+					minLat = middle
+				} else {
+					maxLat = middle
+				}
+			}
+			even = !even
+		}
+	}
+	return (minLat + maxLat) / 2, (minLon + maxLon) / 2, nil
+}
+
+// EncodeGeohash returns the geohash of the position with the given number of characters.
+func EncodeGeohash(latitude float64, longitude float64, precision int) string {
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+	var geohash strings.Builder
+	even := true
+	value, bits := 0, 0
+	for geohash.Len() < precision {
+		value <<= 1
+		if even {
+			middle := (minLon + maxLon) / 2
+			if longitude >= middle {
+				value |= 1
+				minLon = middle
+			} else {
+				maxLon = middle
+			}
+		} else {
+			middle := (minLat + maxLat) / 2
+			if latitude >= middle {
+				value |= 1
+				minLat = middle
+			} else {
+				maxLat = middle
+			}
+		}
+		even = !even
+		bits++
+		if bits == 5 {
+			geohash.WriteByte(geohashAlphabet[value])
+			value, bits = 0, 0
+		}
+	}
+	return geohash.String()
+}
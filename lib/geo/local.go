@@ -0,0 +1,34 @@
+package geo
+
+import "math"
+
+// LocalProjection is an equirectangular projection in kilometers centered at an origin. Distances and
+// areas are accurate within a few hundred kilometers of the origin, which is enough for a region.
+type LocalProjection struct {
+	latitude  float64
+	longitude float64
+	// Kilometers per degree of latitude and of longitude at the origin.
+	kmPerLatitude  float64
+	kmPerLongitude float64
+}
+
+// NewLocalProjection creates a projection centered at the position.
+func NewLocalProjection(latitude float64, longitude float64) *LocalProjection {
+	kmPerLatitude := Distance(0, 0, 1, 0)
+	return &LocalProjection{
+		latitude:       latitude,
+		longitude:      longitude,
+		kmPerLatitude:  kmPerLatitude,
+		kmPerLongitude: kmPerLatitude * math.Cos(latitude*math.Pi/180),
+	}
+}
+
+// Forward returns the position in kilometers east and north of the origin.
+func (projection *LocalProjection) Forward(latitude float64, longitude float64) (float64, float64) {
+	return (longitude - projection.longitude) * projection.kmPerLongitude, (latitude - projection.latitude) * projection.kmPerLatitude
+}
+
+// Inverse converts kilometers east and north of the origin back to latitude and longitude.
+func (projection *LocalProjection) Inverse(x float64, y float64) (float64, float64) {
+	return projection.latitude + y/projection.kmPerLatitude, projection.longitude + x/projection.kmPerLongitude
+}
@@ -0,0 +1,65 @@
+package geo
+
+import "strings"
+
+const (
+	plusCodeAlphabet  = "23456789CFGHJMPQRVWX"
+	plusCodeSeparator = '+'
+	plusCodePadding   = '0'
+	// Position of the separator in full codes.
+	plusCodeSeparatorPosition = 8
+	// Number of characters encoded as latitude/longitude pairs, the rest use the 4x5 grid.
+	plusCodePairLength = 10
+	plusCodeGridRows   = 5
+	plusCodeGridCols   = 4
+)
+
+// DecodePlusCode returns the latitude and longitude at the center of the area of a full Open Location
+// Code (plus code) such as 48Q3CJ22+2M. Short codes are not supported since they need a reference location.
+func DecodePlusCode(code string) (float64, float64, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	separator := strings.IndexRune(code, plusCodeSeparator)
+	if separator != plusCodeSeparatorPosition || strings.Count(code, string(plusCodeSeparator)) != 1 {
+		return 0, 0, ErrInvalidCoordinate
+	}
+	digits := code[:separator] + code[separator+1:]
+	if padding := strings.IndexRune(digits, plusCodePadding); padding >= 0 {
+		// Padded codes such as 48Q30000+ only have full pairs followed by padding.
+		if padding%2 != 0 || strings.Trim(digits[padding:], string(plusCodePadding)) != "" {
+			return 0, 0, ErrInvalidCoordinate
+		}
+		digits = digits[:padding]
+	}
+	if len(digits) < 2 || (len(digits) < plusCodePairLength && len(digits)%2 != 0) {
+		return 0, 0, ErrInvalidCoordinate
+	}
+	latitude, longitude := -90.0, -180.0
+	latResolution, lonResolution := 400.0, 400.0
+	for i := 0; i < len(digits) && i < plusCodePairLength; i += 2 {
+		latDigit := strings.IndexByte(plusCodeAlphabet, digits[i])
+		lonDigit := strings.IndexByte(plusCodeAlphabet, digits[i+1])
+		if latDigit < 0 || lonDigit < 0 {
+			return 0, 0, ErrInvalidCoordinate
+		}
+		latResolution /= 20
+		lonResolution /= 20
+		latitude += float64(latDigit) * latResolution
+		longitude += float64(lonDigit) * lonResolution
+	}
+	for i := plusCodePairLength; i < len(digits); i++ {
+		digit := strings.IndexByte(plusCodeAlphabet, digits[i])
+		if digit < 0 {
+			return 0, 0, ErrInvalidCoordinate
+		}
+		latResolution /= plusCodeGridRows
+		lonResolution /= plusCodeGridCols
+		latitude += float64(digit/plusCodeGridCols) * latResolution
+		longitude += float64(digit%plusCodeGridCols) * lonResolution
+	}
+	latitude += latResolution / 2
+	longitude += lonResolution / 2
+	if latitude > 90 || longitude > 180 {
+		return 0, 0, ErrCoordinateOutOfRange
+	}
+	return latitude, longitude, nil
+}
@@ -0,0 +1,212 @@
+package geo
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// WGS84 semi-major axis in meters. POSGAR uses GRS80, which only differs from WGS84 by
+	// fractions of a millimeter.
+	wgs84A = 6378137.0
+	// WGS84 flattening.
+	wgs84F = 1 / 298.257223563
+	// Scale factor at the central meridian of UTM zones.
+	utmScale = 0.9996
+	// Number of POSGAR Gauss-Krüger zones covering Argentina.
+	posgarZones = 7
+)
+
+// ErrUnsupportedProjection is returned for reference systems that can't be converted to WGS84.
+var ErrUnsupportedProjection = errors.New("unsupported projection")
+
+// transverseMercator holds the parameters of a Transverse Mercator projection.
+type transverseMercator struct {
+	centralMeridian float64
+	originLatitude  float64
+	scale           float64
+	falseEasting    float64
+	falseNorthing   float64
+}
+
+// Coefficients of the Krüger series, accurate to well under a millimeter within a zone.
+var (
+	tmN     = wgs84F / (2 - wgs84F)
+	tmA     = wgs84A / (1 + tmN) * (1 + tmN*tmN/4 + tmN*tmN*tmN*tmN/64)
+	tmAlpha = [3]float64{
+		tmN/2 - 2*tmN*tmN/3 + 5*tmN*tmN*tmN/16,
+		13*tmN*tmN/48 - 3*tmN*tmN*tmN/5,
+		61 * tmN * tmN * tmN / 240,
+	}
+	tmBeta = [3]float64{
+		tmN/2 - 2*tmN*tmN/3 + 37*tmN*tmN*tmN/96,
+		tmN*tmN/48 + tmN*tmN*tmN/15,
+		17 * tmN * tmN * tmN / 480,
+	}
+	tmDelta = [3]float64{
+		2*tmN - 2*tmN*tmN/3 - 2*tmN*tmN*tmN,
+		7*tmN*tmN/3 - 8*tmN*tmN*tmN/5,
+		56 * tmN * tmN * tmN / 15,
+	}
+)
+
+// forward projects a WGS84 position to easting and northing in meters.
+func (projection *transverseMercator) forward(latitude float64, longitude float64) (float64, float64) {
+	xi, eta := tmForward(latitude*math.Pi/180, (longitude-projection.centralMeridian)*math.Pi/180)
+	xi0, _ := tmForward(projection.originLatitude*math.Pi/180, 0)
+	easting := projection.falseEasting + projection.scale*tmA*eta
+	northing := projection.falseNorthing + projection.scale*tmA*(xi-xi0)
+	return easting, northing
+}
+
+// inverse converts easting and northing in meters back to a WGS84 position.
+func (projection *transverseMercator) inverse(easting float64, northing float64) (float64, float64) {
+	xi0, _ := tmForward(projection.originLatitude*math.Pi/180, 0)
+	xi := (northing-projection.falseNorthing)/(projection.scale*tmA) + xi0
+	eta := (easting - projection.falseEasting) / (projection.scale * tmA)
+	xiPrime, etaPrime := xi, eta
+	for j := 1; j <= 3; j++ {
+		xiPrime -= tmBeta[j-1] * math.Sin(2*float64(j)*xi) * math.Cosh(2*float64(j)*eta)
+		etaPrime -= tmBeta[j-1] * math.Cos(2*float64(j)*xi) * math.Sinh(2*float64(j)*eta)
+	}
+	chi := math.Asin(math.Sin(xiPrime) / math.Cosh(etaPrime))
+	latitude := chi
+	for j := 1; j <= 3; j++ {
+		latitude += tmDelta[j-1] * math.Sin(2*float64(j)*chi)
+	}
+	longitude := math.Atan2(math.Sinh(etaPrime), math.Cos(xiPrime))
+	return latitude * 180 / math.Pi, projection.centralMeridian + longitude*180/math.Pi
+}
+
+// tmForward returns the Krüger series ξ and η of a position relative to the central meridian, in radians.
+func tmForward(latitude float64, deltaLongitude float64) (float64, float64) {
+	e := 2 * math.Sqrt(tmN) / (1 + tmN)
+	t := math.Sinh(math.Atanh(math.Sin(latitude)) - e*math.Atanh(e*math.Sin(latitude)))
+	xiPrime := math.Atan2(t, math.Cos(deltaLongitude))
+	etaPrime := math.Atanh(math.Sin(deltaLongitude) / math.Sqrt(1+t*t))
+	xi, eta := xiPrime, etaPrime
+	for j := 1; j <= 3; j++ {
+		xi += tmAlpha[j-1] * math.Sin(2*float64(j)*xiPrime) * math.Cosh(2*float64(j)*etaPrime)
+		eta += tmAlpha[j-1] * math.Cos(2*float64(j)*xiPrime) * math.Sinh(2*float64(j)*etaPrime)
+	}
+	return xi, eta
+}
+
+func utmProjection(zone int, south bool) (*transverseMercator, error) {
+	if zone < 1 || zone > 60 {
+		return nil, ErrInvalidCoordinate
+	}
+	projection := &transverseMercator{
+		centralMeridian: float64(zone*6 - 183),
+		scale:           utmScale,
+		falseEasting:    500000,
+	}
+	if south {
+		projection.falseNorthing = 10000000
+	}
+	return projection, nil
+}
+
+// posgarProjection returns the Gauss-Krüger projection of the POSGAR zones of Argentina. Zone 1 is
+// centered at 72°W and every following zone is 3° to the east. Eastings carry the zone number as
+// their millions digit.
+func posgarProjection(zone int) (*transverseMercator, error) {
+	if zone < 1 || zone > posgarZones {
+		return nil, ErrInvalidCoordinate
+	}
+	return &transverseMercator{
+		centralMeridian: float64(-72 + (zone-1)*3),
+		originLatitude:  -90,
+		scale:           1,
+		falseEasting:    float64(zone)*1000000 + 500000,
+	}, nil
+}
+
+// FromUTM converts UTM coordinates to WGS84 latitude and longitude.
+func FromUTM(zone int, south bool, easting float64, northing float64) (float64, float64, error) {
+	projection, err := utmProjection(zone, south)
+	if err != nil {
+		return 0, 0, err
+	}
+	latitude, longitude := projection.inverse(easting, northing)
+	return validProjected(latitude, longitude)
+}
+
+// ToUTM converts a WGS84 position to UTM coordinates in the zone that contains it.
+func ToUTM(latitude float64, longitude float64) (int, bool, float64, float64) {
+	zone := int(math.Floor((longitude+180)/6)) + 1
+	if zone > 60 {
+		zone = 60
+	}
+	projection, _ := utmProjection(zone, latitude < 0)
+	easting, northing := projection.forward(latitude, longitude)
+	return zone, latitude < 0, easting, northing
+}
+
+// FromPosgar converts POSGAR Gauss-Krüger coordinates of the given zone to WGS84 latitude and longitude.
+// Eastings without the zone prefix (below 1000000) are accepted too.
+func FromPosgar(zone int, easting float64, northing float64) (float64, float64, error) {
+	projection, err := posgarProjection(zone)
+	if err != nil {
+		return 0, 0, err
+	}
+	if easting < 1000000 {
+		easting += float64(zone) * 1000000
+	}
+	latitude, longitude := projection.inverse(easting, northing)
+	return validProjected(latitude, longitude)
+}
+
+// ToPosgar converts a WGS84 position to POSGAR Gauss-Krüger coordinates of the given zone.
+func ToPosgar(zone int, latitude float64, longitude float64) (float64, float64, error) {
+	projection, err := posgarProjection(zone)
+	if err != nil {
+		return 0, 0, err
+	}
+	easting, northing := projection.forward(latitude, longitude)
+	return easting, northing, nil
+}
+
+// FromWebMercator converts Web Mercator (EPSG:3857) meters to WGS84 latitude and longitude.
+func FromWebMercator(x float64, y float64) (float64, float64) {
+	longitude := x / wgs84A * 180 / math.Pi
+	latitude := (2*math.Atan(math.Exp(y/wgs84A)) - math.Pi/2) * 180 / math.Pi
+	return latitude, longitude
+}
+
+// ToWebMercator converts a WGS84 position to Web Mercator (EPSG:3857) meters.
+func ToWebMercator(latitude float64, longitude float64) (float64, float64) {
+	x := wgs84A * longitude * math.Pi / 180
+	y := wgs84A * math.Log(math.Tan(math.Pi/4+latitude*math.Pi/360))
+	return x, y
+}
+
+// FromEPSG converts projected coordinates of a supported EPSG reference system to WGS84 latitude and
+// longitude. Supported systems are Web Mercator (3857), UTM (326xx, 327xx) and the POSGAR 94 (22181-22187),
+// POSGAR 98 (22171-22177) and POSGAR 2007 (5343-5349) Gauss-Krüger zones.
+func FromEPSG(code int, x float64, y float64) (float64, float64, error) {
+	switch {
+	case code == 3857 || code == 900913:
+		latitude, longitude := FromWebMercator(x, y)
+		return validProjected(latitude, longitude)
+	case code > 32600 && code <= 32660:
+		return FromUTM(code-32600, false, x, y)
+	case code > 32700 && code <= 32760:
+		return FromUTM(code-32700, true, x, y)
+	case code > 22180 && code <= 22180+posgarZones:
+		return FromPosgar(code-22180, x, y)
+	case code > 22170 && code <= 22170+posgarZones:
+		return FromPosgar(code-22170, x, y)
+	case code >= 5343 && code < 5343+posgarZones:
+		return FromPosgar(code-5342, x, y)
+	}
+	return 0, 0, ErrUnsupportedProjection
+}
+
+func validProjected(latitude float64, longitude float64) (float64, float64, error) {
+	if math.IsNaN(latitude) || math.IsNaN(longitude) || latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return 0, 0, ErrCoordinateOutOfRange
+	}
+	return latitude, longitude, nil
+}
@@ -0,0 +1,42 @@
+package geo
+
+import "math"
+
+// MaxMercatorLatitude is the latitude where the Web Mercator projection is cut to make the world square.
+const MaxMercatorLatitude = 85.05112878
+
+// WorldPixel returns the position in pixels of the Web Mercator world map at the given zoom, where the
+// whole world is tileSize * 2^zoom pixels wide. The origin is the top left corner.
+func WorldPixel(latitude float64, longitude float64, zoom int, tileSize float64) (float64, float64) {
+	latitude = math.Max(math.Min(latitude, MaxMercatorLatitude), -MaxMercatorLatitude)
+	scale := tileSize * math.Exp2(float64(zoom))
+	x := (longitude + 180) / 360 * scale
+	sinLatitude := math.Sin(latitude * math.Pi / 180)
+	y := (0.5 - math.Log((1+sinLatitude)/(1-sinLatitude))/(4*math.Pi)) * scale
+	return x, y
+}
+
+// FromWorldPixel converts a Web Mercator world pixel at the given zoom back to latitude and longitude.
+func FromWorldPixel(x float64, y float64, zoom int, tileSize float64) (float64, float64) {
+	scale := tileSize * math.Exp2(float64(zoom))
+	longitude := x/scale*360 - 180
+	n := math.Pi - 2*math.Pi*y/scale
+	latitude := math.Atan(math.Sinh(n)) * 180 / math.Pi
+	return latitude, longitude
+}
+
+// TileBounds returns the south, west, north and east edges of a Web Mercator tile in degrees.
+func TileBounds(zoom int, x int, y int) (float64, float64, float64, float64) {
+	north, west := FromWorldPixel(float64(x), float64(y), zoom, 1)
+	south, east := FromWorldPixel(float64(x+1), float64(y+1), zoom, 1)
+	return south, west, north, east
+}
+
+// ValidTile reports whether the tile coordinates exist at the zoom level.
+func ValidTile(zoom int, x int, y int) bool {
+	if zoom < 0 || zoom > 30 {
+		return false
+	}
+	tiles := 1 << uint(zoom)
+	return x >= 0 && y >= 0 && x < tiles && y < tiles
+}
@@ -0,0 +1,274 @@
+package geocoding
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+	"github.com/pkg/errors"
+)
+
+// GazetteerProvider is the provider name reported by gazetteer results.
+const GazetteerProvider = "gazetteer"
+
+const (
+	// DefaultMinConfidence is the lowest confidence accepted by the gazetteer when none is configured.
+	DefaultMinConfidence = 0.5
+	// DefaultMaxReverseDistanceKm is the farthest an address can be from a location to be returned
+	// by ReverseGeocode when none is configured.
+	DefaultMaxReverseDistanceKm = 0.5
+	// gridCellDegrees is the size of the cells used to index entries by location.
+	gridCellDegrees = 0.01
+)
+
+// Entry is a known address and its location.
+type Entry struct {
+	Address    string
+	Components Address
+	Latitude   float64
+	Longitude  float64
+	tokens     []string
+}
+
+type gridCell struct {
+	latitude  int
+	longitude int
+}
+
+// Gazetteer geocodes addresses against a list of known addresses loaded in memory.
+type Gazetteer struct {
+	// Matches below this confidence are discarded.
+	MinConfidence float64
+	// Addresses farther than this from the location are not returned by ReverseGeocode.
+	MaxReverseDistanceKm float64
+	exact                map[string]*Entry
+	index                map[string][]*Entry
+	grid                 map[gridCell][]*Entry
+}
+
+// NewGazetteer builds a gazetteer from the given entries.
+func NewGazetteer(entries []Entry) *Gazetteer {
+	gazetteer := &Gazetteer{
+		MinConfidence:        DefaultMinConfidence,
+		MaxReverseDistanceKm: DefaultMaxReverseDistanceKm,
+		exact:                map[string]*Entry{},
+		index:                map[string][]*Entry{},
+		grid:                 map[gridCell][]*Entry{},
+	}
+	for i := range entries {
+		entry := entries[i]
+		entry.tokens = Tokenize(entry.Address)
+		cell := cellOf(entry.Latitude, entry.Longitude)
+		gazetteer.grid[cell] = append(gazetteer.grid[cell], &entry)
+		gazetteer.exact[strings.Join(entry.tokens, " ")] = &entry
+		for _, token := range uniqueTokens(entry.tokens) {
+			if !isNumber(token) {
+				gazetteer.index[token] = append(gazetteer.index[token], &entry)
+			}
+		}
+	}
+	return gazetteer
+}
+
+// LoadGazetteer reads a gazetteer from a CSV file. The file must have a header row with at least
+// the address, latitude and longitude columns. The street, number, locality, province, postal_code
+// and country columns are optional and used by ReverseGeocode.
+func LoadGazetteer(path string) (*Gazetteer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening gazetteer file")
+	}
+	defer file.Close()
+	entries, err := ReadGazetteer(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewGazetteer(entries), nil
+}
+
+// ReadGazetteer parses gazetteer entries from CSV.
+func ReadGazetteer(reader io.Reader) ([]Entry, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading gazetteer header")
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"address", "latitude", "longitude"} {
+		if _, ok := columns[required]; !ok {
+			return nil, errors.Errorf("gazetteer is missing the %s column", required)
+		}
+	}
+	entries := []Entry{}
+	for line := 2; ; line++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading gazetteer record")
+		}
+		column := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		latitude, err := strconv.ParseFloat(column("latitude"), 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing latitude in gazetteer line %d", line)
+		}
+		longitude, err := strconv.ParseFloat(column("longitude"), 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing longitude in gazetteer line %d", line)
+		}
+		entries = append(entries, Entry{
+			Address: column("address"),
+			Components: Address{
+				Street:     column("street"),
+				Number:     column("number"),
+				Locality:   column("locality"),
+				Province:   column("province"),
+				PostalCode: column("postal_code"),
+				Country:    column("country"),
+			},
+			Latitude:  latitude,
+			Longitude: longitude,
+		})
+	}
+	return entries, nil
+}
+
+// Geocode returns the best matching gazetteer entry for the address.
+func (gazetteer *Gazetteer) Geocode(address string) (*Result, error) {
+	tokens := Tokenize(address)
+	if len(tokens) == 0 {
+		return nil, ErrAddressNotFound
+	}
+	if entry, ok := gazetteer.exact[strings.Join(tokens, " ")]; ok {
+		return gazetteer.result(entry, 1), nil
+	}
+	var best *Entry
+	bestScore := 0.0
+	seen := map[*Entry]bool{}
+	for _, token := range uniqueTokens(tokens) {
+		for _, entry := range gazetteer.index[token] {
+			if seen[entry] {
+				continue
+			}
+			seen[entry] = true
+			score := matchScore(tokens, entry.tokens)
+			if score > bestScore {
+				best = entry
+				bestScore = score
+			}
+		}
+	}
+	if best == nil || bestScore < gazetteer.MinConfidence {
+		return nil, ErrAddressNotFound
+	}
+	return gazetteer.result(best, bestScore), nil
+}
+
+// ReverseGeocode returns the gazetteer entry closest to the location, as long as it is within
+// MaxReverseDistanceKm.
+func (gazetteer *Gazetteer) ReverseGeocode(latitude float64, longitude float64) (*ReverseResult, error) {
+	center := cellOf(latitude, longitude)
+	// A degree of latitude is ~111 km. Longitude degrees shrink towards the poles, so the number of
+	// cells searched around the center is sized for them.
+	rings := int(math.Ceil(gazetteer.MaxReverseDistanceKm/(gridCellDegrees*111)/math.Max(math.Cos(latitude*math.Pi/180), 0.1))) + 1
+	var closest *Entry
+	closestDistance := math.Inf(1)
+	for dLat := -rings; dLat <= rings; dLat++ {
+		for dLon := -rings; dLon <= rings; dLon++ {
+			for _, entry := range gazetteer.grid[gridCell{center.latitude + dLat, center.longitude + dLon}] {
+				distance := geo.Distance(latitude, longitude, entry.Latitude, entry.Longitude)
+				if distance < closestDistance {
+					closest = entry
+					closestDistance = distance
+				}
+			}
+		}
+	}
+	if closest == nil || closestDistance > gazetteer.MaxReverseDistanceKm {
+		return nil, ErrLocationNotFound
+	}
+	return &ReverseResult{
+		Address:          closest.Components,
+		FormattedAddress: closest.Address,
+		DistanceKm:       closestDistance,
+		Provider:         GazetteerProvider,
+	}, nil
+}
+
+func cellOf(latitude float64, longitude float64) gridCell {
+	return gridCell{int(math.Floor(latitude / gridCellDegrees)), int(math.Floor(longitude / gridCellDegrees))}
+}
+
+func (gazetteer *Gazetteer) result(entry *Entry, confidence float64) *Result {
+	return &Result{
+		Latitude:       entry.Latitude,
+		Longitude:      entry.Longitude,
+		Confidence:     confidence,
+		MatchedAddress: entry.Address,
+		Provider:       GazetteerProvider,
+	}
+}
+
+// TokenSimilarity scores from 0 to 1 how alike two tokenized addresses are, the same way Geocode
+// scores its matches.
+func TokenSimilarity(a []string, b []string) float64 {
+	return matchScore(a, b)
+}
+
+// matchScore compares the words of both addresses with the Jaccard index and penalizes
+// mismatching street numbers.
+func matchScore(query []string, candidate []string) float64 {
+	return splitTokens(query).Similarity(splitTokens(candidate))
+}
+
+func splitNumbers(tokens []string) (map[string]bool, map[string]bool) {
+	words := map[string]bool{}
+	numbers := map[string]bool{}
+	for _, token := range tokens {
+		if isNumber(token) {
+			numbers[token] = true
+		} else {
+			words[token] = true
+		}
+	}
+	return words, numbers
+}
+
+func sameSet(a map[string]bool, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key := range a {
+		if !b[key] {
+			return false
+		}
+	}
+	return true
+}
+
+func uniqueTokens(tokens []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			result = append(result, token)
+		}
+	}
+	return result
+}
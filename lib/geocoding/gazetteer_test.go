@@ -0,0 +1,60 @@
+package geocoding
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testGazetteer = `address,latitude,longitude
+"Florida 296, C1005 CABA",-34.604258,-58.375094
+"Av. Corrientes 1200, C1043 CABA",-34.603500,-58.383000
+"Av. Gral. Paz 10000, Villa Lugano",-34.680000,-58.470000
+`
+
+func TestGazetteerGeocode(t *testing.T) {
+	entries, err := ReadGazetteer(strings.NewReader(testGazetteer))
+	require.NoError(t, err)
+	gazetteer := NewGazetteer(entries)
+
+	result, err := gazetteer.Geocode("florida 296 c1005 caba")
+	require.NoError(t, err)
+	require.Equal(t, 1.0, result.Confidence)
+	require.Equal(t, "Florida 296, C1005 CABA", result.MatchedAddress)
+
+	result, err = gazetteer.Geocode("Avenida Corrientes 1200, Ciudad Autónoma de Buenos Aires")
+	require.NoError(t, err)
+	require.Equal(t, -34.6035, result.Latitude)
+	require.True(t, result.Confidence < 1)
+
+	result, err = gazetteer.Geocode("Avenida General Paz 10000")
+	require.NoError(t, err)
+	require.Equal(t, "Av. Gral. Paz 10000, Villa Lugano", result.MatchedAddress)
+
+	_, err = gazetteer.Geocode("Calle Falsa 123, Springfield")
+	require.Equal(t, ErrAddressNotFound, err)
+}
+
+func TestGazetteerReverseGeocode(t *testing.T) {
+	entries, err := ReadGazetteer(strings.NewReader(`address,latitude,longitude,street,number,locality,province,postal_code,country
+"Florida 296, C1005AAF CABA",-34.604258,-58.375094,Florida,296,Ciudad Autónoma de Buenos Aires,C,C1005AAF,AR
+"Av. Corrientes 1200, C1043AAZ CABA",-34.603500,-58.383000,Av. Corrientes,1200,Ciudad Autónoma de Buenos Aires,C,C1043AAZ,AR
+`))
+	require.NoError(t, err)
+	gazetteer := NewGazetteer(entries)
+
+	result, err := gazetteer.ReverseGeocode(-34.6043, -58.3760)
+	require.NoError(t, err)
+	require.Equal(t, "Florida", result.Street)
+	require.Equal(t, "C1005AAF", result.PostalCode)
+	require.True(t, result.DistanceKm < 0.1)
+
+	_, err = gazetteer.ReverseGeocode(-34.5600, -58.4560)
+	require.Equal(t, ErrLocationNotFound, err)
+}
+
+func TestReadGazetteerRequiresCoordinates(t *testing.T) {
+	_, err := ReadGazetteer(strings.NewReader("address,latitude\nFlorida 296,-34.6\n"))
+	require.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package geocoding
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrAddressNotFound is returned by geocoders when no location matches the address.
+var ErrAddressNotFound = errors.New("address not found")
+
+// ErrLocationNotFound is returned by reverse geocoders when no address is close enough to the location.
+var ErrLocationNotFound = errors.New("location not found")
+
+// Result is a location resolved for an address.
+type Result struct {
+	// Latitude in decimal degrees.
+	Latitude float64
+	// Longitude in decimal degrees.
+	Longitude float64
+	// Confidence of the match, between 0 and 1.
+	Confidence float64
+	// Address that was matched by the provider.
+	MatchedAddress string
+	// Name of the provider that resolved the address.
+	Provider string
+}
+
+// Geocoder resolves free-text addresses into coordinates.
+//
+//go:generate mockery --name Geocoder
+type Geocoder interface {
+	Geocode(address string) (*Result, error)
+}
+
+// Address holds the structured components of an address.
+type Address struct {
+	Street     string
+	Number     string
+	Locality   string
+	Province   string
+	PostalCode string
+	Country    string
+}
+
+// ReverseResult is an address resolved for a location.
+type ReverseResult struct {
+	Address
+	// Full address as written in the provider data.
+	FormattedAddress string
+	// Distance in kilometers between the requested location and the matched address.
+	DistanceKm float64
+	// Name of the provider that resolved the location.
+	Provider string
+}
+
+// ReverseGeocoder resolves coordinates into structured addresses.
+//
+//go:generate mockery --name ReverseGeocoder
+type ReverseGeocoder interface {
+	ReverseGeocode(latitude float64, longitude float64) (*ReverseResult, error)
+}
+
+// Chain tries every geocoder in order and returns the first match. This allows combining
+// the local gazetteer with remote providers.
+type Chain []Geocoder
+
+func (chain Chain) Geocode(address string) (*Result, error) {
+	for _, geocoder := range chain {
+		result, err := geocoder.Geocode(address)
+		if err == nil {
+			return result, nil
+		}
+		if errors.Cause(err) != ErrAddressNotFound {
+			return nil, err
+		}
+	}
+	return nil, ErrAddressNotFound
+}
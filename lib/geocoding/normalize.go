@@ -0,0 +1,65 @@
+package geocoding
+
+import (
+	"strings"
+	"unicode"
+)
+
+var accents = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+	"Á", "a", "É", "e", "Í", "i", "Ó", "o", "Ú", "u", "Ü", "u", "Ñ", "n",
+)
+
+// abbreviations maps common abbreviations found in Argentine addresses to their expanded form.
+var abbreviations = map[string]string{
+	"av":    "avenida",
+	"avda":  "avenida",
+	"avd":   "avenida",
+	"gral":  "general",
+	"pte":   "presidente",
+	"pres":  "presidente",
+	"dr":    "doctor",
+	"sta":   "santa",
+	"sto":   "santo",
+	"cnel":  "coronel",
+	"tte":   "teniente",
+	"ing":   "ingeniero",
+	"bv":    "boulevard",
+	"bvd":   "boulevard",
+	"bvard": "boulevard",
+	"blvd":  "boulevard",
+	"pje":   "pasaje",
+	"caba":  "ciudad autonoma de buenos aires",
+}
+
+// Normalize lowercases the address, strips accents and punctuation and expands common abbreviations
+// so that different spellings of the same address compare equal.
+func Normalize(address string) string {
+	return strings.Join(Tokenize(address), " ")
+}
+
+// Tokenize returns the normalized tokens of the address.
+func Tokenize(address string) []string {
+	address = strings.ToLower(accents.Replace(address))
+	fields := strings.FieldsFunc(address, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := []string{}
+	for _, field := range fields {
+		if expanded, ok := abbreviations[field]; ok {
+			tokens = append(tokens, strings.Fields(expanded)...)
+			continue
+		}
+		tokens = append(tokens, field)
+	}
+	return tokens
+}
+
+func isNumber(token string) bool {
+	for _, r := range token {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return token != ""
+}
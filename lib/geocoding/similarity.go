@@ -0,0 +1,112 @@
+package geocoding
+
+import (
+	"math"
+	"sort"
+)
+
+// AddressTokens is an address tokenized and split into words and street numbers, so that it can be
+// compared with many others without tokenizing it again.
+type AddressTokens struct {
+	words   map[string]bool
+	numbers map[string]bool
+}
+
+// TokenizeAddress tokenizes the address for Similarity.
+func TokenizeAddress(address string) *AddressTokens {
+	return splitTokens(Tokenize(address))
+}
+
+func splitTokens(tokens []string) *AddressTokens {
+	words, numbers := splitNumbers(tokens)
+	return &AddressTokens{words: words, numbers: numbers}
+}
+
+// Similarity scores from 0 to 1 how alike both addresses are, as TokenSimilarity does.
+func (address *AddressTokens) Similarity(other *AddressTokens) float64 {
+	intersection := 0
+	for word := range address.words {
+		if other.words[word] {
+			intersection++
+		}
+	}
+	union := len(address.words) + len(other.words) - intersection
+	if union == 0 {
+		return 0
+	}
+	score := float64(intersection) / float64(union)
+	switch {
+	case len(address.numbers) == 0 && len(other.numbers) == 0:
+	case len(address.numbers) == 0 || len(other.numbers) == 0:
+		score *= 0.9
+	case !sameSet(address.numbers, other.numbers):
+		score *= 0.75
+	}
+	return score
+}
+
+// AddressIndex finds the addresses alike to an address without comparing it with every one of them.
+// Addresses are bucketed by their words, since alike addresses must share some.
+type AddressIndex struct {
+	addresses map[string]*AddressTokens
+	byWord    map[string][]string
+}
+
+// NewAddressIndex indexes the tokenized addresses, by id.
+func NewAddressIndex(addresses map[string]*AddressTokens) *AddressIndex {
+	index := &AddressIndex{addresses: addresses, byWord: map[string][]string{}}
+	for id, address := range addresses {
+		for word := range address.words {
+			index.byWord[word] = append(index.byWord[word], id)
+		}
+	}
+	return index
+}
+
+// Get returns the address with the id, or nil.
+func (index *AddressIndex) Get(id string) *AddressTokens {
+	return index.addresses[id]
+}
+
+// Similar returns the similarity of the indexed addresses at least minSimilarity alike to the
+// address, by id. minSimilarity must be positive.
+//
+// Similarity never exceeds the Jaccard index of the words, so an address that alike shares at least
+// ceil(minSimilarity * words) of them. Any words but that many minus one include a shared one, so
+// only the buckets of the words with the fewest addresses are looked at.
+func (index *AddressIndex) Similar(address *AddressTokens, minSimilarity float64) map[string]float64 {
+	words := make([]string, 0, len(address.words))
+	for word := range address.words {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		first, second := len(index.byWord[words[i]]), len(index.byWord[words[j]])
+		if first != second {
+			return first < second
+		}
+		return words[i] < words[j]
+	})
+	// Rounding errors may only make it look at more buckets.
+	shared := int(math.Ceil(minSimilarity*float64(len(words)) - 1e-9))
+	if shared < 1 {
+		shared = 1
+	}
+	similar := map[string]float64{}
+	if shared > len(words) {
+		return similar
+	}
+	for _, word := range words[:len(words)-shared+1] {
+		for _, id := range index.byWord[word] {
+			if _, ok := similar[id]; ok {
+				continue
+			}
+			similar[id] = address.Similarity(index.addresses[id])
+		}
+	}
+	for id, similarity := range similar {
+		if similarity < minSimilarity {
+			delete(similar, id)
+		}
+	}
+	return similar
+}
@@ -0,0 +1,44 @@
+package geocoding
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddressIndexFindsEveryAlikeAddress(t *testing.T) {
+	addresses := []string{
+		"Florida 296, C1005 CABA",
+		"Florida 296, Ciudad Autónoma de Buenos Aires",
+		"Florida 300, CABA",
+		"Av. Corrientes 1200, C1043 CABA",
+		"Avenida Corrientes 1200, Buenos Aires",
+		"Av. Gral. Paz 10000, Villa Lugano",
+		"General Paz 10000",
+		"Av. Colón 500, X5000 Córdoba",
+		"",
+	}
+	byID := map[string]string{}
+	tokenized := map[string]*AddressTokens{}
+	for i, address := range addresses {
+		byID[strconv.Itoa(i)] = address
+		tokenized[strconv.Itoa(i)] = TokenizeAddress(address)
+	}
+	index := NewAddressIndex(tokenized)
+	for _, minSimilarity := range []float64{0.1, 0.3, 0.5, 0.8, 1} {
+		for i, address := range addresses {
+			query := TokenizeAddress(address)
+			expected := map[string]float64{}
+			for id, other := range tokenized {
+				similarity := TokenSimilarity(Tokenize(address), Tokenize(byID[id]))
+				require.Equal(t, similarity, query.Similarity(other))
+				if similarity >= minSimilarity {
+					expected[id] = similarity
+				}
+			}
+			require.Equal(t, expected, index.Similar(query, minSimilarity), "%d at %g", i, minSimilarity)
+		}
+	}
+	require.Empty(t, index.Similar(TokenizeAddress("Florida 296"), 1.5))
+}
@@ -0,0 +1,58 @@
+package geojson
+
+// MediaType is the media type of GeoJSON documents.
+const MediaType = "application/geo+json"
+
+const (
+	TypeFeature           = "Feature"
+	TypeFeatureCollection = "FeatureCollection"
+	TypePoint             = "Point"
+	TypePolygon           = "Polygon"
+	TypeMultiPolygon      = "MultiPolygon"
+)
+
+// Geometry is a GeoJSON geometry. Coordinates are in [longitude, latitude] order.
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// Feature is a GeoJSON feature.
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id,omitempty"`
+	Geometry   *Geometry              `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// FeatureCollection is a GeoJSON feature collection.
+type FeatureCollection struct {
+	Type     string     `json:"type"`
+	Features []*Feature `json:"features"`
+}
+
+// NewPoint returns a point geometry for the position.
+func NewPoint(latitude float64, longitude float64) *Geometry {
+	return &Geometry{Type: TypePoint, Coordinates: []float64{longitude, latitude}}
+}
+
+// NewPolygon returns a polygon geometry made of the given rings of [longitude, latitude] positions.
+func NewPolygon(rings [][][]float64) *Geometry {
+	return &Geometry{Type: TypePolygon, Coordinates: rings}
+}
+
+// NewFeature returns a feature with the given geometry and properties.
+func NewFeature(id string, geometry *Geometry, properties map[string]interface{}) *Feature {
+	if properties == nil {
+		properties = map[string]interface{}{}
+	}
+	return &Feature{Type: TypeFeature, ID: id, Geometry: geometry, Properties: properties}
+}
+
+// NewFeatureCollection returns a collection of the given features.
+func NewFeatureCollection(features []*Feature) *FeatureCollection {
+	if features == nil {
+		features = []*Feature{}
+	}
+	return &FeatureCollection{Type: TypeFeatureCollection, Features: features}
+}
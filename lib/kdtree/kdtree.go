@@ -0,0 +1,187 @@
+package kdtree
+
+import (
+	"math"
+	"sort"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+)
+
+// Mean radius of the Earth in kilometers, only used to bound range queries. Results are filtered
+// with geo.Distance.
+const earthRadiusKm = 6371.0
+
+// Point is a location stored in the tree.
+type Point struct {
+	ID        string
+	Latitude  float64
+	Longitude float64
+}
+
+type node struct {
+	point       Point
+	xyz         [3]float64
+	axis        int
+	left, right *node
+}
+
+// Tree is a static 3-d tree of points on the unit sphere. Since the straight line distance between
+// two points on the sphere grows with their great-circle distance, nearest neighbour searches are
+// exact without having to deal with the antimeridian or the poles.
+type Tree struct {
+	root *node
+	size int
+}
+
+// New builds a balanced tree holding the points.
+func New(points []Point) *Tree {
+	nodes := make([]*node, len(points))
+	for i, point := range points {
+		nodes[i] = &node{point: point, xyz: toXYZ(point.Latitude, point.Longitude)}
+	}
+	return &Tree{root: build(nodes, 0), size: len(points)}
+}
+
+func build(nodes []*node, depth int) *node {
+	if len(nodes) == 0 {
+		return nil
+	}
+	axis := depth % 3
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].xyz[axis] < nodes[j].xyz[axis]
+	})
+	median := len(nodes) / 2
+	root := nodes[median]
+	root.axis = axis
+	root.left = build(nodes[:median], depth+1)
+	root.right = build(nodes[median+1:], depth+1)
+	return root
+}
+
+// Len returns the number of points in the tree.
+func (tree *Tree) Len() int {
+	return tree.size
+}
+
+// Nearest returns the point closest to the position. It returns false when the tree is empty.
+func (tree *Tree) Nearest(latitude float64, longitude float64) (Point, bool) {
+	if tree.root == nil {
+		return Point{}, false
+	}
+	target := toXYZ(latitude, longitude)
+	best := tree.root
+	bestDistance := squaredDistance(target, best.xyz)
+	var search func(current *node)
+	search = func(current *node) {
+		if current == nil {
+			return
+		}
+		if distance := squaredDistance(target, current.xyz); distance < bestDistance {
+			best, bestDistance = current, distance
+		}
+		delta := target[current.axis] - current.xyz[current.axis]
+		near, far := current.left, current.right
+		if delta > 0 {
+			near, far = far, near
+		}
+		search(near)
+		if delta*delta < bestDistance {
+			search(far)
+		}
+	}
+	search(tree.root)
+	return best.point, true
+}
+
+// NearestN returns the count points closest to the position, closest first. It returns every point
+// when the tree holds fewer.
+func (tree *Tree) NearestN(latitude float64, longitude float64, count int) []Point {
+	target := toXYZ(latitude, longitude)
+	// The best nodes found so far, sorted by their distance to the target.
+	best := make([]*node, 0, count)
+	distances := make([]float64, 0, count)
+	var search func(current *node)
+	search = func(current *node) {
+		if current == nil || count <= 0 {
+			return
+		}
+		if distance := squaredDistance(target, current.xyz); len(best) < count || distance < distances[len(best)-1] {
+			i := sort.SearchFloat64s(distances, distance)
+			if len(best) < count {
+				best = append(best, nil)
+				distances = append(distances, 0)
+			}
+			copy(best[i+1:], best[i:])
+			copy(distances[i+1:], distances[i:])
+			best[i], distances[i] = current, distance
+		}
+		delta := target[current.axis] - current.xyz[current.axis]
+		near, far := current.left, current.right
+		if delta > 0 {
+			near, far = far, near
+		}
+		search(near)
+		if len(best) < count || delta*delta < distances[len(best)-1] {
+			search(far)
+		}
+	}
+	search(tree.root)
+	points := make([]Point, len(best))
+	for i, found := range best {
+		points[i] = found.point
+	}
+	return points
+}
+
+// Within returns the points at most radiusKm away from the position, closest first.
+func (tree *Tree) Within(latitude float64, longitude float64, radiusKm float64) []Point {
+	target := toXYZ(latitude, longitude)
+	// Chord length of the radius, with some slack for the difference between Earth radii.
+	angle := math.Min(radiusKm/earthRadiusKm*1.01, math.Pi)
+	chord := 2 * math.Sin(angle/2)
+	maxDistance := chord * chord
+	type match struct {
+		point    Point
+		distance float64
+	}
+	matches := []match{}
+	var search func(current *node)
+	search = func(current *node) {
+		if current == nil {
+			return
+		}
+		if squaredDistance(target, current.xyz) <= maxDistance {
+			distance := geo.Distance(latitude, longitude, current.point.Latitude, current.point.Longitude)
+			if distance <= radiusKm {
+				matches = append(matches, match{current.point, distance})
+			}
+		}
+		delta := target[current.axis] - current.xyz[current.axis]
+		if delta <= 0 || delta*delta <= maxDistance {
+			search(current.left)
+		}
+		if delta >= 0 || delta*delta <= maxDistance {
+			search(current.right)
+		}
+	}
+	search(tree.root)
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+	points := make([]Point, len(matches))
+	for i, found := range matches {
+		points[i] = found.point
+	}
+	return points
+}
+
+func toXYZ(latitude float64, longitude float64) [3]float64 {
+	lat := latitude * math.Pi / 180
+	lon := longitude * math.Pi / 180
+	return [3]float64{math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)}
+}
+
+func squaredDistance(a [3]float64, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
@@ -0,0 +1,88 @@
+package kdtree
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+	"github.com/stretchr/testify/require"
+)
+
+func randomPoints(random *rand.Rand, count int) []Point {
+	points := make([]Point, count)
+	for i := range points {
+		points[i] = Point{ID: fmt.Sprint(i), Latitude: random.Float64()*180 - 90, Longitude: random.Float64()*360 - 180}
+	}
+	return points
+}
+
+func TestNearestMatchesFullScan(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	points := randomPoints(random, 500)
+	tree := New(append([]Point{}, points...))
+	require.Equal(t, 500, tree.Len())
+	for i := 0; i < 200; i++ {
+		latitude, longitude := random.Float64()*180-90, random.Float64()*360-180
+		expected := points[0]
+		for _, point := range points {
+			if geo.Distance(latitude, longitude, point.Latitude, point.Longitude) < geo.Distance(latitude, longitude, expected.Latitude, expected.Longitude) {
+				expected = point
+			}
+		}
+		nearest, ok := tree.Nearest(latitude, longitude)
+		require.True(t, ok)
+		require.Equal(t, expected, nearest)
+	}
+}
+
+func TestNearestAcrossAntimeridian(t *testing.T) {
+	tree := New([]Point{{ID: "east", Latitude: 0, Longitude: 179.9}, {ID: "west", Latitude: 0, Longitude: -170}})
+	nearest, _ := tree.Nearest(0, -179.9)
+	require.Equal(t, "east", nearest.ID)
+
+	_, ok := New(nil).Nearest(0, 0)
+	require.False(t, ok)
+}
+
+func TestNearestNMatchesFullScan(t *testing.T) {
+	random := rand.New(rand.NewSource(3))
+	points := randomPoints(random, 500)
+	tree := New(append([]Point{}, points...))
+	for _, count := range []int{0, 1, 5, 600} {
+		latitude, longitude := random.Float64()*180-90, random.Float64()*360-180
+		expected := append([]Point{}, points...)
+		sort.SliceStable(expected, func(i, j int) bool {
+			return geo.Distance(latitude, longitude, expected[i].Latitude, expected[i].Longitude) < geo.Distance(latitude, longitude, expected[j].Latitude, expected[j].Longitude)
+		})
+		if count < len(expected) {
+			expected = expected[:count]
+		}
+		require.Equal(t, expected, tree.NearestN(latitude, longitude, count), "count %d", count)
+	}
+}
+
+func TestWithinMatchesFullScan(t *testing.T) {
+	random := rand.New(rand.NewSource(2))
+	points := randomPoints(random, 500)
+	tree := New(append([]Point{}, points...))
+	for _, radiusKm := range []float64{10, 500, 2000, 30000} {
+		latitude, longitude := random.Float64()*180-90, random.Float64()*360-180
+		expected := map[string]bool{}
+		for _, point := range points {
+			if geo.Distance(latitude, longitude, point.Latitude, point.Longitude) <= radiusKm {
+				expected[point.ID] = true
+			}
+		}
+		found := tree.Within(latitude, longitude, radiusKm)
+		require.Len(t, found, len(expected), "radius %f", radiusKm)
+		for i, point := range found {
+			require.True(t, expected[point.ID])
+			if i > 0 {
+				previous := found[i-1]
+				require.LessOrEqual(t, geo.Distance(latitude, longitude, previous.Latitude, previous.Longitude), geo.Distance(latitude, longitude, point.Latitude, point.Longitude))
+			}
+		}
+	}
+}
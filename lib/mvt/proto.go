@@ -0,0 +1,75 @@
+package mvt
+
+import "math"
+
+// Protocol buffers wire types used by the vector tile format.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// buffer is a minimal protocol buffers encoder.
+type buffer []byte
+
+func (b *buffer) varint(value uint64) {
+	for value >= 0x80 {
+		*b = append(*b, byte(value)|0x80)
+		value >>= 7
+	}
+	*b = append(*b, byte(value))
+}
+
+func (b *buffer) key(field int, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *buffer) uintField(field int, value uint64) {
+	b.key(field, wireVarint)
+	b.varint(value)
+}
+
+func (b *buffer) sintField(field int, value int64) {
+	b.key(field, wireVarint)
+	b.varint(zigzag(value))
+}
+
+func (b *buffer) boolField(field int, value bool) {
+	b.key(field, wireVarint)
+	if value {
+		b.varint(1)
+	} else {
+		b.varint(0)
+	}
+}
+
+func (b *buffer) doubleField(field int, value float64) {
+	b.key(field, wireFixed64)
+	bits := math.Float64bits(value)
+	for i := 0; i < 8; i++ {
+		*b = append(*b, byte(bits>>(8*uint(i))))
+	}
+}
+
+func (b *buffer) bytesField(field int, value []byte) {
+	b.key(field, wireBytes)
+	b.varint(uint64(len(value)))
+	*b = append(*b, value...)
+}
+
+func (b *buffer) stringField(field int, value string) {
+	b.bytesField(field, []byte(value))
+}
+
+func (b *buffer) packedField(field int, values []uint32) {
+	var packed buffer
+	for _, value := range values {
+		packed.varint(uint64(value))
+	}
+	b.bytesField(field, packed)
+}
+
+func zigzag(value int64) uint64 {
+	return uint64((value << 1) ^ (value >> 63))
+}
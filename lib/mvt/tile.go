@@ -0,0 +1,178 @@
+package mvt
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+)
+
+// MediaType is the media type of Mapbox Vector Tiles.
+const MediaType = "application/vnd.mapbox-vector-tile"
+
+// DefaultExtent is the number of units across a tile in its own coordinate system.
+const DefaultExtent = 4096
+
+// Field numbers of the vector tile protobuf schema, see https://github.com/mapbox/vector-tile-spec.
+const (
+	tileLayers = 3
+
+	layerName     = 1
+	layerFeatures = 2
+	layerKeys     = 3
+	layerValues   = 4
+	layerExtent   = 5
+	layerVersion  = 15
+
+	featureTags     = 2
+	featureType     = 3
+	featureGeometry = 4
+
+	valueString = 1
+	valueDouble = 3
+	valueSint   = 6
+	valueBool   = 7
+
+	geometryPoint = 1
+	commandMoveTo = 1
+	specVersion   = 2
+)
+
+// Tile is a Mapbox Vector Tile made of layers.
+type Tile struct {
+	Zoom   int
+	X      int
+	Y      int
+	layers []*Layer
+}
+
+// Layer is a named set of features in a tile.
+type Layer struct {
+	name     string
+	extent   int
+	tile     *Tile
+	features []buffer
+	keys     []string
+	keyIndex map[string]int
+	values   []buffer
+	valueIds map[string]int
+}
+
+// NewTile creates an empty tile with the given coordinates.
+func NewTile(zoom int, x int, y int) *Tile {
+	return &Tile{Zoom: zoom, X: x, Y: y}
+}
+
+// AddLayer adds an empty layer to the tile.
+func (tile *Tile) AddLayer(name string, extent int) *Layer {
+	layer := &Layer{
+		name:     name,
+		extent:   extent,
+		tile:     tile,
+		keyIndex: map[string]int{},
+		valueIds: map[string]int{},
+	}
+	tile.layers = append(tile.layers, layer)
+	return layer
+}
+
+// Marshal encodes the tile as protocol buffers.
+func (tile *Tile) Marshal() []byte {
+	var encoded buffer
+	for _, layer := range tile.layers {
+		encoded.bytesField(tileLayers, layer.marshal())
+	}
+	return encoded
+}
+
+// AddPoint adds a point feature for the position if it falls inside the tile, including a margin of
+// the given units around it. Supported property values are strings, booleans, integers and floats.
+func (layer *Layer) AddPoint(latitude float64, longitude float64, margin int, properties map[string]interface{}) bool {
+	x, y := geo.WorldPixel(latitude, longitude, layer.tile.Zoom, float64(layer.extent))
+	tileX := int(x) - layer.tile.X*layer.extent
+	tileY := int(y) - layer.tile.Y*layer.extent
+	if tileX < -margin || tileY < -margin || tileX > layer.extent+margin || tileY > layer.extent+margin {
+		return false
+	}
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tags := []uint32{}
+	for _, key := range keys {
+		value, ok := layer.valueIndex(properties[key])
+		if !ok {
+			continue
+		}
+		tags = append(tags, uint32(layer.keyIndexOf(key)), uint32(value))
+	}
+	var feature buffer
+	feature.packedField(featureTags, tags)
+	feature.uintField(featureType, geometryPoint)
+	feature.packedField(featureGeometry, []uint32{
+		commandMoveTo&0x7 | 1<<3,
+		uint32(zigzag(int64(tileX))),
+		uint32(zigzag(int64(tileY))),
+	})
+	layer.features = append(layer.features, feature)
+	return true
+}
+
+// Len returns the number of features in the layer.
+func (layer *Layer) Len() int {
+	return len(layer.features)
+}
+
+func (layer *Layer) keyIndexOf(key string) int {
+	index, ok := layer.keyIndex[key]
+	if !ok {
+		index = len(layer.keys)
+		layer.keys = append(layer.keys, key)
+		layer.keyIndex[key] = index
+	}
+	return index
+}
+
+func (layer *Layer) valueIndex(value interface{}) (int, bool) {
+	var encoded buffer
+	switch typed := value.(type) {
+	case string:
+		encoded.stringField(valueString, typed)
+	case bool:
+		encoded.boolField(valueBool, typed)
+	case int:
+		encoded.sintField(valueSint, int64(typed))
+	case int64:
+		encoded.sintField(valueSint, typed)
+	case float64:
+		encoded.doubleField(valueDouble, typed)
+	default:
+		return 0, false
+	}
+	id := fmt.Sprintf("%T:%v", value, value)
+	index, ok := layer.valueIds[id]
+	if !ok {
+		index = len(layer.values)
+		layer.values = append(layer.values, encoded)
+		layer.valueIds[id] = index
+	}
+	return index, true
+}
+
+func (layer *Layer) marshal() []byte {
+	var encoded buffer
+	encoded.uintField(layerVersion, specVersion)
+	encoded.stringField(layerName, layer.name)
+	for _, feature := range layer.features {
+		encoded.bytesField(layerFeatures, feature)
+	}
+	for _, key := range layer.keys {
+		encoded.stringField(layerKeys, key)
+	}
+	for _, value := range layer.values {
+		encoded.bytesField(layerValues, value)
+	}
+	encoded.uintField(layerExtent, uint64(layer.extent))
+	return encoded
+}
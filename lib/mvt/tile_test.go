@@ -0,0 +1,112 @@
+package mvt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// field is a decoded protocol buffers field, holding either a varint or the bytes of a length
+// delimited value.
+type field struct {
+	number int
+	value  uint64
+	bytes  []byte
+}
+
+func readVarint(t *testing.T, data []byte) (uint64, []byte) {
+	var value uint64
+	for shift := uint(0); ; shift += 7 {
+		require.NotEmpty(t, data)
+		b := data[0]
+		data = data[1:]
+		value |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return value, data
+		}
+	}
+}
+
+func decode(t *testing.T, data []byte) []field {
+	fields := []field{}
+	for len(data) > 0 {
+		var key uint64
+		key, data = readVarint(t, data)
+		decoded := field{number: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			decoded.value, data = readVarint(t, data)
+		case wireBytes:
+			var length uint64
+			length, data = readVarint(t, data)
+			decoded.bytes, data = data[:length], data[length:]
+		case wireFixed64:
+			decoded.bytes, data = data[:8], data[8:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		fields = append(fields, decoded)
+	}
+	return fields
+}
+
+func decodePacked(t *testing.T, data []byte) []uint64 {
+	values := []uint64{}
+	for len(data) > 0 {
+		var value uint64
+		value, data = readVarint(t, data)
+		values = append(values, value)
+	}
+	return values
+}
+
+func TestMarshalPointLayer(t *testing.T) {
+	tile := NewTile(0, 0, 0)
+	layer := tile.AddLayer("sucursales", DefaultExtent)
+	require.True(t, layer.AddPoint(0, 0, 0, map[string]interface{}{"id": "a", "cluster": true}))
+	require.True(t, layer.AddPoint(0, 90, 0, map[string]interface{}{"id": "b"}))
+	require.Equal(t, 2, layer.Len())
+
+	tileFields := decode(t, tile.Marshal())
+	require.Len(t, tileFields, 1)
+	require.Equal(t, tileLayers, tileFields[0].number)
+
+	var name string
+	var keys []string
+	features := [][]field{}
+	values := 0
+	for _, layerField := range decode(t, tileFields[0].bytes) {
+		switch layerField.number {
+		case layerVersion:
+			require.Equal(t, uint64(specVersion), layerField.value)
+		case layerName:
+			name = string(layerField.bytes)
+		case layerKeys:
+			keys = append(keys, string(layerField.bytes))
+		case layerValues:
+			values++
+		case layerFeatures:
+			features = append(features, decode(t, layerField.bytes))
+		case layerExtent:
+			require.Equal(t, uint64(DefaultExtent), layerField.value)
+		}
+	}
+	require.Equal(t, "sucursales", name)
+	require.Equal(t, []string{"cluster", "id"}, keys)
+	require.Equal(t, 3, values)
+	require.Len(t, features, 2)
+
+	// The first point is at the center of the only tile of zoom 0, the second a quarter further east.
+	require.Equal(t, []uint64{0, 0, 1, 1}, decodePacked(t, features[0][0].bytes))
+	require.Equal(t, uint64(geometryPoint), features[0][1].value)
+	require.Equal(t, []uint64{9, zigzag(2048), zigzag(2048)}, decodePacked(t, features[0][2].bytes))
+	require.Equal(t, []uint64{1, 2}, decodePacked(t, features[1][0].bytes))
+	require.Equal(t, []uint64{9, zigzag(3072), zigzag(2048)}, decodePacked(t, features[1][2].bytes))
+}
+
+func TestAddPointOutsideTile(t *testing.T) {
+	layer := NewTile(1, 0, 0).AddLayer("sucursales", DefaultExtent)
+	require.False(t, layer.AddPoint(-34.6, -58.4, 64, nil))
+	require.True(t, layer.AddPoint(-0.01, -58.4, 64, nil))
+	require.Equal(t, 1, layer.Len())
+}
@@ -0,0 +1,95 @@
+// Package osm reads the nodes and ways of OpenStreetMap extracts, in XML or PBF format.
+package osm
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Node is a point of the map. Only the nodes of Extract.Points keep their tags.
+type Node struct {
+	ID        int64
+	Latitude  float64
+	Longitude float64
+	Tags      map[string]string
+}
+
+// Way is a line through nodes, such as a street.
+type Way struct {
+	ID    int64
+	Nodes []int64
+	Tags  map[string]string
+}
+
+// Extract holds the ways and the tagged nodes that were kept while reading a file, along with the
+// nodes the ways go through.
+type Extract struct {
+	Nodes map[int64]Node
+	Ways  []Way
+	// Nodes with tags, such as a bank mapped as a single point.
+	Points []Node
+}
+
+// Filter decides which ways and tagged nodes to keep while reading an extract.
+type Filter func(tags map[string]string) bool
+
+// extractBuilder collects every node, since they come before the ways in OSM files, and drops those
+// that no kept way goes through at the end.
+type extractBuilder struct {
+	filter Filter
+	nodes  map[int64]Node
+	ways   []Way
+	points []Node
+}
+
+func newExtractBuilder(filter Filter) *extractBuilder {
+	return &extractBuilder{filter: filter, nodes: map[int64]Node{}, ways: []Way{}, points: []Node{}}
+}
+
+func (builder *extractBuilder) node(node Node) {
+	if len(node.Tags) > 0 && (builder.filter == nil || builder.filter(node.Tags)) {
+		builder.points = append(builder.points, node)
+	}
+	node.Tags = nil
+	builder.nodes[node.ID] = node
+}
+
+func (builder *extractBuilder) way(way Way) {
+	if builder.filter == nil || builder.filter(way.Tags) {
+		builder.ways = append(builder.ways, way)
+	}
+}
+
+func (builder *extractBuilder) extract() *Extract {
+	nodes := map[int64]Node{}
+	for _, way := range builder.ways {
+		for _, id := range way.Nodes {
+			if node, ok := builder.nodes[id]; ok {
+				nodes[id] = node
+			}
+		}
+	}
+	return &Extract{Nodes: nodes, Ways: builder.ways, Points: builder.points}
+}
+
+// Load reads an extract from a file, in PBF format when its name ends in .pbf and in XML otherwise,
+// keeping the ways and tagged nodes accepted by the filter.
+func Load(path string, filter Filter) (*Extract, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening OSM extract")
+	}
+	defer file.Close()
+	var extract *Extract
+	if strings.HasSuffix(strings.ToLower(path), ".pbf") {
+		extract, err = ReadPBF(file, filter)
+	} else {
+		extract, err = ReadXML(file, filter)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading OSM extract %s", path)
+	}
+	return extract, nil
+}
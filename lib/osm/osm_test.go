@@ -0,0 +1,249 @@
+package osm
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testXML = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6">
+  <node id="1" lat="-34.6" lon="-58.4"/>
+  <node id="2" lat="-34.601" lon="-58.4"/>
+  <node id="3" lat="-34.601" lon="-58.401">
+    <tag k="amenity" v="bank"/>
+  </node>
+  <node id="4" lat="-34.7" lon="-58.5"/>
+  <way id="10">
+    <nd ref="1"/>
+    <nd ref="2"/>
+    <nd ref="3"/>
+    <tag k="highway" v="residential"/>
+    <tag k="name" v="Florida"/>
+  </way>
+  <way id="11">
+    <nd ref="3"/>
+    <nd ref="4"/>
+    <tag k="waterway" v="river"/>
+  </way>
+  <relation id="20">
+    <member type="way" ref="10" role=""/>
+  </relation>
+</osm>`
+
+func isHighway(tags map[string]string) bool {
+	return tags["highway"] != ""
+}
+
+func expectedExtract() *Extract {
+	return &Extract{
+		Nodes: map[int64]Node{
+			1: {ID: 1, Latitude: -34.6, Longitude: -58.4},
+			2: {ID: 2, Latitude: -34.601, Longitude: -58.4},
+			3: {ID: 3, Latitude: -34.601, Longitude: -58.401},
+		},
+		Ways:   []Way{{ID: 10, Nodes: []int64{1, 2, 3}, Tags: map[string]string{"highway": "residential", "name": "Florida"}}},
+		Points: []Node{},
+	}
+}
+
+func isBank(tags map[string]string) bool {
+	return tags["amenity"] == "bank"
+}
+
+func expectedBanks() *Extract {
+	return &Extract{
+		Nodes:  map[int64]Node{},
+		Ways:   []Way{},
+		Points: []Node{{ID: 3, Latitude: -34.601, Longitude: -58.401, Tags: map[string]string{"amenity": "bank"}}},
+	}
+}
+
+func TestReadXMLKeepsFilteredWaysAndTheirNodes(t *testing.T) {
+	extract, err := ReadXML(strings.NewReader(testXML), isHighway)
+	require.NoError(t, err)
+	require.Equal(t, expectedExtract(), extract)
+}
+
+func TestReadXMLKeepsFilteredNodes(t *testing.T) {
+	extract, err := ReadXML(strings.NewReader(testXML), isBank)
+	require.NoError(t, err)
+	require.Equal(t, expectedBanks(), extract)
+}
+
+func TestReadXMLWithoutFilterKeepsEveryWay(t *testing.T) {
+	extract, err := ReadXML(strings.NewReader(testXML), nil)
+	require.NoError(t, err)
+	require.Len(t, extract.Ways, 2)
+	require.Len(t, extract.Nodes, 4)
+}
+
+func TestReadXMLRejectsInvalidDocuments(t *testing.T) {
+	_, err := ReadXML(strings.NewReader(`<osm><node id="x"/></osm>`), nil)
+	require.Error(t, err)
+}
+
+// protoWriter encodes protocol buffers messages for the tests.
+type protoWriter struct {
+	bytes.Buffer
+}
+
+func (writer *protoWriter) key(number int, wireType int) {
+	writer.uvarint(uint64(number<<3 | wireType))
+}
+
+func (writer *protoWriter) uvarint(value uint64) {
+	buffer := make([]byte, binary.MaxVarintLen64)
+	writer.Write(buffer[:binary.PutUvarint(buffer, value)])
+}
+
+func (writer *protoWriter) varint(number int, value uint64) {
+	writer.key(number, wireVarint)
+	writer.uvarint(value)
+}
+
+func (writer *protoWriter) message(number int, data []byte) {
+	writer.key(number, wireBytes)
+	writer.uvarint(uint64(len(data)))
+	writer.Write(data)
+}
+
+func (writer *protoWriter) packed(number int, values ...uint64) {
+	packed := &protoWriter{}
+	for _, value := range values {
+		packed.uvarint(value)
+	}
+	writer.message(number, packed.Bytes())
+}
+
+func sint(value int64) uint64 {
+	return uint64((value << 1) ^ (value >> 63))
+}
+
+func packedDeltas(number int, values ...int64) func(*protoWriter) {
+	return func(writer *protoWriter) {
+		encoded := make([]uint64, len(values))
+		var previous int64
+		for i, value := range values {
+			encoded[i] = sint(value - previous)
+			previous = value
+		}
+		writer.packed(number, encoded...)
+	}
+}
+
+func writeBlob(t *testing.T, file *bytes.Buffer, blobType string, data []byte, compress bool) {
+	blob := &protoWriter{}
+	if compress {
+		compressed := &bytes.Buffer{}
+		deflater := zlib.NewWriter(compressed)
+		_, err := deflater.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, deflater.Close())
+		blob.varint(2, uint64(len(data)))
+		blob.message(3, compressed.Bytes())
+	} else {
+		blob.message(1, data)
+	}
+	header := &protoWriter{}
+	header.message(1, []byte(blobType))
+	header.varint(3, uint64(blob.Len()))
+	require.NoError(t, binary.Write(file, binary.BigEndian, uint32(header.Len())))
+	file.Write(header.Bytes())
+	file.Write(blob.Bytes())
+}
+
+// testPBF encodes the same data as testXML, with the first node as a plain node and the others as
+// dense nodes, in a block with a non-default granularity and offset.
+func testPBF(t *testing.T, features ...string) []byte {
+	file := &bytes.Buffer{}
+	header := &protoWriter{}
+	for _, feature := range features {
+		header.message(4, []byte(feature))
+	}
+	writeBlob(t, file, "OSMHeader", header.Bytes(), false)
+
+	strings := &protoWriter{}
+	for _, value := range []string{"", "highway", "residential", "name", "Florida", "waterway", "river", "amenity", "bank"} {
+		strings.message(1, []byte(value))
+	}
+	// Coordinates in units of 1000 nanodegrees from an offset of one degree.
+	coordinate := func(degrees float64) int64 { return int64(math.Round((degrees - 1) * 1e6)) }
+	node := &protoWriter{}
+	node.varint(1, sint(1))
+	node.varint(8, sint(coordinate(-34.6)))
+	node.varint(9, sint(coordinate(-58.4)))
+	dense := &protoWriter{}
+	packedDeltas(1, 2, 3, 4)(dense)
+	packedDeltas(8, coordinate(-34.601), coordinate(-34.601), coordinate(-34.7))(dense)
+	packedDeltas(9, coordinate(-58.4), coordinate(-58.401), coordinate(-58.5))(dense)
+	// Node 3 is a bank.
+	dense.packed(10, 0, 7, 8, 0, 0)
+	nodes := &protoWriter{}
+	nodes.message(1, node.Bytes())
+	nodes.message(2, dense.Bytes())
+	street := &protoWriter{}
+	street.varint(1, 10)
+	street.packed(2, 1, 3)
+	street.packed(3, 2, 4)
+	packedDeltas(8, 1, 2, 3)(street)
+	river := &protoWriter{}
+	river.varint(1, 11)
+	river.packed(2, 5)
+	river.packed(3, 6)
+	packedDeltas(8, 3, 4)(river)
+	ways := &protoWriter{}
+	ways.message(3, street.Bytes())
+	ways.message(3, river.Bytes())
+
+	block := &protoWriter{}
+	block.message(1, strings.Bytes())
+	block.message(2, nodes.Bytes())
+	block.message(2, ways.Bytes())
+	block.varint(17, 1000)
+	block.varint(19, uint64(1e9))
+	block.varint(20, uint64(1e9))
+	writeBlob(t, file, "OSMData", block.Bytes(), true)
+	return file.Bytes()
+}
+
+func TestReadPBFMatchesXML(t *testing.T) {
+	extract, err := ReadPBF(bytes.NewReader(testPBF(t, "OsmSchema-V0.6", "DenseNodes")), isHighway)
+	require.NoError(t, err)
+	expected := expectedExtract()
+	require.Equal(t, expected.Ways, extract.Ways)
+	require.Len(t, extract.Nodes, len(expected.Nodes))
+	for id, node := range expected.Nodes {
+		require.Equal(t, id, extract.Nodes[id].ID)
+		require.InDelta(t, node.Latitude, extract.Nodes[id].Latitude, 1e-6)
+		require.InDelta(t, node.Longitude, extract.Nodes[id].Longitude, 1e-6)
+	}
+}
+
+func TestReadPBFKeepsFilteredNodes(t *testing.T) {
+	extract, err := ReadPBF(bytes.NewReader(testPBF(t, "OsmSchema-V0.6", "DenseNodes")), isBank)
+	require.NoError(t, err)
+	require.Len(t, extract.Points, 1)
+	bank := extract.Points[0]
+	require.Equal(t, int64(3), bank.ID)
+	require.Equal(t, map[string]string{"amenity": "bank"}, bank.Tags)
+	require.InDelta(t, -34.601, bank.Latitude, 1e-6)
+	require.InDelta(t, -58.401, bank.Longitude, 1e-6)
+	require.Empty(t, extract.Ways)
+}
+
+func TestReadPBFRejectsUnsupportedFeatures(t *testing.T) {
+	_, err := ReadPBF(bytes.NewReader(testPBF(t, "OsmSchema-V0.6", "HistoricalInformation")), isHighway)
+	require.Error(t, err)
+}
+
+func TestReadPBFRejectsTruncatedFiles(t *testing.T) {
+	file := testPBF(t)
+	_, err := ReadPBF(bytes.NewReader(file[:len(file)-10]), isHighway)
+	require.Error(t, err)
+}
@@ -0,0 +1,401 @@
+package osm
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// Limits of the PBF format, to reject corrupt files before allocating.
+const (
+	maxBlobHeaderSize = 64 * 1024
+	maxBlobSize       = 32 * 1024 * 1024
+)
+
+// Features of the PBF header that the reader understands.
+var supportedFeatures = map[string]bool{"OsmSchema-V0.6": true, "DenseNodes": true}
+
+// ReadPBF reads an extract in the OSM PBF format, keeping the ways and tagged nodes accepted by the
+// filter. Blobs must be uncompressed or compressed with zlib, as the common tools write them.
+func ReadPBF(reader io.Reader, filter Filter) (*Extract, error) {
+	builder := newExtractBuilder(filter)
+	buffered := bufio.NewReader(reader)
+	for {
+		var headerSize uint32
+		err := binary.Read(buffered, binary.BigEndian, &headerSize)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading blob header size")
+		}
+		if headerSize > maxBlobHeaderSize {
+			return nil, errors.Errorf("blob header of %d bytes is too large", headerSize)
+		}
+		header := make([]byte, headerSize)
+		if _, err := io.ReadFull(buffered, header); err != nil {
+			return nil, errors.Wrap(err, "reading blob header")
+		}
+		blobType, blobSize, err := parseBlobHeader(header)
+		if err != nil {
+			return nil, err
+		}
+		blob := make([]byte, blobSize)
+		if _, err := io.ReadFull(buffered, blob); err != nil {
+			return nil, errors.Wrap(err, "reading blob")
+		}
+		data, err := blobData(blob)
+		if err != nil {
+			return nil, err
+		}
+		switch blobType {
+		case "OSMHeader":
+			err = checkHeaderBlock(data)
+		case "OSMData":
+			err = readPrimitiveBlock(data, builder)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return builder.extract(), nil
+}
+
+func parseBlobHeader(header []byte) (string, int, error) {
+	message := &protoMessage{data: header}
+	blobType := ""
+	blobSize := -1
+	for {
+		field, ok, err := message.next()
+		if err != nil {
+			return "", 0, errors.Wrap(err, "parsing blob header")
+		}
+		if !ok {
+			break
+		}
+		switch field.number {
+		case 1:
+			blobType = string(field.bytes)
+		case 3:
+			blobSize = int(field.varint)
+		}
+	}
+	if blobSize < 0 || blobSize > maxBlobSize {
+		return "", 0, errors.Errorf("invalid blob size %d", blobSize)
+	}
+	return blobType, blobSize, nil
+}
+
+// blobData returns the uncompressed contents of a blob.
+func blobData(blob []byte) ([]byte, error) {
+	message := &protoMessage{data: blob}
+	for {
+		field, ok, err := message.next()
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing blob")
+		}
+		if !ok {
+			return nil, errors.New("blob has no data")
+		}
+		switch field.number {
+		case 1:
+			return field.bytes, nil
+		case 3:
+			inflater, err := zlib.NewReader(bytes.NewReader(field.bytes))
+			if err != nil {
+				return nil, errors.Wrap(err, "inflating blob")
+			}
+			data, err := ioutil.ReadAll(io.LimitReader(inflater, maxBlobSize+1))
+			if err != nil {
+				return nil, errors.Wrap(err, "inflating blob")
+			}
+			if len(data) > maxBlobSize {
+				return nil, errors.New("inflated blob is too large")
+			}
+			return data, nil
+		case 4, 5, 6, 7:
+			return nil, errors.Errorf("unsupported blob compression %d, only zlib is supported", field.number)
+		}
+	}
+}
+
+func checkHeaderBlock(data []byte) error {
+	message := &protoMessage{data: data}
+	for {
+		field, ok, err := message.next()
+		if err != nil {
+			return errors.Wrap(err, "parsing header block")
+		}
+		if !ok {
+			return nil
+		}
+		// Required features.
+		if field.number == 4 && !supportedFeatures[string(field.bytes)] {
+			return errors.Errorf("unsupported PBF feature %s", field.bytes)
+		}
+	}
+}
+
+// primitiveBlock holds what is needed to decode the groups of a block.
+type primitiveBlock struct {
+	strings     [][]byte
+	granularity int64
+	latOffset   int64
+	lonOffset   int64
+}
+
+func (block *primitiveBlock) latitude(value int64) float64 {
+	return 1e-9 * float64(block.latOffset+block.granularity*value)
+}
+
+func (block *primitiveBlock) longitude(value int64) float64 {
+	return 1e-9 * float64(block.lonOffset+block.granularity*value)
+}
+
+func (block *primitiveBlock) string(index uint64) (string, error) {
+	if index >= uint64(len(block.strings)) {
+		return "", errors.Errorf("string %d is out of the string table", index)
+	}
+	return string(block.strings[index]), nil
+}
+
+func readPrimitiveBlock(data []byte, builder *extractBuilder) error {
+	block := &primitiveBlock{granularity: 100}
+	groups := [][]byte{}
+	message := &protoMessage{data: data}
+	for {
+		field, ok, err := message.next()
+		if err != nil {
+			return errors.Wrap(err, "parsing primitive block")
+		}
+		if !ok {
+			break
+		}
+		switch field.number {
+		case 1:
+			if block.strings, err = parseStringTable(field.bytes); err != nil {
+				return err
+			}
+		case 2:
+			groups = append(groups, field.bytes)
+		case 17:
+			block.granularity = int64(field.varint)
+		case 19:
+			block.latOffset = int64(field.varint)
+		case 20:
+			block.lonOffset = int64(field.varint)
+		}
+	}
+	for _, group := range groups {
+		if err := block.readGroup(group, builder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseStringTable(data []byte) ([][]byte, error) {
+	strings := [][]byte{}
+	message := &protoMessage{data: data}
+	for {
+		field, ok, err := message.next()
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing string table")
+		}
+		if !ok {
+			return strings, nil
+		}
+		if field.number == 1 {
+			strings = append(strings, field.bytes)
+		}
+	}
+}
+
+func (block *primitiveBlock) readGroup(data []byte, builder *extractBuilder) error {
+	message := &protoMessage{data: data}
+	for {
+		field, ok, err := message.next()
+		if err != nil {
+			return errors.Wrap(err, "parsing primitive group")
+		}
+		if !ok {
+			return nil
+		}
+		switch field.number {
+		case 1:
+			err = block.readNode(field.bytes, builder)
+		case 2:
+			err = block.readDenseNodes(field.bytes, builder)
+		case 3:
+			err = block.readWay(field.bytes, builder)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (block *primitiveBlock) readNode(data []byte, builder *extractBuilder) error {
+	node := Node{}
+	var keys, values []uint64
+	message := &protoMessage{data: data}
+	for {
+		field, ok, err := message.next()
+		if err != nil {
+			return errors.Wrap(err, "parsing node")
+		}
+		if !ok {
+			break
+		}
+		var decoded []uint64
+		switch field.number {
+		case 2, 3:
+			if decoded, err = field.varints(); err != nil {
+				return errors.Wrap(err, "parsing node")
+			}
+		}
+		switch field.number {
+		case 1:
+			node.ID = zigzag(field.varint)
+		case 2:
+			keys = append(keys, decoded...)
+		case 3:
+			values = append(values, decoded...)
+		case 8:
+			node.Latitude = block.latitude(zigzag(field.varint))
+		case 9:
+			node.Longitude = block.longitude(zigzag(field.varint))
+		}
+	}
+	tags, err := block.tags(keys, values)
+	if err != nil {
+		return errors.Wrapf(err, "parsing node %d", node.ID)
+	}
+	node.Tags = tags
+	builder.node(node)
+	return nil
+}
+
+// tags returns the tags whose keys and values are at the indexes of the string table.
+func (block *primitiveBlock) tags(keys []uint64, values []uint64) (map[string]string, error) {
+	if len(keys) != len(values) {
+		return nil, errors.New("different numbers of tag keys and values")
+	}
+	tags := make(map[string]string, len(keys))
+	for i := range keys {
+		key, err := block.string(keys[i])
+		if err != nil {
+			return nil, err
+		}
+		value, err := block.string(values[i])
+		if err != nil {
+			return nil, err
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
+
+func (block *primitiveBlock) readDenseNodes(data []byte, builder *extractBuilder) error {
+	var ids, latitudes, longitudes, keysValues []uint64
+	message := &protoMessage{data: data}
+	for {
+		field, ok, err := message.next()
+		if err != nil {
+			return errors.Wrap(err, "parsing dense nodes")
+		}
+		if !ok {
+			break
+		}
+		var values []uint64
+		switch field.number {
+		case 1, 8, 9, 10:
+			if values, err = field.varints(); err != nil {
+				return errors.Wrap(err, "parsing dense nodes")
+			}
+		}
+		switch field.number {
+		case 1:
+			ids = append(ids, values...)
+		case 8:
+			latitudes = append(latitudes, values...)
+		case 9:
+			longitudes = append(longitudes, values...)
+		case 10:
+			keysValues = append(keysValues, values...)
+		}
+	}
+	if len(latitudes) != len(ids) || len(longitudes) != len(ids) {
+		return errors.New("dense nodes have different numbers of ids and coordinates")
+	}
+	decodedLatitudes, decodedLongitudes := deltas(latitudes), deltas(longitudes)
+	for i, id := range deltas(ids) {
+		node := Node{ID: id, Latitude: block.latitude(decodedLatitudes[i]), Longitude: block.longitude(decodedLongitudes[i])}
+		// The keys and values of the tags of every node, one after the other, with a 0 after those of
+		// each node. It is empty when no node has tags.
+		var keys, values []uint64
+		for len(keysValues) > 0 && keysValues[0] != 0 {
+			if len(keysValues) < 2 {
+				return errors.New("dense nodes have a tag key without a value")
+			}
+			keys = append(keys, keysValues[0])
+			values = append(values, keysValues[1])
+			keysValues = keysValues[2:]
+		}
+		if len(keysValues) > 0 {
+			keysValues = keysValues[1:]
+		}
+		tags, err := block.tags(keys, values)
+		if err != nil {
+			return errors.Wrapf(err, "parsing node %d", id)
+		}
+		node.Tags = tags
+		builder.node(node)
+	}
+	return nil
+}
+
+func (block *primitiveBlock) readWay(data []byte, builder *extractBuilder) error {
+	way := Way{}
+	var keys, values, refs []uint64
+	message := &protoMessage{data: data}
+	for {
+		field, ok, err := message.next()
+		if err != nil {
+			return errors.Wrap(err, "parsing way")
+		}
+		if !ok {
+			break
+		}
+		var decoded []uint64
+		switch field.number {
+		case 2, 3, 8:
+			if decoded, err = field.varints(); err != nil {
+				return errors.Wrap(err, "parsing way")
+			}
+		}
+		switch field.number {
+		case 1:
+			way.ID = int64(field.varint)
+		case 2:
+			keys = append(keys, decoded...)
+		case 3:
+			values = append(values, decoded...)
+		case 8:
+			refs = append(refs, decoded...)
+		}
+	}
+	tags, err := block.tags(keys, values)
+	if err != nil {
+		return errors.Wrapf(err, "parsing way %d", way.ID)
+	}
+	way.Tags = tags
+	way.Nodes = deltas(refs)
+	builder.way(way)
+	return nil
+}
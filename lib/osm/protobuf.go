@@ -0,0 +1,133 @@
+package osm
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Wire types of protocol buffers fields.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// protoField is a field of a protocol buffers message. Varint holds the value of varint fields and
+// Bytes the value of length-delimited ones. Fixed-size fields are skipped.
+type protoField struct {
+	number   int
+	wireType int
+	varint   uint64
+	bytes    []byte
+}
+
+// protoMessage iterates over the fields of an encoded protocol buffers message. Only what the PBF
+// format needs is supported.
+type protoMessage struct {
+	data []byte
+}
+
+// next returns the next field of the message, or false at its end.
+func (message *protoMessage) next() (protoField, bool, error) {
+	if len(message.data) == 0 {
+		return protoField{}, false, nil
+	}
+	key, err := message.varint()
+	if err != nil {
+		return protoField{}, false, err
+	}
+	field := protoField{number: int(key >> 3), wireType: int(key & 7)}
+	switch field.wireType {
+	case wireVarint:
+		field.varint, err = message.varint()
+	case wireBytes:
+		var length uint64
+		length, err = message.varint()
+		if err == nil {
+			if length > uint64(len(message.data)) {
+				return protoField{}, false, errTruncated
+			}
+			field.bytes = message.data[:length]
+			message.data = message.data[length:]
+		}
+	case wireFixed64:
+		err = message.skip(8)
+	case wireFixed32:
+		err = message.skip(4)
+	default:
+		err = errors.Errorf("unsupported protobuf wire type %d", field.wireType)
+	}
+	if err != nil {
+		return protoField{}, false, err
+	}
+	return field, true, nil
+}
+
+func (message *protoMessage) varint() (uint64, error) {
+	value, size := decodeVarint(message.data)
+	if size == 0 {
+		return 0, errTruncated
+	}
+	message.data = message.data[size:]
+	return value, nil
+}
+
+func (message *protoMessage) skip(size int) error {
+	if len(message.data) < size {
+		return errTruncated
+	}
+	message.data = message.data[size:]
+	return nil
+}
+
+// decodeVarint returns the varint at the start of the data and its size in bytes, which is 0 when the
+// data ends before it does.
+func decodeVarint(data []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		value |= uint64(data[i]&0x7f) << (7 * uint(i))
+		if data[i] < 0x80 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// zigzag decodes a sint64 value.
+func zigzag(value uint64) int64 {
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+// varints returns the values of a repeated varint field, which is packed when it is length-delimited.
+func (field protoField) varints() ([]uint64, error) {
+	if field.wireType == wireVarint {
+		return []uint64{field.varint}, nil
+	}
+	if field.wireType != wireBytes {
+		return nil, errors.Errorf("field %d is not a varint", field.number)
+	}
+	values := []uint64{}
+	for data := field.bytes; len(data) > 0; {
+		value, size := decodeVarint(data)
+		if size == 0 {
+			return nil, errTruncated
+		}
+		values = append(values, value)
+		data = data[size:]
+	}
+	return values, nil
+}
+
+// deltas decodes the values of a repeated sint64 field where every value is the difference from the
+// previous one.
+func deltas(values []uint64) []int64 {
+	decoded := make([]int64, len(values))
+	var previous int64
+	for i, value := range values {
+		previous += zigzag(value)
+		decoded[i] = previous
+	}
+	return decoded
+}
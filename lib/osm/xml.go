@@ -0,0 +1,79 @@
+package osm
+
+import (
+	"encoding/xml"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+type xmlTag struct {
+	Key   string `xml:"k,attr"`
+	Value string `xml:"v,attr"`
+}
+
+type xmlNode struct {
+	ID        int64    `xml:"id,attr"`
+	Latitude  float64  `xml:"lat,attr"`
+	Longitude float64  `xml:"lon,attr"`
+	Tags      []xmlTag `xml:"tag"`
+}
+
+type xmlWay struct {
+	ID   int64 `xml:"id,attr"`
+	Refs []struct {
+		Ref int64 `xml:"ref,attr"`
+	} `xml:"nd"`
+	Tags []xmlTag `xml:"tag"`
+}
+
+// ReadXML reads an extract in the OSM XML format, keeping the ways and tagged nodes accepted by the
+// filter. Elements are decoded one at a time, so the whole document is never in memory.
+func ReadXML(reader io.Reader, filter Filter) (*Extract, error) {
+	builder := newExtractBuilder(filter)
+	decoder := xml.NewDecoder(reader)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding XML")
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "node":
+			node := xmlNode{}
+			if err := decoder.DecodeElement(&node, &start); err != nil {
+				return nil, errors.Wrap(err, "decoding node")
+			}
+			builder.node(Node{ID: node.ID, Latitude: node.Latitude, Longitude: node.Longitude, Tags: xmlTags(node.Tags)})
+		case "way":
+			way := xmlWay{}
+			if err := decoder.DecodeElement(&way, &start); err != nil {
+				return nil, errors.Wrap(err, "decoding way")
+			}
+			nodes := make([]int64, len(way.Refs))
+			for i, ref := range way.Refs {
+				nodes[i] = ref.Ref
+			}
+			builder.way(Way{ID: way.ID, Nodes: nodes, Tags: xmlTags(way.Tags)})
+		case "relation":
+			if err := decoder.Skip(); err != nil {
+				return nil, errors.Wrap(err, "skipping relation")
+			}
+		}
+	}
+	return builder.extract(), nil
+}
+
+func xmlTags(list []xmlTag) map[string]string {
+	tags := make(map[string]string, len(list))
+	for _, tag := range list {
+		tags[tag.Key] = tag.Value
+	}
+	return tags
+}
@@ -0,0 +1,165 @@
+package roads
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Kilometers per mile, for speed limits tagged in mph.
+const kmPerMile = 1.609344
+
+// Profile describes how a mode of transport uses the roads.
+type Profile struct {
+	Name string
+	// Speed in km/h on each kind of highway. Highways missing from it are not used.
+	Speeds map[string]float64
+	// Tags that allow or forbid the mode, from the most specific, overriding the access tag.
+	AccessTags []string
+	// Whether oneway streets can only be travelled one way.
+	Oneway bool
+	// Whether maxspeed tags replace the speed of the highway.
+	MaxSpeed bool
+	// Speed in km/h between a position and the closest node of the network.
+	AccessSpeed float64
+}
+
+// Driving follows the roads open to cars, at typical urban speeds.
+var Driving = &Profile{
+	Name: "driving",
+	Speeds: map[string]float64{
+		"motorway":       100,
+		"motorway_link":  60,
+		"trunk":          80,
+		"trunk_link":     50,
+		"primary":        60,
+		"primary_link":   50,
+		"secondary":      50,
+		"secondary_link": 40,
+		"tertiary":       40,
+		"tertiary_link":  30,
+		"unclassified":   30,
+		"residential":    30,
+		"road":           30,
+		"living_street":  10,
+		"service":        15,
+	},
+	AccessTags:  []string{"motorcar", "motor_vehicle", "vehicle"},
+	Oneway:      true,
+	MaxSpeed:    true,
+	AccessSpeed: 15,
+}
+
+// Walking follows every road and path open to pedestrians except motorways, in both directions.
+var Walking = &Profile{
+	Name: "walking",
+	Speeds: map[string]float64{
+		"primary":        5,
+		"primary_link":   5,
+		"secondary":      5,
+		"secondary_link": 5,
+		"tertiary":       5,
+		"tertiary_link":  5,
+		"unclassified":   5,
+		"residential":    5,
+		"road":           5,
+		"living_street":  5,
+		"service":        5,
+		"pedestrian":     5,
+		"footway":        5,
+		"path":           5,
+		"track":          5,
+		"steps":          3,
+	},
+	AccessTags:  []string{"foot"},
+	AccessSpeed: 5,
+}
+
+var profiles = map[string]*Profile{Driving.Name: Driving, Walking.Name: Walking}
+
+// ProfileByName returns the driving or walking profile.
+func ProfileByName(name string) (*Profile, error) {
+	profile, ok := profiles[name]
+	if !ok {
+		return nil, errors.Errorf("unknown routing profile %s", name)
+	}
+	return profile, nil
+}
+
+// Accepts reports whether the mode can use a way with the tags. It is meant to filter the ways read
+// from an extract.
+func (profile *Profile) Accepts(tags map[string]string) bool {
+	if _, ok := profile.Speeds[tags["highway"]]; !ok || tags["area"] == "yes" {
+		return false
+	}
+	for _, tag := range profile.AccessTags {
+		if allowed, ok := access(tags[tag]); ok {
+			return allowed
+		}
+	}
+	allowed, ok := access(tags["access"])
+	return allowed || !ok
+}
+
+// access reports whether an access tag allows or forbids using a way, or false when it does neither.
+func access(value string) (bool, bool) {
+	switch value {
+	case "no", "private":
+		return false, true
+	case "yes", "designated", "permissive", "destination":
+		return true, true
+	}
+	return false, false
+}
+
+// speed returns the speed in km/h on a way.
+func (profile *Profile) speed(tags map[string]string) float64 {
+	if profile.MaxSpeed {
+		if maxSpeed, ok := parseMaxSpeed(tags["maxspeed"]); ok {
+			return maxSpeed
+		}
+	}
+	return profile.Speeds[tags["highway"]]
+}
+
+// directions reports whether a way can be travelled along and against the order of its nodes.
+func (profile *Profile) directions(tags map[string]string) (bool, bool) {
+	if !profile.Oneway {
+		return true, true
+	}
+	switch tags["oneway"] {
+	case "yes", "true", "1":
+		return true, false
+	case "-1", "reverse":
+		return false, true
+	case "no", "false", "0":
+		return true, true
+	}
+	if tags["junction"] == "roundabout" || tags["highway"] == "motorway" {
+		return true, false
+	}
+	return true, true
+}
+
+// parseMaxSpeed parses speed limits such as 60 or 40 mph.
+func parseMaxSpeed(value string) (float64, bool) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0, false
+	}
+	speed, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || speed <= 0 {
+		return 0, false
+	}
+	if len(fields) == 2 {
+		switch fields[1] {
+		case "mph":
+			speed *= kmPerMile
+		case "km/h", "kmh":
+		default:
+			return 0, false
+		}
+	}
+	return speed, true
+}
@@ -0,0 +1,260 @@
+// Package roads finds the shortest paths along the road network of an OpenStreetMap extract.
+package roads
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+	"github.com/NJRodriguez/shiny-waddle/lib/kdtree"
+	"github.com/NJRodriguez/shiny-waddle/lib/osm"
+)
+
+// DefaultMaxSnapDistanceKm is how far from the network positions can be routed from by default.
+const DefaultMaxSnapDistanceKm = 1.0
+
+type edge struct {
+	to         int
+	distanceKm float64
+	seconds    float64
+}
+
+// Network is the road graph of a profile. It is safe for concurrent use once built.
+type Network struct {
+	Profile *Profile
+	// Positions farther than this from every node of the network can't be routed.
+	MaxSnapDistanceKm float64
+
+	latitudes  []float64
+	longitudes []float64
+	// The edges leaving node i are edges[offsets[i]:offsets[i+1]].
+	offsets []int
+	edges   []edge
+	tree    *kdtree.Tree
+}
+
+// Route is the shortest path between two positions.
+type Route struct {
+	DistanceKm      float64
+	DurationSeconds float64
+}
+
+// Load reads the ways that the profile can use from an extract and builds their network.
+func Load(path string, profile *Profile) (*Network, error) {
+	extract, err := osm.Load(path, profile.Accepts)
+	if err != nil {
+		return nil, err
+	}
+	return Build(extract, profile), nil
+}
+
+// Build builds the network of the ways of the extract that the profile can use. Only the largest
+// connected part of them is kept, so that positions aren't snapped to isolated pieces of road that
+// lead nowhere, such as those cut by the border of the extract.
+func Build(extract *osm.Extract, profile *Profile) *Network {
+	type link struct {
+		from, to int
+		edge     edge
+	}
+	indexes := map[int64]int{}
+	nodes := []osm.Node{}
+	index := func(node osm.Node) int {
+		if i, ok := indexes[node.ID]; ok {
+			return i
+		}
+		indexes[node.ID] = len(nodes)
+		nodes = append(nodes, node)
+		return len(nodes) - 1
+	}
+	links := []link{}
+	for _, way := range extract.Ways {
+		if !profile.Accepts(way.Tags) {
+			continue
+		}
+		speed := profile.speed(way.Tags)
+		forward, backward := profile.directions(way.Tags)
+		for i := 1; i < len(way.Nodes); i++ {
+			// Extracts cut at a border leave out the nodes outside of it.
+			from, fromOK := extract.Nodes[way.Nodes[i-1]]
+			to, toOK := extract.Nodes[way.Nodes[i]]
+			if !fromOK || !toOK {
+				continue
+			}
+			distance := geo.Distance(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+			seconds := distance / speed * 3600
+			if forward {
+				links = append(links, link{index(from), index(to), edge{distanceKm: distance, seconds: seconds}})
+			}
+			if backward {
+				links = append(links, link{index(to), index(from), edge{distanceKm: distance, seconds: seconds}})
+			}
+		}
+	}
+	kept := largestComponent(len(nodes), func(visit func(a int, b int)) {
+		for _, link := range links {
+			visit(link.from, link.to)
+		}
+	})
+	network := &Network{Profile: profile, MaxSnapDistanceKm: DefaultMaxSnapDistanceKm}
+	renumbered := make([]int, len(nodes))
+	points := []kdtree.Point{}
+	for i, node := range nodes {
+		renumbered[i] = -1
+		if kept[i] {
+			renumbered[i] = len(network.latitudes)
+			points = append(points, kdtree.Point{ID: strconv.Itoa(renumbered[i]), Latitude: node.Latitude, Longitude: node.Longitude})
+			network.latitudes = append(network.latitudes, node.Latitude)
+			network.longitudes = append(network.longitudes, node.Longitude)
+		}
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		return renumbered[links[i].from] < renumbered[links[j].from]
+	})
+	network.offsets = make([]int, len(network.latitudes)+1)
+	for _, link := range links {
+		if renumbered[link.from] < 0 {
+			continue
+		}
+		link.edge.to = renumbered[link.to]
+		network.edges = append(network.edges, link.edge)
+		network.offsets[renumbered[link.from]+1]++
+	}
+	for i := 1; i < len(network.offsets); i++ {
+		network.offsets[i] += network.offsets[i-1]
+	}
+	network.tree = kdtree.New(points)
+	return network
+}
+
+// largestComponent returns which nodes are in the largest set of nodes connected by the links,
+// ignoring their direction.
+func largestComponent(nodes int, links func(visit func(a int, b int))) []bool {
+	parents := make([]int, nodes)
+	for i := range parents {
+		parents[i] = i
+	}
+	var find func(node int) int
+	find = func(node int) int {
+		if parents[node] != node {
+			parents[node] = find(parents[node])
+		}
+		return parents[node]
+	}
+	links(func(a int, b int) {
+		parents[find(a)] = find(b)
+	})
+	sizes := make([]int, nodes)
+	largest := -1
+	for i := range parents {
+		root := find(i)
+		sizes[root]++
+		if largest < 0 || sizes[root] > sizes[largest] {
+			largest = root
+		}
+	}
+	kept := make([]bool, nodes)
+	for i := range parents {
+		kept[i] = find(i) == largest
+	}
+	return kept
+}
+
+// Nodes returns the number of nodes of the network.
+func (network *Network) Nodes() int {
+	return len(network.latitudes)
+}
+
+// Edges returns the number of directed edges of the network.
+func (network *Network) Edges() int {
+	return len(network.edges)
+}
+
+// Route finds the shortest path between two positions, from and to the nodes of the network closest
+// to them. It returns false when either position is too far from the network or there is no path
+// between them, as with oneway streets leading out of the extract.
+//
+// The search is A*, guided by the straight line distance to the destination, which is never longer
+// than a path along the roads.
+func (network *Network) Route(fromLatitude float64, fromLongitude float64, toLatitude float64, toLongitude float64) (Route, bool) {
+	from, fromDistance, ok := network.snap(fromLatitude, fromLongitude)
+	if !ok {
+		return Route{}, false
+	}
+	to, toDistance, ok := network.snap(toLatitude, toLongitude)
+	if !ok {
+		return Route{}, false
+	}
+	distance, seconds, ok := network.shortestPath(from, to)
+	if !ok {
+		return Route{}, false
+	}
+	access := fromDistance + toDistance
+	return Route{
+		DistanceKm:      distance + access,
+		DurationSeconds: seconds + access/network.Profile.AccessSpeed*3600,
+	}, true
+}
+
+// snap returns the node closest to the position and its distance from it.
+func (network *Network) snap(latitude float64, longitude float64) (int, float64, bool) {
+	point, ok := network.tree.Nearest(latitude, longitude)
+	if !ok {
+		return 0, 0, false
+	}
+	node, _ := strconv.Atoi(point.ID)
+	distance := geo.Distance(latitude, longitude, point.Latitude, point.Longitude)
+	if network.MaxSnapDistanceKm > 0 && distance > network.MaxSnapDistanceKm {
+		return 0, 0, false
+	}
+	return node, distance, true
+}
+
+func (network *Network) shortestPath(from int, to int) (float64, float64, bool) {
+	distances := map[int]float64{from: 0}
+	durations := map[int]float64{from: 0}
+	settled := map[int]bool{}
+	estimate := func(node int) float64 {
+		return geo.Distance(network.latitudes[node], network.longitudes[node], network.latitudes[to], network.longitudes[to])
+	}
+	queue := &priorityQueue{{node: from, priority: estimate(from)}}
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(queueItem).node
+		if current == to {
+			return distances[to], durations[to], true
+		}
+		if settled[current] {
+			continue
+		}
+		settled[current] = true
+		for _, next := range network.edges[network.offsets[current]:network.offsets[current+1]] {
+			distance := distances[current] + next.distanceKm
+			if known, ok := distances[next.to]; ok && known <= distance {
+				continue
+			}
+			distances[next.to] = distance
+			durations[next.to] = durations[current] + next.seconds
+			heap.Push(queue, queueItem{node: next.to, priority: distance + estimate(next.to)})
+		}
+	}
+	return math.Inf(1), math.Inf(1), false
+}
+
+type queueItem struct {
+	node     int
+	priority float64
+}
+
+type priorityQueue []queueItem
+
+func (queue priorityQueue) Len() int            { return len(queue) }
+func (queue priorityQueue) Less(i, j int) bool  { return queue[i].priority < queue[j].priority }
+func (queue priorityQueue) Swap(i, j int)       { queue[i], queue[j] = queue[j], queue[i] }
+func (queue *priorityQueue) Push(x interface{}) { *queue = append(*queue, x.(queueItem)) }
+func (queue *priorityQueue) Pop() interface{} {
+	old := *queue
+	item := old[len(old)-1]
+	*queue = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,222 @@
+package roads
+
+import (
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+	"github.com/NJRodriguez/shiny-waddle/lib/osm"
+	"github.com/stretchr/testify/require"
+)
+
+// Degrees per kilometer at the equator.
+var degreesPerKm = 1 / geo.Distance(0, 0, 0, 1)
+
+// extractBuilder makes extracts with nodes in km east and north of the origin.
+type extractBuilder struct {
+	extract *osm.Extract
+}
+
+func newExtract() *extractBuilder {
+	return &extractBuilder{&osm.Extract{Nodes: map[int64]osm.Node{}, Ways: []osm.Way{}}}
+}
+
+func (builder *extractBuilder) node(id int64, xKm float64, yKm float64) *extractBuilder {
+	builder.extract.Nodes[id] = osm.Node{ID: id, Latitude: yKm * degreesPerKm, Longitude: xKm * degreesPerKm}
+	return builder
+}
+
+func (builder *extractBuilder) way(tags map[string]string, nodes ...int64) *extractBuilder {
+	builder.extract.Ways = append(builder.extract.Ways, osm.Way{ID: int64(len(builder.extract.Ways) + 1), Nodes: nodes, Tags: tags})
+	return builder
+}
+
+func residential(extra ...string) map[string]string {
+	tags := map[string]string{"highway": "residential"}
+	for i := 0; i+1 < len(extra); i += 2 {
+		tags[extra[i]] = extra[i+1]
+	}
+	return tags
+}
+
+// riverExtract has two streets on either bank of a river, joined by a bridge 5 km upstream.
+func riverExtract() *osm.Extract {
+	return newExtract().
+		node(1, 0, 0).node(2, 5, 0).
+		node(3, 0, 1).node(4, 5, 1).
+		way(residential(), 1, 2).
+		way(residential(), 3, 4).
+		way(residential("bridge", "yes"), 2, 4).
+		extract
+}
+
+func TestRouteFollowsTheRoads(t *testing.T) {
+	network := Build(riverExtract(), Driving)
+	require.Equal(t, 4, network.Nodes())
+	require.Equal(t, 6, network.Edges())
+	route, ok := network.Route(0, 0, 1*degreesPerKm, 0)
+	require.True(t, ok)
+	require.InDelta(t, 11, route.DistanceKm, 0.001)
+	require.InDelta(t, 11.0/30*3600, route.DurationSeconds, 0.5)
+}
+
+func TestRouteAddsTheWayToTheNetwork(t *testing.T) {
+	network := Build(riverExtract(), Walking)
+	route, ok := network.Route(-0.5*degreesPerKm, 0, 1*degreesPerKm, 0)
+	require.True(t, ok)
+	require.InDelta(t, 11.5, route.DistanceKm, 0.001)
+	require.InDelta(t, 11.5/5*3600, route.DurationSeconds, 0.5)
+
+	_, ok = network.Route(-2*degreesPerKm, 0, 1*degreesPerKm, 0)
+	require.False(t, ok)
+}
+
+func TestRouteRespectsOnewayStreetsWhenDriving(t *testing.T) {
+	extract := newExtract().
+		node(1, 0, 0).node(2, 1, 0).node(3, 1, 1).node(4, 0, 1).
+		way(residential("oneway", "yes"), 2, 1).
+		way(residential(), 2, 3, 4, 1).
+		extract
+	driving, ok := Build(extract, Driving).Route(0, 0, 0, 1*degreesPerKm)
+	require.True(t, ok)
+	require.InDelta(t, 3, driving.DistanceKm, 0.001)
+	walking, ok := Build(extract, Walking).Route(0, 0, 0, 1*degreesPerKm)
+	require.True(t, ok)
+	require.InDelta(t, 1, walking.DistanceKm, 0.001)
+}
+
+func TestBuildKeepsTheLargestConnectedRoads(t *testing.T) {
+	extract := newExtract().
+		node(1, 0, 0).node(2, 1, 0).node(3, 2, 0).
+		node(4, 0, 0.1).node(5, 0.5, 0.1).
+		node(6, 3, 0).
+		way(residential(), 1, 2, 3).
+		way(residential(), 4, 5).
+		// Node 7 was left out of the extract.
+		way(residential(), 3, 7, 6).
+		extract
+	network := Build(extract, Driving)
+	require.Equal(t, 3, network.Nodes())
+	route, ok := network.Route(0.1*degreesPerKm, 0, 0, 2*degreesPerKm)
+	require.True(t, ok)
+	require.InDelta(t, 2.1, route.DistanceKm, 0.001)
+}
+
+func TestBuildSkipsWaysTheProfileCantUse(t *testing.T) {
+	extract := newExtract().
+		node(1, 0, 0).node(2, 1, 0).node(3, 1, 1).node(4, 0, 1).
+		way(residential("access", "private"), 1, 2).
+		way(map[string]string{"highway": "footway"}, 1, 4).
+		way(residential(), 2, 3, 4).
+		way(map[string]string{"highway": "motorway", "foot": "yes"}, 1, 3).
+		extract
+	// The private street and the footway are left out when driving, which follows the motorway one way
+	// only.
+	driving := Build(extract, Driving)
+	require.Equal(t, 4, driving.Nodes())
+	require.Equal(t, 5, driving.Edges())
+	// The private street and the motorway are left out when walking.
+	walking := Build(extract, Walking)
+	require.Equal(t, 4, walking.Nodes())
+	require.Equal(t, 6, walking.Edges())
+}
+
+func TestProfileAccessTags(t *testing.T) {
+	require.True(t, Walking.Accepts(map[string]string{"highway": "footway"}))
+	require.False(t, Driving.Accepts(map[string]string{"highway": "footway"}))
+	require.False(t, Driving.Accepts(map[string]string{"highway": "residential", "access": "no"}))
+	require.True(t, Driving.Accepts(map[string]string{"highway": "residential", "access": "no", "motor_vehicle": "yes"}))
+	require.False(t, Walking.Accepts(map[string]string{"highway": "residential", "foot": "no"}))
+	require.False(t, Walking.Accepts(map[string]string{"highway": "pedestrian", "area": "yes"}))
+}
+
+func TestParseMaxSpeed(t *testing.T) {
+	cases := map[string]float64{"60": 60, "40 km/h": 40, "30 mph": 30 * kmPerMile, "": 0, "none": 0, "AR:urban": 0}
+	for value, expected := range cases {
+		speed, ok := parseMaxSpeed(value)
+		require.Equal(t, expected > 0, ok, value)
+		require.InDelta(t, expected, speed, 1e-9, value)
+	}
+	tags := residential("maxspeed", "60")
+	require.Equal(t, 60.0, Driving.speed(tags))
+	require.Equal(t, 5.0, Walking.speed(tags))
+}
+
+func TestProfileByName(t *testing.T) {
+	profile, err := ProfileByName("walking")
+	require.NoError(t, err)
+	require.Equal(t, Walking, profile)
+	_, err = ProfileByName("flying")
+	require.Error(t, err)
+}
+
+func TestRouteMatchesBellmanFord(t *testing.T) {
+	random := rand.New(rand.NewSource(3))
+	builder := newExtract()
+	const size = 8
+	id := func(x int, y int) int64 { return int64(y*size + x + 1) }
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			builder.node(id(x, y), float64(x)+random.Float64()*0.4, float64(y)+random.Float64()*0.4)
+		}
+	}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if x+1 < size && random.Float64() < 0.8 {
+				builder.way(residential("oneway", []string{"no", "yes", "-1"}[random.Intn(3)]), id(x, y), id(x+1, y))
+			}
+			if y+1 < size && random.Float64() < 0.8 {
+				builder.way(residential(), id(x, y), id(x, y+1))
+			}
+		}
+	}
+	network := Build(builder.extract, Driving)
+	for round := 0; round < 20; round++ {
+		from, to := random.Intn(network.Nodes()), random.Intn(network.Nodes())
+		expected := bellmanFord(network, from)[to]
+		distance, _, ok := network.shortestPath(from, to)
+		require.Equal(t, !math.IsInf(expected, 1), ok)
+		if ok {
+			require.InDelta(t, expected, distance, 1e-9)
+		}
+	}
+}
+
+func bellmanFord(network *Network, from int) []float64 {
+	distances := make([]float64, network.Nodes())
+	for i := range distances {
+		distances[i] = math.Inf(1)
+	}
+	distances[from] = 0
+	for round := 0; round < network.Nodes(); round++ {
+		for node := 0; node < network.Nodes(); node++ {
+			for _, next := range network.edges[network.offsets[node]:network.offsets[node+1]] {
+				distances[next.to] = math.Min(distances[next.to], distances[node]+next.distanceKm)
+			}
+		}
+	}
+	return distances
+}
+
+func TestLoadReadsAnExtract(t *testing.T) {
+	dir, err := ioutil.TempDir("", "roads")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "extract.osm")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`<osm>
+  <node id="1" lat="0" lon="0"/>
+  <node id="2" lat="0" lon="0.01"/>
+  <node id="3" lat="0.01" lon="0.01"/>
+  <way id="1"><nd ref="1"/><nd ref="2"/><tag k="highway" v="primary"/></way>
+  <way id="2"><nd ref="2"/><nd ref="3"/><tag k="waterway" v="river"/></way>
+</osm>`), 0644))
+	network, err := Load(path, Driving)
+	require.NoError(t, err)
+	require.Equal(t, 2, network.Nodes())
+	_, err = Load(filepath.Join(dir, "missing.osm.pbf"), Driving)
+	require.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package route
+
+import (
+	"math"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+)
+
+// Rounds of 2-opt improvement applied to a route before giving up on finding a better one.
+const maxImprovementRounds = 100
+
+// Point is a location to visit or to start or end a route at.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Plan orders the visits to the stops from start, ending at end if given, and splits them into routes
+// of at most maxStops stops each, if positive. Every route starts at start and ends at end. It returns
+// the indexes of the stops of each route, in visiting order.
+//
+// A single route is built by always visiting the closest stop next and then improved with 2-opt until
+// no pair of legs can be swapped to make it shorter. It is then cut into routes of at most maxStops
+// stops, choosing the cuts that make the total distance shortest, and each route is improved again.
+func Plan(start Point, stops []Point, end *Point, maxStops int) [][]int {
+	order := improve(start, stops, end, nearestNeighbour(start, stops))
+	if maxStops <= 0 || len(order) <= maxStops {
+		return [][]int{order}
+	}
+	routes := split(start, stops, end, order, maxStops)
+	for i := range routes {
+		routes[i] = improve(start, stops, end, routes[i])
+	}
+	return routes
+}
+
+// Distance returns the length in kilometers of the route from start through the stops, in order, to
+// end if given.
+func Distance(start Point, stops []Point, end *Point, order []int) float64 {
+	total := 0.0
+	previous := start
+	for _, stop := range order {
+		total += distance(previous, stops[stop])
+		previous = stops[stop]
+	}
+	if end != nil {
+		total += distance(previous, *end)
+	}
+	return total
+}
+
+func nearestNeighbour(start Point, stops []Point) []int {
+	visited := make([]bool, len(stops))
+	order := make([]int, 0, len(stops))
+	current := start
+	for len(order) < len(stops) {
+		next := -1
+		nextDistance := math.Inf(1)
+		for i, stop := range stops {
+			if visited[i] {
+				continue
+			}
+			if d := distance(current, stop); d < nextDistance {
+				next = i
+				nextDistance = d
+			}
+		}
+		visited[next] = true
+		order = append(order, next)
+		current = stops[next]
+	}
+	return order
+}
+
+// improve applies 2-opt to the route, reversing the stretches of stops that make it shorter. The start
+// and end don't move. Without an end, the route can finish at any stop.
+func improve(start Point, stops []Point, end *Point, order []int) []int {
+	// Nodes of the path: the start, the stops and the end, which is at no distance from anywhere when
+	// the route has no end.
+	path := make([]int, 0, len(order)+2)
+	path = append(path, -1)
+	path = append(path, order...)
+	path = append(path, -2)
+	point := func(node int) (Point, bool) {
+		switch node {
+		case -1:
+			return start, true
+		case -2:
+			if end == nil {
+				return Point{}, false
+			}
+			return *end, true
+		}
+		return stops[node], true
+	}
+	cost := func(a int, b int) float64 {
+		first, ok := point(a)
+		if !ok {
+			return 0
+		}
+		second, ok := point(b)
+		if !ok {
+			return 0
+		}
+		return distance(first, second)
+	}
+	for round := 0; round < maxImprovementRounds; round++ {
+		improved := false
+		for i := 1; i < len(path)-2; i++ {
+			for j := i + 1; j < len(path)-1; j++ {
+				delta := cost(path[i-1], path[j]) + cost(path[i], path[j+1]) - cost(path[i-1], path[i]) - cost(path[j], path[j+1])
+				if delta < -1e-9 {
+					for left, right := i, j; left < right; left, right = left+1, right-1 {
+						path[left], path[right] = path[right], path[left]
+					}
+					improved = true
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return append([]int{}, path[1:len(path)-1]...)
+}
+
+// split cuts the ordered stops into consecutive routes of at most maxStops stops, each from start to
+// end, with the shortest total distance.
+func split(start Point, stops []Point, end *Point, order []int, maxStops int) [][]int {
+	// shortest[i] is the shortest distance of the routes visiting the first i stops, and cut[i] where
+	// the last of those routes begins.
+	shortest := make([]float64, len(order)+1)
+	cut := make([]int, len(order)+1)
+	for i := 1; i <= len(order); i++ {
+		shortest[i] = math.Inf(1)
+		for first := i - 1; first >= 0 && i-first <= maxStops; first-- {
+			if total := shortest[first] + Distance(start, stops, end, order[first:i]); total < shortest[i] {
+				shortest[i] = total
+				cut[i] = first
+			}
+		}
+	}
+	routes := [][]int{}
+	for i := len(order); i > 0; i = cut[i] {
+		routes = append([][]int{append([]int{}, order[cut[i]:i]...)}, routes...)
+	}
+	return routes
+}
+
+func distance(a Point, b Point) float64 {
+	return geo.Distance(a.Latitude, a.Longitude, b.Latitude, b.Longitude)
+}
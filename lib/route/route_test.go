@@ -0,0 +1,106 @@
+package route
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+	"github.com/stretchr/testify/require"
+)
+
+// Degrees per kilometer at the equator.
+var degreesPerKm = 1 / geo.Distance(0, 0, 0, 1)
+
+func at(xKm float64, yKm float64) Point {
+	return Point{Latitude: yKm * degreesPerKm, Longitude: xKm * degreesPerKm}
+}
+
+func TestPlanVisitsStopsAlongTheWay(t *testing.T) {
+	stops := []Point{at(3, 0), at(1, 0), at(4, 0), at(2, 0)}
+	routes := Plan(at(0, 0), stops, nil, 0)
+	require.Equal(t, [][]int{{1, 3, 0, 2}}, routes)
+	require.InDelta(t, 4, Distance(at(0, 0), stops, nil, routes[0]), 0.001)
+}
+
+func TestPlanRemovesCrossings(t *testing.T) {
+	// Visiting the closest stop next goes up to (0, 1) and then crosses the square diagonally.
+	stops := []Point{at(1, 0), at(0, 1), at(1, 1), at(0, 2.1), at(1, 2.1)}
+	start := at(0, 0)
+	routes := Plan(start, stops, &start, 0)
+	require.Len(t, routes, 1)
+	require.InDelta(t, 2+2*2.1, Distance(start, stops, &start, routes[0]), 0.01)
+}
+
+func TestPlanKeepsTheEnd(t *testing.T) {
+	stops := []Point{at(8, 1), at(2, -1), at(5, 1)}
+	end := at(10, 0)
+	routes := Plan(at(0, 0), stops, &end, 0)
+	require.Equal(t, [][]int{{1, 2, 0}}, routes)
+}
+
+func TestPlanIsCloseToTheShortestRoute(t *testing.T) {
+	random := rand.New(rand.NewSource(7))
+	for round := 0; round < 50; round++ {
+		start := at(random.Float64()*20, random.Float64()*20)
+		stops := make([]Point, 6)
+		for i := range stops {
+			stops[i] = at(random.Float64()*20, random.Float64()*20)
+		}
+		var end *Point
+		if round%2 == 0 {
+			end = &start
+		}
+		routes := Plan(start, stops, end, 0)
+		require.Len(t, routes, 1)
+		visited := append([]int{}, routes[0]...)
+		sort.Ints(visited)
+		require.Equal(t, []int{0, 1, 2, 3, 4, 5}, visited)
+		shortest := math.Inf(1)
+		permute([]int{0, 1, 2, 3, 4, 5}, 0, func(order []int) {
+			shortest = math.Min(shortest, Distance(start, stops, end, order))
+		})
+		require.LessOrEqual(t, Distance(start, stops, end, routes[0]), shortest*1.1)
+	}
+}
+
+func TestPlanSplitsRoutes(t *testing.T) {
+	// Two groups of stops on either side of the start: going back to the start between them is free.
+	stops := []Point{at(-10, 0), at(10, 0), at(-11, 0), at(11, 0)}
+	start := at(0, 0)
+	routes := Plan(start, stops, &start, 2)
+	require.Len(t, routes, 2)
+	for _, route := range routes {
+		require.Len(t, route, 2)
+		sort.Ints(route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i][0] < routes[j][0]
+	})
+	require.Equal(t, [][]int{{0, 2}, {1, 3}}, routes)
+}
+
+func TestPlanSplitsRoutesWithoutEnd(t *testing.T) {
+	stops := []Point{at(1, 0), at(2, 0), at(3, 0), at(4, 0), at(5, 0)}
+	routes := Plan(at(0, 0), stops, nil, 2)
+	total := 0
+	for _, route := range routes {
+		require.LessOrEqual(t, len(route), 2)
+		total += len(route)
+	}
+	require.Equal(t, 5, total)
+	require.Len(t, routes, 3)
+}
+
+func permute(order []int, from int, visit func([]int)) {
+	if from == len(order) {
+		visit(order)
+		return
+	}
+	for i := from; i < len(order); i++ {
+		order[from], order[i] = order[i], order[from]
+		permute(order, from+1, visit)
+		order[from], order[i] = order[i], order[from]
+	}
+}
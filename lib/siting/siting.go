@@ -0,0 +1,306 @@
+package siting
+
+import (
+	"math"
+	"sort"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+)
+
+const (
+	// Most demand points considered as initial locations of new sites. Larger demands are sampled.
+	maxCandidates = 250
+	// Most rounds of moving the sites to the middle of the demand they serve.
+	maxRounds = 20
+	// Iterations of the Weiszfeld algorithm used to find the middle of the demand of a site.
+	medianIterations = 50
+	// Moves shorter than this, in kilometers, end the Weiszfeld iterations.
+	medianToleranceKm = 0.001
+)
+
+// Kilometers per radian of the sphere measured by geo.Distance.
+var kmPerRadian = geo.Distance(0, 0, 1, 0) * 180 / math.Pi
+
+// Demand is a location where customers are, weighted by how many there are.
+type Demand struct {
+	Latitude  float64
+	Longitude float64
+	Weight    float64
+}
+
+// Location is an existing or proposed site.
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Site is a proposed location along with the weight of the demand that would be closest to it.
+type Site struct {
+	Location
+	Weight float64
+}
+
+// Stats summarizes the distance from the demand to its closest site.
+type Stats struct {
+	MeanKm float64
+	P95Km  float64
+}
+
+// Result holds the proposed sites and the distance to the closest site before and after adding them.
+// Without existing sites, the distances before are infinite.
+type Result struct {
+	Sites  []Site
+	Before Stats
+	After  Stats
+}
+
+type point struct {
+	xyz    [3]float64
+	weight float64
+}
+
+// Recommend proposes up to count new sites that minimize the weighted mean distance from the demand to
+// its closest site, existing or new. It solves the p-median problem heuristically: sites are added
+// greedily at the demand point that lowers the mean distance the most and then moved, round by round,
+// to the geometric median of the demand they serve until the mean distance stops improving. Fewer
+// sites are returned when adding more doesn't bring any demand closer.
+func Recommend(demand []Demand, existing []Location, count int) Result {
+	points := make([]point, len(demand))
+	for i, location := range demand {
+		points[i] = point{xyz: toXYZ(location.Latitude, location.Longitude), weight: location.Weight}
+	}
+	// Distance from each demand point to its closest existing site, which never moves.
+	base := make([]float64, len(points))
+	for i := range base {
+		base[i] = math.Inf(1)
+	}
+	for _, location := range existing {
+		xyz := toXYZ(location.Latitude, location.Longitude)
+		for i := range points {
+			base[i] = math.Min(base[i], distance(points[i].xyz, xyz))
+		}
+	}
+	result := Result{Before: stats(points, base)}
+
+	sites := greedySites(points, base, count)
+	sites = refineSites(points, base, sites)
+
+	closest, weights := assign(points, base, sites)
+	result.After = stats(points, closest)
+	result.Sites = make([]Site, len(sites))
+	for i, xyz := range sites {
+		latitude, longitude := fromXYZ(xyz)
+		// Rounded to about a centimeter, dropping the noise of the conversions to and from the sphere.
+		result.Sites[i] = Site{
+			Location: Location{Latitude: math.Round(latitude*1e7) / 1e7, Longitude: math.Round(longitude*1e7) / 1e7},
+			Weight:   weights[i],
+		}
+	}
+	sort.SliceStable(result.Sites, func(i, j int) bool {
+		return result.Sites[i].Weight > result.Sites[j].Weight
+	})
+	return result
+}
+
+// greedySites adds sites one at a time at the candidate demand point that lowers the total weighted
+// distance the most.
+func greedySites(points []point, base []float64, count int) [][3]float64 {
+	stride := (len(points) + maxCandidates - 1) / maxCandidates
+	if stride < 1 {
+		stride = 1
+	}
+	current := append([]float64{}, base...)
+	cost := totalCost(points, current)
+	sites := [][3]float64{}
+	for len(sites) < count {
+		best := -1
+		bestCost := cost
+		for candidate := 0; candidate < len(points); candidate += stride {
+			candidateCost := 0.0
+			for i := range points {
+				candidateCost += points[i].weight * math.Min(current[i], distance(points[i].xyz, points[candidate].xyz))
+			}
+			if candidateCost < bestCost {
+				best = candidate
+				bestCost = candidateCost
+			}
+		}
+		if best < 0 {
+			break
+		}
+		sites = append(sites, points[best].xyz)
+		for i := range points {
+			current[i] = math.Min(current[i], distance(points[i].xyz, points[best].xyz))
+		}
+		cost = bestCost
+	}
+	return sites
+}
+
+// refineSites moves every site to the geometric median of the demand it serves, as long as it lowers
+// the total weighted distance.
+func refineSites(points []point, base []float64, sites [][3]float64) [][3]float64 {
+	closest, _ := assign(points, base, sites)
+	cost := totalCost(points, closest)
+	for round := 0; round < maxRounds; round++ {
+		served := make([][]int, len(sites))
+		for i := range points {
+			if site := closestSite(points[i].xyz, sites); site >= 0 && distance(points[i].xyz, sites[site]) < base[i] {
+				served[site] = append(served[site], i)
+			}
+		}
+		moved := make([][3]float64, len(sites))
+		for site := range sites {
+			moved[site] = geometricMedian(points, served[site], sites[site])
+		}
+		closest, _ = assign(points, base, moved)
+		movedCost := totalCost(points, closest)
+		if movedCost >= cost {
+			break
+		}
+		sites, cost = moved, movedCost
+	}
+	return sites
+}
+
+// geometricMedian finds the location minimizing the weighted distance to the points with the
+// Weiszfeld algorithm, in a flat projection centered at the current location of the site. The
+// iterations start at the weighted centroid of the points.
+func geometricMedian(points []point, served []int, site [3]float64) [3]float64 {
+	if len(served) == 0 {
+		return site
+	}
+	latitude, longitude := fromXYZ(site)
+	projection := geo.NewLocalProjection(latitude, longitude)
+	xs := make([]float64, len(served))
+	ys := make([]float64, len(served))
+	x, y, totalWeight := 0.0, 0.0, 0.0
+	for i, index := range served {
+		pointLatitude, pointLongitude := fromXYZ(points[index].xyz)
+		xs[i], ys[i] = projection.Forward(pointLatitude, pointLongitude)
+		x += points[index].weight * xs[i]
+		y += points[index].weight * ys[i]
+		totalWeight += points[index].weight
+	}
+	if totalWeight == 0 {
+		return site
+	}
+	x, y = x/totalWeight, y/totalWeight
+	for iteration := 0; iteration < medianIterations; iteration++ {
+		sumX, sumY, sumWeights := 0.0, 0.0, 0.0
+		for i, index := range served {
+			d := math.Hypot(xs[i]-x, ys[i]-y)
+			// A point at the current location would divide by zero.
+			if d < 1e-9 {
+				continue
+			}
+			weight := points[index].weight / d
+			sumX += weight * xs[i]
+			sumY += weight * ys[i]
+			sumWeights += weight
+		}
+		if sumWeights == 0 {
+			break
+		}
+		nextX, nextY := sumX/sumWeights, sumY/sumWeights
+		done := math.Hypot(nextX-x, nextY-y) < medianToleranceKm
+		x, y = nextX, nextY
+		if done {
+			break
+		}
+	}
+	return toXYZ(projection.Inverse(x, y))
+}
+
+// assign returns the distance from each point to its closest site, existing or new, and the weight
+// of the points closest to each new site.
+func assign(points []point, base []float64, sites [][3]float64) ([]float64, []float64) {
+	closest := append([]float64{}, base...)
+	weights := make([]float64, len(sites))
+	for i := range points {
+		site := closestSite(points[i].xyz, sites)
+		if site < 0 {
+			continue
+		}
+		if d := distance(points[i].xyz, sites[site]); d < closest[i] {
+			closest[i] = d
+			weights[site] += points[i].weight
+		}
+	}
+	return closest, weights
+}
+
+func closestSite(xyz [3]float64, sites [][3]float64) int {
+	closest := -1
+	closestDistance := math.Inf(1)
+	for site := range sites {
+		if d := distance(xyz, sites[site]); d < closestDistance {
+			closest = site
+			closestDistance = d
+		}
+	}
+	return closest
+}
+
+func totalCost(points []point, distances []float64) float64 {
+	cost := 0.0
+	for i := range points {
+		// Points without weight don't count, even when no site serves them yet.
+		if points[i].weight > 0 {
+			cost += points[i].weight * distances[i]
+		}
+	}
+	return cost
+}
+
+// Summarize returns the weighted mean and 95th percentile of the distance from each demand point to
+// its closest site.
+func Summarize(demand []Demand, distances []float64) Stats {
+	points := make([]point, len(demand))
+	for i, location := range demand {
+		points[i] = point{weight: location.Weight}
+	}
+	return stats(points, distances)
+}
+
+// stats returns the weighted mean and 95th percentile of the distances.
+func stats(points []point, distances []float64) Stats {
+	order := make([]int, len(points))
+	totalWeight := 0.0
+	for i := range points {
+		order[i] = i
+		totalWeight += points[i].weight
+	}
+	if totalWeight == 0 {
+		return Stats{}
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return distances[order[i]] < distances[order[j]]
+	})
+	result := Stats{MeanKm: totalCost(points, distances) / totalWeight}
+	cumulative := 0.0
+	for _, i := range order {
+		cumulative += points[i].weight
+		if cumulative >= 0.95*totalWeight {
+			result.P95Km = distances[i]
+			break
+		}
+	}
+	return result
+}
+
+func toXYZ(latitude float64, longitude float64) [3]float64 {
+	lat := latitude * math.Pi / 180
+	lon := longitude * math.Pi / 180
+	return [3]float64{math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)}
+}
+
+func fromXYZ(xyz [3]float64) (float64, float64) {
+	return math.Atan2(xyz[2], math.Hypot(xyz[0], xyz[1])) * 180 / math.Pi, math.Atan2(xyz[1], xyz[0]) * 180 / math.Pi
+}
+
+// distance returns the great-circle distance in kilometers between two points on the unit sphere.
+func distance(a [3]float64, b [3]float64) float64 {
+	dot := a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+	return math.Acos(math.Max(-1, math.Min(1, dot))) * kmPerRadian
+}
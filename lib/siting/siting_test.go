@@ -0,0 +1,78 @@
+package siting
+
+import (
+	"math"
+	"testing"
+
+	"github.com/NJRodriguez/shiny-waddle/lib/geo"
+	"github.com/stretchr/testify/require"
+)
+
+// square returns demand at the corners of a square of side degrees centered at the location.
+func square(latitude float64, longitude float64, side float64, weight float64) []Demand {
+	half := side / 2
+	return []Demand{
+		{latitude - half, longitude - half, weight},
+		{latitude - half, longitude + half, weight},
+		{latitude + half, longitude - half, weight},
+		{latitude + half, longitude + half, weight},
+	}
+}
+
+func TestRecommendPlacesSitesAtTheMiddleOfEachDemandCluster(t *testing.T) {
+	demand := append(square(-34.6, -58.4, 0.02, 1), square(-31.4, -64.2, 0.02, 3)...)
+	result := Recommend(demand, nil, 2)
+
+	require.Len(t, result.Sites, 2)
+	require.InDelta(t, -31.4, result.Sites[0].Latitude, 1e-4)
+	require.InDelta(t, -64.2, result.Sites[0].Longitude, 1e-4)
+	require.Equal(t, 12.0, result.Sites[0].Weight)
+	require.InDelta(t, -34.6, result.Sites[1].Latitude, 1e-4)
+	require.InDelta(t, -58.4, result.Sites[1].Longitude, 1e-4)
+	require.Equal(t, 4.0, result.Sites[1].Weight)
+	require.True(t, math.IsInf(result.Before.MeanKm, 1))
+	halfDiagonal := geo.Distance(-34.6, -58.4, -34.59, -58.39)
+	require.InDelta(t, halfDiagonal, result.After.MeanKm, 0.05)
+	require.InDelta(t, halfDiagonal, result.After.P95Km, 0.05)
+}
+
+func TestRecommendServesTheDemandFarthestFromExistingSites(t *testing.T) {
+	demand := append(square(-34.6, -58.4, 0.02, 5), square(-31.4, -64.2, 0.02, 1)...)
+	existing := []Location{{-34.6, -58.4}}
+	result := Recommend(demand, existing, 1)
+
+	require.Len(t, result.Sites, 1)
+	require.InDelta(t, -31.4, result.Sites[0].Latitude, 1e-4)
+	require.InDelta(t, -64.2, result.Sites[0].Longitude, 1e-4)
+	require.Equal(t, 4.0, result.Sites[0].Weight)
+	require.Greater(t, result.Before.MeanKm, 100.0)
+	require.Greater(t, result.Before.P95Km, 600.0)
+	require.Less(t, result.After.MeanKm, 1.5)
+	require.Less(t, result.After.P95Km, 1.5)
+}
+
+func TestRecommendStopsWhenNewSitesDontHelp(t *testing.T) {
+	demand := []Demand{{-34.6, -58.4, 1}, {-34.6, -58.4, 2}}
+	result := Recommend(demand, []Location{{-34.6, -58.4}}, 3)
+	require.Empty(t, result.Sites)
+	require.Equal(t, Stats{}, result.Before)
+	require.Equal(t, result.Before, result.After)
+}
+
+func TestGeometricMedianIsPulledToHeavyPoints(t *testing.T) {
+	// The geometric median of three points where one carries most of the weight is that point.
+	points := []point{
+		{xyz: toXYZ(-34.6, -58.4), weight: 10},
+		{xyz: toXYZ(-34.7, -58.4), weight: 1},
+		{xyz: toXYZ(-34.6, -58.5), weight: 1},
+	}
+	latitude, longitude := fromXYZ(geometricMedian(points, []int{0, 1, 2}, toXYZ(-34.65, -58.45)))
+	require.InDelta(t, -34.6, latitude, 1e-4)
+	require.InDelta(t, -58.4, longitude, 1e-4)
+}
+
+func TestSummarizeWeighsDistances(t *testing.T) {
+	demand := []Demand{{Weight: 1}, {Weight: 1}, {Weight: 18}}
+	require.Equal(t, Stats{MeanKm: 1.5, P95Km: 2}, Summarize(demand, []float64{10, 2, 1}))
+	require.Equal(t, Stats{}, Summarize(nil, nil))
+}
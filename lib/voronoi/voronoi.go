@@ -0,0 +1,98 @@
+package voronoi
+
+import (
+	"math"
+	"sort"
+)
+
+// Point is a position in a plane.
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Cells returns the Voronoi cell of every site clipped to the boundary, a ring of points without the
+// closing point. The cell of a site is the region of the boundary closer to it than to any other site.
+// Cells outside the boundary are empty. Sites at the same position share the same cell.
+//
+// Each cell is the boundary clipped by the perpendicular bisectors with the other sites, closest first,
+// stopping once the remaining sites are too far to cut the cell.
+func Cells(sites []Point, boundary []Point) [][]Point {
+	cells := make([][]Point, len(sites))
+	others := make([]int, len(sites))
+	for i := range others {
+		others[i] = i
+	}
+	for i, site := range sites {
+		sort.Slice(others, func(a, b int) bool {
+			return squaredDistance(site, sites[others[a]]) < squaredDistance(site, sites[others[b]])
+		})
+		cell := append([]Point{}, boundary...)
+		for _, j := range others {
+			other := sites[j]
+			distance := squaredDistance(site, other)
+			if distance == 0 {
+				continue
+			}
+			// A site more than twice as far as the farthest point of the cell can't cut it.
+			if distance > 4*maxSquaredDistance(site, cell) {
+				break
+			}
+			cell = clip(cell, site, other)
+			if len(cell) == 0 {
+				break
+			}
+		}
+		cells[i] = cell
+	}
+	return cells
+}
+
+// clip keeps the part of the polygon closer to the site than to the other site, using the
+// Sutherland-Hodgman algorithm with the perpendicular bisector of both sites.
+func clip(polygon []Point, site Point, other Point) []Point {
+	middle := Point{(site.X + other.X) / 2, (site.Y + other.Y) / 2}
+	normal := Point{other.X - site.X, other.Y - site.Y}
+	side := func(point Point) float64 {
+		return (point.X-middle.X)*normal.X + (point.Y-middle.Y)*normal.Y
+	}
+	clipped := []Point{}
+	for i, current := range polygon {
+		previous := polygon[(i+len(polygon)-1)%len(polygon)]
+		currentSide, previousSide := side(current), side(previous)
+		if (currentSide <= 0) != (previousSide <= 0) {
+			t := previousSide / (previousSide - currentSide)
+			clipped = append(clipped, Point{previous.X + t*(current.X-previous.X), previous.Y + t*(current.Y-previous.Y)})
+		}
+		if currentSide <= 0 {
+			clipped = append(clipped, current)
+		}
+	}
+	if len(clipped) < 3 {
+		return nil
+	}
+	return clipped
+}
+
+// Area returns the area of a ring of points without the closing point. It is positive when the points
+// are in counterclockwise order and negative otherwise.
+func Area(ring []Point) float64 {
+	area := 0.0
+	for i, current := range ring {
+		next := ring[(i+1)%len(ring)]
+		area += current.X*next.Y - next.X*current.Y
+	}
+	return area / 2
+}
+
+func squaredDistance(a Point, b Point) float64 {
+	return (a.X-b.X)*(a.X-b.X) + (a.Y-b.Y)*(a.Y-b.Y)
+}
+
+func maxSquaredDistance(site Point, polygon []Point) float64 {
+	farthest := 0.0
+	for _, point := range polygon {
+		farthest = math.Max(farthest, squaredDistance(site, point))
+	}
+	return farthest
+}
@@ -0,0 +1,41 @@
+package voronoi
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var square = []Point{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+
+func TestCellsSplitSquareBetweenTwoSites(t *testing.T) {
+	cells := Cells([]Point{{2, 5}, {8, 5}}, square)
+	require.Len(t, cells, 2)
+	require.InDelta(t, 50, Area(cells[0]), 1e-9)
+	require.InDelta(t, 50, Area(cells[1]), 1e-9)
+	for _, point := range cells[0] {
+		require.LessOrEqual(t, point.X, 5.0)
+	}
+}
+
+func TestCellsPartitionTheBoundary(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	sites := make([]Point, 50)
+	for i := range sites {
+		sites[i] = Point{random.Float64() * 10, random.Float64() * 10}
+	}
+	total := 0.0
+	for i, cell := range Cells(sites, square) {
+		require.NotEmpty(t, cell, "site %d", i)
+		total += Area(cell)
+	}
+	require.InDelta(t, 100, total, 1e-6)
+}
+
+func TestCellsOutsideBoundaryAreEmpty(t *testing.T) {
+	cells := Cells([]Point{{5, 5}, {50, 50}, {5, 5}}, square)
+	require.InDelta(t, 100, Area(cells[0]), 1e-9)
+	require.Empty(t, cells[1])
+	require.Equal(t, cells[0], cells[2])
+}
@@ -19,6 +19,36 @@ func main() {
 			HashKey:       pulumi.String("id"),
 			ReadCapacity:  pulumi.Int(5),
 			WriteCapacity: pulumi.Int(5),
+			// Lets every API instance keep its caches in sync with the changes made by the others.
+			StreamEnabled:  pulumi.Bool(true),
+			StreamViewType: pulumi.String("NEW_AND_OLD_IMAGES"),
+		})
+		if err != nil {
+			return err
+		}
+		// Closest sucursal searches, partitioned by day and sorted by time. Kept apart from the
+		// sucursales so that recording them doesn't use the capacity of the sucursal table.
+		_, err = dynamodb.NewTable(ctx, "demand_table", &dynamodb.TableArgs{
+			Attributes: dynamodb.TableAttributeArray{
+				&dynamodb.TableAttributeArgs{
+					Name: pulumi.String("day"),
+					Type: pulumi.String("S"),
+				},
+				&dynamodb.TableAttributeArgs{
+					Name: pulumi.String("id"),
+					Type: pulumi.String("S"),
+				},
+			},
+			Name:          pulumi.String("demand_table"),
+			BillingMode:   pulumi.String("PROVISIONED"),
+			HashKey:       pulumi.String("day"),
+			RangeKey:      pulumi.String("id"),
+			ReadCapacity:  pulumi.Int(5),
+			WriteCapacity: pulumi.Int(5),
+			Ttl: &dynamodb.TableTtlArgs{
+				AttributeName: pulumi.String("expiresAt"),
+				Enabled:       pulumi.Bool(true),
+			},
 		})
 		if err != nil {
 			return err